@@ -0,0 +1,284 @@
+// apfake is a minimal ActivityPub server for testing federation locally.
+// It serves one actor and an inbox that checks and prints the signed
+// activities the blog delivers, and can follow a blog user and reply to a
+// post. The blog must run with federation.allow_insecure to reach it, off
+// in the checked-in config:
+//
+//	FEDERATION_ALLOW_INSECURE=true go run ./cmd/app
+//	go run ./cmd/apfake -blog http://localhost:8080 -follow alice -reply 1
+//
+// The key pair is generated on every start, the blog fetches the actor
+// again when a signature doesn't match.
+package main
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"blog/internal/federation"
+)
+
+type fake struct {
+	log     *slog.Logger
+	base    string
+	key     *rsa.PrivateKey
+	pubPEM  string
+	client  *http.Client
+	noteSeq atomic.Int64
+}
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:8090", "address to serve the fake actor on")
+	blog := flag.String("blog", "http://localhost:8080", "base url of the blog")
+	follow := flag.String("follow", "", "username of the blog user to follow")
+	reply := flag.Int64("reply", 0, "id of a blog post to reply to")
+	text := flag.String("text", "hello from the fediverse!", "content of the reply")
+	del := flag.String("delete", "", "id of an earlier reply to delete")
+	undo := flag.Bool("undo", false, "unfollow again before exiting")
+	flag.Parse()
+
+	log := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	privPEM, pubPEM, err := federation.GenerateKey()
+	if err != nil {
+		log.Error("generate key", "err", err)
+		os.Exit(1)
+	}
+	key, err := federation.ParsePrivateKey(privPEM)
+	if err != nil {
+		log.Error("parse key", "err", err)
+		os.Exit(1)
+	}
+
+	f := &fake{
+		log:    log,
+		base:   "http://" + *listen,
+		key:    key,
+		pubPEM: pubPEM,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /actor", f.actor)
+	mux.HandleFunc("POST /inbox", f.inbox)
+	go func() {
+		if err := http.ListenAndServe(*listen, mux); err != nil {
+			log.Error("serve", "err", err)
+			os.Exit(1)
+		}
+	}()
+	log.Info("fake actor ready", "actor", f.actorID())
+
+	var target *federation.Actor
+	if *follow != "" {
+		if target, err = f.lookup(*blog, *follow); err != nil {
+			log.Error("lookup", "err", err)
+			os.Exit(1)
+		}
+		if err := f.send(target.Inbox, f.followActivity(target.ID)); err != nil {
+			log.Error("follow", "err", err)
+			os.Exit(1)
+		}
+	}
+	if *reply != 0 {
+		inbox := fmt.Sprintf("%s/ap/inbox", *blog)
+		note := f.note(fmt.Sprintf("%s/ap/posts/%d", *blog, *reply), *text)
+		if err := f.send(inbox, f.activity("Create", note)); err != nil {
+			log.Error("reply", "err", err)
+			os.Exit(1)
+		}
+		log.Info("replied", "note", note.ID)
+	}
+	if *del != "" {
+		if err := f.send(*blog+"/ap/inbox", f.activity("Delete", *del)); err != nil {
+			log.Error("delete", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	if *undo && target != nil {
+		if err := f.send(target.Inbox, f.activity("Undo", f.followActivity(target.ID))); err != nil {
+			log.Error("undo follow", "err", err)
+		}
+	}
+}
+
+func (f *fake) actorID() string {
+	return f.base + "/actor"
+}
+
+func (f *fake) actor(w http.ResponseWriter, r *http.Request) {
+	f.write(w, &federation.Actor{
+		Context:           []string{"https://www.w3.org/ns/activitystreams", "https://w3id.org/security/v1"},
+		ID:                f.actorID(),
+		Type:              "Person",
+		PreferredUsername: "fake",
+		Inbox:             f.base + "/inbox",
+		PublicKey: federation.PublicKey{
+			ID:           f.actorID() + "#main-key",
+			Owner:        f.actorID(),
+			PublicKeyPEM: f.pubPEM,
+		},
+	})
+}
+
+// inbox verifies the signature of a delivery against the key of the
+// sending actor and prints the activity.
+func (f *fake) inbox(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var activity federation.Activity
+	if err := json.Unmarshal(body, &activity); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := f.verify(r, body, activity.Actor); err != nil {
+		f.log.Warn("rejected delivery", "err", err, "type", activity.Type, "actor", activity.Actor)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var pretty bytes.Buffer
+	json.Indent(&pretty, body, "", "  ")
+	f.log.Info("verified delivery", "type", activity.Type, "actor", activity.Actor, "object", activity.ObjectID())
+	fmt.Println(pretty.String())
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (f *fake) verify(r *http.Request, body []byte, actorID string) error {
+	sig, err := federation.ParseSignature(r, body)
+	if err != nil {
+		return err
+	}
+	var actor federation.Actor
+	if err := f.get(actorID, &actor); err != nil {
+		return err
+	}
+	if actor.PublicKey.ID != sig.KeyID || actor.PublicKey.Owner != actorID {
+		return fmt.Errorf("key %s does not belong to %s", sig.KeyID, actorID)
+	}
+	key, err := federation.ParsePublicKey(actor.PublicKey.PublicKeyPEM)
+	if err != nil {
+		return err
+	}
+	return sig.Verify(key)
+}
+
+// lookup resolves a blog user through WebFinger to its actor.
+func (f *fake) lookup(blog, username string) (*federation.Actor, error) {
+	u, err := url.Parse(blog)
+	if err != nil {
+		return nil, err
+	}
+	resource := url.QueryEscape(fmt.Sprintf("acct:%s@%s", username, u.Host))
+
+	var jrd federation.WebFinger
+	if err := f.get(blog+"/.well-known/webfinger?resource="+resource, &jrd); err != nil {
+		return nil, err
+	}
+	for _, link := range jrd.Links {
+		if link.Rel == "self" && link.Type == federation.ContentType {
+			var actor federation.Actor
+			if err := f.get(link.Href, &actor); err != nil {
+				return nil, err
+			}
+			f.log.Info("found actor", "id", actor.ID, "inbox", actor.Inbox)
+			return &actor, nil
+		}
+	}
+	return nil, errors.New("no self link in webfinger response")
+}
+
+func (f *fake) followActivity(object string) *federation.Activity {
+	activity, _ := federation.NewActivity("Follow", f.actorID()+"#follow", f.actorID(), object, nil, nil)
+	return activity
+}
+
+func (f *fake) activity(typ string, object any) *federation.Activity {
+	id := fmt.Sprintf("%s#%s-%d", f.actorID(), typ, time.Now().UnixNano())
+	activity, _ := federation.NewActivity(typ, id, f.actorID(), object, nil, nil)
+	return activity
+}
+
+func (f *fake) note(inReplyTo, content string) *federation.Object {
+	now := time.Now().UTC()
+	return &federation.Object{
+		ID:           f.base + "/notes/" + strconv.FormatInt(f.noteSeq.Add(1), 10) + "-" + strconv.FormatInt(now.Unix(), 10),
+		Type:         "Note",
+		AttributedTo: f.actorID(),
+		InReplyTo:    inReplyTo,
+		Content:      "<p>" + content + "</p>",
+		To:           []string{"https://www.w3.org/ns/activitystreams#Public"},
+		Published:    &now,
+	}
+}
+
+func (f *fake) send(inbox string, activity *federation.Activity) error {
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", federation.ContentType)
+	if err := federation.Sign(req, f.actorID()+"#main-key", f.key, body); err != nil {
+		return err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(resp.Body)
+	f.log.Info("sent", "type", activity.Type, "inbox", inbox, "status", resp.StatusCode)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", resp.Status, msg)
+	}
+	return nil
+}
+
+func (f *fake) get(rawURL string, v any) error {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", federation.ContentType)
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("get %s: %s", rawURL, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (f *fake) write(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", federation.ContentType)
+	json.NewEncoder(w).Encode(v)
+}
@@ -5,23 +5,38 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"blog/internal/api/cursor"
+	"blog/internal/api/inproc"
+	"blog/internal/api/openapi"
 	"blog/internal/config"
-	"blog/internal/handlers/url/comment"
-	"blog/internal/handlers/url/post"
-	"blog/internal/handlers/url/user"
-	"blog/internal/middlewares/auth"
+	"blog/internal/events"
+	"blog/internal/federation"
+	homefeed "blog/internal/feed"
+	"blog/internal/handlers/rpc"
+	"blog/internal/jobs/deliver"
+	"blog/internal/jobs/purge"
+	webhookjob "blog/internal/jobs/webhook"
+	webmentionjob "blog/internal/jobs/webmention"
+	"blog/internal/mention"
 	logmd "blog/internal/middlewares/log_md"
 	requestid "blog/internal/middlewares/request_id"
+	"blog/internal/notify"
 	"blog/internal/repository/redisrepo"
 	"blog/internal/repository/sqliterepo"
+	sitemapgen "blog/internal/sitemap"
+	"blog/internal/spam"
+	streams "blog/internal/stream"
 	"blog/internal/util/logger"
 	"blog/internal/util/logger/sl"
+	webhooks "blog/internal/webhook"
+	webmentions "blog/internal/webmention"
 )
 
 const (
@@ -72,6 +87,19 @@ func main() {
 	postRepo := sqlRepo.Post()
 	userRepo := sqlRepo.User()
 	commentRepo := sqlRepo.Comment()
+	revisionRepo := sqlRepo.Revision()
+	spamRepo := sqlRepo.Spam()
+	reportRepo := sqlRepo.Report()
+	auditRepo := sqlRepo.Audit()
+	sanctionRepo := sqlRepo.Sanction()
+	reactionRepo := sqlRepo.Reaction()
+	bookmarkRepo := sqlRepo.Bookmark()
+	followRepo := sqlRepo.Follow()
+	notificationRepo := sqlRepo.Notification()
+	mentionRepo := sqlRepo.Mention()
+	federationRepo := sqlRepo.Federation()
+	webmentionRepo := sqlRepo.Webmention()
+	webhookRepo := sqlRepo.Webhook()
 	log.Info("SQL repositories initialized.")
 
 	var rdb *redisrepo.RedisRepo
@@ -96,28 +124,161 @@ func main() {
 	if err := commentRepo.InitCommentDatabase(); err != nil {
 		log.Warn("Failed to initialize comment table", sl.Error(err))
 	}
+	if err := revisionRepo.InitRevisionDatabase(); err != nil {
+		log.Error("Failed to initialize post revision table", sl.Error(err))
+		os.Exit(1)
+	}
+	if err := spamRepo.InitSpamDatabase(); err != nil {
+		log.Error("Failed to initialize spam tables", sl.Error(err))
+		os.Exit(1)
+	}
+	if err := reportRepo.InitReportDatabase(); err != nil {
+		log.Error("Failed to initialize report table", sl.Error(err))
+		os.Exit(1)
+	}
+	if err := auditRepo.InitAuditDatabase(); err != nil {
+		log.Error("Failed to initialize audit log table", sl.Error(err))
+		os.Exit(1)
+	}
+	if err := sanctionRepo.InitSanctionDatabase(); err != nil {
+		log.Error("Failed to initialize user sanction table", sl.Error(err))
+		os.Exit(1)
+	}
+	if err := reactionRepo.InitReactionDatabase(); err != nil {
+		log.Error("Failed to initialize reaction tables", sl.Error(err))
+		os.Exit(1)
+	}
+	if err := bookmarkRepo.InitBookmarkDatabase(); err != nil {
+		log.Error("Failed to initialize bookmark tables", sl.Error(err))
+		os.Exit(1)
+	}
+	if err := followRepo.InitFollowDatabase(); err != nil {
+		log.Error("Failed to initialize follow table", sl.Error(err))
+		os.Exit(1)
+	}
+	if err := notificationRepo.InitNotificationDatabase(); err != nil {
+		log.Error("Failed to initialize notification table", sl.Error(err))
+		os.Exit(1)
+	}
+	if err := mentionRepo.InitMentionDatabase(); err != nil {
+		log.Error("Failed to initialize mention table", sl.Error(err))
+		os.Exit(1)
+	}
+	if err := federationRepo.InitFederationDatabase(); err != nil {
+		log.Error("Failed to initialize federation tables", sl.Error(err))
+		os.Exit(1)
+	}
+	if err := webmentionRepo.InitWebmentionDatabase(); err != nil {
+		log.Error("Failed to initialize webmention tables", sl.Error(err))
+		os.Exit(1)
+	}
+	if err := webhookRepo.InitWebhookDatabase(); err != nil {
+		log.Error("Failed to initialize webhook tables", sl.Error(err))
+		os.Exit(1)
+	}
 	log.Info("Database tables initialized successfully.")
 
-	log.Info("Registering HTTP routes...")
-	mux := http.NewServeMux()
+	if err := userRepo.PromoteAdmins(cfg.Auth.AdminEmails); err != nil {
+		log.Error("Failed to promote admin users", sl.Error(err))
+		os.Exit(1)
+	}
 
-	// Post handlers
-	mux.Handle("POST /api/post", auth.AuthMiddleware(post.Create(log, postRepo)))
-	mux.Handle("PATCH api/post/{id}", auth.AuthMiddleware(post.Update(log, postRepo)))
-	mux.Handle("DELETE /api/post/{id}", auth.AuthMiddleware(post.Delete(log, postRepo)))
-	mux.HandleFunc("GET /api/post/{id}", post.Read(log, postRepo, userRepo, rdb))
-	mux.HandleFunc("GET /api/posts", post.GetList(log, postRepo, userRepo))
+	spamClassifier := spam.New(log, spamRepo, commentRepo, userRepo, cfg.Spam)
+	homeFeed := homefeed.New(log, postRepo, followRepo, rdb, cfg.Feed)
+	mentions := mention.New(log, userRepo, mentionRepo, cfg.Mentions)
+	cursors := cursor.New(cfg.Auth.SecretKey)
+
+	sitemaps := sitemapgen.New(log, postRepo, cfg.Syndication.BaseURL, cfg.Sitemap)
+	if err := sitemaps.Load(); err != nil {
+		log.Error("Failed to load sitemap", sl.Error(err))
+		os.Exit(1)
+	}
 
-	// User handlers
-	mux.HandleFunc("POST /api/user/signup", user.SignUpHandler(log, userRepo))
-	mux.HandleFunc("POST /api/user/signin", user.SignInHandler(log, userRepo))
+	bus := events.NewBus(log)
+	homeFeed.Subscribe(bus)
+	sitemaps.Subscribe(bus)
+	notify.New(log, notificationRepo, postRepo, commentRepo, mentionRepo, sanctionRepo).Subscribe(bus)
+	hooks := webhooks.New(log, webhookRepo, postRepo, userRepo, sanctionRepo, cfg.Syndication.BaseURL, cfg.Webhooks)
+	hooks.Subscribe(bus)
+	hub := streams.New(log, rdb, cfg.Stream)
+	streams.NewRelay(hub, postRepo, userRepo, sanctionRepo).Subscribe(bus)
 
-	// Comment handlers
-	mux.HandleFunc("POST /api/comment", auth.AuthMiddleware(comment.Create(log, commentRepo)))
-	mux.HandleFunc("DELETE /api/comment/{id}", auth.AuthMiddleware(comment.Delete(log, commentRepo)))
-	mux.HandleFunc("PATCH /api/comment/{id}", auth.AuthMiddleware(comment.Update(log, commentRepo)))
-	mux.HandleFunc("GET /api/comments", comment.GetList(log, commentRepo, userRepo))
+	var fed *federation.Service
+	if cfg.Federation.Enabled {
+		fed, err = federation.New(log, federationRepo, userRepo, postRepo, commentRepo, sanctionRepo, spamClassifier,
+			mentions, bus, cfg.Syndication.BaseURL, cfg.Federation, cfg.Comments)
+		if err != nil {
+			log.Error("Failed to initialize federation", sl.Error(err))
+			os.Exit(1)
+		}
+		fed.Subscribe(bus)
+	}
 
+	var mentionQueue *webmentions.Service
+	if cfg.Webmention.Enabled {
+		mentionQueue, err = webmentions.New(log, webmentionRepo, postRepo, cfg.Syndication.BaseURL, cfg.Webmention)
+		if err != nil {
+			log.Error("Failed to initialize webmentions", sl.Error(err))
+			os.Exit(1)
+		}
+		mentionQueue.Subscribe(bus)
+	}
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	log.Info("Starting trash purge job...",
+		slog.Int("retention_days", cfg.Trash.RetentionDays),
+		slog.Duration("interval", cfg.Trash.PurgeInterval))
+	go purge.New(log, postRepo, commentRepo, cfg.Trash.Retention(), cfg.Trash.PurgeInterval).Run(jobsCtx)
+
+	if fed != nil {
+		log.Info("Starting ActivityPub delivery job...",
+			slog.Duration("interval", cfg.Federation.DeliveryInterval),
+			slog.Int("max_attempts", cfg.Federation.MaxAttempts))
+		go deliver.New(log, federationRepo, fed, fed.Queued(), cfg.Federation).Run(jobsCtx)
+	}
+
+	log.Info("Starting webhook delivery job...",
+		slog.Duration("interval", cfg.Webhooks.Interval),
+		slog.Int("max_attempts", cfg.Webhooks.MaxAttempts))
+	go webhookjob.New(log, webhookRepo, hooks, hooks.Queued(), cfg.Webhooks).Run(jobsCtx)
+
+	log.Info("Starting stream hub...", slog.Bool("redis", rdb != nil), slog.Int("max_clients", cfg.Stream.MaxClients))
+	go hub.Run(jobsCtx)
+
+	if mentionQueue != nil {
+		log.Info("Starting webmention job...",
+			slog.Duration("interval", cfg.Webmention.Interval),
+			slog.Int("max_attempts", cfg.Webmention.MaxAttempts))
+		go webmentionjob.New(log, webmentionRepo, mentionQueue, mentionQueue.Queued(), cfg.Webmention).Run(jobsCtx)
+	}
+
+	log.Info("Registering HTTP routes...", slog.Bool("validate_requests", cfg.OpenAPI.Validate))
+	mux := openapi.NewServeMux(newSpec(cfg), cfg.OpenAPI.Validate)
+	err = registerRoutes(mux, log, cfg, services{
+		repo:        sqlRepo,
+		rdb:         rdb,
+		bus:         bus,
+		cursors:     cursors,
+		mentions:    mentions,
+		spam:        spamClassifier,
+		homeFeed:    homeFeed,
+		sitemaps:    sitemaps,
+		hub:         hub,
+		hooks:       hooks,
+		fed:         fed,
+		webmentions: mentionQueue,
+	})
+	if err != nil {
+		log.Error("Failed to register HTTP routes", sl.Error(err))
+		os.Exit(1)
+	}
+
+	if err := mux.Check(); err != nil {
+		log.Error("HTTP routes don't match the OpenAPI spec", sl.Error(err))
+		os.Exit(1)
+	}
 	log.Info("HTTP routes registered.")
 
 	log.Info("Applying middlewares...")
@@ -132,6 +293,8 @@ func main() {
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
+	// streams outlive the write timeout and would keep Shutdown waiting
+	server.RegisterOnShutdown(hub.Close)
 
 	go func() {
 		log.Info("HTTP server starting...",
@@ -147,6 +310,27 @@ func main() {
 		log.Info("HTTP server stopped gracefully.")
 	}()
 
+	var grpcServer *rpc.Server
+	if cfg.GRPC.Enabled {
+		listener, err := net.Listen("tcp", cfg.GRPC.Address)
+		if err != nil {
+			log.Error("Failed to listen for gRPC", slog.String("address", cfg.GRPC.Address), sl.Error(err))
+			os.Exit(1)
+		}
+		grpcServer = rpc.New(log, sqlRepo, inproc.New(mux), cursors, cfg.GRPC)
+		go func() {
+			log.Info("gRPC server starting...",
+				slog.String("address", listener.Addr().String()),
+				slog.Bool("reflection", cfg.GRPC.Reflection),
+			)
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Error("gRPC server failed", sl.Error(err))
+				os.Exit(1)
+			}
+			log.Info("gRPC server stopped gracefully.")
+		}()
+	}
+
 	// Setting Graceful Shutdown
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM) // get Ctrl+C and SIGTERM
@@ -154,16 +338,38 @@ func main() {
 	sig := <-stop
 	log.Info("Received shutdown signal", slog.String("signal", sig.String()))
 
-	log.Info("Attempting graceful shutdown of HTTP server...")
+	stopJobs()
+
+	log.Info("Attempting graceful shutdown of HTTP and gRPC servers...")
 	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeoutSeconds*time.Second)
 	defer cancelShutdown()
 
+	// both drain within the same timeout, gRPC writes still need the mux
+	// and the bus, which stay up until Wait below
+	grpcStopped := make(chan error, 1)
+	go func() {
+		if grpcServer == nil {
+			grpcStopped <- nil
+			return
+		}
+		grpcStopped <- grpcServer.Shutdown(shutdownCtx)
+	}()
+
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		log.Error("HTTP server graceful shutdown failed", sl.Error(err))
 		os.Exit(1)
 	}
-
 	log.Info("HTTP server gracefully stopped.")
+
+	if err := <-grpcStopped; err != nil {
+		log.Error("gRPC server graceful shutdown failed", sl.Error(err))
+		os.Exit(1)
+	}
+	if grpcServer != nil {
+		log.Info("gRPC server gracefully stopped.")
+	}
+
+	bus.Wait()
 	log.Info("Application shutdown complete. Goodbye!")
 	os.Exit(0)
 }
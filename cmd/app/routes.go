@@ -0,0 +1,244 @@
+package main
+
+import (
+	"blog/internal/api/cursor"
+	"blog/internal/api/httpcache"
+	"blog/internal/api/inproc"
+	"blog/internal/api/openapi"
+	"blog/internal/config"
+	"blog/internal/events"
+	"blog/internal/federation"
+	homefeed "blog/internal/feed"
+	graphs "blog/internal/graph"
+	"blog/internal/handlers/url/activitypub"
+	"blog/internal/handlers/url/admin"
+	"blog/internal/handlers/url/apidoc"
+	"blog/internal/handlers/url/bookmark"
+	"blog/internal/handlers/url/comment"
+	"blog/internal/handlers/url/feed"
+	"blog/internal/handlers/url/follow"
+	"blog/internal/handlers/url/graph"
+	"blog/internal/handlers/url/moderation"
+	"blog/internal/handlers/url/notification"
+	"blog/internal/handlers/url/post"
+	"blog/internal/handlers/url/reaction"
+	"blog/internal/handlers/url/revision"
+	"blog/internal/handlers/url/sitemap"
+	"blog/internal/handlers/url/stream"
+	"blog/internal/handlers/url/syndication"
+	"blog/internal/handlers/url/trash"
+	"blog/internal/handlers/url/user"
+	"blog/internal/handlers/url/webmention"
+	"blog/internal/mention"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/repository/redisrepo"
+	sitemapgen "blog/internal/sitemap"
+	"blog/internal/spam"
+	streams "blog/internal/stream"
+	feedformat "blog/internal/syndication"
+	"blog/internal/util/logger"
+	webhooks "blog/internal/webhook"
+	webmentions "blog/internal/webmention"
+)
+
+var feedFormats = []feedformat.Format{feedformat.RSS, feedformat.Atom, feedformat.JSON}
+
+// services are what the HTTP routes are served by. fed and webmentions are
+// nil when the feature is disabled, their routes are left out then.
+type services struct {
+	repo        repository.Repository
+	rdb         *redisrepo.RedisRepo
+	bus         *events.Bus
+	cursors     *cursor.Codec
+	mentions    *mention.Linker
+	spam        *spam.Classifier
+	homeFeed    *homefeed.Feed
+	sitemaps    *sitemapgen.Sitemap
+	hub         *streams.Hub
+	hooks       *webhooks.Service
+	fed         *federation.Service
+	webmentions *webmentions.Service
+}
+
+// newSpec documents the routes registerRoutes registers, each handler
+// package describes its own.
+func newSpec(cfg *config.Config) *openapi.Spec {
+	spec := openapi.New("blog", "1.0", "Blog posts, comments and the people writing them.")
+	spec.Add("docs", apidoc.Operations()...)
+	spec.Add("posts", post.Operations()...)
+	spec.Add("revisions", revision.Operations()...)
+	spec.Add("users", user.Operations()...)
+	spec.Add("comments", comment.Operations()...)
+	spec.Add("streams", stream.Operations(cfg.Stream)...)
+	spec.Add("reactions", reaction.Operations(cfg.Reactions)...)
+	spec.Add("follows", follow.Operations()...)
+	spec.Add("feed", feed.Operations()...)
+	spec.Add("syndication", syndication.Operations(feedFormats...)...)
+	spec.Add("sitemap", sitemap.Operations()...)
+	spec.Add("activitypub", activitypub.Operations()...)
+	spec.Add("webmention", webmention.Operations()...)
+	spec.Add("notifications", notification.Operations()...)
+	spec.Add("bookmarks", bookmark.Operations()...)
+	spec.Add("moderation", moderation.Operations()...)
+	spec.Add("admin", admin.Operations()...)
+	spec.Add("trash", trash.Operations()...)
+	spec.Add("graphql", graph.Operations()...)
+
+	return spec
+}
+
+// registerRoutes registers the HTTP routes on mux. Every one of them has to
+// be in the spec of mux, main refuses to start otherwise, see mux.Check.
+func registerRoutes(mux *openapi.ServeMux, log logger.Logger, cfg *config.Config, s services) error {
+	postRepo := s.repo.Post()
+	userRepo := s.repo.User()
+	commentRepo := s.repo.Comment()
+	revisionRepo := s.repo.Revision()
+	reportRepo := s.repo.Report()
+	auditRepo := s.repo.Audit()
+	sanctionRepo := s.repo.Sanction()
+	reactionRepo := s.repo.Reaction()
+	bookmarkRepo := s.repo.Bookmark()
+	followRepo := s.repo.Follow()
+	notificationRepo := s.repo.Notification()
+	webmentionRepo := s.repo.Webmention()
+	webhookRepo := s.repo.Webhook()
+	rdb, bus, cursors, mentions := s.rdb, s.bus, s.cursors, s.mentions
+	spamClassifier, homeFeed, sitemaps, hub, hooks := s.spam, s.homeFeed, s.sitemaps, s.hub, s.hooks
+
+	authenticate := auth.AuthMiddleware(sanctionRepo)
+	optionalAuth := auth.OptionalAuth(sanctionRepo)
+
+	// Post handlers
+	mux.Handle("POST /api/post", post.Create(log, postRepo, mentions, bus), authenticate)
+	mux.Handle("PATCH /api/post/{id}", post.Update(log, postRepo, mentions, bus, rdb), authenticate)
+	mux.Handle("DELETE /api/post/{id}", post.Delete(log, postRepo, bus, rdb), authenticate)
+	mux.Handle("POST /api/post/{id}/restore", post.Restore(log, postRepo, bus), authenticate)
+	mux.Handle("PATCH /api/post/{id}/comment-settings", post.CommentSettings(log, postRepo, rdb), authenticate)
+	readPost := httpcache.CacheControl(cfg.HTTPCache.Policy("post"), optionalAuth(post.Read(log, postRepo, userRepo, mentions, reactionRepo, bookmarkRepo, rdb)))
+	if s.webmentions != nil {
+		readPost = webmention.Advertise(s.webmentions.EndpointURL(), readPost)
+	}
+	mux.HandleFunc("GET /api/post/{id}", readPost)
+	mux.HandleFunc("GET /api/posts", httpcache.CacheControl(cfg.HTTPCache.Policy("posts"), post.GetList(log, postRepo, userRepo, reactionRepo, cursors)))
+	mux.HandleFunc("GET /api/posts/stream", stream.Posts(log, hub, cfg.Stream))
+
+	// Post revision handlers
+	mux.Handle("GET /api/post/{id}/revisions", revision.List(log, postRepo, revisionRepo), authenticate)
+	mux.Handle("GET /api/post/{id}/revisions/diff", revision.Diff(log, postRepo, revisionRepo), authenticate)
+	mux.Handle("GET /api/post/{id}/revisions/{revision_id}", revision.Get(log, postRepo, revisionRepo), authenticate)
+	mux.Handle("POST /api/post/{id}/revisions/{revision_id}/restore", revision.Restore(log, postRepo, revisionRepo, mentions, bus, rdb), authenticate)
+
+	// User handlers
+	mux.HandleFunc("POST /api/user/signup", user.SignUpHandler(log, userRepo, bus))
+	mux.HandleFunc("POST /api/user/signin", user.SignInHandler(log, userRepo, sanctionRepo))
+
+	// Comment handlers
+	mux.HandleFunc("POST /api/comment", comment.Create(log, commentRepo, postRepo, spamClassifier, mentions, bus, cfg.Comments), authenticate)
+	mux.HandleFunc("DELETE /api/comment/{id}", comment.Delete(log, commentRepo), authenticate)
+	mux.HandleFunc("PATCH /api/comment/{id}", comment.Update(log, commentRepo, mentions, bus), authenticate)
+	mux.HandleFunc("POST /api/comment/{id}/restore", comment.Restore(log, commentRepo), authenticate)
+	mux.HandleFunc("GET /api/comment/{id}", httpcache.CacheControl(cfg.HTTPCache.Policy("comment"), optionalAuth(comment.Read(log, commentRepo, userRepo, sanctionRepo, mentions, reactionRepo))))
+	mux.HandleFunc("GET /api/post/{id}/comments", httpcache.CacheControl(cfg.HTTPCache.Policy("comments"), optionalAuth(comment.Thread(log, commentRepo, userRepo, sanctionRepo, mentions, reactionRepo, webmentionRepo))))
+	mux.HandleFunc("GET /api/post/{id}/comments/stream", stream.Comments(log, postRepo, hub, cfg.Stream))
+	mux.HandleFunc("GET /api/comments", httpcache.CacheControl(cfg.HTTPCache.Policy("comments"), optionalAuth(comment.GetList(log, commentRepo, userRepo, reactionRepo, cursors))))
+
+	// Reaction handlers
+	reactionTargets := reaction.Targets{Posts: postRepo, Comments: commentRepo}
+	mux.Handle("POST /api/post/{id}/reactions", reaction.Add(log, reactionRepo, reactionTargets, models.TargetPost, cfg.Reactions), authenticate)
+	mux.Handle("DELETE /api/post/{id}/reactions/{emoji}", reaction.Remove(log, reactionRepo, reactionTargets, models.TargetPost, cfg.Reactions), authenticate)
+	mux.Handle("POST /api/comment/{id}/reactions", reaction.Add(log, reactionRepo, reactionTargets, models.TargetComment, cfg.Reactions), authenticate)
+	mux.Handle("DELETE /api/comment/{id}/reactions/{emoji}", reaction.Remove(log, reactionRepo, reactionTargets, models.TargetComment, cfg.Reactions), authenticate)
+
+	// Follow and feed handlers
+	mux.Handle("PUT /api/user/{id}/follow", follow.Follow(log, followRepo, bus), authenticate)
+	mux.Handle("DELETE /api/user/{id}/follow", follow.Unfollow(log, followRepo, bus), authenticate)
+	mux.HandleFunc("GET /api/user/{id}/followers", follow.Followers(log, followRepo, userRepo, cursors))
+	mux.HandleFunc("GET /api/user/{id}/following", follow.Following(log, followRepo, userRepo, cursors))
+	mux.HandleFunc("GET /api/user/{id}/posts", httpcache.CacheControl(cfg.HTTPCache.Policy("posts"), post.ByAuthor(log, postRepo, userRepo, reactionRepo, cursors)))
+	mux.Handle("GET /api/feed", feed.Home(log, homeFeed, userRepo, reactionRepo, cursors), authenticate)
+
+	// Syndication feeds
+	for _, format := range feedFormats {
+		feedPolicy := cfg.HTTPCache.Policy("feeds")
+		mux.HandleFunc("GET /feed."+string(format), httpcache.CacheControl(feedPolicy, syndication.Site(log, postRepo, userRepo, mentions, cfg.Syndication, format)))
+		mux.HandleFunc("GET /user/{id}/feed."+string(format), httpcache.CacheControl(feedPolicy, syndication.Author(log, postRepo, userRepo, mentions, cfg.Syndication, format)))
+		mux.HandleFunc("GET /tag/{tag}/feed."+string(format), httpcache.CacheControl(feedPolicy, syndication.Tag(log, postRepo, userRepo, mentions, cfg.Syndication, format)))
+	}
+
+	// Sitemap and robots.txt
+	sitemapPolicy := cfg.HTTPCache.Policy("sitemap")
+	mux.HandleFunc("GET /sitemap.xml", httpcache.CacheControl(sitemapPolicy, sitemap.Root(log, sitemaps)))
+	mux.HandleFunc("GET /sitemaps/{name}", httpcache.CacheControl(sitemapPolicy, sitemap.Part(log, sitemaps)))
+	mux.HandleFunc("GET /robots.txt", httpcache.CacheControl(sitemapPolicy, sitemap.Robots(log, sitemapgen.Robots(cfg.Syndication.BaseURL, cfg.Sitemap))))
+
+	// ActivityPub
+	if s.fed != nil {
+		mux.HandleFunc("GET /.well-known/webfinger", activitypub.WebFinger(log, userRepo, s.fed))
+		mux.HandleFunc("GET /ap/users/{id}", activitypub.Actor(log, userRepo, s.fed))
+		mux.HandleFunc("GET /ap/users/{id}/outbox", activitypub.Outbox(log, userRepo, s.fed))
+		mux.HandleFunc("GET /ap/users/{id}/followers", activitypub.Followers(log, userRepo, s.fed))
+		mux.HandleFunc("GET /ap/posts/{id}", activitypub.Post(log, postRepo, s.fed))
+		mux.HandleFunc("POST /ap/users/{id}/inbox", activitypub.Inbox(log, userRepo, s.fed))
+		mux.HandleFunc("POST /ap/inbox", activitypub.Inbox(log, userRepo, s.fed))
+	}
+
+	// Webmentions
+	if s.webmentions != nil {
+		mux.HandleFunc("POST /webmention", webmention.Receive(log, s.webmentions))
+	}
+
+	// Notification handlers
+	mux.Handle("GET /api/notifications", notification.List(log, notificationRepo, userRepo, cursors), authenticate)
+	mux.Handle("POST /api/notifications/read", notification.MarkRead(log, notificationRepo), authenticate)
+	mux.Handle("POST /api/notifications/read-all", notification.MarkAllRead(log, notificationRepo), authenticate)
+
+	// Bookmark handlers
+	mux.Handle("PUT /api/post/{id}/bookmark", bookmark.Save(log, bookmarkRepo, postRepo), authenticate)
+	mux.Handle("DELETE /api/post/{id}/bookmark", bookmark.Delete(log, bookmarkRepo), authenticate)
+	mux.Handle("GET /api/bookmarks", bookmark.List(log, bookmarkRepo, userRepo, cursors), authenticate)
+	mux.Handle("POST /api/bookmarks/collections", bookmark.CreateCollection(log, bookmarkRepo), authenticate)
+	mux.Handle("GET /api/bookmarks/collections", bookmark.ListCollections(log, bookmarkRepo), authenticate)
+	mux.Handle("DELETE /api/bookmarks/collections/{id}", bookmark.DeleteCollection(log, bookmarkRepo), authenticate)
+
+	// Moderation handlers
+	requireModerator := auth.RequireRole(userRepo, models.RoleModerator, models.RoleAdmin)
+	requireAdmin := auth.RequireRole(userRepo, models.RoleAdmin)
+	mux.Handle("GET /api/moderation/comments", moderation.Queue(log, commentRepo, userRepo, cursors), authenticate, requireModerator)
+	mux.Handle("POST /api/moderation/comments", moderation.Act(log, commentRepo, spamClassifier, auditRepo, bus), authenticate, requireModerator)
+	mux.Handle("GET /api/moderation/reports", moderation.ReportQueue(log, reportRepo, postRepo, commentRepo, cursors), authenticate, requireModerator)
+	mux.Handle("POST /api/moderation/reports/resolve", moderation.ResolveReport(log, reportRepo, postRepo, commentRepo, userRepo, sanctionRepo, auditRepo, bus, rdb, cfg.Reports), authenticate, requireModerator)
+	mux.Handle("POST /api/report", moderation.Report(log, reportRepo, postRepo, commentRepo, auditRepo, bus, rdb, cfg.Reports), authenticate)
+
+	// Admin handlers
+	mux.Handle("PUT /api/admin/user/{id}/role", admin.SetRole(log, userRepo, auditRepo), authenticate, requireAdmin)
+	mux.Handle("POST /api/admin/user/{id}/sanctions", admin.Sanction(log, sanctionRepo, userRepo, auditRepo), authenticate, requireAdmin)
+	mux.Handle("GET /api/admin/user/{id}/sanctions", admin.ListSanctions(log, sanctionRepo), authenticate, requireAdmin)
+	mux.Handle("DELETE /api/admin/sanctions/{id}", admin.RevokeSanction(log, sanctionRepo, auditRepo), authenticate, requireAdmin)
+	mux.Handle("GET /api/admin/audit", admin.AuditLog(log, auditRepo, cursors), authenticate, requireAdmin)
+	mux.Handle("POST /api/admin/webhooks", admin.CreateWebhook(log, webhookRepo, auditRepo, cfg.Webhooks), authenticate, requireAdmin)
+	mux.Handle("GET /api/admin/webhooks", admin.ListWebhooks(log, webhookRepo), authenticate, requireAdmin)
+	mux.Handle("PATCH /api/admin/webhooks/{id}", admin.UpdateWebhook(log, webhookRepo, auditRepo, cfg.Webhooks), authenticate, requireAdmin)
+	mux.Handle("DELETE /api/admin/webhooks/{id}", admin.DeleteWebhook(log, webhookRepo, auditRepo), authenticate, requireAdmin)
+	mux.Handle("GET /api/admin/webhooks/{id}/deliveries", admin.WebhookDeliveries(log, webhookRepo, cursors), authenticate, requireAdmin)
+	mux.Handle("POST /api/admin/webhooks/{id}/deliveries/{delivery_id}/redeliver", admin.Redeliver(log, webhookRepo, hooks), authenticate, requireAdmin)
+
+	// Trash handlers
+	mux.HandleFunc("GET /api/me/trash", trash.List(log, postRepo, commentRepo, cfg.Trash.Retention()), authenticate)
+
+	// GraphQL, its mutations go through the REST routes above
+	graphAPI, err := graphs.New(log, userRepo, postRepo, commentRepo, sanctionRepo, reactionRepo, cursors, inproc.New(mux), cfg.GraphQL)
+	if err != nil {
+		return err
+	}
+	graphQuery := optionalAuth(graph.Query(log, graphAPI))
+	mux.HandleFunc("GET /graphql", graphQuery)
+	mux.HandleFunc("POST /graphql", graphQuery)
+
+	// API description
+	mux.HandleFunc("GET /api/openapi.json", apidoc.Spec(log, mux))
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/openapi"
+	"blog/internal/config"
+	"blog/internal/events"
+	"blog/internal/federation"
+	"blog/internal/handlers/auth"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/repository/sqliterepo"
+	"blog/internal/util/logger"
+	webmentions "blog/internal/webmention"
+)
+
+// newTestMux registers the routes of the checked-in config on a mux over
+// an empty database, with the optional features turned on so their routes
+// are covered too.
+func newTestMux(t *testing.T) (*openapi.ServeMux, repository.Repository) {
+	t.Helper()
+
+	cfg, err := config.Load("../../config/local.yaml")
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	cfg.Federation.Enabled = true
+	cfg.Webmention.Enabled = true
+
+	log := logger.NewLogger(nil)
+	db, err := sqliterepo.New("file:"+filepath.Join(t.TempDir(), "blog.db")+"?_foreign_keys=on", log)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	repo := sqliterepo.NewSQLiteRepository(log, db)
+	if err := repo.User().InitUserDatabase(); err != nil {
+		t.Fatalf("init users: %v", err)
+	}
+	if err := repo.Sanction().InitSanctionDatabase(); err != nil {
+		t.Fatalf("init sanctions: %v", err)
+	}
+
+	s := services{
+		repo:    repo,
+		bus:     events.NewBus(log),
+		cursors: cursor.New(cfg.Auth.SecretKey),
+	}
+	s.fed, err = federation.New(log, repo.Federation(), repo.User(), repo.Post(), repo.Comment(), repo.Sanction(),
+		nil, nil, s.bus, cfg.Syndication.BaseURL, cfg.Federation, cfg.Comments)
+	if err != nil {
+		t.Fatalf("federation: %v", err)
+	}
+	s.webmentions, err = webmentions.New(log, repo.Webmention(), repo.Post(), cfg.Syndication.BaseURL, cfg.Webmention)
+	if err != nil {
+		t.Fatalf("webmentions: %v", err)
+	}
+
+	mux := openapi.NewServeMux(newSpec(cfg), true)
+	if err := registerRoutes(mux, log, cfg, s); err != nil {
+		t.Fatalf("register routes: %v", err)
+	}
+	return mux, repo
+}
+
+func TestSpecCoversRoutes(t *testing.T) {
+	mux, _ := newTestMux(t)
+	if err := mux.Check(); err != nil {
+		t.Fatalf("routes don't match the OpenAPI spec:\n%v", err)
+	}
+	if _, err := mux.Document(); err != nil {
+		t.Fatalf("document: %v", err)
+	}
+}
+
+// TestGuardsRunBeforeValidation makes sure a caller who may not use a route
+// is refused as such, not told how to build a valid request.
+func TestGuardsRunBeforeValidation(t *testing.T) {
+	mux, repo := newTestMux(t)
+
+	userID, err := repo.User().CreateUser(&models.User{Username: "reader", Email: "reader@example.com", Password: "x"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	token, err := auth.GenerateToken(userID, "reader", "reader@example.com")
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		{"anonymous post", http.MethodPost, "/api/post", "", `{"x": 1}`, http.StatusUnauthorized},
+		{"anonymous admin", http.MethodPost, "/api/admin/webhooks", "", `{}`, http.StatusUnauthorized},
+		{"bad token", http.MethodPost, "/api/admin/webhooks", "nope", `{}`, http.StatusUnauthorized},
+		{"missing role", http.MethodPost, "/api/admin/webhooks", token, `{}`, http.StatusForbidden},
+		{"missing moderator role", http.MethodPost, "/api/moderation/comments", token, `{}`, http.StatusForbidden},
+		{"signed in, invalid body", http.MethodPost, "/api/post", token, `{"x": 1}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package cursor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog/internal/api/openapi"
+	"blog/internal/repository"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100 // hard cap of every paginated list
+
+	sigSize = 16
+)
+
+var (
+	ErrInvalid      = errors.New("invalid cursor")
+	ErrInvalidLimit = fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+	ErrOffset       = errors.New("offset is not supported, use cursor")
+)
+
+// Direction tells which way a cursor pages from its position.
+type Direction byte
+
+const (
+	Next Direction = 'n' // later rows, older ones in a list ordered newest first
+	Prev Direction = 'p' // earlier rows
+)
+
+// Cursor is a position in a list ordered by (created_at, id), newest first
+// unless the list says otherwise. Lists ordered by id alone leave CreatedAt
+// zero.
+type Cursor struct {
+	Dir       Direction
+	CreatedAt time.Time
+	ID        int64
+}
+
+// Codec turns cursors into opaque strings signed with HMAC-SHA256, so
+// clients can't forge positions or tamper with them.
+type Codec struct {
+	key []byte
+}
+
+// New derives the signing key from secret, the key is never used directly
+// so it can be shared with other signers.
+func New(secret string) *Codec {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("blog/api/cursor"))
+	return &Codec{key: mac.Sum(nil)}
+}
+
+func (c *Codec) Encode(cur Cursor) string {
+	var ts int64
+	if !cur.CreatedAt.IsZero() {
+		ts = cur.CreatedAt.UnixNano()
+	}
+	payload := fmt.Sprintf("%c%d.%d", cur.Dir, ts, cur.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(c.sign(payload))
+}
+
+func (c *Codec) Decode(s string) (Cursor, error) {
+	rawPayload, rawSig, ok := strings.Cut(s, ".")
+	if !ok {
+		return Cursor{}, ErrInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(rawPayload)
+	if err != nil {
+		return Cursor{}, ErrInvalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(rawSig)
+	if err != nil || !hmac.Equal(sig, c.sign(string(payload))) {
+		return Cursor{}, ErrInvalid
+	}
+
+	if len(payload) < 1 {
+		return Cursor{}, ErrInvalid
+	}
+	cur := Cursor{Dir: Direction(payload[0])}
+	if cur.Dir != Next && cur.Dir != Prev {
+		return Cursor{}, ErrInvalid
+	}
+	rawTS, rawID, ok := strings.Cut(string(payload[1:]), ".")
+	if !ok {
+		return Cursor{}, ErrInvalid
+	}
+	ts, err := strconv.ParseInt(rawTS, 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalid
+	}
+	cur.ID, err = strconv.ParseInt(rawID, 10, 64)
+	if err != nil || cur.ID < 1 {
+		return Cursor{}, ErrInvalid
+	}
+	if ts != 0 {
+		cur.CreatedAt = time.Unix(0, ts).UTC()
+	}
+	return cur, nil
+}
+
+// Parse decodes the cursor query parameter, an empty one means the first page.
+func (c *Codec) Parse(raw string) (*Cursor, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	cur, err := c.Decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &cur, nil
+}
+
+// Query reads the limit and cursor parameters of a list request, def is the
+// limit when none is given. Offsets are rejected rather than ignored, a
+// client still sending one would loop over the first page.
+func (c *Codec) Query(q url.Values, def int) (int, *Cursor, error) {
+	if q.Has("offset") {
+		return 0, nil, ErrOffset
+	}
+	limit, err := ParseLimit(q.Get("limit"), def)
+	if err != nil {
+		return 0, nil, err
+	}
+	cur, err := c.Parse(q.Get("cursor"))
+	if err != nil {
+		return 0, nil, err
+	}
+	return limit, cur, nil
+}
+
+// QueryID is Query for lists ordered by id descending, it returns the id of
+// the cursor made by EncodeID or 0 on the first page.
+func (c *Codec) QueryID(q url.Values, def int) (int, int64, error) {
+	if q.Has("offset") {
+		return 0, 0, ErrOffset
+	}
+	limit, err := ParseLimit(q.Get("limit"), def)
+	if err != nil {
+		return 0, 0, err
+	}
+	var id int64
+	if raw := q.Get("cursor"); raw != "" {
+		if id, err = c.DecodeID(raw); err != nil {
+			return 0, 0, err
+		}
+	}
+	return limit, id, nil
+}
+
+// EncodeID returns a cursor to the rows after id, for lists ordered by id descending.
+func (c *Codec) EncodeID(id int64) string {
+	return c.Encode(Cursor{Dir: Next, ID: id})
+}
+
+// DecodeID returns the id of a cursor made by EncodeID.
+func (c *Codec) DecodeID(s string) (int64, error) {
+	cur, err := c.Decode(s)
+	if err != nil {
+		return 0, err
+	}
+	if cur.Dir != Next {
+		return 0, ErrInvalid
+	}
+	return cur.ID, nil
+}
+
+func (c *Codec) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)[:sigSize]
+}
+
+// ParseLimit parses the limit query parameter, an empty one means def.
+func ParseLimit(raw string, def int) (int, error) {
+	if raw == "" {
+		return def, nil
+	}
+	l, err := strconv.Atoi(raw)
+	if err != nil || l < 1 || l > MaxLimit {
+		return 0, ErrInvalidLimit
+	}
+	return l, nil
+}
+
+// Page is the repository page of the rows after or before cur, or of the
+// first rows when cur is nil. One extra row is asked for, it tells Paginate
+// whether there is another page in that direction.
+func Page(cur *Cursor, limit int) repository.Page {
+	page := repository.Page{Limit: limit + 1}
+	if cur != nil {
+		key := &repository.Key{CreatedAt: cur.CreatedAt, ID: cur.ID}
+		if cur.Dir == Prev {
+			page.Before = key
+		} else {
+			page.After = key
+		}
+	}
+	return page
+}
+
+// Paginate trims rows read with Page to limit and returns the cursors of the
+// next and previous pages, empty when there is none. key
+// returns the position of a row.
+func Paginate[T any](c *Codec, cur *Cursor, limit int, rows []T, key func(T) (time.Time, int64)) (page []T, next, prev string) {
+	more := len(rows) > limit
+	backwards := cur != nil && cur.Dir == Prev
+	if more && backwards {
+		// the extra row comes first
+		rows = rows[len(rows)-limit:]
+	} else if more {
+		rows = rows[:limit]
+	}
+	if len(rows) == 0 {
+		return rows, "", ""
+	}
+
+	at := func(row T, dir Direction) string {
+		createdAt, id := key(row)
+		return c.Encode(Cursor{Dir: dir, CreatedAt: createdAt, ID: id})
+	}
+	// coming from a cursor there is always a page on the side it came from
+	if more || backwards {
+		next = at(rows[len(rows)-1], Next)
+	}
+	if cur != nil && (more || !backwards) {
+		prev = at(rows[0], Prev)
+	}
+	return rows, next, prev
+}
+
+// Params documents the parameters read by Query.
+func Params(def int) []openapi.Param {
+	return []openapi.Param{
+		openapi.Query("limit", openapi.IntegerRange(1, MaxLimit), fmt.Sprintf("page size, %d by default", def)),
+		openapi.Query("cursor", openapi.String(), "next_cursor or prev_cursor of the previous page"),
+	}
+}
@@ -0,0 +1,260 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"blog/internal/repository"
+)
+
+func TestRoundTrip(t *testing.T) {
+	c := New("secret")
+	tests := []Cursor{
+		{Dir: Next, CreatedAt: time.Date(2025, 6, 27, 13, 39, 55, 123456789, time.UTC), ID: 11},
+		{Dir: Prev, CreatedAt: time.Date(2025, 6, 27, 13, 39, 55, 0, time.UTC), ID: 1},
+		{Dir: Next, ID: 42}, // ordered by id alone
+	}
+	for _, want := range tests {
+		got, err := c.Decode(c.Encode(want))
+		if err != nil {
+			t.Fatalf("Decode(Encode(%+v)): %v", want, err)
+		}
+		if got != want {
+			t.Fatalf("round trip = %+v, want %+v", got, want)
+		}
+	}
+}
+
+// forge returns a cursor of payload signed with the key of c, to check what
+// Decode makes of payloads Encode never produces.
+func forge(c *Codec, payload string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(c.sign(payload))
+}
+
+func TestDecodeRejects(t *testing.T) {
+	c := New("secret")
+	valid := c.Encode(Cursor{Dir: Next, CreatedAt: time.Unix(1751031595, 0), ID: 11})
+	rawPayload, rawSig, _ := strings.Cut(valid, ".")
+
+	// the same position paging the other way, with the signature of the original
+	flipped, _ := base64.RawURLEncoding.DecodeString(rawPayload)
+	flipped[0] = byte(Prev)
+	otherDir := base64.RawURLEncoding.EncodeToString(flipped) + "." + rawSig
+
+	// a later position, with the signature of the original
+	moved := base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(flipped[1:]), ".11", ".12", 1)))
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"empty", ""},
+		{"no signature", rawPayload},
+		{"empty signature", rawPayload + "."},
+		{"direction changed", otherDir},
+		{"position changed", "n" + moved + "." + rawSig},
+		{"signature truncated", rawPayload + "." + rawSig[:len(rawSig)-2]},
+		{"signed by another secret", New("other secret").Encode(Cursor{Dir: Next, ID: 11})},
+		{"not base64", "!!!." + rawSig},
+		{"unknown direction", forge(c, "x0.11")},
+		{"no id", forge(c, "n0")},
+		{"zero id", forge(c, "n0.0")},
+		{"negative id", forge(c, "n0.-3")},
+		{"empty payload", forge(c, "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cur, err := c.Decode(tt.cursor); !errors.Is(err, ErrInvalid) {
+				t.Fatalf("Decode(%q) = %+v, %v, want ErrInvalid", tt.cursor, cur, err)
+			}
+		})
+	}
+}
+
+func TestDecodeID(t *testing.T) {
+	c := New("secret")
+	if id, err := c.DecodeID(c.EncodeID(7)); err != nil || id != 7 {
+		t.Fatalf("DecodeID(EncodeID(7)) = %d, %v", id, err)
+	}
+	// a prev cursor of a (created_at, id) list is no id cursor
+	prev := c.Encode(Cursor{Dir: Prev, ID: 7})
+	if _, err := c.DecodeID(prev); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("DecodeID of a prev cursor: %v, want ErrInvalid", err)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	c := New("secret")
+	cursor := c.EncodeID(5)
+	tests := []struct {
+		query string
+		limit int
+		err   error
+	}{
+		{"", 20, nil},
+		{"limit=7&cursor=" + cursor, 7, nil},
+		{"limit=0", 0, ErrInvalidLimit},
+		{"limit=101", 0, ErrInvalidLimit},
+		{"limit=ten", 0, ErrInvalidLimit},
+		{"offset=20", 0, ErrOffset},
+		{"cursor=" + cursor + "x", 0, ErrInvalid},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		limit, _, err := c.Query(q, DefaultLimit)
+		if !errors.Is(err, tt.err) || limit != tt.limit {
+			t.Errorf("Query(%q) = %d, %v, want %d, %v", tt.query, limit, err, tt.limit, tt.err)
+		}
+	}
+}
+
+func TestQueryID(t *testing.T) {
+	c := New("secret")
+	tests := []struct {
+		query string
+		limit int
+		id    int64
+		err   error
+	}{
+		{"", 20, 0, nil},
+		{"limit=5&cursor=" + c.EncodeID(9), 5, 9, nil},
+		{"limit=101", 0, 0, ErrInvalidLimit},
+		{"offset=20", 0, 0, ErrOffset},
+		{"cursor=" + c.Encode(Cursor{Dir: Prev, ID: 9}), 0, 0, ErrInvalid},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		limit, id, err := c.QueryID(q, DefaultLimit)
+		if !errors.Is(err, tt.err) || limit != tt.limit || id != tt.id {
+			t.Errorf("QueryID(%q) = %d, %d, %v, want %d, %d, %v", tt.query, limit, id, err, tt.limit, tt.id, tt.err)
+		}
+	}
+}
+
+type row struct {
+	createdAt time.Time
+	id        int64
+}
+
+func rowKey(r row) (time.Time, int64) { return r.createdAt, r.id }
+
+func less(a, b row) bool {
+	return a.createdAt.Before(b.createdAt) || a.createdAt.Equal(b.createdAt) && a.id < b.id
+}
+
+// read answers a page the way the repositories do: rows after a key newest
+// first, rows before it read oldest first and turned around.
+func read(rows []row, page repository.Page) []row {
+	var out []row
+	switch {
+	case page.After != nil:
+		at := row{page.After.CreatedAt, page.After.ID}
+		for _, r := range rows {
+			if less(r, at) && len(out) < page.Limit {
+				out = append(out, r)
+			}
+		}
+	case page.Before != nil:
+		at := row{page.Before.CreatedAt, page.Before.ID}
+		for i := len(rows) - 1; i >= 0; i-- {
+			if less(at, rows[i]) && len(out) < page.Limit {
+				out = append(out, rows[i])
+			}
+		}
+		slices.Reverse(out)
+	default:
+		out = rows[:min(page.Limit, len(rows))]
+	}
+	return out
+}
+
+func ids(rows []row) []int64 {
+	out := make([]int64, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, r.id)
+	}
+	return out
+}
+
+// TestPaginate pages through a list to its end with next cursors and back
+// to its start with prev cursors, the pages have to match both ways.
+func TestPaginate(t *testing.T) {
+	base := time.Date(2025, 6, 27, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		n     int
+		limit int
+	}{
+		{"empty", 0, 3},
+		{"one page", 2, 3},
+		{"exactly one page", 3, 3},
+		{"page boundary at the end", 9, 3},
+		{"short last page", 10, 3},
+		{"pages of one", 4, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New("secret")
+			// newest first, every two rows share a timestamp so ties are
+			// broken by id
+			var rows []row
+			for i := tt.n; i >= 1; i-- {
+				rows = append(rows, row{base.Add(time.Duration(i/2) * time.Second), int64(i)})
+			}
+
+			var forward [][]int64
+			var cur *Cursor
+			var last string
+			for range tt.n + 2 {
+				page, next, prev := Paginate(c, cur, tt.limit, read(rows, Page(cur, tt.limit)), rowKey)
+				if (cur == nil) != (prev == "") {
+					t.Fatalf("page %d: prev cursor %q", len(forward), prev)
+				}
+				forward = append(forward, ids(page))
+				if next == "" {
+					break
+				}
+				last = next
+				decoded, err := c.Parse(next)
+				if err != nil {
+					t.Fatalf("next cursor: %v", err)
+				}
+				cur = decoded
+			}
+
+			if got, want := slices.Concat(forward...), ids(rows); !slices.Equal(got, want) {
+				t.Fatalf("paged forward through %v, want %v", got, want)
+			}
+			if wantPages := max(1, (tt.n+tt.limit-1)/tt.limit); len(forward) != wantPages {
+				t.Fatalf("%d pages, want %d: %v", len(forward), wantPages, forward)
+			}
+			if len(forward) == 1 {
+				return
+			}
+
+			// back from the last page, whose prev cursor leads to the one before
+			cur, _ = c.Parse(last)
+			_, _, prev := Paginate(c, cur, tt.limit, read(rows, Page(cur, tt.limit)), rowKey)
+			backward := [][]int64{forward[len(forward)-1]}
+			for prev != "" {
+				cur, _ = c.Parse(prev)
+				var page []row
+				var next string
+				page, next, prev = Paginate(c, cur, tt.limit, read(rows, Page(cur, tt.limit)), rowKey)
+				if next == "" {
+					t.Fatalf("no next cursor on page %v coming back", ids(page))
+				}
+				backward = append([][]int64{ids(page)}, backward...)
+			}
+			if !slices.EqualFunc(backward, forward, slices.Equal) {
+				t.Fatalf("paged back through %v, forward through %v", backward, forward)
+			}
+		})
+	}
+}
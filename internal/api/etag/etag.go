@@ -0,0 +1,49 @@
+package etag
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+// Version returns the entity tag for a resource version.
+func Version(version int64) string {
+	return fmt.Sprintf(`"v%d"`, version)
+}
+
+// VersionWith returns the entity tag of a representation that also contains
+// data changing independently of the resource version, e.g. reaction counts.
+// It still matches the version in If-Match.
+func VersionWith(version, revision int64) string {
+	return fmt.Sprintf(`"v%d-r%d"`, version, revision)
+}
+
+// Variant marks an entity tag as a per-user variant of the representation,
+// e.g. one carrying the caller's bookmark flag.
+func Variant(tag, variant string) string {
+	return strings.TrimSuffix(tag, `"`) + "-" + variant + `"`
+}
+
+// MatchVersion reports whether an If-Match header value matches the given version.
+// An empty header always matches. Weak tags never match, as If-Match requires
+// strong comparison.
+func MatchVersion(ifMatch string, version int64) bool {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" || ifMatch == "*" {
+		return true
+	}
+
+	want := Version(version)
+	prefix := strings.TrimSuffix(want, `"`) + "-"
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == want || strings.HasPrefix(tag, prefix) {
+			return true
+		}
+	}
+	return false
+}
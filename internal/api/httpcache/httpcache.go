@@ -0,0 +1,170 @@
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	headerCacheControl    = "Cache-Control"
+	headerETag            = "ETag"
+	headerLastModified    = "Last-Modified"
+	headerIfNoneMatch     = "If-None-Match"
+	headerIfModifiedSince = "If-Modified-Since"
+)
+
+// Validators describe the current state of a representation.
+// An empty ETag means "derive a weak ETag from the response body".
+type Validators struct {
+	ETag         string
+	LastModified time.Time
+}
+
+// WeakETag returns a weak entity tag derived from the body bytes.
+func WeakETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf(`W/"%s"`, hex.EncodeToString(sum[:16]))
+}
+
+// SetValidators writes ETag and Last-Modified headers.
+func SetValidators(w http.ResponseWriter, v Validators) {
+	if v.ETag != "" {
+		w.Header().Set(headerETag, v.ETag)
+	}
+	if !v.LastModified.IsZero() {
+		w.Header().Set(headerLastModified, v.LastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// NotModified evaluates If-None-Match and If-Modified-Since against the validators.
+// If-None-Match takes precedence, as required by RFC 9110.
+func NotModified(r *http.Request, v Validators) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get(headerIfNoneMatch); inm != "" {
+		if v.ETag == "" {
+			return false
+		}
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || weakMatch(tag, v.ETag) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get(headerIfModifiedSince); ims != "" && !v.LastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		return !v.LastModified.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
+
+func weakMatch(a, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+// WriteJSON writes data as JSON with validators attached, or an empty
+// 304 Not Modified if the request's conditional headers match.
+func WriteJSON(w http.ResponseWriter, r *http.Request, status int, data any, v Validators) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("Internal Server Error"))
+		return err
+	}
+	return Write(w, r, status, "application/json; charset=utf-8", body, v)
+}
+
+// Write is WriteJSON for a body that is already encoded.
+func Write(w http.ResponseWriter, r *http.Request, status int, contentType string, body []byte, v Validators) error {
+	if v.ETag == "" {
+		v.ETag = WeakETag(body)
+	}
+	SetValidators(w, v)
+
+	if NotModified(r, v) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, err := w.Write(body)
+	return err
+}
+
+// CacheControl sets the given Cache-Control policy on successful and
+// 304 responses. Error responses are left uncacheable. Responses to
+// authenticated requests may be personalized, so "public" becomes "private" for them.
+func CacheControl(policy string, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if policy == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Authorization")
+		p := policy
+		if r.Header.Get("Authorization") != "" {
+			p = privatePolicy(policy)
+		}
+		next.ServeHTTP(&cacheControlWriter{ResponseWriter: w, policy: p}, r)
+	}
+}
+
+func privatePolicy(policy string) string {
+	directives := strings.Split(policy, ",")
+	kept := directives[:0]
+	for _, d := range directives {
+		switch strings.ToLower(strings.TrimSpace(d)) {
+		case "public", "private":
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(d)), "s-maxage") {
+			continue
+		}
+		kept = append(kept, strings.TrimSpace(d))
+	}
+	return strings.Join(append([]string{"private"}, kept...), ", ")
+}
+
+type cacheControlWriter struct {
+	http.ResponseWriter
+	policy      string
+	wroteHeader bool
+}
+
+func (w *cacheControlWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status < http.StatusBadRequest {
+			w.Header().Set(headerCacheControl, w.policy)
+		} else {
+			w.Header().Set(headerCacheControl, "no-store")
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *cacheControlWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *cacheControlWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,81 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ServeMux is an http.ServeMux that remembers its routes, so they can be
+// checked against the spec and the served document lists exactly them.
+// With validate, requests are checked against their operation before they
+// reach the handler.
+type ServeMux struct {
+	*http.ServeMux
+	spec     *Spec
+	validate bool
+	gen      *generator // schemas of the validated request bodies
+
+	patterns []string
+	problems []error
+
+	once sync.Once
+	doc  []byte
+	err  error
+}
+
+func NewServeMux(spec *Spec, validate bool) *ServeMux {
+	return &ServeMux{
+		ServeMux: http.NewServeMux(),
+		spec:     spec,
+		validate: validate,
+		gen:      newGenerator(),
+	}
+}
+
+// Guard is a middleware that may turn a request away before it reaches
+// the route, like auth.AuthMiddleware or auth.RequireRole.
+type Guard func(http.Handler) http.HandlerFunc
+
+// Handle registers handler for pattern. guards wrap it outermost first and
+// run before the request is validated: a caller who may not use the route
+// is refused as such, and learns nothing about its parameters or body.
+func (m *ServeMux) Handle(pattern string, handler http.Handler, guards ...Guard) {
+	m.patterns = append(m.patterns, pattern)
+	// without the leading slash ServeMux takes the first segment for a
+	// host name, "PATCH api/post/{id}" registers fine and never matches
+	if _, path, _ := strings.Cut(pattern, " "); !strings.HasPrefix(path, "/") {
+		m.problems = append(m.problems, fmt.Errorf("route %q: path must start with /", pattern))
+	}
+	op, ok := m.spec.Operation(pattern)
+	if !ok {
+		m.problems = append(m.problems, fmt.Errorf("route %q is missing from the OpenAPI spec", pattern))
+	} else if m.validate {
+		handler = m.validator(op, handler)
+	}
+	for i := len(guards) - 1; i >= 0; i-- {
+		handler = guards[i](handler)
+	}
+	m.ServeMux.Handle(pattern, handler)
+}
+
+func (m *ServeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request), guards ...Guard) {
+	m.Handle(pattern, http.HandlerFunc(handler), guards...)
+}
+
+// Check reports the routes that are badly formed or not documented. It's
+// called once all routes are registered, the server doesn't start until
+// the spec covers them.
+func (m *ServeMux) Check() error {
+	return errors.Join(m.problems...)
+}
+
+// Document returns the OpenAPI document of the registered routes.
+func (m *ServeMux) Document() ([]byte, error) {
+	m.once.Do(func() {
+		m.doc, m.err = m.spec.Document(m.patterns)
+	})
+	return m.doc, m.err
+}
@@ -0,0 +1,177 @@
+// Package openapi describes the HTTP API as an OpenAPI 3.1 document. Every
+// handler package lists its operations with the request and response
+// structs it really uses, the schemas are generated from them, and the
+// ServeMux refuses routes that aren't described.
+package openapi
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"blog/internal/api/problem"
+)
+
+const (
+	ContentJSON = "application/json"
+	ContentForm = "application/x-www-form-urlencoded"
+)
+
+// Auth is how an operation authenticates.
+type Auth int
+
+const (
+	Public Auth = iota
+	// Optional operations show more to signed in users.
+	Optional
+	Required
+)
+
+// Operation describes one route. Path parameters are taken from the
+// pattern: ids ("id", "..._id") are integers, the rest strings, a Param
+// with In "path" overrides that.
+type Operation struct {
+	Pattern      string // as registered, "PATCH /api/post/{id}"
+	ID           string // operationId, derived from the pattern if empty
+	Summary      string
+	Description  string
+	Auth         Auth
+	Params       []Param
+	Body         any    // zero value of the request struct, or a *Schema
+	BodyType     string // ContentJSON if empty
+	BodyOptional bool
+	Responses    []Response
+}
+
+// Param is a query, header or path parameter.
+type Param struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Response is one possible answer of an operation. Body is a zero value of
+// the response struct or a *Schema, nil for an empty body.
+type Response struct {
+	Status      int
+	Description string
+	ContentType string // ContentJSON if empty
+	Body        any
+}
+
+// Query is an optional query parameter.
+func Query(name string, schema *Schema, description string) Param {
+	return Param{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+// Header is an optional request header.
+func Header(name string, schema *Schema, description string) Param {
+	return Param{Name: name, In: "header", Description: description, Schema: schema}
+}
+
+// Path documents a path parameter.
+func Path(name string, schema *Schema, description string) Param {
+	return Param{Name: name, In: "path", Description: description, Required: true, Schema: schema}
+}
+
+// JSON is a response with a JSON body.
+func JSON(status int, description string, body any) Response {
+	return Response{Status: status, Description: description, Body: body}
+}
+
+// Content is a response with another content type than plain JSON.
+func Content(status int, description, contentType string, body any) Response {
+	return Response{Status: status, Description: description, ContentType: contentType, Body: body}
+}
+
+// Error is an error response, all of them are problem details.
+func Error(status int, description string) Response {
+	return Response{Status: status, Description: description, ContentType: problem.ContentType, Body: problem.Problem{}}
+}
+
+// Empty is a response without a body.
+func Empty(status int, description string) Response {
+	return Response{Status: status, Description: description}
+}
+
+func (op *Operation) method() string {
+	method, _, _ := strings.Cut(op.Pattern, " ")
+	return method
+}
+
+// path is the pattern path in OpenAPI form, Go's "{name...}" wildcards
+// become "{name}" and the "{$}" anchor is dropped.
+func (op *Operation) path() string {
+	_, path, _ := strings.Cut(op.Pattern, " ")
+	path = strings.ReplaceAll(path, "...}", "}")
+	return strings.TrimSuffix(path, "{$}")
+}
+
+func (op *Operation) pathParams() []Param {
+	var params []Param
+	rest := op.path()
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			return params
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return params
+		}
+		name := rest[start+1 : start+end]
+		rest = rest[start+end+1:]
+
+		i := slices.IndexFunc(op.Params, func(p Param) bool { return p.In == "path" && p.Name == name })
+		if i >= 0 {
+			params = append(params, op.Params[i])
+			continue
+		}
+		schema := String()
+		if name == "id" || strings.HasSuffix(name, "_id") {
+			schema = Integer()
+		}
+		params = append(params, Path(name, schema, ""))
+	}
+}
+
+// operationID turns "GET /api/post/{id}/comments" into "getPostComments".
+func (op *Operation) operationID() string {
+	if op.ID != "" {
+		return op.ID
+	}
+	var b strings.Builder
+	b.WriteString(strings.ToLower(op.method()))
+	for _, seg := range strings.Split(op.path(), "/") {
+		if seg == "" || seg == "api" || strings.HasPrefix(seg, "{") {
+			continue
+		}
+		for _, word := range strings.FieldsFunc(seg, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			b.WriteString(upperFirst(word))
+		}
+	}
+	return b.String()
+}
+
+func (op *Operation) validate() error {
+	method := op.method()
+	if method == "" || method != strings.ToUpper(method) || !strings.HasPrefix(op.path(), "/") {
+		return fmt.Errorf("openapi: operation pattern %q needs a method and a path starting with /", op.Pattern)
+	}
+	if len(op.Responses) == 0 {
+		return fmt.Errorf("openapi: operation %q has no responses", op.Pattern)
+	}
+	return nil
+}
+
+// sortedResponses orders the responses by status for a stable document.
+func sortedResponses(responses []Response) []Response {
+	sorted := slices.Clone(responses)
+	slices.SortStableFunc(sorted, func(a, b Response) int { return a.Status - b.Status })
+	return sorted
+}
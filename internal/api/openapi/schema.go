@@ -0,0 +1,317 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Schema is a JSON Schema (draft 2020-12) as used by OpenAPI 3.1. Type is a
+// string, or a list of strings for nullable values.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// String, Integer and Boolean are the schemas of the plain parameter types.
+func String() *Schema  { return &Schema{Type: "string"} }
+func Integer() *Schema { return &Schema{Type: "integer", Format: "int64"} }
+func Boolean() *Schema { return &Schema{Type: "boolean"} }
+
+// IntegerRange is an integer schema with inclusive bounds.
+func IntegerRange(minimum, maximum int) *Schema {
+	lo, hi := float64(minimum), float64(maximum)
+	return &Schema{Type: "integer", Minimum: &lo, Maximum: &hi}
+}
+
+// Enum is a string schema allowing the given values.
+func Enum(values ...string) *Schema {
+	s := String()
+	for _, v := range values {
+		s.Enum = append(s.Enum, v)
+	}
+	return s
+}
+
+const componentsPrefix = "#/components/schemas/"
+
+var (
+	timeType = reflect.TypeFor[time.Time]()
+	rawType  = reflect.TypeFor[json.RawMessage]()
+)
+
+// generator turns Go types into schemas. Named structs become components
+// that are referenced, so recursive types like comment threads work.
+type generator struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+// schemaOf returns the schema of the type of v, nil for a nil v.
+func (g *generator) schemaOf(v any) *Schema {
+	if v == nil {
+		return nil
+	}
+	if s, ok := v.(*Schema); ok {
+		return s
+	}
+	return g.schema(reflect.TypeOf(v))
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := g.schema(t.Elem())
+		if typ, ok := s.Type.(string); ok {
+			s.Type = []string{typ, "null"}
+		}
+		return s
+	case reflect.Bool:
+		return Boolean()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return Integer()
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return String()
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.componentName(t)
+			g.names[t] = name
+			g.components[name] = &Schema{}
+			*g.components[name] = *g.object(t)
+		}
+		return &Schema{Ref: componentsPrefix + name}
+	}
+	// interfaces take any value
+	return &Schema{}
+}
+
+// componentName is the type name, prefixed with its package unless it's a
+// shared one or already named after it: comment.CreateRequest is
+// CommentCreateRequest, models.Post is Post and problem.Problem is Problem.
+func (g *generator) componentName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	name := upperFirst(t.Name())
+	if pkg != "models" && pkg != "response" && !strings.EqualFold(pkg, name) {
+		name = upperFirst(pkg) + name
+	}
+	// different types may still end up with the same name
+	base := name
+	for i := 2; ; i++ {
+		if _, taken := g.components[name]; !taken {
+			return name
+		}
+		name = base + strconv.Itoa(i)
+	}
+}
+
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.fields(t, s)
+	return s
+}
+
+func (g *generator) fields(t reflect.Type, s *Schema) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.fields(ft, s)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := g.schema(f.Type)
+		if applyRules(prop, f.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+}
+
+// applyRules adds the constraints of a validator tag to s and reports
+// whether the field is required. Rules after "dive" apply to the items.
+func applyRules(s *Schema, tag string) bool {
+	if tag == "" {
+		return false
+	}
+	required := false
+	target := s
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			if target == s {
+				required = true
+				if s.Type == "string" && s.MinLength == nil {
+					s.MinLength = intPtr(1)
+				}
+			}
+		case "dive":
+			if s.Items == nil {
+				return required
+			}
+			target = s.Items
+		case "min", "max", "len":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			setBound(target, name, n)
+		case "gte", "lte", "gt", "lt":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			switch name {
+			case "gte":
+				target.Minimum = &n
+			case "lte":
+				target.Maximum = &n
+			case "gt":
+				target.ExclusiveMinimum = &n
+			case "lt":
+				target.ExclusiveMaximum = &n
+			}
+		case "oneof":
+			target.Enum = nil
+			for _, v := range strings.Fields(param) {
+				target.Enum = append(target.Enum, v)
+			}
+		case "email":
+			target.Format = "email"
+		case "url", "http_url":
+			target.Format = "uri"
+		case "username":
+			// see util.NewCustomValidator
+			target.Pattern = "^[a-zA-Z0-9]{5,30}$"
+		case "tag":
+			// see util.NewCustomValidator
+			target.Pattern = "^[a-zA-Z0-9]+(-[a-zA-Z0-9]+)*$"
+		case "password":
+			target.MinLength = intPtr(8)
+			target.Description = "at least one digit, one upper and one lower case letter"
+		}
+	}
+	return required
+}
+
+func setBound(s *Schema, rule string, n int) {
+	lower, upper := rule != "max", rule != "min"
+	switch {
+	case isType(s, "array"):
+		if lower {
+			s.MinItems = intPtr(n)
+		}
+		if upper {
+			s.MaxItems = intPtr(n)
+		}
+	case isType(s, "string"):
+		if lower {
+			s.MinLength = intPtr(n)
+		}
+		if upper {
+			s.MaxLength = intPtr(n)
+		}
+	case isType(s, "integer"), isType(s, "number"):
+		f := float64(n)
+		if lower {
+			s.Minimum = &f
+		}
+		if upper {
+			s.Maximum = &f
+		}
+	}
+}
+
+// isType reports whether s is of type typ, nullable or not.
+func isType(s *Schema, typ string) bool {
+	switch t := s.Type.(type) {
+	case string:
+		return t == typ
+	case []string:
+		for _, v := range t {
+			if v == typ {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func upperFirst(s string) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
@@ -0,0 +1,211 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const version = "3.1.0"
+
+const bearerAuth = "bearerAuth"
+
+// Spec is the set of documented operations.
+type Spec struct {
+	title       string
+	version     string
+	description string
+	ops         map[string]*Operation
+	tags        map[string]string // pattern to tag
+	tagOrder    []string
+}
+
+func New(title, apiVersion, description string) *Spec {
+	return &Spec{
+		title:       title,
+		version:     apiVersion,
+		description: description,
+		ops:         make(map[string]*Operation),
+		tags:        make(map[string]string),
+	}
+}
+
+// Add documents operations under tag. It panics on an invalid or twice
+// documented pattern, like http.ServeMux does on a bad route.
+func (s *Spec) Add(tagName string, ops ...Operation) {
+	if !slices.Contains(s.tagOrder, tagName) {
+		s.tagOrder = append(s.tagOrder, tagName)
+	}
+	for _, op := range ops {
+		if err := op.validate(); err != nil {
+			panic(err)
+		}
+		if _, ok := s.ops[op.Pattern]; ok {
+			panic(fmt.Sprintf("openapi: operation %q documented twice", op.Pattern))
+		}
+		s.ops[op.Pattern] = &op
+		s.tags[op.Pattern] = tagName
+	}
+}
+
+// Operation returns the documented operation of a route pattern.
+func (s *Spec) Operation(pattern string) (*Operation, bool) {
+	op, ok := s.ops[pattern]
+	return op, ok
+}
+
+type document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       info                            `json:"info"`
+	Tags       []tag                           `json:"tags,omitempty"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components components                      `json:"components"`
+}
+
+type info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type tag struct {
+	Name string `json:"name"`
+}
+
+type operation struct {
+	OperationID string                    `json:"operationId"`
+	Tags        []string                  `json:"tags,omitempty"`
+	Summary     string                    `json:"summary,omitempty"`
+	Description string                    `json:"description,omitempty"`
+	Security    []map[string][]string     `json:"security,omitempty"`
+	Parameters  []Param                   `json:"parameters,omitempty"`
+	RequestBody *requestBody              `json:"requestBody,omitempty"`
+	Responses   map[string]responseObject `json:"responses"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type responseObject struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Document renders the operations of the given patterns, the routes this
+// instance serves. Optional features that are turned off are left out.
+func (s *Spec) Document(patterns []string) ([]byte, error) {
+	g := newGenerator()
+	doc := document{
+		OpenAPI: version,
+		Info:    info{Title: s.title, Version: s.version, Description: s.description},
+		Paths:   make(map[string]map[string]operation),
+		Components: components{
+			Schemas: g.components,
+			SecuritySchemes: map[string]securityScheme{
+				bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	used := make(map[string]bool)
+	ids := make(map[string]string)
+	for _, pattern := range patterns {
+		op, ok := s.ops[pattern]
+		if !ok {
+			return nil, fmt.Errorf("openapi: route %q is not documented", pattern)
+		}
+		id := op.operationID()
+		if other, ok := ids[id]; ok {
+			return nil, fmt.Errorf("openapi: routes %q and %q have the same operation id %q", other, pattern, id)
+		}
+		ids[id] = pattern
+
+		path := op.path()
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]operation)
+		}
+		used[s.tags[pattern]] = true
+		doc.Paths[path][strings.ToLower(op.method())] = s.render(g, op, s.tags[pattern])
+	}
+
+	for _, name := range s.tagOrder {
+		if used[name] {
+			doc.Tags = append(doc.Tags, tag{Name: name})
+		}
+	}
+	return json.Marshal(doc)
+}
+
+func (s *Spec) render(g *generator, op *Operation, tagName string) operation {
+	out := operation{
+		OperationID: op.operationID(),
+		Tags:        []string{tagName},
+		Summary:     op.Summary,
+		Description: op.Description,
+		Responses:   make(map[string]responseObject),
+	}
+
+	switch op.Auth {
+	case Required:
+		out.Security = []map[string][]string{{bearerAuth: {}}}
+	case Optional:
+		out.Security = []map[string][]string{{}, {bearerAuth: {}}}
+	}
+
+	out.Parameters = op.pathParams()
+	for _, p := range op.Params {
+		if p.In != "path" {
+			out.Parameters = append(out.Parameters, p)
+		}
+	}
+
+	if op.Body != nil {
+		out.RequestBody = &requestBody{
+			Required: !op.BodyOptional,
+			Content:  map[string]mediaType{op.bodyType(): {Schema: g.schemaOf(op.Body)}},
+		}
+	}
+
+	responses := op.Responses
+	if op.Auth == Required && !slices.ContainsFunc(responses, func(r Response) bool { return r.Status == 401 }) {
+		responses = append(slices.Clone(responses), Error(401, "missing or invalid token"))
+	}
+	for _, r := range sortedResponses(responses) {
+		res := responseObject{Description: r.Description}
+		if r.Body != nil {
+			contentType := r.ContentType
+			if contentType == "" {
+				contentType = ContentJSON
+			}
+			res.Content = map[string]mediaType{contentType: {Schema: g.schemaOf(r.Body)}}
+		}
+		out.Responses[strconv.Itoa(r.Status)] = res
+	}
+	return out
+}
+
+func (op *Operation) bodyType() string {
+	if op.BodyType == "" {
+		return ContentJSON
+	}
+	return op.BodyType
+}
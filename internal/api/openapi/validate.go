@@ -0,0 +1,238 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"blog/internal/api/problem"
+	"blog/internal/util"
+)
+
+// maxValidatedBody is the largest request body that is validated, bigger
+// ones are refused.
+const maxValidatedBody = 1 << 20
+
+// validator checks the path and query parameters and the JSON body of a
+// request against op. Form bodies are left to the handler.
+func (m *ServeMux) validator(op *Operation, next http.Handler) http.Handler {
+	params := op.pathParams()
+	for _, p := range op.Params {
+		if p.In == "query" {
+			params = append(params, p)
+		}
+	}
+	var body *Schema
+	if op.Body != nil && op.bodyType() == ContentJSON {
+		body = m.gen.schemaOf(op.Body)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var errs []problem.FieldError
+		for _, p := range params {
+			raw := r.URL.Query().Get(p.Name)
+			if p.In == "path" {
+				raw = r.PathValue(p.Name)
+			}
+			if raw == "" {
+				if p.Required {
+					errs = append(errs, problem.FieldError{Field: p.Name, Tag: "required"})
+				}
+				continue
+			}
+			errs = append(errs, m.checkParam(p, raw)...)
+		}
+
+		if body != nil {
+			raw, err := io.ReadAll(io.LimitReader(r.Body, maxValidatedBody+1))
+			if err != nil {
+				util.ErrorResponse(w, r, http.StatusBadRequest, "The request body couldn't be read.")
+				return
+			}
+			if len(raw) > maxValidatedBody {
+				util.ErrorResponse(w, r, http.StatusRequestEntityTooLarge, "Request Entity Too Large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(raw))
+
+			if len(bytes.TrimSpace(raw)) == 0 {
+				if !op.BodyOptional {
+					errs = append(errs, problem.FieldError{Field: "body", Tag: "required"})
+				}
+			} else {
+				dec := json.NewDecoder(bytes.NewReader(raw))
+				dec.UseNumber()
+				var v any
+				if err := dec.Decode(&v); err != nil {
+					util.ValidationError(w, r, err)
+					return
+				}
+				errs = append(errs, m.check(body, v, "")...)
+			}
+		}
+
+		if len(errs) > 0 {
+			problem.Write(w, r, problem.Validation(errs))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *ServeMux) checkParam(p Param, raw string) []problem.FieldError {
+	var v any = raw
+	switch {
+	case isType(p.Schema, "integer"), isType(p.Schema, "number"):
+		v = json.Number(raw)
+	case isType(p.Schema, "boolean"):
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return []problem.FieldError{{Field: p.Name, Tag: "type"}}
+		}
+		v = b
+	}
+	return m.check(p.Schema, v, p.Name)
+}
+
+// check validates a decoded JSON value, numbers are json.Number.
+func (m *ServeMux) check(s *Schema, v any, field string) []problem.FieldError {
+	if s.Ref != "" {
+		s = m.gen.components[strings.TrimPrefix(s.Ref, componentsPrefix)]
+		if s == nil {
+			return nil
+		}
+	}
+	fail := func(rule string) []problem.FieldError {
+		name := field
+		if name == "" {
+			name = "body"
+		}
+		return []problem.FieldError{{Field: name, Tag: rule}}
+	}
+
+	if v == nil {
+		if s.Type == nil || isType(s, "null") {
+			return nil
+		}
+		return fail("type")
+	}
+
+	switch v := v.(type) {
+	case string:
+		if s.Type != nil && !isType(s, "string") {
+			return fail("type")
+		}
+		n := utf8.RuneCountInString(v)
+		switch {
+		case s.MinLength != nil && n < *s.MinLength:
+			return fail("minLength")
+		case s.MaxLength != nil && n > *s.MaxLength:
+			return fail("maxLength")
+		case len(s.Enum) > 0 && !enumHas(s.Enum, v):
+			return fail("enum")
+		case s.Pattern != "" && !matches(s.Pattern, v):
+			return fail("pattern")
+		}
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return fail("type")
+		}
+		switch {
+		case isType(s, "integer"):
+			if _, err := v.Int64(); err != nil {
+				return fail("type")
+			}
+		case s.Type != nil && !isType(s, "number"):
+			return fail("type")
+		}
+		switch {
+		case s.Minimum != nil && f < *s.Minimum:
+			return fail("minimum")
+		case s.Maximum != nil && f > *s.Maximum:
+			return fail("maximum")
+		case s.ExclusiveMinimum != nil && f <= *s.ExclusiveMinimum:
+			return fail("exclusiveMinimum")
+		case s.ExclusiveMaximum != nil && f >= *s.ExclusiveMaximum:
+			return fail("exclusiveMaximum")
+		}
+	case bool:
+		if s.Type != nil && !isType(s, "boolean") {
+			return fail("type")
+		}
+	case []any:
+		if s.Type != nil && !isType(s, "array") {
+			return fail("type")
+		}
+		switch {
+		case s.MinItems != nil && len(v) < *s.MinItems:
+			return fail("minItems")
+		case s.MaxItems != nil && len(v) > *s.MaxItems:
+			return fail("maxItems")
+		}
+		var errs []problem.FieldError
+		if s.Items != nil {
+			for i, item := range v {
+				errs = append(errs, m.check(s.Items, item, fmt.Sprintf("%s[%d]", field, i))...)
+			}
+		}
+		return errs
+	case map[string]any:
+		if s.Type != nil && !isType(s, "object") {
+			return fail("type")
+		}
+		var errs []problem.FieldError
+		for _, name := range s.Required {
+			if v[name] == nil {
+				errs = append(errs, problem.FieldError{Field: join(field, name), Tag: "required"})
+			}
+		}
+		// sorted, so the errors come in the same order every time
+		for _, name := range slices.Sorted(maps.Keys(v)) {
+			value := v[name]
+			if value == nil && slices.Contains(s.Required, name) {
+				continue
+			}
+			prop := s.Properties[name]
+			if prop == nil {
+				prop = s.AdditionalProperties
+			}
+			if prop != nil {
+				errs = append(errs, m.check(prop, value, join(field, name))...)
+			}
+		}
+		return errs
+	}
+	return nil
+}
+
+// matches reports whether v matches pattern, an invalid pattern matches
+// everything.
+func matches(pattern, v string) bool {
+	ok, err := regexp.MatchString(pattern, v)
+	return ok || err != nil
+}
+
+func enumHas(enum []any, v string) bool {
+	for _, e := range enum {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
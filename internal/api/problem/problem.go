@@ -0,0 +1,140 @@
+// Package problem writes errors as RFC 7807 problem details and maps the
+// errors of the lower layers to HTTP statuses.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	requestid "blog/internal/middlewares/request_id"
+	"blog/internal/repository"
+)
+
+const ContentType = "application/problem+json"
+
+const (
+	// TypeDefault is for problems that are described by their status alone.
+	TypeDefault = "about:blank"
+	// TypeValidation is for requests with invalid fields, listed in Errors.
+	TypeValidation = "/problems/validation"
+)
+
+// Problem is a problem details object. Instance is the request id, the same
+// as in the X-Request-ID header and the logs.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is a request field that failed a check. Field is the JSON path,
+// "events[1]", or the parameter name; Tag the validator tag, or the schema
+// keyword when the request was checked against the OpenAPI spec.
+type FieldError struct {
+	Field string `json:"field"`
+	Tag   string `json:"tag"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Tag
+}
+
+// New is a problem of status. A detail that only repeats the status text is
+// left out.
+func New(status int, detail string) Problem {
+	p := Problem{Type: TypeDefault, Title: http.StatusText(status), Status: status}
+	if !strings.EqualFold(detail, p.Title) {
+		p.Detail = detail
+	}
+	return p
+}
+
+// Validation is a 400 problem listing the fields that failed.
+func Validation(errs []FieldError) Problem {
+	p := New(http.StatusBadRequest, "The request has invalid fields.")
+	p.Type = TypeValidation
+	p.Errors = errs
+	return p
+}
+
+// Write sends p, with the id of r as instance.
+func Write(w http.ResponseWriter, r *http.Request, p Problem) {
+	if r != nil && p.Instance == "" {
+		p.Instance = requestid.Get(r.Context())
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	_, _ = w.Write(body)
+}
+
+// statuses maps the repository sentinels, checked in order.
+var statuses = []struct {
+	err    error
+	status int
+}{
+	{repository.ErrNotExists, http.StatusNotFound},
+	{repository.ErrEmailAlreadyExists, http.StatusConflict},
+	{repository.ErrUsernameAlreadyExists, http.StatusConflict},
+	{repository.ErrAlreadyExists, http.StatusConflict},
+	{repository.ErrVersionConflict, http.StatusPreconditionFailed},
+	{repository.ErrForeignKeyFailed, http.StatusUnprocessableEntity},
+}
+
+// Status is the HTTP status of err, 500 for errors that aren't the
+// client's fault.
+func Status(err error) int {
+	for _, s := range statuses {
+		if errors.Is(err, s.err) {
+			return s.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// FieldErrors lists the fields of a decoding or validator error. ok is false
+// for a body that isn't JSON at all.
+func FieldErrors(err error) (errs []FieldError, ok bool) {
+	var (
+		validation validator.ValidationErrors
+		typeErr    *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &validation):
+		for _, fe := range validation {
+			errs = append(errs, FieldError{Field: fieldPath(fe), Tag: fe.Tag()})
+		}
+		return errs, true
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		return []FieldError{{Field: field, Tag: "type"}}, true
+	case errors.Is(err, io.EOF):
+		return []FieldError{{Field: "body", Tag: "required"}}, true
+	}
+	return nil, false
+}
+
+// fieldPath drops the struct name from the namespace: with the JSON names
+// registered, "CreateRequest.content" is "content".
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if _, path, ok := strings.Cut(ns, "."); ok {
+		return path
+	}
+	return fe.Field()
+}
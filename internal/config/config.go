@@ -5,12 +5,29 @@ import (
 )
 
 type Config struct {
-	Environment string       `yaml:"env" env-default:"local"`
-	Server      ServerConfig `yaml:"server"`
-	Logger      LoggerConfig `yaml:"logger"`
-	SQLite      SQLiteConfig `yaml:"sqlite"`
-	Redis       RedisConfig  `yaml:"redis"`
-	Auth        AuthConfig   `yaml:"auth"`
+	Environment string            `yaml:"env" env-default:"local"`
+	Server      ServerConfig      `yaml:"server"`
+	Logger      LoggerConfig      `yaml:"logger"`
+	SQLite      SQLiteConfig      `yaml:"sqlite"`
+	Redis       RedisConfig       `yaml:"redis"`
+	Auth        AuthConfig        `yaml:"auth"`
+	HTTPCache   HTTPCache         `yaml:"http_cache"`
+	Trash       TrashConfig       `yaml:"trash"`
+	Comments    CommentsConfig    `yaml:"comments"`
+	Spam        SpamConfig        `yaml:"spam"`
+	Reports     ReportsConfig     `yaml:"reports"`
+	Reactions   ReactionsConfig   `yaml:"reactions"`
+	Feed        FeedConfig        `yaml:"feed"`
+	Mentions    MentionsConfig    `yaml:"mentions"`
+	Syndication SyndicationConfig `yaml:"syndication"`
+	Sitemap     SitemapConfig     `yaml:"sitemap"`
+	Federation  FederationConfig  `yaml:"federation"`
+	Webmention  WebmentionConfig  `yaml:"webmention"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	Stream      StreamConfig      `yaml:"stream"`
+	OpenAPI     OpenAPIConfig     `yaml:"openapi"`
+	GraphQL     GraphQLConfig     `yaml:"graphql"`
+	GRPC        GRPCConfig        `yaml:"grpc"`
 }
 
 type ServerConfig struct {
@@ -53,5 +70,147 @@ type TemplateFilePath struct {
 }
 
 type AuthConfig struct {
-	SecretKey string `yaml:"secret_key"`
+	SecretKey   string   `yaml:"secret_key"`
+	AdminEmails []string `yaml:"admin_emails"` // users with these emails get the admin role on startup
+}
+
+// HTTPCache holds Cache-Control policies keyed by route name, e.g. "post" or "posts".
+type HTTPCache struct {
+	Default string            `yaml:"default" env-default:"no-cache"`
+	Routes  map[string]string `yaml:"routes"`
+}
+
+func (c HTTPCache) Policy(route string) string {
+	if policy, ok := c.Routes[route]; ok {
+		return policy
+	}
+	return c.Default
+}
+
+type TrashConfig struct {
+	RetentionDays int           `yaml:"retention_days" env-default:"30"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+}
+
+func (c TrashConfig) Retention() time.Duration {
+	return time.Duration(c.RetentionDays) * 24 * time.Hour
+}
+
+type CommentsConfig struct {
+	MaxDepth   int    `yaml:"max_depth" env-default:"8"`     // replies deeper than this are rejected
+	Moderation string `yaml:"moderation" env-default:"open"` // open | first_time | all, posts may override
+}
+
+// SpamConfig tunes the local comment spam classifier. Scores are in [0, 1].
+type SpamConfig struct {
+	Enabled         bool          `yaml:"enabled" env-default:"true"`
+	HoldThreshold   float64       `yaml:"hold_threshold" env-default:"0.5"`   // comments scoring at least this wait for review
+	SpamThreshold   float64       `yaml:"spam_threshold" env-default:"0.9"`   // comments scoring at least this go straight to spam
+	MinTraining     int           `yaml:"min_training" env-default:"10"`      // spam and ham samples needed before the bayes model is used
+	MaxLinks        int           `yaml:"max_links" env-default:"2"`          // more links than this is suspicious
+	NewAccountAge   time.Duration `yaml:"new_account_age" env-default:"24h"`  // accounts younger than this are treated as new
+	VelocityWindow  time.Duration `yaml:"velocity_window" env-default:"10m"`  // window for counting recent comments
+	VelocityLimit   int           `yaml:"velocity_limit" env-default:"5"`     // comments allowed per window before it looks like flooding
+	DuplicateWindow time.Duration `yaml:"duplicate_window" env-default:"24h"` // window for detecting repeated content
+}
+
+type ReportsConfig struct {
+	HideThreshold int `yaml:"hide_threshold" env-default:"3"` // distinct reporters needed to hide content automatically
+	SuspendDays   int `yaml:"suspend_days" env-default:"7"`   // default length of a suspension issued from a report
+}
+
+type ReactionsConfig struct {
+	Emoji []string `yaml:"emoji" env-default:"👍,❤️,😂,🎉,😮,😢"` // allowed reactions
+}
+
+type FeedConfig struct {
+	FanOutOnWrite bool          `yaml:"fan_out_on_write" env-default:"false"` // keep materialized feeds in Redis instead of querying SQLite on every read
+	MaxItems      int           `yaml:"max_items" env-default:"500"`          // posts kept per materialized feed
+	TTL           time.Duration `yaml:"ttl" env-default:"168h"`               // materialized feeds of inactive users expire after this
+}
+
+type MentionsConfig struct {
+	ProfileURL  string `yaml:"profile_url" env-default:"/api/user/{id}/posts"` // link of a mentioned user, {id} and {username} are replaced
+	MaxPerEntry int    `yaml:"max_per_entry" env-default:"10"`                 // distinct users linked and notified per post or comment
+}
+
+// SyndicationConfig describes the blog in its RSS, Atom and JSON feeds.
+type SyndicationConfig struct {
+	BaseURL     string `yaml:"base_url" env-default:"http://localhost:8080"` // public origin, feeds need absolute links
+	Title       string `yaml:"title" env-default:"stupid blog"`
+	Description string `yaml:"description" env-default:"stupid blog api"`
+	Items       int    `yaml:"items" env-default:"20"` // newest posts per feed
+}
+
+// SitemapConfig tunes sitemap.xml and robots.txt. Links use syndication.base_url.
+type SitemapConfig struct {
+	MaxURLs  int      `yaml:"max_urls" env-default:"50000"` // URLs per sitemap file, the protocol allows at most 50000
+	Allow    []string `yaml:"allow"`                        // robots.txt Allow rules
+	Disallow []string `yaml:"disallow"`                     // robots.txt Disallow rules
+}
+
+// FederationConfig controls ActivityPub. Actors, links and the WebFinger
+// domain are derived from syndication.base_url.
+type FederationConfig struct {
+	Enabled          bool          `yaml:"enabled" env-default:"false"`
+	AllowInsecure    bool          `yaml:"allow_insecure" env:"FEDERATION_ALLOW_INSECURE" env-default:"false"` // allow http:// and private addresses, for testing against a local server only
+	Timeout          time.Duration `yaml:"timeout" env-default:"10s"`                                          // per remote request
+	DeliveryInterval time.Duration `yaml:"delivery_interval" env-default:"10s"`                                // how often the queue is checked for due deliveries
+	MaxAttempts      int           `yaml:"max_attempts" env-default:"10"`                                      // a delivery is dropped after this many failures
+	RetryBackoff     time.Duration `yaml:"retry_backoff" env-default:"1m"`                                     // wait after the first failure, doubled after each further one
+	ActorCacheTTL    time.Duration `yaml:"actor_cache_ttl" env-default:"24h"`                                  // remote actor documents are fetched again after this
+}
+
+// WebmentionConfig controls sending webmentions for links in posts and
+// receiving them at /webmention. Both go through a queue in the database.
+type WebmentionConfig struct {
+	Enabled       bool          `yaml:"enabled" env-default:"false"`
+	AllowInsecure bool          `yaml:"allow_insecure" env:"WEBMENTION_ALLOW_INSECURE" env-default:"false"` // allow http:// and private addresses, for testing against a local server only
+	Timeout       time.Duration `yaml:"timeout" env-default:"10s"`                                          // per remote request
+	Interval      time.Duration `yaml:"interval" env-default:"30s"`                                         // how often the queue is checked for due jobs
+	MaxAttempts   int           `yaml:"max_attempts" env-default:"6"`                                       // a job is dropped after this many failures
+	RetryBackoff  time.Duration `yaml:"retry_backoff" env-default:"1m"`                                     // wait after the first failure, doubled after each further one
+	MaxLinks      int           `yaml:"max_links" env-default:"20"`                                         // external links of a post that are sent webmentions
+}
+
+// WebhooksConfig controls the delivery of webhook payloads. Webhooks
+// themselves are registered by admins through the API.
+type WebhooksConfig struct {
+	AllowPrivate bool          `yaml:"allow_private" env:"WEBHOOKS_ALLOW_PRIVATE" env-default:"false"` // allow http:// and private addresses, e.g. for a CI server on the local network
+	Timeout      time.Duration `yaml:"timeout" env-default:"10s"`                                      // per delivery attempt
+	Interval     time.Duration `yaml:"interval" env-default:"10s"`                                     // how often the queue is checked for due deliveries
+	MaxAttempts  int           `yaml:"max_attempts" env-default:"8"`                                   // a delivery fails after this many attempts
+	RetryBackoff time.Duration `yaml:"retry_backoff" env-default:"30s"`                                // wait after the first failure, doubled after each further one
+	Retention    time.Duration `yaml:"retention" env-default:"720h"`                                   // finished deliveries are kept this long in the delivery log
+}
+
+// StreamConfig controls the server-sent event streams. Streams are exempt
+// from server.write_timeout, instead every single write has WriteTimeout.
+type StreamConfig struct {
+	MaxClients   int           `yaml:"max_clients" env-default:"1000"`  // open streams per instance
+	Buffer       int           `yaml:"buffer" env-default:"100"`        // recent events kept per topic for Last-Event-ID resume
+	ReplayWindow time.Duration `yaml:"replay_window" env-default:"5m"`  // how long events are kept for resume
+	Heartbeat    time.Duration `yaml:"heartbeat" env-default:"15s"`     // comment line sent when idle, keeps proxies from closing the stream
+	WriteTimeout time.Duration `yaml:"write_timeout" env-default:"10s"` // per event, a client that can't take it is dropped
+	Retry        time.Duration `yaml:"retry" env-default:"3s"`          // reconnect delay suggested to clients
+}
+
+type OpenAPIConfig struct {
+	Validate bool `yaml:"validate" env-default:"false"` // check requests against the spec before the handlers see them
+}
+
+// GraphQLConfig limits the queries of the /graphql endpoint. Complexity
+// counts every selected field once per item it may return, so a list asking
+// for first: 50 costs fifty times its fields.
+type GraphQLConfig struct {
+	MaxDepth      int `yaml:"max_depth" env-default:"10"`        // nesting of selections, fragments included
+	MaxComplexity int `yaml:"max_complexity" env-default:"5000"` // estimated fields resolved by a query
+}
+
+// GRPCConfig controls the gRPC API, served on its own listener next to the
+// HTTP server and stopped together with it.
+type GRPCConfig struct {
+	Enabled    bool   `yaml:"enabled" env-default:"false"`
+	Address    string `yaml:"address" env-default:"localhost:9090"`
+	Reflection bool   `yaml:"reflection" env-default:"true"` // lets grpcurl and similar tools list the services
 }
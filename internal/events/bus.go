@@ -0,0 +1,129 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+// Event is something that happened in a write path. Kind names the event
+// type and is what subscribers are registered for.
+type Event interface {
+	Kind() string
+}
+
+// Handler reacts to an event. Errors are logged, they never reach the publisher.
+type Handler func(ctx context.Context, e Event) error
+
+// Bus delivers events from the write paths to subscribers such as the
+// notification inbox, so handlers don't need to know who is interested.
+// Delivery is asynchronous and best effort: a publisher never waits for or
+// fails because of a subscriber.
+//
+// Events are delivered one at a time, in the order they were published, so
+// a subscriber sees a post created before it sees it updated. The webhook
+// and federation queues and the stream relay depend on it. A handler holds
+// up every event after it, slow work belongs in a queue of its own.
+type Bus struct {
+	log logger.Logger
+
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	wg       sync.WaitGroup
+
+	queueMu  sync.Mutex
+	queue    []delivery
+	draining bool
+}
+
+// delivery is a published event waiting for its handlers.
+type delivery struct {
+	ctx      context.Context
+	event    Event
+	handlers []Handler
+}
+
+func NewBus(log logger.Logger) *Bus {
+	return &Bus{
+		log:      log,
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Subscribe registers h for events of the given kind.
+func (b *Bus) Subscribe(kind string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[kind] = append(b.handlers[kind], h)
+}
+
+// On registers a handler for one event type.
+func On[E Event](b *Bus, h func(ctx context.Context, e E) error) {
+	var zero E
+	b.Subscribe(zero.Kind(), func(ctx context.Context, e Event) error {
+		return h(ctx, e.(E))
+	})
+}
+
+// Publish queues the event for its subscribers and returns. The context is
+// detached from the request, values like the request id stay available.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	b.mu.RLock()
+	handlers := b.handlers[e.Kind()]
+	b.mu.RUnlock()
+	if len(handlers) == 0 {
+		return
+	}
+
+	b.wg.Add(1)
+	b.queueMu.Lock()
+	defer b.queueMu.Unlock()
+	b.queue = append(b.queue, delivery{ctx: context.WithoutCancel(ctx), event: e, handlers: handlers})
+	if !b.draining {
+		b.draining = true
+		go b.drain()
+	}
+}
+
+// drain delivers the queued events until the queue is empty. At most one
+// drain runs at a time, which keeps the events in order.
+func (b *Bus) drain() {
+	for {
+		b.queueMu.Lock()
+		if len(b.queue) == 0 {
+			b.draining = false
+			b.queueMu.Unlock()
+			return
+		}
+		d := b.queue[0]
+		b.queue[0] = delivery{}
+		b.queue = b.queue[1:]
+		b.queueMu.Unlock()
+
+		for _, h := range d.handlers {
+			b.dispatch(d.ctx, d.event, h)
+		}
+		b.wg.Done()
+	}
+}
+
+func (b *Bus) dispatch(ctx context.Context, e Event, h Handler) {
+	log := b.log.With(slog.String("fn", "events.Bus.dispatch"), slog.String("kind", e.Kind()))
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error("event handler panicked", slog.String("panic", fmt.Sprint(p)))
+		}
+	}()
+	if err := h(ctx, e); err != nil {
+		log.Error("event handler failed", sl.Error(err))
+	}
+}
+
+// Wait blocks until the events published so far are delivered. It is used on shutdown.
+func (b *Bus) Wait() {
+	b.wg.Wait()
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"blog/internal/models"
+	"blog/internal/util/logger"
+)
+
+func TestBusKeepsOrder(t *testing.T) {
+	bus := NewBus(logger.NewLogger(nil))
+
+	var (
+		mu  sync.Mutex
+		got []string
+	)
+	record := func(s string) {
+		mu.Lock()
+		got = append(got, s)
+		mu.Unlock()
+	}
+	On(bus, func(ctx context.Context, e PostPublished) error {
+		// a slow first handler must not let the update overtake it
+		time.Sleep(20 * time.Millisecond)
+		record("created")
+		return nil
+	})
+	On(bus, func(ctx context.Context, e PostUpdated) error {
+		record("updated")
+		return nil
+	})
+
+	post := &models.Post{ID: 1}
+	bus.Publish(context.Background(), PostPublished{Post: post})
+	for range 3 {
+		bus.Publish(context.Background(), PostUpdated{Post: post})
+	}
+	bus.Wait()
+
+	want := []string{"created", "updated", "updated", "updated"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("handled %v, want %v", got, want)
+	}
+}
+
+func TestBusSurvivesPanics(t *testing.T) {
+	bus := NewBus(logger.NewLogger(nil))
+
+	var followed []int64
+	On(bus, func(ctx context.Context, e UserCreated) error {
+		panic("boom")
+	})
+	On(bus, func(ctx context.Context, e UserFollowed) error {
+		followed = append(followed, e.FollowerID)
+		return nil
+	})
+
+	bus.Publish(context.Background(), UserCreated{UserID: 1})
+	bus.Publish(context.Background(), UserFollowed{FollowerID: 2, FolloweeID: 1})
+	bus.Wait()
+
+	if !slices.Equal(followed, []int64{2}) {
+		t.Fatalf("followed = %v, want [2]", followed)
+	}
+}
+
+// TestBusWaitsForFollowUps checks that Wait covers events published by
+// handlers, as the federation inbox does.
+func TestBusWaitsForFollowUps(t *testing.T) {
+	bus := NewBus(logger.NewLogger(nil))
+
+	done := false
+	On(bus, func(ctx context.Context, e PostPublished) error {
+		bus.Publish(ctx, PostUpdated{Post: e.Post})
+		return nil
+	})
+	On(bus, func(ctx context.Context, e PostUpdated) error {
+		time.Sleep(10 * time.Millisecond)
+		done = true
+		return nil
+	})
+
+	bus.Publish(context.Background(), PostPublished{Post: &models.Post{ID: 1}})
+	bus.Wait()
+
+	if !done {
+		t.Fatal("Wait returned before the follow-up event was handled")
+	}
+}
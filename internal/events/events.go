@@ -0,0 +1,74 @@
+package events
+
+import "blog/internal/models"
+
+const (
+	KindPostPublished    = "post.published"
+	KindPostUpdated      = "post.updated"
+	KindPostVisibility   = "post.visibility"
+	KindCommentPublished = "comment.published"
+	KindCommentUpdated   = "comment.updated"
+	KindUserFollowed     = "user.followed"
+	KindUserUnfollowed   = "user.unfollowed"
+	KindUserCreated      = "user.created"
+)
+
+// PostPublished is sent when a new post is created.
+type PostPublished struct {
+	Post *models.Post
+}
+
+func (PostPublished) Kind() string { return KindPostPublished }
+
+// PostUpdated is sent when the content of a post changes, by an edit or a
+// restored revision.
+type PostUpdated struct {
+	Post *models.Post
+}
+
+func (PostUpdated) Kind() string { return KindPostUpdated }
+
+// PostVisibilityChanged is sent when a post may have appeared or disappeared
+// without being edited: deleted, restored from the trash, hidden or unhidden.
+// Subscribers look the post up to learn its current state.
+type PostVisibilityChanged struct {
+	PostID int64
+}
+
+func (PostVisibilityChanged) Kind() string { return KindPostVisibility }
+
+// CommentPublished is sent when a comment becomes visible: on creation when
+// it needs no review, or when a moderator approves it.
+type CommentPublished struct {
+	Comment *models.Comment
+}
+
+func (CommentPublished) Kind() string { return KindCommentPublished }
+
+// CommentUpdated is sent when a published comment is edited.
+type CommentUpdated struct {
+	Comment *models.Comment
+}
+
+func (CommentUpdated) Kind() string { return KindCommentUpdated }
+
+type UserFollowed struct {
+	FollowerID int64
+	FolloweeID int64
+}
+
+func (UserFollowed) Kind() string { return KindUserFollowed }
+
+type UserUnfollowed struct {
+	FollowerID int64
+	FolloweeID int64
+}
+
+func (UserUnfollowed) Kind() string { return KindUserUnfollowed }
+
+// UserCreated is sent when someone signs up.
+type UserCreated struct {
+	UserID int64
+}
+
+func (UserCreated) Kind() string { return KindUserCreated }
@@ -0,0 +1,462 @@
+package federation
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/events"
+	"blog/internal/mention"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/spam"
+	"blog/internal/util/logger"
+	"blog/internal/util/safehttp"
+)
+
+type store interface {
+	ActorKey(userID int64) (*models.ActorKey, error)
+	SaveActorKey(key *models.ActorKey) (*models.ActorKey, error)
+	SaveRemoteActor(actor *models.RemoteActor) error
+	GetRemoteActor(actorID string) (*models.RemoteActor, error)
+	SetRemoteActorUser(id, userID int64) error
+	AddRemoteFollower(userID, remoteActorID int64) (bool, error)
+	RemoveRemoteFollower(userID, remoteActorID int64) (bool, error)
+	FollowerInboxes(userID int64) ([]string, error)
+	CountRemoteFollowers(userID int64) (int64, error)
+	SetPostFederated(postID, userID int64, deleted bool) error
+	FederatedPost(postID int64) (userID int64, deleted bool, err error)
+	SaveRemoteObject(objectID string, commentID int64) error
+	RemoteObjectComment(objectID string) (int64, error)
+	EnqueueDeliveries(userID int64, inboxes []string, activity []byte) error
+}
+
+type userStore interface {
+	GetUserByID(id int64) (*models.User, error)
+	GetUserByEmail(email string) (*models.User, error)
+	CreateUser(user *models.User) (int64, error)
+	SetUserRole(id int64, role string) error
+}
+
+type postStore interface {
+	GetPostByID(id int64) (*models.Post, error)
+	GetPostsByIDs(ids []int64) (map[int64]*models.Post, error)
+	GetPostsByAuthor(authorID int64, page repository.Page) ([]*models.Post, error)
+}
+
+type commentStore interface {
+	CreateComment(comment *models.Comment) (int64, error)
+	GetCommentByID(id int64) (*models.Comment, error)
+	HasApprovedComment(authorID int64) (bool, error)
+	DeleteComment(id int64) error
+}
+
+type sanctionChecker interface {
+	ActiveSanction(userID int64, kinds ...string) (*models.Sanction, error)
+}
+
+type spamScorer interface {
+	Score(comment *models.Comment) (spam.Result, error)
+	Status(score float64) string
+}
+
+type mentionLinker interface {
+	Sync(targetType string, targetID int64, content string) error
+	Mentions(targetType string, ids []int64) (map[int64][]*models.Mention, error)
+}
+
+type eventPublisher interface {
+	Publish(ctx context.Context, e events.Event)
+}
+
+// Service makes every local user an ActivityPub actor. Posts are pushed to
+// remote followers as Create, Update and Delete activities through the
+// delivery queue, and replies from remote servers become comments authored
+// by a local stand-in user of role models.RoleRemote.
+type Service struct {
+	log        logger.Logger
+	store      store
+	users      userStore
+	posts      postStore
+	comments   commentStore
+	sanctions  sanctionChecker
+	spam       spamScorer
+	mentions   mentionLinker
+	publisher  eventPublisher
+	cfg        config.FederationConfig
+	commentCfg config.CommentsConfig
+
+	baseURL   string
+	host      string
+	userAgent string
+	client    *http.Client
+
+	keysMu sync.Mutex
+	keys   map[int64]*signingKey
+
+	queued chan struct{}
+}
+
+func New(log logger.Logger, store store, users userStore, posts postStore, comments commentStore, sanctions sanctionChecker,
+	spam spamScorer, mentions mentionLinker, publisher eventPublisher, baseURL string, cfg config.FederationConfig, commentCfg config.CommentsConfig) (*Service, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid base url %q", baseURL)
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	return &Service{
+		log:        log,
+		store:      store,
+		users:      users,
+		posts:      posts,
+		comments:   comments,
+		sanctions:  sanctions,
+		spam:       spam,
+		mentions:   mentions,
+		publisher:  publisher,
+		cfg:        cfg,
+		commentCfg: commentCfg,
+		baseURL:    baseURL,
+		host:       u.Host,
+		userAgent:  "blog (+" + baseURL + ")",
+		client:     safehttp.NewClient(cfg.Timeout, cfg.AllowInsecure),
+		keys:       make(map[int64]*signingKey),
+		queued:     make(chan struct{}, 1),
+	}, nil
+}
+
+// Host is the domain in acct: URIs of local users.
+func (s *Service) Host() string {
+	return s.host
+}
+
+// Queued signals after activities were added to the delivery queue.
+func (s *Service) Queued() <-chan struct{} {
+	return s.queued
+}
+
+func (s *Service) ActorURL(userID int64) string {
+	return fmt.Sprintf("%s/ap/users/%d", s.baseURL, userID)
+}
+
+func (s *Service) keyID(userID int64) string {
+	return s.ActorURL(userID) + "#main-key"
+}
+
+func (s *Service) PostURL(postID int64) string {
+	return fmt.Sprintf("%s/ap/posts/%d", s.baseURL, postID)
+}
+
+func (s *Service) SharedInboxURL() string {
+	return s.baseURL + "/ap/inbox"
+}
+
+// localUserID returns the user of a local actor IRI.
+func (s *Service) localUserID(iri string) (int64, bool) {
+	rest, ok := strings.CutPrefix(iri, s.baseURL+"/ap/users/")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(rest, 10, 64)
+	return id, err == nil
+}
+
+// localPostID accepts the note IRI of a post as well as its API URL, which
+// is what the feeds link to.
+func (s *Service) localPostID(iri string) (int64, bool) {
+	for _, prefix := range []string{s.baseURL + "/ap/posts/", s.baseURL + "/api/post/"} {
+		if rest, ok := strings.CutPrefix(iri, prefix); ok {
+			id, err := strconv.ParseInt(rest, 10, 64)
+			return id, err == nil
+		}
+	}
+	return 0, false
+}
+
+type signingKey struct {
+	private   *rsa.PrivateKey
+	publicPEM string
+}
+
+// privateKey returns the signing key of a user, generating it on first use.
+func (s *Service) privateKey(userID int64) (*rsa.PrivateKey, string, error) {
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+	if key, ok := s.keys[userID]; ok {
+		return key.private, key.publicPEM, nil
+	}
+
+	stored, err := s.store.ActorKey(userID)
+	if errors.Is(err, repository.ErrNotExists) {
+		priv, pub, genErr := GenerateKey()
+		if genErr != nil {
+			return nil, "", genErr
+		}
+		stored, err = s.store.SaveActorKey(&models.ActorKey{UserID: userID, PrivateKeyPEM: priv, PublicKeyPEM: pub})
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	key, err := ParsePrivateKey(stored.PrivateKeyPEM)
+	if err != nil {
+		return nil, "", fmt.Errorf("parse key of user %d: %w", userID, err)
+	}
+	s.keys[userID] = &signingKey{private: key, publicPEM: stored.PublicKeyPEM}
+	return key, stored.PublicKeyPEM, nil
+}
+
+// Subscribe pushes new, edited and removed posts to remote followers.
+func (s *Service) Subscribe(bus *events.Bus) {
+	events.On(bus, func(ctx context.Context, e events.PostPublished) error {
+		return s.publishPost(e.Post, "Create")
+	})
+	events.On(bus, func(ctx context.Context, e events.PostUpdated) error {
+		_, deleted, err := s.store.FederatedPost(e.Post.ID)
+		if errors.Is(err, repository.ErrNotExists) || deleted {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return s.publishPost(e.Post, "Update")
+	})
+	events.On(bus, func(ctx context.Context, e events.PostVisibilityChanged) error {
+		return s.postVisibilityChanged(e.PostID)
+	})
+}
+
+// postVisibilityChanged sends a Delete when a federated post was removed and
+// creates it again when it comes back from the trash or moderation.
+func (s *Service) postVisibilityChanged(postID int64) error {
+	authorID, deleted, err := s.store.FederatedPost(postID)
+	if errors.Is(err, repository.ErrNotExists) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	posts, err := s.posts.GetPostsByIDs([]int64{postID})
+	if err != nil {
+		return err
+	}
+
+	post, visible := posts[postID]
+	switch {
+	case visible && deleted:
+		return s.publishPost(post, "Create")
+	case !visible && !deleted:
+		now := time.Now().UTC()
+		tombstone := &Object{ID: s.PostURL(postID), Type: "Tombstone", FormerType: "Article", Deleted: &now}
+		if err := s.deliverToFollowers(authorID, "Delete", tombstone, now); err != nil {
+			return err
+		}
+		return s.store.SetPostFederated(postID, authorID, true)
+	}
+	return nil
+}
+
+func (s *Service) publishPost(post *models.Post, typ string) error {
+	article, err := s.Article(post)
+	if err != nil {
+		return err
+	}
+	if err := s.deliverToFollowers(post.AuthorID, typ, article, time.Now().UTC()); err != nil {
+		return err
+	}
+	return s.store.SetPostFederated(post.ID, post.AuthorID, false)
+}
+
+func (s *Service) deliverToFollowers(userID int64, typ string, object *Object, now time.Time) error {
+	activity, err := NewActivity(typ, fmt.Sprintf("%s#%s-%d", object.ID, strings.ToLower(typ), now.UnixNano()),
+		s.ActorURL(userID), object, []string{publicAudience}, []string{s.ActorURL(userID) + "/followers"})
+	if err != nil {
+		return err
+	}
+	activity.Context = activityStreams
+	activity.Published = &now
+
+	inboxes, err := s.store.FollowerInboxes(userID)
+	if err != nil {
+		return err
+	}
+	return s.enqueue(userID, inboxes, activity)
+}
+
+func (s *Service) enqueue(userID int64, inboxes []string, activity *Activity) error {
+	if len(inboxes) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	if err := s.store.EnqueueDeliveries(userID, inboxes, body); err != nil {
+		return err
+	}
+	s.log.Debug("activity queued", slog.String("fn", "federation.enqueue"),
+		slog.String("type", activity.Type), slog.String("id", activity.ID), slog.Int("inboxes", len(inboxes)))
+	select {
+	case s.queued <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Deliver posts a queued activity, signed with the key of userID. Errors
+// wrapping ErrRejected are final.
+func (s *Service) Deliver(ctx context.Context, userID int64, inbox string, activity []byte) error {
+	key, _, err := s.privateKey(userID)
+	if err != nil {
+		return err
+	}
+	return s.post(ctx, inbox, s.keyID(userID), key, activity)
+}
+
+// WebFinger resolves acct:user@host to the actor of a local user.
+func (s *Service) WebFinger(user *models.User) *WebFinger {
+	actor := s.ActorURL(user.ID)
+	return &WebFinger{
+		Subject: fmt.Sprintf("acct:%s@%s", user.Username, s.host),
+		Aliases: []string{actor},
+		Links: []WebFingerLink{
+			{Rel: "self", Type: ContentType, Href: actor},
+			{Rel: "http://webfinger.net/rel/profile-page", Type: "text/html", Href: s.profileURL(user.ID)},
+		},
+	}
+}
+
+// Actor returns the Person document of a local user.
+func (s *Service) Actor(user *models.User) (*Actor, error) {
+	_, pub, err := s.privateKey(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	id := s.ActorURL(user.ID)
+	published := user.CreatedAt.UTC()
+	return &Actor{
+		Context:           []string{activityStreams, securityV1},
+		ID:                id,
+		Type:              "Person",
+		PreferredUsername: user.Username,
+		Name:              user.Username,
+		URL:               s.profileURL(user.ID),
+		Inbox:             id + "/inbox",
+		Outbox:            id + "/outbox",
+		Followers:         id + "/followers",
+		Published:         &published,
+		Endpoints:         &Endpoints{SharedInbox: s.SharedInboxURL()},
+		PublicKey:         PublicKey{ID: s.keyID(user.ID), Owner: id, PublicKeyPEM: pub},
+	}, nil
+}
+
+func (s *Service) profileURL(userID int64) string {
+	return fmt.Sprintf("%s/api/user/%d/posts", s.baseURL, userID)
+}
+
+// Article returns the object a post is published as.
+func (s *Service) Article(post *models.Post) (*Object, error) {
+	mentions, err := s.mentions.Mentions(models.TargetPost, []int64{post.ID})
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mentions[post.ID] {
+		if strings.HasPrefix(m.URL, "/") {
+			m.URL = s.baseURL + m.URL
+		}
+	}
+
+	published := post.CreatedAt.UTC()
+	article := &Object{
+		ID:           s.PostURL(post.ID),
+		Type:         "Article",
+		AttributedTo: s.ActorURL(post.AuthorID),
+		Name:         post.Title,
+		Content:      paragraphs(mention.HTML(post.Content, mentions[post.ID])),
+		URL:          fmt.Sprintf("%s/api/post/%d", s.baseURL, post.ID),
+		To:           []string{publicAudience},
+		Cc:           []string{s.ActorURL(post.AuthorID) + "/followers"},
+		Published:    &published,
+	}
+	if post.UpdatedAt.After(post.CreatedAt) {
+		updated := post.UpdatedAt.UTC()
+		article.Updated = &updated
+	}
+	return article, nil
+}
+
+// PostDocument returns the object of a post as served at its IRI.
+func (s *Service) PostDocument(post *models.Post) (*Object, error) {
+	article, err := s.Article(post)
+	if err != nil {
+		return nil, err
+	}
+	article.Context = activityStreams
+	return article, nil
+}
+
+// Outbox lists the newest posts of a user as Create activities.
+func (s *Service) Outbox(user *models.User, limit int) (*OrderedCollection, error) {
+	posts, err := s.posts.GetPostsByAuthor(user.ID, repository.Page{Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	outbox := &OrderedCollection{
+		Context:      activityStreams,
+		ID:           s.ActorURL(user.ID) + "/outbox",
+		Type:         "OrderedCollection",
+		OrderedItems: make([]Activity, 0, len(posts)),
+	}
+	for _, post := range posts {
+		article, err := s.Article(post)
+		if err != nil {
+			return nil, err
+		}
+		activity, err := NewActivity("Create", article.ID+"#create", article.AttributedTo, article, article.To, article.Cc)
+		if err != nil {
+			return nil, err
+		}
+		activity.Published = article.Published
+		outbox.OrderedItems = append(outbox.OrderedItems, *activity)
+	}
+	return outbox, nil
+}
+
+// Followers returns the follower collection of a user, only its size is public.
+func (s *Service) Followers(user *models.User) (*OrderedCollection, error) {
+	count, err := s.store.CountRemoteFollowers(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &OrderedCollection{
+		Context:    activityStreams,
+		ID:         s.ActorURL(user.ID) + "/followers",
+		Type:       "OrderedCollection",
+		TotalItems: &count,
+	}, nil
+}
+
+// paragraphs wraps escaped text into <p> elements at blank lines, keeping
+// single line breaks.
+func paragraphs(escaped string) string {
+	var b strings.Builder
+	for _, p := range strings.Split(strings.ReplaceAll(escaped, "\r\n", "\n"), "\n\n") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(p, "\n", "<br>"))
+		b.WriteString("</p>")
+	}
+	return b.String()
+}
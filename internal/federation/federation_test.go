@@ -0,0 +1,423 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/events"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/repository/sqliterepo"
+	"blog/internal/spam"
+	"blog/internal/util/logger"
+)
+
+const testBaseURL = "https://blog.example"
+
+// remote is a fake ActivityPub server with one actor. Its inbox checks the
+// signature of what is delivered, against verifier when set.
+type remote struct {
+	t      *testing.T
+	srv    *httptest.Server
+	key    *rsa.PrivateKey
+	pubPEM string
+
+	mu       sync.Mutex
+	verifier *rsa.PublicKey
+	received []*Activity
+}
+
+func newRemote(t *testing.T) *remote {
+	t.Helper()
+	privPEM, pubPEM, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	key, err := ParsePrivateKey(privPEM)
+	if err != nil {
+		t.Fatalf("parse key: %v", err)
+	}
+
+	rm := &remote{t: t, key: key, pubPEM: pubPEM}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /actor", rm.actor)
+	mux.HandleFunc("POST /inbox", rm.inbox)
+	rm.srv = httptest.NewServer(mux)
+	t.Cleanup(rm.srv.Close)
+	return rm
+}
+
+func (rm *remote) actorID() string { return rm.srv.URL + "/actor" }
+func (rm *remote) keyID() string   { return rm.actorID() + "#main-key" }
+
+func (rm *remote) actor(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	json.NewEncoder(w).Encode(Actor{
+		ID:                rm.actorID(),
+		Type:              "Person",
+		PreferredUsername: "bob",
+		Inbox:             rm.srv.URL + "/inbox",
+		PublicKey:         PublicKey{ID: rm.keyID(), Owner: rm.actorID(), PublicKeyPEM: rm.pubPEM},
+	})
+}
+
+func (rm *remote) inbox(w http.ResponseWriter, r *http.Request) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	sig, err := ParseSignature(r, body)
+	if err == nil && rm.verifier != nil {
+		err = sig.Verify(rm.verifier)
+	}
+	if err != nil {
+		rm.t.Errorf("delivery to the remote inbox: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var activity Activity
+	if err := json.Unmarshal(body, &activity); err != nil {
+		rm.t.Errorf("delivered activity: %v", err)
+	}
+	rm.received = append(rm.received, &activity)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (rm *remote) activities() []*Activity {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return rm.received
+}
+
+// request returns an inbox request carrying activity, signed by the actor
+// unless key is nil.
+func (rm *remote) request(t *testing.T, activity any, key *rsa.PrivateKey) (*http.Request, []byte) {
+	t.Helper()
+	body, err := json.Marshal(activity)
+	if err != nil {
+		t.Fatalf("marshal activity: %v", err)
+	}
+	r := httptest.NewRequest(http.MethodPost, testBaseURL+"/ap/inbox", bytes.NewReader(body))
+	r.Header.Set("Content-Type", ContentType)
+	if key != nil {
+		if err := Sign(r, rm.keyID(), key, body); err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+	}
+	return r, body
+}
+
+type noSpam struct{}
+
+func (noSpam) Score(comment *models.Comment) (spam.Result, error) { return spam.Result{}, nil }
+func (noSpam) Status(score float64) string                        { return "" }
+
+type noMentions struct{}
+
+func (noMentions) Sync(targetType string, targetID int64, content string) error { return nil }
+func (noMentions) Mentions(targetType string, ids []int64) (map[int64][]*models.Mention, error) {
+	return nil, nil
+}
+
+// newTestService returns a service over an empty database that may talk to
+// the fake remote on localhost.
+func newTestService(t *testing.T) (*Service, repository.Repository) {
+	t.Helper()
+	log := logger.NewLogger(nil)
+	db, err := sqliterepo.New("file:"+filepath.Join(t.TempDir(), "blog.db")+"?_foreign_keys=on", log)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	repo := sqliterepo.NewSQLiteRepository(log, db)
+	for _, init := range []func() error{
+		repo.User().InitUserDatabase,
+		repo.Post().InitPostDatabase,
+		repo.Revision().InitRevisionDatabase,
+		repo.Comment().InitCommentDatabase,
+		repo.Sanction().InitSanctionDatabase,
+		repo.Federation().InitFederationDatabase,
+	} {
+		if err := init(); err != nil {
+			t.Fatalf("init database: %v", err)
+		}
+	}
+
+	cfg := config.FederationConfig{
+		Enabled:       true,
+		AllowInsecure: true,
+		Timeout:       5 * time.Second,
+		MaxAttempts:   3,
+		RetryBackoff:  time.Minute,
+		ActorCacheTTL: time.Hour,
+	}
+	bus := events.NewBus(log)
+	t.Cleanup(bus.Wait)
+	s, err := New(log, repo.Federation(), repo.User(), repo.Post(), repo.Comment(), repo.Sanction(),
+		noSpam{}, noMentions{}, bus, testBaseURL, cfg, config.CommentsConfig{MaxDepth: 8, Moderation: models.ModerationOpen})
+	if err != nil {
+		t.Fatalf("new service: %v", err)
+	}
+	return s, repo
+}
+
+func createUser(t *testing.T, repo repository.Repository, name string) int64 {
+	t.Helper()
+	id, err := repo.User().CreateUser(&models.User{Username: name, Email: name + "@example.com", Password: "x"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return id
+}
+
+func TestSignature(t *testing.T) {
+	rm := newRemote(t)
+	_, otherPEM, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	otherKey, err := ParsePublicKey(otherPEM)
+	if err != nil {
+		t.Fatalf("parse key: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(r *http.Request, body []byte) []byte
+		key    *rsa.PublicKey
+		parse  bool // ParseSignature accepts the request
+		verify bool // and the signature is valid
+	}{
+		{"valid", nil, &rm.key.PublicKey, true, true},
+		{"other key", nil, otherKey, true, false},
+		{"body changed", func(r *http.Request, body []byte) []byte {
+			return bytes.Replace(body, []byte("Follow"), []byte("Delete"), 1)
+		}, &rm.key.PublicKey, false, false},
+		{"digest replaced along with the body", func(r *http.Request, body []byte) []byte {
+			body = bytes.Replace(body, []byte("Follow"), []byte("Delete"), 1)
+			r.Header.Set("Digest", digest(body))
+			return body
+		}, &rm.key.PublicKey, true, false},
+		{"path changed", func(r *http.Request, body []byte) []byte {
+			r.URL.Path = "/ap/users/1/inbox"
+			return body
+		}, &rm.key.PublicKey, true, false},
+		{"stale date", func(r *http.Request, body []byte) []byte {
+			r.Header.Set("Date", time.Now().Add(-2*maxClockSkew).UTC().Format(http.TimeFormat))
+			return body
+		}, &rm.key.PublicKey, false, false},
+		{"digest not signed", func(r *http.Request, body []byte) []byte {
+			sig := r.Header.Get("Signature")
+			r.Header.Set("Signature", strings.Replace(sig, " digest", "", 1))
+			return body
+		}, &rm.key.PublicKey, false, false},
+		{"unsigned", func(r *http.Request, body []byte) []byte {
+			r.Header.Del("Signature")
+			return body
+		}, &rm.key.PublicKey, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, body := rm.request(t, map[string]string{"type": "Follow", "actor": rm.actorID()}, rm.key)
+			if tt.tamper != nil {
+				body = tt.tamper(r, body)
+			}
+			sig, err := ParseSignature(r, body)
+			if (err == nil) != tt.parse {
+				t.Fatalf("ParseSignature error = %v, want accepted %v", err, tt.parse)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrSignature) {
+					t.Fatalf("error %v is not ErrSignature", err)
+				}
+				return
+			}
+			if sig.KeyID != rm.keyID() {
+				t.Fatalf("key id = %q, want %q", sig.KeyID, rm.keyID())
+			}
+			if err := sig.Verify(tt.key); (err == nil) != tt.verify {
+				t.Fatalf("Verify error = %v, want valid %v", err, tt.verify)
+			}
+		})
+	}
+}
+
+func TestInboxChecksSignature(t *testing.T) {
+	s, repo := newTestService(t)
+	rm := newRemote(t)
+	userID := createUser(t, repo, "alice")
+
+	privPEM, _, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	forged, err := ParsePrivateKey(privPEM)
+	if err != nil {
+		t.Fatalf("parse key: %v", err)
+	}
+	follow := map[string]string{"id": rm.srv.URL + "/follows/1", "type": "Follow", "actor": rm.actorID(), "object": s.ActorURL(userID)}
+
+	tests := []struct {
+		name string
+		key  *rsa.PrivateKey
+	}{
+		{"unsigned", nil},
+		{"signed with a key not of the actor", forged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, body := rm.request(t, follow, tt.key)
+			if err := s.HandleInbox(context.Background(), r, body); !errors.Is(err, ErrSignature) {
+				t.Fatalf("HandleInbox error = %v, want ErrSignature", err)
+			}
+		})
+	}
+
+	n, err := repo.Federation().CountRemoteFollowers(userID)
+	if err != nil {
+		t.Fatalf("count followers: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("%d followers added by unverified requests", n)
+	}
+}
+
+func TestFollowIsAccepted(t *testing.T) {
+	s, repo := newTestService(t)
+	rm := newRemote(t)
+	userID := createUser(t, repo, "alice")
+	ctx := context.Background()
+
+	followID := rm.srv.URL + "/follows/1"
+	r, body := rm.request(t, map[string]string{"id": followID, "type": "Follow", "actor": rm.actorID(), "object": s.ActorURL(userID)}, rm.key)
+	if err := s.HandleInbox(ctx, r, body); err != nil {
+		t.Fatalf("HandleInbox: %v", err)
+	}
+
+	n, err := repo.Federation().CountRemoteFollowers(userID)
+	if err != nil {
+		t.Fatalf("count followers: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("followers = %d, want 1", n)
+	}
+
+	due, err := repo.Federation().DueDeliveries(time.Now(), 10)
+	if err != nil {
+		t.Fatalf("due deliveries: %v", err)
+	}
+	if len(due) != 1 || due[0].Inbox != rm.srv.URL+"/inbox" || due[0].UserID != userID {
+		t.Fatalf("queued deliveries = %+v, want one Accept to the remote inbox", due)
+	}
+
+	// the remote checks that the Accept is signed by alice's key
+	_, pubPEM, err := s.privateKey(userID)
+	if err != nil {
+		t.Fatalf("key of alice: %v", err)
+	}
+	key, err := ParsePublicKey(pubPEM)
+	if err != nil {
+		t.Fatalf("parse key of alice: %v", err)
+	}
+	rm.mu.Lock()
+	rm.verifier = key
+	rm.mu.Unlock()
+	if err := s.Deliver(ctx, due[0].UserID, due[0].Inbox, due[0].Activity); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	received := rm.activities()
+	if len(received) != 1 {
+		t.Fatalf("remote received %d activities, want 1", len(received))
+	}
+	accept := received[0]
+	if accept.Type != "Accept" || accept.Actor != s.ActorURL(userID) || accept.ObjectID() != followID {
+		t.Fatalf("got %s by %s of %s, want Accept by %s of %s", accept.Type, accept.Actor, accept.ObjectID(), s.ActorURL(userID), followID)
+	}
+}
+
+func TestReplyBecomesComment(t *testing.T) {
+	s, repo := newTestService(t)
+	rm := newRemote(t)
+	userID := createUser(t, repo, "alice")
+	postID, err := repo.Post().CreatePost(&models.Post{Title: "Hello", Content: "World", AuthorID: userID})
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	ctx := context.Background()
+
+	create := func(noteID, inReplyTo, content string) error {
+		note := Object{ID: noteID, Type: "Note", AttributedTo: rm.actorID(), InReplyTo: inReplyTo, Content: content}
+		activity, err := NewActivity("Create", noteID+"/activity", rm.actorID(), note, []string{publicAudience}, nil)
+		if err != nil {
+			t.Fatalf("new activity: %v", err)
+		}
+		r, body := rm.request(t, activity, rm.key)
+		return s.HandleInbox(ctx, r, body)
+	}
+
+	noteID := rm.srv.URL + "/notes/1"
+	if err := create(noteID, s.PostURL(postID), "<p>Nice post &amp; thanks</p>"); err != nil {
+		t.Fatalf("HandleInbox: %v", err)
+	}
+	commentID, err := repo.Federation().RemoteObjectComment(noteID)
+	if err != nil {
+		t.Fatalf("stored reply: %v", err)
+	}
+	comment, err := repo.Comment().GetCommentByID(commentID)
+	if err != nil {
+		t.Fatalf("get comment: %v", err)
+	}
+	if comment.PostID != postID || comment.Content != "Nice post & thanks" || comment.Status != models.CommentStatusApproved {
+		t.Fatalf("comment = %+v, want approved plain text reply to post %d", comment, postID)
+	}
+	author, err := repo.User().GetUserByID(comment.AuthorID)
+	if err != nil {
+		t.Fatalf("get author: %v", err)
+	}
+	if author.Role != models.RoleRemote || author.Username != "bob@"+strings.TrimPrefix(rm.srv.URL, "http://") {
+		t.Fatalf("author = %s (%s), want remote stand-in of bob", author.Username, author.Role)
+	}
+
+	// delivered again, the reply is not stored twice
+	if err := create(noteID, s.PostURL(postID), "<p>Nice post &amp; thanks</p>"); err != nil {
+		t.Fatalf("HandleInbox again: %v", err)
+	}
+
+	// a reply to the reply is threaded under its comment
+	replyID := rm.srv.URL + "/notes/2"
+	if err := create(replyID, noteID, "and another thing"); err != nil {
+		t.Fatalf("HandleInbox reply: %v", err)
+	}
+	replyCommentID, err := repo.Federation().RemoteObjectComment(replyID)
+	if err != nil {
+		t.Fatalf("stored reply to the reply: %v", err)
+	}
+	reply, err := repo.Comment().GetCommentByID(replyCommentID)
+	if err != nil {
+		t.Fatalf("get comment: %v", err)
+	}
+	if reply.ParentID == nil || *reply.ParentID != commentID || reply.Depth != comment.Depth+1 {
+		t.Fatalf("reply parent = %v depth %d, want %d depth %d", reply.ParentID, reply.Depth, commentID, comment.Depth+1)
+	}
+
+	comments, err := repo.Comment().ListComments(repository.Page{Limit: 10}, postID, 0)
+	if err != nil {
+		t.Fatalf("list comments: %v", err)
+	}
+	if len(comments) != 2 {
+		t.Fatalf("post has %d comments, want 2", len(comments))
+	}
+}
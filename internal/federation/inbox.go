@@ -0,0 +1,446 @@
+package federation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"blog/internal/events"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger/sl"
+)
+
+// maxCommentLength matches the limit of comments created through the API.
+const maxCommentLength = 1024
+
+var ErrBadActivity = errors.New("malformed activity")
+
+var (
+	breakRe = regexp.MustCompile(`(?i)<br\s*/?>|</p>`)
+	tagRe   = regexp.MustCompile(`<[^>]*>`)
+	blankRe = regexp.MustCompile(`\n{3,}`)
+)
+
+// HandleInbox processes an activity posted to an inbox. The request must be
+// signed by the key of the activity's actor. Activities that don't concern
+// this server are accepted and dropped.
+func (s *Service) HandleInbox(ctx context.Context, r *http.Request, body []byte) error {
+	log := s.log.With(slog.String("fn", "federation.HandleInbox"))
+
+	var activity Activity
+	if err := json.Unmarshal(body, &activity); err != nil {
+		return fmt.Errorf("%w: %v", ErrBadActivity, err)
+	}
+	if activity.Type == "" || activity.Actor == "" {
+		return fmt.Errorf("%w: missing type or actor", ErrBadActivity)
+	}
+	log = log.With(slog.String("type", activity.Type), slog.String("actor", activity.Actor))
+
+	sig, err := ParseSignature(r, body)
+	if err != nil {
+		return err
+	}
+
+	// servers announce deleted accounts to everyone, there's no key left to
+	// check and nothing to do for actors never seen
+	if activity.Type == "Delete" && activity.ObjectID() == activity.Actor {
+		if _, err := s.store.GetRemoteActor(activity.Actor); errors.Is(err, repository.ErrNotExists) {
+			return nil
+		}
+	}
+
+	actor, err := s.verifiedActor(ctx, activity.Actor, sig)
+	if err != nil {
+		return err
+	}
+
+	switch activity.Type {
+	case "Follow":
+		return s.follow(actor, &activity, body)
+	case "Undo":
+		return s.undo(actor, &activity)
+	case "Create":
+		return s.create(ctx, actor, &activity)
+	case "Delete":
+		return s.delete(actor, &activity)
+	}
+	log.Debug("activity ignored")
+	return nil
+}
+
+// verifiedActor checks that the request is signed by the actor. A remote
+// key may have been rotated, so the actor is fetched once more before a
+// signature is refused.
+func (s *Service) verifiedActor(ctx context.Context, actorID string, sig *Signature) (*models.RemoteActor, error) {
+	for _, refresh := range []bool{false, true} {
+		actor, err := s.RemoteActor(ctx, actorID, refresh)
+		if err != nil {
+			return nil, err
+		}
+		if actor.KeyID != sig.KeyID {
+			continue
+		}
+		key, err := ParsePublicKey(actor.PublicKeyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSignature, err)
+		}
+		if err := sig.Verify(key); err == nil {
+			return actor, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: not signed by %s", ErrSignature, actorID)
+}
+
+// RemoteActor returns a remote actor, fetching its document when it's not
+// known yet, older than actor_cache_ttl or refresh is set.
+func (s *Service) RemoteActor(ctx context.Context, actorID string, refresh bool) (*models.RemoteActor, error) {
+	cached, err := s.store.GetRemoteActor(actorID)
+	if err != nil && !errors.Is(err, repository.ErrNotExists) {
+		return nil, err
+	}
+	if cached != nil && !refresh && time.Since(cached.FetchedAt) < s.cfg.ActorCacheTTL {
+		return cached, nil
+	}
+
+	var doc Actor
+	if err := s.fetch(ctx, actorID, &doc); err != nil {
+		return nil, fmt.Errorf("%w: fetch actor: %v", ErrSignature, err)
+	}
+	if doc.ID != actorID || doc.PublicKey.Owner != doc.ID || doc.PublicKey.ID == "" {
+		return nil, fmt.Errorf("%w: actor document of %s doesn't match", ErrSignature, actorID)
+	}
+	if _, err := ParsePublicKey(doc.PublicKey.PublicKeyPEM); err != nil {
+		return nil, fmt.Errorf("%w: actor key: %v", ErrSignature, err)
+	}
+	if !sameHost(doc.ID, doc.Inbox) {
+		return nil, fmt.Errorf("%w: inbox of %s is on another host", ErrBadActivity, actorID)
+	}
+
+	actor := &models.RemoteActor{
+		ActorID:      doc.ID,
+		Username:     doc.PreferredUsername,
+		Inbox:        doc.Inbox,
+		KeyID:        doc.PublicKey.ID,
+		PublicKeyPEM: doc.PublicKey.PublicKeyPEM,
+	}
+	if doc.Endpoints != nil && sameHost(doc.ID, doc.Endpoints.SharedInbox) {
+		actor.SharedInbox = doc.Endpoints.SharedInbox
+	}
+	if err := s.store.SaveRemoteActor(actor); err != nil {
+		return nil, err
+	}
+	return actor, nil
+}
+
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Host != "" && ua.Scheme == ub.Scheme && ua.Host == ub.Host
+}
+
+// localActor returns the local user an activity is addressed to, nil if
+// there is none.
+func (s *Service) localActor(iri string) (*models.User, error) {
+	userID, ok := s.localUserID(iri)
+	if !ok {
+		return nil, nil
+	}
+	user, err := s.users.GetUserByID(userID)
+	if errors.Is(err, repository.ErrNotExists) || (err == nil && user.Role == models.RoleRemote) {
+		return nil, nil
+	}
+	return user, err
+}
+
+// follow adds a remote follower and answers with an Accept.
+func (s *Service) follow(actor *models.RemoteActor, activity *Activity, body []byte) error {
+	user, err := s.localActor(activity.ObjectID())
+	if err != nil || user == nil {
+		return err
+	}
+	added, err := s.store.AddRemoteFollower(user.ID, actor.ID)
+	if err != nil {
+		return err
+	}
+
+	// the Follow is accepted again if it's repeated, the remote side
+	// may have missed the first Accept
+	now := time.Now().UTC()
+	accept, err := NewActivity("Accept", fmt.Sprintf("%s#accept-%d", s.ActorURL(user.ID), now.UnixNano()),
+		s.ActorURL(user.ID), json.RawMessage(body), []string{actor.ActorID}, nil)
+	if err != nil {
+		return err
+	}
+	accept.Context = activityStreams
+	if err := s.enqueue(user.ID, []string{actor.Inbox}, accept); err != nil {
+		return err
+	}
+	s.log.Info("remote follower", slog.String("fn", "federation.follow"),
+		slog.Int64("user_id", user.ID), slog.String("actor", actor.ActorID), slog.Bool("new", added))
+	return nil
+}
+
+// undo handles Undo Follow, the only activity that is undone here.
+func (s *Service) undo(actor *models.RemoteActor, activity *Activity) error {
+	var inner Activity
+	if err := json.Unmarshal(activity.Object, &inner); err != nil || inner.Type != "Follow" {
+		return nil
+	}
+	if inner.Actor != actor.ActorID {
+		return fmt.Errorf("%w: undo of a foreign follow", ErrBadActivity)
+	}
+	user, err := s.localActor(inner.ObjectID())
+	if err != nil || user == nil {
+		return err
+	}
+	removed, err := s.store.RemoveRemoteFollower(user.ID, actor.ID)
+	if err != nil {
+		return err
+	}
+	if removed {
+		s.log.Info("remote follower left", slog.String("fn", "federation.undo"),
+			slog.Int64("user_id", user.ID), slog.String("actor", actor.ActorID))
+	}
+	return nil
+}
+
+// create turns Notes replying to local posts, or to remote replies already
+// stored, into comments. Everything else is dropped.
+func (s *Service) create(ctx context.Context, actor *models.RemoteActor, activity *Activity) error {
+	log := s.log.With(slog.String("fn", "federation.create"), slog.String("actor", actor.ActorID))
+
+	var note Object
+	if err := json.Unmarshal(activity.Object, &note); err != nil || note.Type != "Note" || note.InReplyTo == "" {
+		return nil
+	}
+	if note.ID == "" || note.AttributedTo != actor.ActorID || !sameHost(note.ID, actor.ActorID) {
+		return fmt.Errorf("%w: note not attributed to the actor", ErrBadActivity)
+	}
+	log = log.With(slog.String("note", note.ID))
+
+	if _, err := s.store.RemoteObjectComment(note.ID); err == nil {
+		return nil
+	} else if !errors.Is(err, repository.ErrNotExists) {
+		return err
+	}
+
+	var parent *models.Comment
+	postID, ok := s.localPostID(note.InReplyTo)
+	if !ok {
+		parentID, err := s.store.RemoteObjectComment(note.InReplyTo)
+		if errors.Is(err, repository.ErrNotExists) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if parent, err = s.comments.GetCommentByID(parentID); err != nil {
+			if errors.Is(err, repository.ErrNotExists) {
+				return nil
+			}
+			return err
+		}
+		postID = parent.PostID
+	}
+
+	post, err := s.posts.GetPostByID(postID)
+	if errors.Is(err, repository.ErrNotExists) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if post.HiddenAt != nil || post.CommentsLocked {
+		log.Info("reply to a locked or hidden post dropped", slog.Int64("post_id", postID))
+		return nil
+	}
+
+	content := plainText(note.Content)
+	if content == "" {
+		return nil
+	}
+
+	authorID, err := s.remoteUser(actor)
+	if err != nil {
+		return err
+	}
+	_, err = s.sanctions.ActiveSanction(authorID, models.SanctionSuspend, models.SanctionBan)
+	if err == nil {
+		log.Info("reply of a sanctioned actor dropped", slog.Int64("user_id", authorID))
+		return nil
+	}
+	if !errors.Is(err, repository.ErrNotExists) {
+		return err
+	}
+
+	status, err := s.moderationStatus(post, authorID)
+	if err != nil {
+		return err
+	}
+	comment := &models.Comment{
+		Content:  content,
+		PostID:   postID,
+		AuthorID: authorID,
+		Status:   status,
+	}
+	if parent != nil {
+		if parent.Status != models.CommentStatusApproved || parent.Depth+1 > s.commentCfg.MaxDepth {
+			log.Info("reply to an unpublished or too deep comment dropped", slog.Int64("parent_id", parent.ID))
+			return nil
+		}
+		comment.ParentID = &parent.ID
+		comment.Depth = parent.Depth + 1
+	}
+
+	res, err := s.spam.Score(comment)
+	if err != nil {
+		// scoring is advisory, fall back to the moderation mode
+		log.Error("failed to score comment", sl.Error(err))
+	}
+	comment.SpamScore = res.Score
+	switch s.spam.Status(res.Score) {
+	case models.CommentStatusSpam:
+		comment.Status = models.CommentStatusSpam
+	case models.CommentStatusPending:
+		comment.Status = models.CommentStatusPending
+	}
+
+	commentID, err := s.comments.CreateComment(comment)
+	if err != nil {
+		return err
+	}
+	if err := s.store.SaveRemoteObject(note.ID, commentID); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			// delivered twice at the same time, the other one won
+			return s.comments.DeleteComment(commentID)
+		}
+		return err
+	}
+	if err := s.mentions.Sync(models.TargetComment, commentID, comment.Content); err != nil {
+		log.Error("failed to link mentions", sl.Error(err), slog.Int64("comment_id", commentID))
+	}
+	if comment.Status == models.CommentStatusApproved {
+		s.publisher.Publish(ctx, events.CommentPublished{Comment: comment})
+	}
+	log.Info("remote reply stored", slog.Int64("comment_id", commentID), slog.String("status", comment.Status))
+	return nil
+}
+
+// moderationStatus applies the moderation mode of the post the same way as
+// for local comments.
+func (s *Service) moderationStatus(post *models.Post, authorID int64) (string, error) {
+	mode := post.CommentMode
+	if mode == "" {
+		mode = s.commentCfg.Moderation
+	}
+	switch mode {
+	case models.ModerationAll:
+		return models.CommentStatusPending, nil
+	case models.ModerationFirstTime:
+		approved, err := s.comments.HasApprovedComment(authorID)
+		if err != nil {
+			return "", err
+		}
+		if !approved {
+			return models.CommentStatusPending, nil
+		}
+	}
+	return models.CommentStatusApproved, nil
+}
+
+// remoteUser returns the local user authoring the comments of a remote
+// actor, creating it on the first reply. It can't sign in: its password is
+// not a hash any password produces and its email is undeliverable.
+func (s *Service) remoteUser(actor *models.RemoteActor) (int64, error) {
+	if actor.UserID != nil {
+		return *actor.UserID, nil
+	}
+
+	host := actor.ActorID
+	if u, err := url.Parse(actor.ActorID); err == nil {
+		host = u.Host
+	}
+	user := &models.User{
+		Username: fmt.Sprintf("%s@%s", actor.Username, host),
+		Password: "!",
+		Email:    fmt.Sprintf("%x@remote.invalid", sha256.Sum256([]byte(actor.ActorID))),
+	}
+	userID, err := s.users.CreateUser(user)
+	if errors.Is(err, repository.ErrUsernameAlreadyExists) {
+		user.Username = fmt.Sprintf("%s-%d", user.Username, actor.ID)
+		userID, err = s.users.CreateUser(user)
+	}
+	if errors.Is(err, repository.ErrEmailAlreadyExists) {
+		// created by a reply delivered at the same time
+		existing, getErr := s.users.GetUserByEmail(user.Email)
+		if getErr != nil {
+			return 0, getErr
+		}
+		userID, err = existing.ID, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := s.users.SetUserRole(userID, models.RoleRemote); err != nil {
+		return 0, err
+	}
+	if err := s.store.SetRemoteActorUser(actor.ID, userID); err != nil {
+		return 0, err
+	}
+	actor.UserID = &userID
+	return userID, nil
+}
+
+// delete removes the comment a deleted remote note was stored as.
+func (s *Service) delete(actor *models.RemoteActor, activity *Activity) error {
+	commentID, err := s.store.RemoteObjectComment(activity.ObjectID())
+	if errors.Is(err, repository.ErrNotExists) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	comment, err := s.comments.GetCommentByID(commentID)
+	if errors.Is(err, repository.ErrNotExists) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if actor.UserID == nil || comment.AuthorID != *actor.UserID {
+		return fmt.Errorf("%w: delete of a foreign note", ErrBadActivity)
+	}
+	if err := s.comments.DeleteComment(commentID); err != nil {
+		return err
+	}
+	s.log.Info("remote reply deleted", slog.String("fn", "federation.delete"), slog.Int64("comment_id", commentID))
+	return nil
+}
+
+// plainText turns the HTML of a remote note into comment text.
+func plainText(content string) string {
+	text := breakRe.ReplaceAllString(content, "\n")
+	text = html.UnescapeString(tagRe.ReplaceAllString(text, ""))
+	text = strings.TrimSpace(blankRe.ReplaceAllString(text, "\n\n"))
+	if utf8.RuneCountInString(text) > maxCommentLength {
+		text = string([]rune(text)[:maxCommentLength])
+	}
+	return text
+}
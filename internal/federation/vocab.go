@@ -0,0 +1,122 @@
+package federation
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ContentType is what actors, objects and activities are served and posted as.
+const ContentType = "application/activity+json"
+
+const (
+	activityStreams = "https://www.w3.org/ns/activitystreams"
+	securityV1      = "https://w3id.org/security/v1"
+	publicAudience  = activityStreams + "#Public"
+)
+
+// acceptedTypes are the media types a remote document may come back as.
+var acceptedTypes = []string{ContentType, "application/ld+json", "application/json"}
+
+// Actor is the document describing a local user, or a remote one as far as
+// it is read.
+type Actor struct {
+	Context           any        `json:"@context,omitempty"`
+	ID                string     `json:"id"`
+	Type              string     `json:"type"`
+	PreferredUsername string     `json:"preferredUsername"`
+	Name              string     `json:"name,omitempty"`
+	URL               string     `json:"url,omitempty"`
+	Inbox             string     `json:"inbox"`
+	Outbox            string     `json:"outbox,omitempty"`
+	Followers         string     `json:"followers,omitempty"`
+	Published         *time.Time `json:"published,omitempty"`
+	Endpoints         *Endpoints `json:"endpoints,omitempty"`
+	PublicKey         PublicKey  `json:"publicKey"`
+}
+
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPEM string `json:"publicKeyPem"`
+}
+
+// Object covers the objects exchanged: posts go out as Articles, replies
+// come in as Notes and deleted posts are Tombstones.
+type Object struct {
+	Context      any        `json:"@context,omitempty"`
+	ID           string     `json:"id"`
+	Type         string     `json:"type"`
+	AttributedTo string     `json:"attributedTo,omitempty"`
+	InReplyTo    string     `json:"inReplyTo,omitempty"`
+	Name         string     `json:"name,omitempty"`
+	Content      string     `json:"content,omitempty"`
+	URL          string     `json:"url,omitempty"`
+	To           []string   `json:"to,omitempty"`
+	Cc           []string   `json:"cc,omitempty"`
+	Published    *time.Time `json:"published,omitempty"`
+	Updated      *time.Time `json:"updated,omitempty"`
+	FormerType   string     `json:"formerType,omitempty"`
+	Deleted      *time.Time `json:"deleted,omitempty"`
+}
+
+// Activity wraps an object, which is either embedded or referenced by its id.
+type Activity struct {
+	Context   any             `json:"@context,omitempty"`
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Actor     string          `json:"actor"`
+	Object    json.RawMessage `json:"object"`
+	To        []string        `json:"to,omitempty"`
+	Cc        []string        `json:"cc,omitempty"`
+	Published *time.Time      `json:"published,omitempty"`
+}
+
+// ObjectID returns the id of the object, embedded or not.
+func (a *Activity) ObjectID() string {
+	var id string
+	if json.Unmarshal(a.Object, &id) == nil {
+		return id
+	}
+	var obj struct {
+		ID string `json:"id"`
+	}
+	if json.Unmarshal(a.Object, &obj) == nil {
+		return obj.ID
+	}
+	return ""
+}
+
+// OrderedCollection is used for outboxes and follower collections.
+type OrderedCollection struct {
+	Context      any        `json:"@context,omitempty"`
+	ID           string     `json:"id"`
+	Type         string     `json:"type"`
+	TotalItems   *int64     `json:"totalItems,omitempty"`
+	OrderedItems []Activity `json:"orderedItems,omitempty"`
+}
+
+// WebFinger is the JRD served for acct: resources.
+type WebFinger struct {
+	Subject string          `json:"subject"`
+	Aliases []string        `json:"aliases,omitempty"`
+	Links   []WebFingerLink `json:"links"`
+}
+
+type WebFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href,omitempty"`
+}
+
+// NewActivity builds an activity with the object embedded.
+func NewActivity(typ, id, actor string, object any, to, cc []string) (*Activity, error) {
+	raw, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	return &Activity{ID: id, Type: typ, Actor: actor, Object: raw, To: to, Cc: cc}, nil
+}
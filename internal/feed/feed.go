@@ -0,0 +1,198 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"blog/internal/config"
+	"blog/internal/events"
+	"blog/internal/models"
+	"blog/internal/repository/redisrepo"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type postStore interface {
+	ListFeed(followerID, beforeID int64, limit int) ([]*models.Post, error)
+	GetPostsByIDs(ids []int64) (map[int64]*models.Post, error)
+}
+
+type followerLister interface {
+	FollowerIDs(userID int64) ([]int64, error)
+}
+
+// Feed builds home feeds out of the posts of followed authors, newest first.
+//
+// By default every read queries SQLite (fan-out-on-read). With
+// feed.fan_out_on_write each feed is materialized as a Redis sorted set of post
+// ids that new posts are pushed into. Materialized feeds are built from SQLite
+// on their first read, capped at feed.max_items, and expire when unused; reads
+// past the cap and reads while Redis is down fall back to SQLite.
+type Feed struct {
+	log     logger.Logger
+	posts   postStore
+	follows followerLister
+	rdb     *redisrepo.RedisRepo
+	cfg     config.FeedConfig
+}
+
+func New(log logger.Logger, posts postStore, follows followerLister, rdb *redisrepo.RedisRepo, cfg config.FeedConfig) *Feed {
+	return &Feed{
+		log:     log,
+		posts:   posts,
+		follows: follows,
+		rdb:     rdb,
+		cfg:     cfg,
+	}
+}
+
+// Subscribe keeps materialized feeds up to date with new posts and follows.
+func (f *Feed) Subscribe(bus *events.Bus) {
+	events.On(bus, func(ctx context.Context, e events.PostPublished) error {
+		f.Published(ctx, e.Post)
+		return nil
+	})
+	events.On(bus, func(ctx context.Context, e events.UserFollowed) error {
+		f.FollowsChanged(ctx, e.FollowerID)
+		return nil
+	})
+	events.On(bus, func(ctx context.Context, e events.UserUnfollowed) error {
+		f.FollowsChanged(ctx, e.FollowerID)
+		return nil
+	})
+}
+
+func (f *Feed) materialized() bool {
+	return f.cfg.FanOutOnWrite && f.rdb != nil
+}
+
+// Page returns up to limit posts with ids below beforeID (0 means from the
+// newest) and the id to continue from, or 0 on the last page.
+func (f *Feed) Page(ctx context.Context, userID, beforeID int64, limit int) ([]*models.Post, int64, error) {
+	log := f.log.With(slog.String("fn", "feed.Page"), slog.Int64("user_id", userID))
+
+	if f.materialized() {
+		posts, next, err := f.pageFromRedis(ctx, userID, beforeID, limit)
+		if err == nil {
+			return posts, next, nil
+		}
+		if errors.Is(err, redisrepo.KeyNotFound) {
+			f.rebuild(ctx, userID)
+		} else {
+			log.Error("failed to read materialized feed", sl.Error(err))
+		}
+	}
+
+	return f.pageFromDB(userID, beforeID, limit)
+}
+
+func (f *Feed) pageFromDB(userID, beforeID int64, limit int) ([]*models.Post, int64, error) {
+	// one extra row tells whether there is a next page
+	posts, err := f.posts.ListFeed(userID, beforeID, limit+1)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var next int64
+	if len(posts) > limit {
+		posts = posts[:limit]
+		next = beforeID
+		if limit > 0 {
+			next = posts[limit-1].ID
+		}
+	}
+	return posts, next, nil
+}
+
+func (f *Feed) pageFromRedis(ctx context.Context, userID, beforeID int64, limit int) ([]*models.Post, int64, error) {
+	ids, err := f.rdb.FeedPage(ctx, userID, beforeID, limit+1, f.cfg.TTL)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(ids) <= limit {
+		// the end of the materialized feed: older posts may have been cut off
+		// by max_items, so SQLite has the rest
+		if len(ids) > 0 {
+			beforeID = ids[len(ids)-1]
+		}
+		byID, err := f.posts.GetPostsByIDs(ids)
+		if err != nil {
+			return nil, 0, err
+		}
+		posts := inOrder(ids, byID)
+		rest, next, err := f.pageFromDB(userID, beforeID, limit-len(posts))
+		if err != nil {
+			return nil, 0, err
+		}
+		return append(posts, rest...), next, nil
+	}
+
+	ids = ids[:limit]
+	byID, err := f.posts.GetPostsByIDs(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	// deleted or hidden posts stay in the sorted set and are skipped here,
+	// the cursor still continues after the last id
+	return inOrder(ids, byID), ids[limit-1], nil
+}
+
+func inOrder(ids []int64, byID map[int64]*models.Post) []*models.Post {
+	posts := make([]*models.Post, 0, len(ids))
+	for _, id := range ids {
+		if post, ok := byID[id]; ok {
+			posts = append(posts, post)
+		}
+	}
+	return posts
+}
+
+// rebuild materializes a feed from SQLite.
+func (f *Feed) rebuild(ctx context.Context, userID int64) {
+	log := f.log.With(slog.String("fn", "feed.rebuild"), slog.Int64("user_id", userID))
+
+	posts, err := f.posts.ListFeed(userID, 0, f.cfg.MaxItems)
+	if err != nil {
+		log.Error("failed to load feed", sl.Error(err))
+		return
+	}
+	ids := make([]int64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	if err := f.rdb.FeedFill(ctx, userID, ids, f.cfg.TTL); err != nil {
+		log.Error("failed to materialize feed", sl.Error(err))
+	}
+}
+
+// Published pushes a new post into the materialized feeds of the author's followers.
+func (f *Feed) Published(ctx context.Context, post *models.Post) {
+	if !f.materialized() {
+		return
+	}
+	log := f.log.With(slog.String("fn", "feed.Published"), slog.Int64("post_id", post.ID))
+
+	followers, err := f.follows.FollowerIDs(post.AuthorID)
+	if err != nil {
+		log.Error("failed to list followers", sl.Error(err))
+		return
+	}
+	if err := f.rdb.FeedPush(ctx, followers, post.ID, f.cfg.MaxItems); err != nil {
+		log.Error("failed to fan out post", sl.Error(err))
+		return
+	}
+	log.Debug("post fanned out", slog.Int("followers", len(followers)))
+}
+
+// FollowsChanged drops the materialized feed of a user who followed or
+// unfollowed someone, it is rebuilt on the next read.
+func (f *Feed) FollowsChanged(ctx context.Context, userID int64) {
+	if !f.materialized() {
+		return
+	}
+	if err := f.rdb.Delete(ctx, redisrepo.FeedKey(userID)); err != nil {
+		f.log.Error("failed to drop materialized feed", sl.Error(err), slog.Int64("user_id", userID))
+	}
+}
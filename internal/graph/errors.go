@@ -0,0 +1,34 @@
+package graph
+
+import (
+	"blog/internal/api/problem"
+)
+
+// Error is a GraphQL error with problem details as extensions, so a
+// rejected mutation tells the same status, type and invalid fields as the
+// REST route it went through.
+type Error struct {
+	Problem problem.Problem
+}
+
+func newError(status int, detail string) *Error {
+	return &Error{Problem: problem.New(status, detail)}
+}
+
+func (e *Error) Error() string {
+	if e.Problem.Detail != "" {
+		return e.Problem.Detail
+	}
+	return e.Problem.Title
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{
+		"status": e.Problem.Status,
+		"type":   e.Problem.Type,
+	}
+	if len(e.Problem.Errors) > 0 {
+		ext["errors"] = e.Problem.Errors
+	}
+	return ext
+}
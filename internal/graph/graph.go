@@ -0,0 +1,164 @@
+// Package graph is a GraphQL API over the blog models. Related objects are
+// read through per-request loaders, so a list of posts with their authors
+// and comments costs one query per kind of object instead of one per row.
+// Mutations run the REST routes in process and follow their validation,
+// ownership and moderation rules to the letter.
+package graph
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/inproc"
+	"blog/internal/config"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+)
+
+type userGetter interface {
+	GetUsersByIDs(ids []int64) (map[int64]*models.User, error)
+}
+
+type postGetter interface {
+	GetPostByID(id int64) (*models.Post, error)
+	GetPostsByIDs(ids []int64) (map[int64]*models.Post, error)
+	ListPosts(page repository.Page) ([]*models.Post, error)
+}
+
+type commentGetter interface {
+	GetCommentByID(id int64) (*models.Comment, error)
+	ListComments(page repository.Page, postID, viewerID int64) ([]*models.Comment, error)
+	ListCommentsByPosts(postIDs []int64, viewerID int64, limit int) (map[int64][]*models.Comment, error)
+}
+
+type shadowBanChecker interface {
+	ShadowBannedUsers(ids []int64) (map[int64]bool, error)
+}
+
+type reactionCounter interface {
+	ReactionSummaries(targetType string, ids []int64) (map[int64]*models.ReactionSummary, error)
+}
+
+type Service struct {
+	log        logger.Logger
+	users      userGetter
+	posts      postGetter
+	comments   commentGetter
+	shadowBans shadowBanChecker
+	reactions  reactionCounter
+	cursors    *cursor.Codec
+	rest       *inproc.Client
+	cfg        config.GraphQLConfig
+
+	schema graphql.Schema
+}
+
+// New builds the schema. rest calls the REST routes the mutations go
+// through.
+func New(log logger.Logger, users userGetter, posts postGetter, comments commentGetter, shadowBans shadowBanChecker,
+	reactions reactionCounter, cursors *cursor.Codec, rest *inproc.Client, cfg config.GraphQLConfig) (*Service, error) {
+	s := &Service{
+		log:        log,
+		users:      users,
+		posts:      posts,
+		comments:   comments,
+		shadowBans: shadowBans,
+		reactions:  reactions,
+		cursors:    cursors,
+		rest:       rest,
+		cfg:        cfg,
+	}
+	schema, err := s.buildSchema()
+	if err != nil {
+		return nil, fmt.Errorf("graphql schema: %w", err)
+	}
+	s.schema = schema
+	return s, nil
+}
+
+// Request is a GraphQL request, the JSON body of a POST or the query
+// parameters of a GET.
+type Request struct {
+	Query         string                 `json:"query" validate:"required"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Operation is the operation of a request that parsed, passed validation
+// and stays within the limits.
+type Operation struct {
+	req Request
+	doc *ast.Document
+	def *ast.OperationDefinition
+}
+
+// Mutation reports whether the operation changes anything.
+func (op *Operation) Mutation() bool {
+	return op.def.Operation == ast.OperationTypeMutation
+}
+
+// Prepare parses and validates req and picks the operation to run. The
+// errors are GraphQL errors, meant for the errors list of the response.
+func (s *Service) Prepare(req Request) (*Operation, []gqlerrors.FormattedError) {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		return nil, gqlerrors.FormatErrors(err)
+	}
+	if res := graphql.ValidateDocument(&s.schema, doc, nil); !res.IsValid {
+		return nil, res.Errors
+	}
+
+	op := &Operation{req: req, doc: doc}
+	for _, node := range doc.Definitions {
+		def, ok := node.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if req.OperationName == "" && op.def != nil {
+			return nil, gqlerrors.FormatErrors(fmt.Errorf("operationName is required when the document has more than one operation"))
+		}
+		if req.OperationName == "" || (def.Name != nil && def.Name.Value == req.OperationName) {
+			op.def = def
+		}
+	}
+	if op.def == nil {
+		return nil, gqlerrors.FormatErrors(fmt.Errorf("unknown operation %q", req.OperationName))
+	}
+
+	if errs := s.checkLimits(op); len(errs) > 0 {
+		return nil, errs
+	}
+	return op, nil
+}
+
+// Execute runs op on behalf of the user of r, mutations are authorized by
+// its Authorization header.
+func (s *Service) Execute(r *http.Request, op *Operation) *graphql.Result {
+	viewerID, _ := r.Context().Value(auth.UserIDCtxKey).(int64)
+	ctx := context.WithValue(r.Context(), ctxKey{}, &call{
+		viewerID: viewerID,
+		loaders:  s.newLoaders(viewerID),
+		rest:     s.rest,
+		r:        r,
+	})
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           op.doc,
+		OperationName: op.req.OperationName,
+		Args:          op.req.Variables,
+		Context:       ctx,
+	})
+}
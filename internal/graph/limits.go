@@ -0,0 +1,159 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+
+	"blog/internal/api/cursor"
+)
+
+// checkLimits rejects operations nested deeper than MaxDepth or estimated
+// to resolve more than MaxComplexity fields. Every field costs one, the
+// selections of a field with a first argument cost first times over, as
+// that many items may come back. Introspection fields are free.
+func (s *Service) checkLimits(op *Operation) []gqlerrors.FormattedError {
+	root := s.schema.QueryType()
+	if op.Mutation() {
+		root = s.schema.MutationType()
+	}
+	m := measurer{
+		schema:    &s.schema,
+		fragments: map[string]*ast.FragmentDefinition{},
+		variables: op.req.Variables,
+		defaults:  map[string]ast.Value{},
+		ceiling:   s.cfg.MaxComplexity + 1,
+	}
+	for _, node := range op.doc.Definitions {
+		if frag, ok := node.(*ast.FragmentDefinition); ok {
+			m.fragments[frag.Name.Value] = frag
+		}
+	}
+	for _, def := range op.def.VariableDefinitions {
+		if def.DefaultValue != nil {
+			m.defaults[def.Variable.Name.Value] = def.DefaultValue
+		}
+	}
+
+	depth, complexity := m.measure(op.def.SelectionSet, root, 1)
+	var errs []gqlerrors.FormattedError
+	if depth > s.cfg.MaxDepth {
+		errs = append(errs, limitError(fmt.Sprintf("query depth %d exceeds the limit of %d", depth, s.cfg.MaxDepth),
+			"QUERY_TOO_DEEP", s.cfg.MaxDepth))
+	}
+	if complexity > s.cfg.MaxComplexity {
+		errs = append(errs, limitError(fmt.Sprintf("query complexity exceeds the limit of %d", s.cfg.MaxComplexity),
+			"QUERY_TOO_COMPLEX", s.cfg.MaxComplexity))
+	}
+	return errs
+}
+
+func limitError(message, code string, limit int) gqlerrors.FormattedError {
+	err := gqlerrors.NewFormattedError(message)
+	err.Extensions = map[string]interface{}{"code": code, "limit": limit}
+	return err
+}
+
+type measurer struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	defaults  map[string]ast.Value
+	ceiling   int // complexities are capped here, anything above is rejected anyway
+}
+
+// measure returns the depth of the deepest field of set, whose fields are
+// at depth, and the complexity of set.
+func (m *measurer) measure(set *ast.SelectionSet, parent *graphql.Object, depth int) (maxDepth, complexity int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		d, c := 0, 0
+		switch sel := selection.(type) {
+		case *ast.Field:
+			name := sel.Name.Value
+			if strings.HasPrefix(name, "__") {
+				continue
+			}
+			def, ok := parent.Fields()[name]
+			if !ok {
+				continue
+			}
+			d, c = depth, 1
+			if child, ok := graphql.GetNamed(def.Type).(*graphql.Object); ok && sel.SelectionSet != nil {
+				childDepth, childComplexity := m.measure(sel.SelectionSet, child, depth+1)
+				d = max(d, childDepth)
+				c += m.first(sel, def) * childComplexity
+			}
+		case *ast.InlineFragment:
+			d, c = m.measure(sel.SelectionSet, m.conditionType(sel.TypeCondition, parent), depth)
+		case *ast.FragmentSpread:
+			frag, ok := m.fragments[sel.Name.Value]
+			if !ok {
+				continue
+			}
+			d, c = m.measure(frag.SelectionSet, m.conditionType(frag.TypeCondition, parent), depth)
+		}
+		maxDepth = max(maxDepth, d)
+		complexity = min(complexity+c, m.ceiling)
+	}
+	return maxDepth, complexity
+}
+
+func (m *measurer) conditionType(cond *ast.Named, parent *graphql.Object) *graphql.Object {
+	if cond == nil {
+		return parent
+	}
+	if obj, ok := m.schema.Type(cond.Name.Value).(*graphql.Object); ok {
+		return obj
+	}
+	return parent
+}
+
+// first is the number of items a field may return, from its first
+// argument, 1 for fields without one. Values out of range are rejected
+// when the field resolves, here they only count as the closest valid one.
+func (m *measurer) first(field *ast.Field, def *graphql.FieldDefinition) int {
+	var n int
+	found := false
+	for _, arg := range def.Args {
+		if arg.Name() == "first" {
+			n, found = toInt(arg.DefaultValue), true
+		}
+	}
+	if !found {
+		return 1
+	}
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			n, _ = strconv.Atoi(value.Value)
+		case *ast.Variable:
+			name := value.Name.Value
+			if v, ok := m.variables[name]; ok {
+				n = toInt(v)
+			} else if v, ok := m.defaults[name].(*ast.IntValue); ok {
+				n, _ = strconv.Atoi(v.Value)
+			}
+		}
+	}
+	return min(max(n, 1), cursor.MaxLimit)
+}
+
+func toInt(v interface{}) int {
+	switch v := v.(type) {
+	case int:
+		return v
+	case float64: // variables decoded from JSON
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,145 @@
+package graph
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"blog/internal/api/inproc"
+	"blog/internal/models"
+)
+
+// loader collects the keys asked for while a level of a query resolves and
+// fetches them all with one call when the first value is needed. Resolvers
+// return the thunk of Load, the executor resolves a whole level before it
+// calls any of them, so every post of a list adds its author to the same
+// batch instead of reading it on its own.
+//
+// Loaders live as long as a request and aren't safe for concurrent use,
+// fields are resolved one after another.
+type loader[K comparable, V any] struct {
+	fetch   func(keys []K) (map[K]V, error)
+	pending []K
+	values  map[K]V
+	errs    map[K]error
+}
+
+func newLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		fetch:  fetch,
+		values: map[K]V{},
+		errs:   map[K]error{},
+	}
+}
+
+// Load queues key and returns a thunk of its value, the zero value when
+// fetch didn't return the key.
+func (l *loader[K, V]) Load(key K) func() (interface{}, error) {
+	if _, done := l.values[key]; !done && l.errs[key] == nil && !slices.Contains(l.pending, key) {
+		l.pending = append(l.pending, key)
+	}
+	return func() (interface{}, error) {
+		if slices.Contains(l.pending, key) {
+			l.flush()
+		}
+		if err := l.errs[key]; err != nil {
+			return nil, err
+		}
+		return l.values[key], nil
+	}
+}
+
+// Prime stores a value that is already known, e.g. a post read by a list.
+func (l *loader[K, V]) Prime(key K, value V) {
+	if _, done := l.values[key]; !done {
+		l.values[key] = value
+	}
+}
+
+func (l *loader[K, V]) flush() {
+	keys := l.pending
+	l.pending = nil
+	values, err := l.fetch(keys)
+	for _, key := range keys {
+		if err != nil {
+			l.errs[key] = err
+			continue
+		}
+		l.values[key] = values[key]
+	}
+}
+
+// commentsKey is the comments of a post, the first limit ones. Posts asking
+// for the same limit are fetched together.
+type commentsKey struct {
+	postID int64
+	limit  int
+}
+
+// loaders are the loaders of one request.
+type loaders struct {
+	users            *loader[int64, *models.User]
+	posts            *loader[int64, *models.Post]
+	comments         *loader[commentsKey, []*models.Comment]
+	postReactions    *loader[int64, *models.ReactionSummary]
+	commentReactions *loader[int64, *models.ReactionSummary]
+}
+
+func (s *Service) newLoaders(viewerID int64) *loaders {
+	return &loaders{
+		users: newLoader(s.users.GetUsersByIDs),
+		posts: newLoader(s.posts.GetPostsByIDs),
+		comments: newLoader(func(keys []commentsKey) (map[commentsKey][]*models.Comment, error) {
+			byLimit := map[int][]int64{}
+			for _, key := range keys {
+				byLimit[key.limit] = append(byLimit[key.limit], key.postID)
+			}
+			result := make(map[commentsKey][]*models.Comment, len(keys))
+			for limit, postIDs := range byLimit {
+				comments, err := s.comments.ListCommentsByPosts(postIDs, viewerID, limit)
+				if err != nil {
+					return nil, err
+				}
+				for _, postID := range postIDs {
+					if comments[postID] == nil {
+						comments[postID] = []*models.Comment{}
+					}
+					result[commentsKey{postID: postID, limit: limit}] = comments[postID]
+				}
+			}
+			return result, nil
+		}),
+		postReactions: newLoader(func(ids []int64) (map[int64]*models.ReactionSummary, error) {
+			return s.reactions.ReactionSummaries(models.TargetPost, ids)
+		}),
+		commentReactions: newLoader(func(ids []int64) (map[int64]*models.ReactionSummary, error) {
+			return s.reactions.ReactionSummaries(models.TargetComment, ids)
+		}),
+	}
+}
+
+type ctxKey struct{}
+
+// call is the state of a GraphQL request the resolvers share.
+type call struct {
+	viewerID int64
+	loaders  *loaders
+	rest     *inproc.Client
+	r        *http.Request
+}
+
+// do runs a REST route with the credentials of the GraphQL request.
+func (c *call) do(method, path string, body any, ifMatch int64, out any) error {
+	return c.rest.Do(c.r.Context(), inproc.Request{
+		Method:        method,
+		Path:          path,
+		Authorization: c.r.Header.Get("Authorization"),
+		IfMatch:       ifMatch,
+		RemoteAddr:    c.r.RemoteAddr,
+		Body:          body,
+	}, out)
+}
+
+func callFrom(ctx context.Context) *call {
+	return ctx.Value(ctxKey{}).(*call)
+}
@@ -0,0 +1,211 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+
+	"blog/internal/api/inproc"
+	"blog/internal/models"
+	"blog/internal/repository"
+)
+
+type createCommentPayload struct {
+	ID               int64           `json:"id"`
+	ModerationStatus string          `json:"moderationStatus"`
+	Comment          *models.Comment `json:"comment"`
+}
+
+// mutationType has the mutations of posts and comments. Each one runs the
+// REST route of the same change, entities are read again afterwards so the
+// response shows what was stored.
+func (s *Service) mutationType(postType, commentType *graphql.Object) *graphql.Object {
+	versionArg := &graphql.ArgumentConfig{
+		Type:        graphql.Int,
+		Description: "Version the change is based on, the change fails if it's no longer the current one.",
+	}
+	payloadType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CreateCommentPayload",
+		Fields: graphql.Fields{
+			"id":               &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"moderationStatus": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "approved, pending or spam."},
+			"comment":          &graphql.Field{Type: commentType, Description: "Null until the comment is approved."},
+		},
+	})
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createPost": &graphql.Field{
+				Type: postType,
+				Args: graphql.FieldConfigArgument{
+					"title":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"content": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: s.createPost,
+			},
+			"updatePost": &graphql.Field{
+				Type: postType,
+				Args: graphql.FieldConfigArgument{
+					"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"title":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"content": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"version": versionArg,
+				},
+				Resolve: s.updatePost,
+			},
+			"deletePost": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.ID),
+				Description: "Moves the post to the trash, returns its id.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: s.deleteRoute("/api/post/%d"),
+			},
+			"createComment": &graphql.Field{
+				Type:        graphql.NewNonNull(payloadType),
+				Description: "Comments on a post, or replies to a comment with parentId.",
+				Args: graphql.FieldConfigArgument{
+					"postId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"content":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"parentId": &graphql.ArgumentConfig{Type: graphql.ID},
+				},
+				Resolve: s.createComment,
+			},
+			"updateComment": &graphql.Field{
+				Type: commentType,
+				Args: graphql.FieldConfigArgument{
+					"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"content": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"version": versionArg,
+				},
+				Resolve: s.updateComment,
+			},
+			"deleteComment": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.ID),
+				Description: "Moves the comment to the trash, returns its id.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: s.deleteRoute("/api/comment/%d"),
+			},
+		},
+	})
+}
+
+func (s *Service) createPost(p graphql.ResolveParams) (interface{}, error) {
+	body := map[string]any{"title": p.Args["title"], "content": p.Args["content"]}
+	var resp struct {
+		PostID int64 `json:"post_id"`
+	}
+	if err := callFrom(p.Context).do(http.MethodPost, "/api/post", body, 0, &resp); err != nil {
+		return nil, s.restError("graph.createPost", err)
+	}
+	return s.readPost("graph.createPost", resp.PostID)
+}
+
+func (s *Service) updatePost(p graphql.ResolveParams) (interface{}, error) {
+	id, err := idArg(p, "id")
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]any{"title": p.Args["title"], "content": p.Args["content"]}
+	version, _ := p.Args["version"].(int)
+	if err := callFrom(p.Context).do(http.MethodPatch, fmt.Sprintf("/api/post/%d", id), body, int64(version), nil); err != nil {
+		return nil, s.restError("graph.updatePost", err)
+	}
+	return s.readPost("graph.updatePost", id)
+}
+
+func (s *Service) createComment(p graphql.ResolveParams) (interface{}, error) {
+	postID, err := idArg(p, "postId")
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]any{"post_id": postID, "content": p.Args["content"]}
+	if _, ok := p.Args["parentId"]; ok {
+		parentID, err := idArg(p, "parentId")
+		if err != nil {
+			return nil, err
+		}
+		body["parent_id"] = parentID
+	}
+	var resp struct {
+		CommentID int64  `json:"comment_id"`
+		Status    string `json:"moderation_status"`
+	}
+	if err := callFrom(p.Context).do(http.MethodPost, "/api/comment", body, 0, &resp); err != nil {
+		return nil, s.restError("graph.createComment", err)
+	}
+
+	payload := &createCommentPayload{ID: resp.CommentID, ModerationStatus: resp.Status}
+	if resp.Status == models.CommentStatusApproved {
+		comment, err := s.comments.GetCommentByID(resp.CommentID)
+		if err != nil {
+			return nil, s.internal("graph.createComment", err)
+		}
+		payload.Comment = comment
+	}
+	return payload, nil
+}
+
+// updateComment sends the post of the comment along, PATCH /api/comment/{id}
+// wants it in the body.
+func (s *Service) updateComment(p graphql.ResolveParams) (interface{}, error) {
+	id, err := idArg(p, "id")
+	if err != nil {
+		return nil, err
+	}
+	comment, err := s.comments.GetCommentByID(id)
+	if errors.Is(err, repository.ErrNotExists) {
+		return nil, newError(http.StatusNotFound, "")
+	} else if err != nil {
+		return nil, s.internal("graph.updateComment", err)
+	}
+
+	body := map[string]any{"post_id": comment.PostID, "content": p.Args["content"]}
+	version, _ := p.Args["version"].(int)
+	if err := callFrom(p.Context).do(http.MethodPatch, fmt.Sprintf("/api/comment/%d", id), body, int64(version), nil); err != nil {
+		return nil, s.restError("graph.updateComment", err)
+	}
+	if comment, err = s.comments.GetCommentByID(id); err != nil {
+		return nil, s.internal("graph.updateComment", err)
+	}
+	return comment, nil
+}
+
+// deleteRoute resolves a mutation by the DELETE route at path, formatted
+// with the id argument.
+func (s *Service) deleteRoute(path string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		id, err := idArg(p, "id")
+		if err != nil {
+			return nil, err
+		}
+		if err := callFrom(p.Context).do(http.MethodDelete, fmt.Sprintf(path, id), nil, 0, nil); err != nil {
+			return nil, s.restError("graph.delete", err)
+		}
+		return id, nil
+	}
+}
+
+// readPost reads a post bypassing the loader, its cached copy may predate
+// the mutation.
+func (s *Service) readPost(fn string, id int64) (interface{}, error) {
+	post, err := s.posts.GetPostByID(id)
+	if err != nil {
+		return nil, s.internal(fn, err)
+	}
+	return post, nil
+}
+
+// restError passes rejections of the REST route on and hides anything else.
+func (s *Service) restError(fn string, err error) error {
+	var e *inproc.Error
+	if errors.As(err, &e) {
+		return &Error{Problem: e.Problem}
+	}
+	return s.internal(fn, err)
+}
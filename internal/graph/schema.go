@@ -0,0 +1,394 @@
+package graph
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	"blog/internal/api/cursor"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger/sl"
+)
+
+const (
+	defaultPosts    = 10
+	defaultComments = 20
+)
+
+type connection[T any] struct {
+	Nodes      []T     `json:"nodes"`
+	NextCursor *string `json:"nextCursor"`
+	PrevCursor *string `json:"prevCursor"`
+}
+
+func newConnection[T any](nodes []T, next, prev string) *connection[T] {
+	c := &connection[T]{Nodes: nodes}
+	if next != "" {
+		c.NextCursor = &next
+	}
+	if prev != "" {
+		c.PrevCursor = &prev
+	}
+	return c
+}
+
+type reactions struct {
+	Total  int64           `json:"total"`
+	Counts []reactionCount `json:"counts"`
+}
+
+type reactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int64  `json:"count"`
+}
+
+func (s *Service) buildSchema() (graphql.Schema, error) {
+	reactionCountType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ReactionCount",
+		Fields: graphql.Fields{
+			"emoji": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"count": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+	reactionsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Reactions",
+		Fields: graphql.Fields{
+			"total":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"counts": &graphql.Field{Type: nonNullList(reactionCountType), Description: "Per emoji, in emoji order."},
+		},
+	})
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"username":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"role":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: userField(func(u *models.User) any { return u.CreatedAt })},
+		},
+	})
+
+	var postType, commentType *graphql.Object
+	postType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Post",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":             &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+				"title":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"content":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"version":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Pass it to updatePost to make sure nobody changed the post in the meantime."},
+				"commentsLocked": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Resolve: postField(func(p *models.Post) any { return p.CommentsLocked })},
+				"createdAt":      &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: postField(func(p *models.Post) any { return p.CreatedAt })},
+				"updatedAt":      &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: postField(func(p *models.Post) any { return p.UpdatedAt })},
+				"author": &graphql.Field{
+					Type: userType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return callFrom(p.Context).loaders.users.Load(p.Source.(*models.Post).AuthorID), nil
+					},
+				},
+				"reactions": &graphql.Field{
+					Type: graphql.NewNonNull(reactionsType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return reactionsOf(callFrom(p.Context).loaders.postReactions.Load(p.Source.(*models.Post).ID)), nil
+					},
+				},
+				"comments": &graphql.Field{
+					Type:        nonNullList(commentType),
+					Description: "The newest comments, the first page of Query.comments.",
+					Args: graphql.FieldConfigArgument{
+						"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultComments},
+					},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						limit, err := firstArg(p)
+						if err != nil {
+							return nil, err
+						}
+						key := commentsKey{postID: p.Source.(*models.Post).ID, limit: limit}
+						return callFrom(p.Context).loaders.comments.Load(key), nil
+					},
+				},
+			}
+		}),
+	})
+	commentType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Comment",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":      &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+				"content": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"depth":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "0 for comments on the post, 1 for replies to them and so on."},
+				"version": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Pass it to updateComment to make sure nobody changed the comment in the meantime."},
+				"parentId": &graphql.Field{Type: graphql.ID, Resolve: commentField(func(c *models.Comment) any {
+					if c.ParentID == nil {
+						return nil
+					}
+					return *c.ParentID
+				})},
+				"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: commentField(func(c *models.Comment) any { return c.CreatedAt })},
+				"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: commentField(func(c *models.Comment) any { return c.UpdatedAt })},
+				"post": &graphql.Field{
+					Type: postType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return callFrom(p.Context).loaders.posts.Load(p.Source.(*models.Comment).PostID), nil
+					},
+				},
+				"author": &graphql.Field{
+					Type: userType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return callFrom(p.Context).loaders.users.Load(p.Source.(*models.Comment).AuthorID), nil
+					},
+				},
+				"reactions": &graphql.Field{
+					Type: graphql.NewNonNull(reactionsType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return reactionsOf(callFrom(p.Context).loaders.commentReactions.Load(p.Source.(*models.Comment).ID)), nil
+					},
+				},
+			}
+		}),
+	})
+
+	connectionType := func(name string, node *graphql.Object) *graphql.Object {
+		return graphql.NewObject(graphql.ObjectConfig{
+			Name: name,
+			Fields: graphql.Fields{
+				"nodes":      &graphql.Field{Type: nonNullList(node)},
+				"nextCursor": &graphql.Field{Type: graphql.String, Description: "Cursor of the next, older, page. Null on the last page."},
+				"prevCursor": &graphql.Field{Type: graphql.String, Description: "Cursor of the previous, newer, page. Null on the first page."},
+			},
+		})
+	}
+	pageArgs := func(def int) graphql.FieldConfigArgument {
+		return graphql.FieldConfigArgument{
+			"first":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: def, Description: fmt.Sprintf("Page size, 1 to %d.", cursor.MaxLimit)},
+			"cursor": &graphql.ArgumentConfig{Type: graphql.String, Description: "nextCursor or prevCursor of the previous page."},
+		}
+	}
+	idArgs := graphql.FieldConfigArgument{
+		"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"post": &graphql.Field{
+				Type:        postType,
+				Description: "Null when the post doesn't exist or is hidden.",
+				Args:        idArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := idArg(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					return callFrom(p.Context).loaders.posts.Load(id), nil
+				},
+			},
+			"posts": &graphql.Field{
+				Type:        graphql.NewNonNull(connectionType("PostConnection", postType)),
+				Description: "The newest posts.",
+				Args:        pageArgs(defaultPosts),
+				Resolve:     s.resolvePosts,
+			},
+			"comment": &graphql.Field{
+				Type:        commentType,
+				Description: "Null when the comment doesn't exist or isn't published.",
+				Args:        idArgs,
+				Resolve:     s.resolveComment,
+			},
+			"comments": &graphql.Field{
+				Type:        graphql.NewNonNull(connectionType("CommentConnection", commentType)),
+				Description: "The newest comments, of one post when postId is given.",
+				Args: graphql.FieldConfigArgument{
+					"postId": &graphql.ArgumentConfig{Type: graphql.ID},
+					"first":  pageArgs(defaultComments)["first"],
+					"cursor": pageArgs(defaultComments)["cursor"],
+				},
+				Resolve: s.resolveComments,
+			},
+			"user": &graphql.Field{
+				Type: userType,
+				Args: idArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := idArg(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					return callFrom(p.Context).loaders.users.Load(id), nil
+				},
+			},
+			"viewer": &graphql.Field{
+				Type:        userType,
+				Description: "The signed in user, null without a bearer token.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					c := callFrom(p.Context)
+					if c.viewerID == 0 {
+						return nil, nil
+					}
+					return c.loaders.users.Load(c.viewerID), nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: s.mutationType(postType, commentType),
+	})
+}
+
+func (s *Service) resolvePosts(p graphql.ResolveParams) (interface{}, error) {
+	limit, cur, err := s.pageArgs(p)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := s.posts.ListPosts(cursor.Page(cur, limit))
+	if err != nil {
+		return nil, s.internal("graph.resolvePosts", err)
+	}
+	posts, next, prev := cursor.Paginate(s.cursors, cur, limit, posts, func(post *models.Post) (time.Time, int64) {
+		return post.CreatedAt, post.ID
+	})
+
+	loaders := callFrom(p.Context).loaders
+	for _, post := range posts {
+		loaders.posts.Prime(post.ID, post)
+	}
+	return newConnection(posts, next, prev), nil
+}
+
+func (s *Service) resolveComments(p graphql.ResolveParams) (interface{}, error) {
+	limit, cur, err := s.pageArgs(p)
+	if err != nil {
+		return nil, err
+	}
+	var postID int64
+	if _, ok := p.Args["postId"]; ok {
+		if postID, err = idArg(p, "postId"); err != nil {
+			return nil, err
+		}
+	}
+	comments, err := s.comments.ListComments(cursor.Page(cur, limit), postID, callFrom(p.Context).viewerID)
+	if err != nil {
+		return nil, s.internal("graph.resolveComments", err)
+	}
+	comments, next, prev := cursor.Paginate(s.cursors, cur, limit, comments, func(comment *models.Comment) (time.Time, int64) {
+		return comment.CreatedAt, comment.ID
+	})
+	return newConnection(comments, next, prev), nil
+}
+
+// resolveComment hides what GET /api/comment/{id} hides: comments that
+// aren't approved and those of shadow banned users, except to themselves.
+func (s *Service) resolveComment(p graphql.ResolveParams) (interface{}, error) {
+	id, err := idArg(p, "id")
+	if err != nil {
+		return nil, err
+	}
+	comment, err := s.comments.GetCommentByID(id)
+	if errors.Is(err, repository.ErrNotExists) {
+		return nil, nil
+	} else if err != nil {
+		return nil, s.internal("graph.resolveComment", err)
+	}
+	if comment.Status != models.CommentStatusApproved {
+		return nil, nil
+	}
+	if comment.AuthorID != callFrom(p.Context).viewerID {
+		banned, err := s.shadowBans.ShadowBannedUsers([]int64{comment.AuthorID})
+		if err != nil {
+			return nil, s.internal("graph.resolveComment", err)
+		}
+		if banned[comment.AuthorID] {
+			return nil, nil
+		}
+	}
+	return comment, nil
+}
+
+func (s *Service) pageArgs(p graphql.ResolveParams) (int, *cursor.Cursor, error) {
+	limit, err := firstArg(p)
+	if err != nil {
+		return 0, nil, err
+	}
+	raw, _ := p.Args["cursor"].(string)
+	cur, err := s.cursors.Parse(raw)
+	if err != nil {
+		return 0, nil, newError(http.StatusBadRequest, err.Error())
+	}
+	return limit, cur, nil
+}
+
+// internal logs err and hides it from the client, like a 500 of the REST API.
+func (s *Service) internal(fn string, err error) error {
+	s.log.With(slog.String("fn", fn)).Error("resolver error", sl.Error(err))
+	return newError(http.StatusInternalServerError, "")
+}
+
+func firstArg(p graphql.ResolveParams) (int, error) {
+	n, _ := p.Args["first"].(int)
+	if n < 1 || n > cursor.MaxLimit {
+		return 0, newError(http.StatusBadRequest, cursor.ErrInvalidLimit.Error())
+	}
+	return n, nil
+}
+
+func idArg(p graphql.ResolveParams, name string) (int64, error) {
+	raw, _ := p.Args[name].(string)
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id < 1 {
+		return 0, newError(http.StatusBadRequest, fmt.Sprintf("%s must be a positive integer", name))
+	}
+	return id, nil
+}
+
+// reactionsOf turns the thunk of a reaction summary into one of reactions,
+// posts and comments nobody reacted to have no summary at all.
+func reactionsOf(load func() (interface{}, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		v, err := load()
+		if err != nil {
+			return nil, err
+		}
+		summary, _ := v.(*models.ReactionSummary)
+		counts := summary.CountMap()
+		r := &reactions{Counts: make([]reactionCount, 0, len(counts))}
+		for emoji, count := range counts {
+			r.Counts = append(r.Counts, reactionCount{Emoji: emoji, Count: count})
+			r.Total += count
+		}
+		slices.SortFunc(r.Counts, func(a, b reactionCount) int {
+			return cmp.Compare(a.Emoji, b.Emoji)
+		})
+		return r, nil
+	}
+}
+
+func nonNullList(t graphql.Type) graphql.Output {
+	return graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(t)))
+}
+
+func postField(get func(*models.Post) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(*models.Post)), nil
+	}
+}
+
+func commentField(get func(*models.Comment) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(*models.Comment)), nil
+	}
+}
+
+func userField(get func(*models.User) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(*models.User)), nil
+	}
+}
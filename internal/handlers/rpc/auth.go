@@ -0,0 +1,68 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"blog/internal/api/inproc"
+	"blog/internal/handlers/auth"
+	authmd "blog/internal/middlewares/auth"
+	"blog/internal/models"
+)
+
+type sanctionChecker interface {
+	ActiveSanction(userID int64, kinds ...string) (*models.Sanction, error)
+}
+
+// authenticate reads the same bearer token as the REST API from the
+// authorization metadata and puts its user under auth.UserIDCtxKey, like
+// OptionalAuth: calls without a valid token, or from blocked users, run
+// anonymously. Writes send the token on to the REST route, which rejects
+// them with UNAUTHENTICATED or PERMISSION_DENIED.
+func authenticate(sanctions sanctionChecker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		headerParts := strings.Split(authorization(ctx), " ")
+		if len(headerParts) != 2 {
+			return handler(ctx, req)
+		}
+		userID, err := auth.ParseToken(headerParts[1])
+		if err != nil {
+			return handler(ctx, req)
+		}
+		if sanction, err := authmd.Blocked(sanctions, userID); err != nil || sanction != nil {
+			return handler(ctx, req)
+		}
+		return handler(context.WithValue(ctx, authmd.UserIDCtxKey, userID), req)
+	}
+}
+
+func authorization(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func viewerID(ctx context.Context) int64 {
+	userID, _ := ctx.Value(authmd.UserIDCtxKey).(int64)
+	return userID
+}
+
+// restCall is a call of a REST route on behalf of the caller of ctx.
+func restCall(ctx context.Context, method, path string, body any, ifMatch int64) inproc.Request {
+	req := inproc.Request{
+		Method:        method,
+		Path:          path,
+		Authorization: authorization(ctx),
+		IfMatch:       ifMatch,
+		Body:          body,
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		req.RemoteAddr = p.Addr.String()
+	}
+	return req
+}
@@ -0,0 +1,175 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/inproc"
+	"blog/internal/api/pb/blogv1"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type commentGetter interface {
+	GetCommentByID(id int64) (*models.Comment, error)
+	ListComments(page repository.Page, postID, viewerID int64) ([]*models.Comment, error)
+}
+
+type shadowBanChecker interface {
+	ShadowBannedUsers(ids []int64) (map[int64]bool, error)
+}
+
+type commentService struct {
+	blogv1.UnimplementedCommentServiceServer
+
+	log        logger.Logger
+	comments   commentGetter
+	users      userGetter
+	shadowBans shadowBanChecker
+	rest       *inproc.Client
+	cursors    *cursor.Codec
+}
+
+// GetComment returns approved comments only, those of shadow banned users
+// only to themselves, as GET /api/comment/{id} does.
+func (s *commentService) GetComment(ctx context.Context, req *blogv1.GetCommentRequest) (*blogv1.Comment, error) {
+	log := s.log.With(slog.String("fn", "handlers.rpc.GetComment"))
+
+	if req.GetId() < 1 {
+		return nil, invalidArgument(ctx, "id", "min")
+	}
+	comment, err := s.comments.GetCommentByID(req.GetId())
+	if err == nil && comment.Status != models.CommentStatusApproved {
+		err = repository.ErrNotExists
+	}
+	if err != nil {
+		return nil, repositoryError(ctx, &log, err, "Comment Not Found")
+	}
+
+	if comment.AuthorID != viewerID(ctx) {
+		banned, err := s.shadowBans.ShadowBannedUsers([]int64{comment.AuthorID})
+		if err != nil {
+			return nil, internalError(&log, "error checking shadow bans", err, slog.Int64("comment_id", comment.ID))
+		}
+		if banned[comment.AuthorID] {
+			return nil, newError(ctx, http.StatusNotFound, "Comment Not Found")
+		}
+	}
+	return s.withAuthor(&log, comment), nil
+}
+
+func (s *commentService) ListComments(ctx context.Context, req *blogv1.ListCommentsRequest) (*blogv1.ListCommentsResponse, error) {
+	log := s.log.With(slog.String("fn", "handlers.rpc.ListComments"))
+
+	if req.GetPostId() < 0 {
+		return nil, invalidArgument(ctx, "post_id", "min")
+	}
+	limit, cur, err := page(ctx, s.cursors, req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.comments.ListComments(cursor.Page(cur, limit), req.GetPostId(), viewerID(ctx))
+	if err != nil {
+		return nil, internalError(&log, "error get comments", err, slog.Int("limit", limit))
+	}
+	comments, next, prev := cursor.Paginate(s.cursors, cur, limit, comments, func(c *models.Comment) (time.Time, int64) {
+		return c.CreatedAt, c.ID
+	})
+
+	authorIDs := make([]int64, 0, len(comments))
+	for _, comment := range comments {
+		authorIDs = append(authorIDs, comment.AuthorID)
+	}
+	users := authors(&log, s.users, authorIDs)
+
+	resp := &blogv1.ListCommentsResponse{
+		Comments:      make([]*blogv1.Comment, 0, len(comments)),
+		NextPageToken: next,
+		PrevPageToken: prev,
+	}
+	for _, comment := range comments {
+		resp.Comments = append(resp.Comments, toComment(comment, users[comment.AuthorID]))
+	}
+	return resp, nil
+}
+
+// CreateComment returns the comment whatever its moderation status, the
+// author may see their own pending comments.
+func (s *commentService) CreateComment(ctx context.Context, req *blogv1.CreateCommentRequest) (*blogv1.Comment, error) {
+	log := s.log.With(slog.String("fn", "handlers.rpc.CreateComment"))
+
+	body := map[string]any{"post_id": req.GetPostId(), "content": req.GetContent()}
+	if req.ParentId != nil {
+		body["parent_id"] = req.GetParentId()
+	}
+	var resp struct {
+		CommentID int64 `json:"comment_id"`
+	}
+	if err := s.rest.Do(ctx, restCall(ctx, http.MethodPost, "/api/comment", body, 0), &resp); err != nil {
+		return nil, restError(ctx, &log, err, slog.Int64("post_id", req.GetPostId()))
+	}
+	return s.read(ctx, &log, resp.CommentID)
+}
+
+// UpdateComment sends the post of the comment along, PATCH /api/comment/{id}
+// wants it in the body.
+func (s *commentService) UpdateComment(ctx context.Context, req *blogv1.UpdateCommentRequest) (*blogv1.Comment, error) {
+	log := s.log.With(slog.String("fn", "handlers.rpc.UpdateComment"))
+
+	if req.GetId() < 1 {
+		return nil, invalidArgument(ctx, "id", "min")
+	}
+	comment, err := s.comments.GetCommentByID(req.GetId())
+	if err != nil {
+		return nil, repositoryError(ctx, &log, err, "Comment Not Found")
+	}
+
+	body := map[string]any{"post_id": comment.PostID, "content": req.GetContent()}
+	path := fmt.Sprintf("/api/comment/%d", req.GetId())
+	if err := s.rest.Do(ctx, restCall(ctx, http.MethodPatch, path, body, req.GetVersion()), nil); err != nil {
+		return nil, restError(ctx, &log, err, slog.Int64("comment_id", req.GetId()))
+	}
+	return s.read(ctx, &log, req.GetId())
+}
+
+func (s *commentService) DeleteComment(ctx context.Context, req *blogv1.DeleteCommentRequest) (*emptypb.Empty, error) {
+	log := s.log.With(slog.String("fn", "handlers.rpc.DeleteComment"))
+
+	if req.GetId() < 1 {
+		return nil, invalidArgument(ctx, "id", "min")
+	}
+	path := fmt.Sprintf("/api/comment/%d", req.GetId())
+	if err := s.rest.Do(ctx, restCall(ctx, http.MethodDelete, path, nil, 0), nil); err != nil {
+		return nil, restError(ctx, &log, err, slog.Int64("comment_id", req.GetId()))
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// read returns a comment after a write, as it was stored.
+func (s *commentService) read(ctx context.Context, log logger.Logger, id int64) (*blogv1.Comment, error) {
+	comment, err := s.comments.GetCommentByID(id)
+	if err != nil {
+		return nil, repositoryError(ctx, log, err, "Comment Not Found")
+	}
+	return s.withAuthor(log, comment), nil
+}
+
+func (s *commentService) withAuthor(log logger.Logger, comment *models.Comment) *blogv1.Comment {
+	author, err := s.users.GetUserByID(comment.AuthorID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotExists) {
+			log.Error("error getting author", slog.Int64("user_id", comment.AuthorID), sl.Error(err))
+		}
+		author = nil
+	}
+	return toComment(comment, author)
+}
@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/pb/blogv1"
+	"blog/internal/models"
+)
+
+// defaultPageSize is the page size of lists when the request has none, the
+// default of the REST lists too.
+const defaultPageSize = 10
+
+// page reads page_size and page_token, the token being a cursor of the REST
+// lists.
+func page(ctx context.Context, cursors *cursor.Codec, size int32, token string) (int, *cursor.Cursor, error) {
+	limit := defaultPageSize
+	switch {
+	case size < 0:
+		return 0, nil, invalidArgument(ctx, "page_size", "min")
+	case size > cursor.MaxLimit:
+		return 0, nil, invalidArgument(ctx, "page_size", "max")
+	case size > 0:
+		limit = int(size)
+	}
+	cur, err := cursors.Parse(token)
+	if err != nil {
+		return 0, nil, invalidArgument(ctx, "page_token", "cursor")
+	}
+	return limit, cur, nil
+}
+
+func toUser(u *models.User) *blogv1.User {
+	if u == nil {
+		return nil
+	}
+	return &blogv1.User{
+		Id:         u.ID,
+		Username:   u.Username,
+		Role:       u.Role,
+		CreateTime: timestamp(u.CreatedAt),
+	}
+}
+
+func toPost(p *models.Post, author *models.User) *blogv1.Post {
+	return &blogv1.Post{
+		Id:             p.ID,
+		Title:          p.Title,
+		Content:        p.Content,
+		AuthorId:       p.AuthorID,
+		Author:         toUser(author),
+		Version:        p.Version,
+		CommentsLocked: p.CommentsLocked,
+		CreateTime:     timestamp(p.CreatedAt),
+		UpdateTime:     timestamp(p.UpdatedAt),
+	}
+}
+
+func toComment(c *models.Comment, author *models.User) *blogv1.Comment {
+	return &blogv1.Comment{
+		Id:         c.ID,
+		PostId:     c.PostID,
+		ParentId:   c.ParentID,
+		Depth:      int32(c.Depth),
+		Content:    c.Content,
+		AuthorId:   c.AuthorID,
+		Author:     toUser(author),
+		Status:     c.Status,
+		Version:    c.Version,
+		CreateTime: timestamp(c.CreatedAt),
+		UpdateTime: timestamp(c.UpdatedAt),
+	}
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,109 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+
+	"blog/internal/api/inproc"
+	"blog/internal/api/problem"
+	requestid "blog/internal/middlewares/request_id"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+// grpcCodes maps the statuses of the REST API to the codes grpc-gateway
+// maps back to them. 412 and 422 have no code of their own and come out as
+// FAILED_PRECONDITION.
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.AlreadyExists,
+	http.StatusPreconditionFailed:  codes.FailedPrecondition,
+	http.StatusUnprocessableEntity: codes.FailedPrecondition,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	http.StatusNotImplemented:      codes.Unimplemented,
+	http.StatusServiceUnavailable:  codes.Unavailable,
+	http.StatusGatewayTimeout:      codes.DeadlineExceeded,
+}
+
+// problemError is the status of p. Invalid fields are attached as
+// BadRequest details, the request id of ctx as RequestInfo.
+func problemError(ctx context.Context, p problem.Problem) error {
+	if p.Instance == "" {
+		p.Instance = requestid.Get(ctx)
+	}
+	code, ok := grpcCodes[p.Status]
+	if !ok {
+		code = codes.Internal
+	}
+	msg := p.Detail
+	if msg == "" {
+		msg = p.Title
+	}
+	st := status.New(code, msg)
+
+	var details []protoadapt.MessageV1
+	if len(p.Errors) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(p.Errors))
+		for _, fe := range p.Errors {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: fe.Field, Description: fe.Tag})
+		}
+		details = append(details, &errdetails.BadRequest{FieldViolations: violations})
+	}
+	if p.Instance != "" {
+		details = append(details, &errdetails.RequestInfo{RequestId: p.Instance})
+	}
+	if len(details) > 0 {
+		if withDetails, err := st.WithDetails(details...); err == nil {
+			st = withDetails
+		}
+	}
+	return st.Err()
+}
+
+func newError(ctx context.Context, status int, detail string) error {
+	return problemError(ctx, problem.New(status, detail))
+}
+
+// invalidArgument rejects a request field, the way a validation error of
+// the REST API lists it.
+func invalidArgument(ctx context.Context, field, tag string) error {
+	return problemError(ctx, problem.Validation([]problem.FieldError{{Field: field, Tag: tag}}))
+}
+
+// internalError logs err and hides it from the caller.
+func internalError(log logger.Logger, msg string, err error, attrs ...any) error {
+	log.Error(msg, append(attrs, sl.Error(err))...)
+	return status.Error(codes.Internal, "Internal Server Error")
+}
+
+// repositoryError is util.RepositoryError for gRPC: the errors the client
+// caused get their code and detail, anything else is logged and hidden.
+func repositoryError(ctx context.Context, log logger.Logger, err error, detail string) error {
+	st := problem.Status(err)
+	if st == http.StatusInternalServerError {
+		return internalError(log, "repository error", err)
+	}
+	return newError(ctx, st, detail)
+}
+
+// restError passes rejections of the REST route on and hides anything else.
+func restError(ctx context.Context, log logger.Logger, err error, attrs ...any) error {
+	var e *inproc.Error
+	if errors.As(err, &e) {
+		if e.Problem.Status >= http.StatusInternalServerError {
+			log.Error("rest route failed", append(attrs, slog.Int("status", e.Problem.Status))...)
+		}
+		return problemError(ctx, e.Problem)
+	}
+	return internalError(log, "error calling rest route", err, attrs...)
+}
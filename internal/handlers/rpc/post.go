@@ -0,0 +1,144 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/inproc"
+	"blog/internal/api/pb/blogv1"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type postGetter interface {
+	GetPostByID(id int64) (*models.Post, error)
+	ListPosts(page repository.Page) ([]*models.Post, error)
+}
+
+type postService struct {
+	blogv1.UnimplementedPostServiceServer
+
+	log     logger.Logger
+	posts   postGetter
+	users   userGetter
+	rest    *inproc.Client
+	cursors *cursor.Codec
+}
+
+// GetPost hides posts hidden by moderation, as GET /api/post/{id} does.
+func (s *postService) GetPost(ctx context.Context, req *blogv1.GetPostRequest) (*blogv1.Post, error) {
+	log := s.log.With(slog.String("fn", "handlers.rpc.GetPost"))
+
+	if req.GetId() < 1 {
+		return nil, invalidArgument(ctx, "id", "min")
+	}
+	post, err := s.posts.GetPostByID(req.GetId())
+	if err == nil && post.HiddenAt != nil {
+		err = repository.ErrNotExists
+	}
+	if err != nil {
+		return nil, repositoryError(ctx, &log, err, "Post Not Found")
+	}
+	return s.withAuthor(&log, post), nil
+}
+
+func (s *postService) ListPosts(ctx context.Context, req *blogv1.ListPostsRequest) (*blogv1.ListPostsResponse, error) {
+	log := s.log.With(slog.String("fn", "handlers.rpc.ListPosts"))
+
+	limit, cur, err := page(ctx, s.cursors, req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	posts, err := s.posts.ListPosts(cursor.Page(cur, limit))
+	if err != nil {
+		return nil, internalError(&log, "error get posts", err, slog.Int("limit", limit))
+	}
+	posts, next, prev := cursor.Paginate(s.cursors, cur, limit, posts, func(p *models.Post) (time.Time, int64) {
+		return p.CreatedAt, p.ID
+	})
+
+	authorIDs := make([]int64, 0, len(posts))
+	for _, post := range posts {
+		authorIDs = append(authorIDs, post.AuthorID)
+	}
+	users := authors(&log, s.users, authorIDs)
+
+	resp := &blogv1.ListPostsResponse{
+		Posts:         make([]*blogv1.Post, 0, len(posts)),
+		NextPageToken: next,
+		PrevPageToken: prev,
+	}
+	for _, post := range posts {
+		resp.Posts = append(resp.Posts, toPost(post, users[post.AuthorID]))
+	}
+	return resp, nil
+}
+
+func (s *postService) CreatePost(ctx context.Context, req *blogv1.CreatePostRequest) (*blogv1.Post, error) {
+	log := s.log.With(slog.String("fn", "handlers.rpc.CreatePost"))
+
+	body := map[string]any{"title": req.GetTitle(), "content": req.GetContent()}
+	var resp struct {
+		PostID int64 `json:"post_id"`
+	}
+	if err := s.rest.Do(ctx, restCall(ctx, http.MethodPost, "/api/post", body, 0), &resp); err != nil {
+		return nil, restError(ctx, &log, err)
+	}
+	return s.read(ctx, &log, resp.PostID)
+}
+
+func (s *postService) UpdatePost(ctx context.Context, req *blogv1.UpdatePostRequest) (*blogv1.Post, error) {
+	log := s.log.With(slog.String("fn", "handlers.rpc.UpdatePost"))
+
+	if req.GetId() < 1 {
+		return nil, invalidArgument(ctx, "id", "min")
+	}
+	body := map[string]any{"title": req.GetTitle(), "content": req.GetContent()}
+	path := fmt.Sprintf("/api/post/%d", req.GetId())
+	if err := s.rest.Do(ctx, restCall(ctx, http.MethodPatch, path, body, req.GetVersion()), nil); err != nil {
+		return nil, restError(ctx, &log, err, slog.Int64("post_id", req.GetId()))
+	}
+	return s.read(ctx, &log, req.GetId())
+}
+
+func (s *postService) DeletePost(ctx context.Context, req *blogv1.DeletePostRequest) (*emptypb.Empty, error) {
+	log := s.log.With(slog.String("fn", "handlers.rpc.DeletePost"))
+
+	if req.GetId() < 1 {
+		return nil, invalidArgument(ctx, "id", "min")
+	}
+	path := fmt.Sprintf("/api/post/%d", req.GetId())
+	if err := s.rest.Do(ctx, restCall(ctx, http.MethodDelete, path, nil, 0), nil); err != nil {
+		return nil, restError(ctx, &log, err, slog.Int64("post_id", req.GetId()))
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// read returns a post after a write, as it was stored.
+func (s *postService) read(ctx context.Context, log logger.Logger, id int64) (*blogv1.Post, error) {
+	post, err := s.posts.GetPostByID(id)
+	if err != nil {
+		return nil, repositoryError(ctx, log, err, "Post Not Found")
+	}
+	return s.withAuthor(log, post), nil
+}
+
+func (s *postService) withAuthor(log logger.Logger, post *models.Post) *blogv1.Post {
+	author, err := s.users.GetUserByID(post.AuthorID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotExists) {
+			log.Error("error getting author", slog.Int64("user_id", post.AuthorID), sl.Error(err))
+		}
+		author = nil
+	}
+	return toPost(post, author)
+}
@@ -0,0 +1,137 @@
+// Package rpc serves the Post, Comment and User services over gRPC. Reads
+// go to the repository with the visibility rules of the REST routes, writes
+// run the REST routes in process like the GraphQL mutations do, so a change
+// is validated, authorized and moderated the same whichever API it came in
+// through. Errors carry the codes grpc-gateway maps back to the statuses of
+// the REST API.
+package rpc
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/inproc"
+	"blog/internal/api/pb/blogv1"
+	"blog/internal/config"
+	requestid "blog/internal/middlewares/request_id"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+)
+
+// Server is a gRPC server with the blog services and the health service.
+type Server struct {
+	*grpc.Server
+	health *health.Server
+}
+
+// New registers the services on a new server. rest calls the REST routes
+// the writes go through.
+func New(log logger.Logger, repo repository.Repository, rest *inproc.Client, cursors *cursor.Codec, cfg config.GRPCConfig) *Server {
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		logging(log),
+		recovery(log),
+		authenticate(repo.Sanction()),
+	))
+
+	blogv1.RegisterPostServiceServer(srv, &postService{
+		log:     log,
+		posts:   repo.Post(),
+		users:   repo.User(),
+		rest:    rest,
+		cursors: cursors,
+	})
+	blogv1.RegisterCommentServiceServer(srv, &commentService{
+		log:        log,
+		comments:   repo.Comment(),
+		users:      repo.User(),
+		shadowBans: repo.Sanction(),
+		rest:       rest,
+		cursors:    cursors,
+	})
+	blogv1.RegisterUserServiceServer(srv, &userService{
+		log:   log,
+		users: repo.User(),
+	})
+
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	if cfg.Reflection {
+		reflection.Register(srv)
+	}
+	return &Server{Server: srv, health: hs}
+}
+
+// Shutdown reports NOT_SERVING to health checks, stops accepting calls and
+// waits for the running ones, like http.Server.Shutdown. When ctx is done
+// first the remaining calls are cancelled and its error returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// logging gives every call a request id, sent back in the x-request-id
+// header, and logs the call like the HTTP logger middleware does.
+func logging(log logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, requestID := requestid.NewContext(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestID))
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		code := status.Code(err)
+
+		log := log.With(
+			slog.String("method", info.FullMethod),
+			slog.String("request_id", requestID),
+			slog.String("code", code.String()),
+			slog.Duration("duration", time.Since(start)),
+		)
+		if code == codes.Internal || code == codes.Unknown {
+			log.Error("grpc call failed")
+		} else {
+			log.Info("grpc call completed")
+		}
+		return resp, err
+	}
+}
+
+// recovery turns a panic of a call into INTERNAL instead of taking the
+// process down.
+func recovery(log logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				log.Error("grpc call panicked",
+					slog.String("method", info.FullMethod),
+					slog.Any("panic", p),
+					slog.String("stack", string(debug.Stack())))
+				err = status.Error(codes.Internal, "Internal Server Error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,73 @@
+package rpc
+
+import (
+	"context"
+	"log/slog"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/pb/blogv1"
+	"blog/internal/models"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type userGetter interface {
+	GetUserByID(id int64) (*models.User, error)
+	GetUsersByIDs(ids []int64) (map[int64]*models.User, error)
+}
+
+type userService struct {
+	blogv1.UnimplementedUserServiceServer
+
+	log   logger.Logger
+	users userGetter
+}
+
+func (s *userService) GetUser(ctx context.Context, req *blogv1.GetUserRequest) (*blogv1.User, error) {
+	log := s.log.With(slog.String("fn", "handlers.rpc.GetUser"))
+
+	if req.GetId() < 1 {
+		return nil, invalidArgument(ctx, "id", "min")
+	}
+	user, err := s.users.GetUserByID(req.GetId())
+	if err != nil {
+		return nil, repositoryError(ctx, &log, err, "User Not Found")
+	}
+	return toUser(user), nil
+}
+
+// BatchGetUsers returns the users in the order of ids, ids of users that
+// don't exist are left out.
+func (s *userService) BatchGetUsers(ctx context.Context, req *blogv1.BatchGetUsersRequest) (*blogv1.BatchGetUsersResponse, error) {
+	log := s.log.With(slog.String("fn", "handlers.rpc.BatchGetUsers"))
+
+	if len(req.GetIds()) > cursor.MaxLimit {
+		return nil, invalidArgument(ctx, "ids", "max")
+	}
+	users, err := s.users.GetUsersByIDs(req.GetIds())
+	if err != nil {
+		return nil, internalError(&log, "error getting users", err)
+	}
+	resp := &blogv1.BatchGetUsersResponse{Users: make([]*blogv1.User, 0, len(users))}
+	for _, id := range req.GetIds() {
+		if user, ok := users[id]; ok {
+			resp.Users = append(resp.Users, toUser(user))
+			delete(users, id) // an id asked for twice is returned once
+		}
+	}
+	return resp, nil
+}
+
+// authors reads the authors of rows in one query, a failure leaves them
+// unset like an author that no longer exists.
+func authors(log logger.Logger, users userGetter, ids []int64) map[int64]*models.User {
+	if len(ids) == 0 {
+		return nil
+	}
+	byID, err := users.GetUsersByIDs(ids)
+	if err != nil {
+		log.Error("error getting authors", slog.Int("count", len(ids)), sl.Error(err))
+		return nil
+	}
+	return byID
+}
@@ -0,0 +1,236 @@
+package activitypub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"blog/internal/api/httpcache"
+	"blog/internal/federation"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+const (
+	jrdContentType = "application/jrd+json"
+	outboxItems    = 20
+	maxInboxBody   = 1 << 20
+)
+
+type usersGetter interface {
+	GetUserByID(id int64) (*models.User, error)
+	GetUsersByUsernames(usernames []string) (map[string]*models.User, error)
+}
+
+type postGetter interface {
+	GetPostByID(id int64) (*models.Post, error)
+}
+
+type documents interface {
+	Host() string
+	WebFinger(user *models.User) *federation.WebFinger
+	Actor(user *models.User) (*federation.Actor, error)
+	Outbox(user *models.User, limit int) (*federation.OrderedCollection, error)
+	Followers(user *models.User) (*federation.OrderedCollection, error)
+	PostDocument(post *models.Post) (*federation.Object, error)
+}
+
+type inboxHandler interface {
+	HandleInbox(ctx context.Context, r *http.Request, body []byte) error
+}
+
+// WebFinger resolves acct:username@host to the actor of a local user.
+func WebFinger(log logger.Logger, users usersGetter, docs documents) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.activitypub.WebFinger"))
+
+		resource := strings.TrimPrefix(r.URL.Query().Get("resource"), "acct:")
+		i := strings.LastIndex(resource, "@")
+		if i <= 0 {
+			util.ErrorResponse(w, r, http.StatusBadRequest, "resource must be acct:username@host")
+			return
+		}
+		username, host := resource[:i], resource[i+1:]
+		if !strings.EqualFold(host, docs.Host()) {
+			util.ErrorResponse(w, r, http.StatusNotFound, "User Not Found")
+			return
+		}
+
+		found, err := users.GetUsersByUsernames([]string{username})
+		if err != nil {
+			log.Error("error getting user", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		user, ok := found[strings.ToLower(username)]
+		if !ok || user.Role == models.RoleRemote {
+			util.ErrorResponse(w, r, http.StatusNotFound, "User Not Found")
+			return
+		}
+		writeDocument(w, r, &log, jrdContentType, docs.WebFinger(user))
+	}
+}
+
+// Actor serves the Person document of a local user.
+func Actor(log logger.Logger, users usersGetter, docs documents) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.activitypub.Actor"))
+
+		user, ok := localUser(w, r, &log, users)
+		if !ok {
+			return
+		}
+		actor, err := docs.Actor(user)
+		if err != nil {
+			log.Error("error building actor", sl.Error(err), slog.Int64("user_id", user.ID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		writeDocument(w, r, &log, federation.ContentType, actor)
+	}
+}
+
+// Outbox serves the newest posts of a local user as Create activities.
+func Outbox(log logger.Logger, users usersGetter, docs documents) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.activitypub.Outbox"))
+
+		user, ok := localUser(w, r, &log, users)
+		if !ok {
+			return
+		}
+		outbox, err := docs.Outbox(user, outboxItems)
+		if err != nil {
+			log.Error("error building outbox", sl.Error(err), slog.Int64("user_id", user.ID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		writeDocument(w, r, &log, federation.ContentType, outbox)
+	}
+}
+
+// Followers serves the size of the remote follower collection of a local user.
+func Followers(log logger.Logger, users usersGetter, docs documents) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.activitypub.Followers"))
+
+		user, ok := localUser(w, r, &log, users)
+		if !ok {
+			return
+		}
+		followers, err := docs.Followers(user)
+		if err != nil {
+			log.Error("error counting followers", sl.Error(err), slog.Int64("user_id", user.ID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		writeDocument(w, r, &log, federation.ContentType, followers)
+	}
+}
+
+// Post serves a post as the Article it was published as.
+func Post(log logger.Logger, posts postGetter, docs documents) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.activitypub.Post"))
+
+		postID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+		post, err := posts.GetPostByID(postID)
+		if err == nil && post.HiddenAt != nil {
+			err = repository.ErrNotExists
+		}
+		if err != nil {
+			util.RepositoryError(w, r, &log, err, "Post Not Found")
+			return
+		}
+
+		article, err := docs.PostDocument(post)
+		if err != nil {
+			log.Error("error building article", sl.Error(err), slog.Int64("post_id", postID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		writeDocument(w, r, &log, federation.ContentType, article)
+	}
+}
+
+// Inbox accepts activities from remote servers, both the inbox of a user
+// and the shared inbox. The request must carry a valid HTTP signature of
+// the sending actor.
+func Inbox(log logger.Logger, users usersGetter, inbox inboxHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.activitypub.Inbox"))
+
+		if r.PathValue("id") != "" {
+			if _, ok := localUser(w, r, &log, users); !ok {
+				return
+			}
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInboxBody))
+		if err != nil {
+			util.ErrorResponse(w, r, http.StatusRequestEntityTooLarge, "activity too large")
+			return
+		}
+
+		if err := inbox.HandleInbox(r.Context(), r, body); err != nil {
+			switch {
+			case errors.Is(err, federation.ErrSignature):
+				log.Info("rejected activity with invalid signature", sl.Error(err))
+				util.ErrorResponse(w, r, http.StatusUnauthorized, "invalid signature")
+			case errors.Is(err, federation.ErrBadActivity):
+				log.Info("rejected activity", sl.Error(err))
+				util.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			default:
+				log.Error("error handling activity", sl.Error(err))
+				util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// localUser resolves the {id} path value to a local user, remote stand-ins
+// are no actors of this server.
+func localUser(w http.ResponseWriter, r *http.Request, log logger.Logger, users usersGetter) (*models.User, bool) {
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Info("invalid path value", sl.Error(err))
+		util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+		return nil, false
+	}
+	user, err := users.GetUserByID(userID)
+	if err == nil && user.Role == models.RoleRemote {
+		err = repository.ErrNotExists
+	}
+	if err != nil {
+		util.RepositoryError(w, r, log, err, "User Not Found")
+		return nil, false
+	}
+	return user, true
+}
+
+func writeDocument(w http.ResponseWriter, r *http.Request, log logger.Logger, contentType string, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Error("failed to encode document", sl.Error(err))
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if err := httpcache.Write(w, r, http.StatusOK, contentType, body, httpcache.Validators{}); err != nil {
+		log.Error("failed to write document", sl.Error(err))
+	}
+}
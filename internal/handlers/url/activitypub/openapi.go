@@ -0,0 +1,87 @@
+package activitypub
+
+import (
+	"net/http"
+
+	"blog/internal/api/openapi"
+	"blog/internal/federation"
+)
+
+// Operations documents the ActivityPub routes.
+func Operations() []openapi.Operation {
+	inbox := func(pattern, id, summary string) openapi.Operation {
+		return openapi.Operation{
+			Pattern:     pattern,
+			ID:          id,
+			Summary:     summary,
+			Description: "Requests must carry an HTTP signature of the sending actor.",
+			Body:        federation.Activity{},
+			BodyType:    federation.ContentType,
+			Responses: []openapi.Response{
+				openapi.Empty(http.StatusAccepted, "activity accepted"),
+				openapi.Error(http.StatusBadRequest, "unsupported or malformed activity"),
+				openapi.Error(http.StatusUnauthorized, "invalid signature"),
+				openapi.Error(http.StatusNotFound, "no such user"),
+				openapi.Error(http.StatusRequestEntityTooLarge, "activity larger than 1MB"),
+			},
+		}
+	}
+
+	return []openapi.Operation{
+		{
+			Pattern: "GET /.well-known/webfinger",
+			ID:      "webFinger",
+			Summary: "Resolve acct:username@host to an actor",
+			Params: []openapi.Param{
+				{Name: "resource", In: "query", Required: true, Schema: openapi.String(), Description: "acct:username@host"},
+			},
+			Responses: []openapi.Response{
+				openapi.Content(http.StatusOK, "the links of the user", jrdContentType, federation.WebFinger{}),
+				openapi.Error(http.StatusBadRequest, "resource isn't an account of this host"),
+				openapi.Error(http.StatusNotFound, "no such user"),
+			},
+		},
+		{
+			Pattern: "GET /ap/users/{id}",
+			ID:      "getActor",
+			Summary: "The actor of a user",
+			Responses: []openapi.Response{
+				openapi.Content(http.StatusOK, "the actor", federation.ContentType, federation.Actor{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				openapi.Error(http.StatusNotFound, "no such user"),
+			},
+		},
+		{
+			Pattern: "GET /ap/users/{id}/outbox",
+			ID:      "getActorOutbox",
+			Summary: "The newest posts of a user as Create activities",
+			Responses: []openapi.Response{
+				openapi.Content(http.StatusOK, "the outbox", federation.ContentType, federation.OrderedCollection{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				openapi.Error(http.StatusNotFound, "no such user"),
+			},
+		},
+		{
+			Pattern: "GET /ap/users/{id}/followers",
+			ID:      "getActorFollowers",
+			Summary: "The remote followers of a user",
+			Responses: []openapi.Response{
+				openapi.Content(http.StatusOK, "the followers collection", federation.ContentType, federation.OrderedCollection{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				openapi.Error(http.StatusNotFound, "no such user"),
+			},
+		},
+		{
+			Pattern: "GET /ap/posts/{id}",
+			ID:      "getArticle",
+			Summary: "A post as Article",
+			Responses: []openapi.Response{
+				openapi.Content(http.StatusOK, "the article", federation.ContentType, federation.Object{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				openapi.Error(http.StatusNotFound, "no such post"),
+			},
+		},
+		inbox("POST /ap/users/{id}/inbox", "postActorInbox", "Deliver an activity to a user"),
+		inbox("POST /ap/inbox", "postSharedInbox", "Deliver an activity to the shared inbox"),
+	}
+}
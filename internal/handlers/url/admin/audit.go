@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type auditLogResponse struct {
+	response.BaseResponse
+	Data []*models.AuditEntry `json:"data"`
+
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+type auditLister interface {
+	ListAuditEntries(page repository.Page) ([]*models.AuditEntry, error)
+}
+
+// auditLimit is the page size of the audit log.
+const auditLimit = 50
+
+// AuditLog lists moderation and administration actions, newest first.
+func AuditLog(log logger.Logger, auditLister auditLister, cursors *cursor.Codec) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.admin.AuditLog"))
+
+		limit, cur, err := cursors.Query(r.URL.Query(), auditLimit)
+		if err != nil {
+			log.Info("invalid page query", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		entries, err := auditLister.ListAuditEntries(cursor.Page(cur, limit))
+		if err != nil {
+			log.Error("error listing audit log", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		entries, nextCursor, prevCursor := cursor.Paginate(cursors, cur, limit, entries, func(e *models.AuditEntry) (time.Time, int64) {
+			return e.CreatedAt, e.ID
+		})
+		if entries == nil {
+			entries = []*models.AuditEntry{}
+		}
+
+		resp := auditLogResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			Data: entries,
+
+			NextCursor: nextCursor,
+			PrevCursor: prevCursor,
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package admin
+
+import (
+	"net/http"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/openapi"
+	"blog/internal/api/response"
+)
+
+// Operations documents the admin routes.
+func Operations() []openapi.Operation {
+	notAdmin := openapi.Error(http.StatusForbidden, "not an admin")
+	return []openapi.Operation{
+		{
+			Pattern: "PUT /api/admin/user/{id}/role",
+			ID:      "setUserRole",
+			Summary: "Change the role of a user",
+			Auth:    openapi.Required,
+			Body:    roleRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "role changed", roleResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id or role"),
+				notAdmin,
+				openapi.Error(http.StatusNotFound, "no such user"),
+			},
+		},
+		{
+			Pattern: "POST /api/admin/user/{id}/sanctions",
+			ID:      "sanctionUser",
+			Summary: "Warn, suspend, ban or shadow-ban a user",
+			Auth:    openapi.Required,
+			Body:    sanctionRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusCreated, "sanction created", sanctionResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id, kind or duration, suspensions need duration_days"),
+				openapi.Error(http.StatusForbidden, "not an admin, or the user is an admin"),
+				openapi.Error(http.StatusNotFound, "no such user"),
+			},
+		},
+		{
+			Pattern: "GET /api/admin/user/{id}/sanctions",
+			ID:      "listUserSanctions",
+			Summary: "List the sanctions of a user, revoked ones included",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "the sanctions", sanctionListResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				notAdmin,
+			},
+		},
+		{
+			Pattern: "DELETE /api/admin/sanctions/{id}",
+			ID:      "revokeSanction",
+			Summary: "Revoke a sanction",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "sanction revoked", sanctionResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				notAdmin,
+				openapi.Error(http.StatusNotFound, "no such active sanction"),
+			},
+		},
+		{
+			Pattern: "GET /api/admin/audit",
+			ID:      "listAuditLog",
+			Summary: "List moderation and admin actions, newest first",
+			Auth:    openapi.Required,
+			Params:  cursor.Params(auditLimit),
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "a page of the audit log", auditLogResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid limit or cursor"),
+				notAdmin,
+			},
+		},
+		{
+			Pattern: "POST /api/admin/webhooks",
+			ID:      "createWebhook",
+			Summary: "Register a webhook",
+			Description: "The response holds the secret the payloads are signed with, it isn't shown again. " +
+				"Deliveries carry X-Webhook-Event, X-Webhook-Delivery, X-Webhook-Timestamp and " +
+				"X-Webhook-Signature, sha256= and the HMAC of timestamp.body.",
+			Auth: openapi.Required,
+			Body: webhookRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusCreated, "webhook created", webhookResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid url, events or secret"),
+				notAdmin,
+			},
+		},
+		{
+			Pattern: "GET /api/admin/webhooks",
+			ID:      "listWebhooks",
+			Summary: "List the webhooks, without their secrets",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "the webhooks", webhookListResponse{}),
+				notAdmin,
+			},
+		},
+		{
+			Pattern: "PATCH /api/admin/webhooks/{id}",
+			ID:      "updateWebhook",
+			Summary: "Change, pause or resume a webhook",
+			Auth:    openapi.Required,
+			Body:    webhookUpdateRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "webhook updated", webhookResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id, url or events"),
+				notAdmin,
+				openapi.Error(http.StatusNotFound, "no such webhook"),
+			},
+		},
+		{
+			Pattern: "DELETE /api/admin/webhooks/{id}",
+			ID:      "deleteWebhook",
+			Summary: "Delete a webhook and its delivery log",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "webhook deleted", response.BaseResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				notAdmin,
+				openapi.Error(http.StatusNotFound, "no such webhook"),
+			},
+		},
+		{
+			Pattern: "GET /api/admin/webhooks/{id}/deliveries",
+			ID:      "listWebhookDeliveries",
+			Summary: "The delivery log of a webhook, newest first",
+			Auth:    openapi.Required,
+			Params:  cursor.Params(deliveryLimit),
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "the deliveries", deliveryListResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id, limit or cursor"),
+				notAdmin,
+				openapi.Error(http.StatusNotFound, "no such webhook"),
+			},
+		},
+		{
+			Pattern: "POST /api/admin/webhooks/{id}/deliveries/{delivery_id}/redeliver",
+			ID:      "redeliverWebhookDelivery",
+			Summary: "Send a delivery again, as a new delivery",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusAccepted, "the new delivery, queued", deliveryResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				notAdmin,
+				openapi.Error(http.StatusNotFound, "no such delivery of the webhook"),
+			},
+		},
+	}
+}
@@ -0,0 +1,92 @@
+package admin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type roleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user moderator admin"`
+}
+
+type roleResponse struct {
+	response.BaseResponse
+	UserID int64  `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+type roleSetter interface {
+	SetUserRole(id int64, role string) error
+}
+
+type auditor interface {
+	AddAuditEntry(entry *models.AuditEntry) error
+}
+
+func SetRole(log logger.Logger, roleSetter roleSetter, auditor auditor) http.HandlerFunc {
+	validate := util.NewCustomValidator()
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.admin.SetRole"))
+
+		userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		var req roleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Info("error decoding request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Info("error validation request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		if err := roleSetter.SetUserRole(userID, req.Role); err != nil {
+			util.RepositoryError(w, r, &log, err, "User Not Found")
+			return
+		}
+
+		resp := roleResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			UserID: userID,
+			Role:   req.Role,
+		}
+
+		adminID, _ := r.Context().Value(auth.UserIDCtxKey).(int64)
+		entry := &models.AuditEntry{
+			ActorID:    &adminID,
+			Action:     models.AuditUserRole,
+			TargetType: models.TargetUser,
+			TargetID:   userID,
+			Details:    req.Role,
+		}
+		if err := auditor.AddAuditEntry(entry); err != nil {
+			log.Error("failed to write audit entry", sl.Error(err))
+		}
+
+		log.Info("user role changed", slog.Int64("user_id", userID), slog.String("role", req.Role))
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package admin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+var sanctionAudit = map[string]string{
+	models.SanctionWarn:      models.AuditUserWarn,
+	models.SanctionSuspend:   models.AuditUserSuspend,
+	models.SanctionBan:       models.AuditUserBan,
+	models.SanctionShadowBan: models.AuditUserShadowBan,
+}
+
+type sanctionRequest struct {
+	Kind         string `json:"kind" validate:"required,oneof=warn suspend ban shadow_ban"`
+	Reason       string `json:"reason" validate:"required,max=500"`
+	DurationDays int    `json:"duration_days" validate:"gte=0,lte=3650"` // 0 means permanent, suspensions need a duration
+}
+
+type sanctionResponse struct {
+	response.BaseResponse
+	Sanction *models.Sanction `json:"sanction"`
+}
+
+type sanctionListResponse struct {
+	response.BaseResponse
+	UserID int64              `json:"user_id"`
+	Data   []*models.Sanction `json:"data"`
+}
+
+type sanctionCreator interface {
+	CreateSanction(sanction *models.Sanction) (int64, error)
+}
+
+type sanctionLister interface {
+	ListSanctions(userID int64) ([]*models.Sanction, error)
+}
+
+type sanctionRevoker interface {
+	RevokeSanction(id, revokedBy int64) (*models.Sanction, error)
+}
+
+type userGetter interface {
+	GetUserByID(id int64) (*models.User, error)
+}
+
+// Sanction warns, suspends, bans or shadow-bans a user. Admins can't be
+// sanctioned, they have to be demoted first.
+func Sanction(log logger.Logger, sanctionCreator sanctionCreator, userGetter userGetter, auditor auditor) http.HandlerFunc {
+	validate := util.NewCustomValidator()
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.admin.Sanction"))
+
+		adminID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		var req sanctionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Info("error decoding request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Info("error validation request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+		if req.Kind == models.SanctionSuspend && req.DurationDays == 0 {
+			util.ErrorResponse(w, r, http.StatusBadRequest, "suspensions need duration_days")
+			return
+		}
+
+		user, err := userGetter.GetUserByID(userID)
+		if err != nil {
+			util.RepositoryError(w, r, &log, err, "User Not Found")
+			return
+		}
+		if user.Role == models.RoleAdmin {
+			util.ErrorResponse(w, r, http.StatusForbidden, "admins can't be sanctioned")
+			return
+		}
+
+		sanction := &models.Sanction{
+			UserID:   userID,
+			Kind:     req.Kind,
+			Reason:   req.Reason,
+			IssuedBy: adminID,
+		}
+		if req.Kind != models.SanctionWarn && req.DurationDays > 0 {
+			expiresAt := time.Now().Add(time.Duration(req.DurationDays) * 24 * time.Hour).UTC().Truncate(time.Second)
+			sanction.ExpiresAt = &expiresAt
+		}
+
+		if _, err := sanctionCreator.CreateSanction(sanction); err != nil {
+			log.Error("error creating sanction", sl.Error(err), slog.Int64("user_id", userID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		details := req.Reason
+		if sanction.ExpiresAt != nil {
+			details += ", until " + sanction.ExpiresAt.Format(time.RFC3339)
+		}
+		entry := &models.AuditEntry{
+			ActorID:    &adminID,
+			Action:     sanctionAudit[req.Kind],
+			TargetType: models.TargetUser,
+			TargetID:   userID,
+			Details:    details,
+		}
+		if err := auditor.AddAuditEntry(entry); err != nil {
+			log.Error("failed to write audit entry", sl.Error(err))
+		}
+
+		resp := sanctionResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusCreated,
+			},
+			Sanction: sanction,
+		}
+
+		log.Info("user sanctioned", slog.Int64("user_id", userID), slog.String("kind", req.Kind))
+		if err := jsonutil.WriteJSON(w, http.StatusCreated, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// ListSanctions returns the full sanction history of a user, including expired and revoked ones.
+func ListSanctions(log logger.Logger, sanctionLister sanctionLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.admin.ListSanctions"))
+
+		userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		sanctions, err := sanctionLister.ListSanctions(userID)
+		if err != nil {
+			log.Error("error listing sanctions", sl.Error(err), slog.Int64("user_id", userID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if sanctions == nil {
+			sanctions = []*models.Sanction{}
+		}
+
+		resp := sanctionListResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			UserID: userID,
+			Data:   sanctions,
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// RevokeSanction lifts a sanction before it expires.
+func RevokeSanction(log logger.Logger, sanctionRevoker sanctionRevoker, auditor auditor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.admin.RevokeSanction"))
+
+		adminID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		sanctionID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		sanction, err := sanctionRevoker.RevokeSanction(sanctionID, adminID)
+		if err != nil {
+			util.RepositoryError(w, r, &log, err, "Sanction Not Found")
+			return
+		}
+
+		entry := &models.AuditEntry{
+			ActorID:    &adminID,
+			Action:     models.AuditSanctionRevoke,
+			TargetType: models.TargetUser,
+			TargetID:   sanction.UserID,
+			Details:    sanction.Kind + " #" + strconv.FormatInt(sanction.ID, 10),
+		}
+		if err := auditor.AddAuditEntry(entry); err != nil {
+			log.Error("failed to write audit entry", sl.Error(err))
+		}
+
+		resp := sanctionResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			Sanction: sanction,
+		}
+
+		log.Info("sanction revoked", slog.Int64("sanction_id", sanctionID))
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,440 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/config"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+	"blog/internal/util/safehttp"
+	"blog/internal/webhook"
+)
+
+type webhookRequest struct {
+	URL    string   `json:"url" validate:"required,url,max=2000"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=post.created post.updated post.deleted comment.created user.created"`
+	Secret string   `json:"secret" validate:"omitempty,min=16,max=200"` // generated when empty
+}
+
+type webhookUpdateRequest struct {
+	URL    *string   `json:"url" validate:"omitempty,url,max=2000"`
+	Events *[]string `json:"events" validate:"omitempty,min=1,dive,oneof=post.created post.updated post.deleted comment.created user.created"`
+	Active *bool     `json:"active"`
+}
+
+type webhookResponse struct {
+	response.BaseResponse
+	Webhook *models.Webhook `json:"webhook"`
+}
+
+type webhookListResponse struct {
+	response.BaseResponse
+	Data []*models.Webhook `json:"data"`
+}
+
+type deliveryResponse struct {
+	response.BaseResponse
+	Delivery *models.WebhookDelivery `json:"delivery"`
+}
+
+type deliveryListResponse struct {
+	response.BaseResponse
+	WebhookID int64                     `json:"webhook_id"`
+	Data      []*models.WebhookDelivery `json:"data"`
+
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// deliveryLimit is the page size of the delivery log.
+const deliveryLimit = 50
+
+type webhookCreator interface {
+	CreateWebhook(h *models.Webhook) error
+}
+
+type webhookLister interface {
+	ListWebhooks() ([]*models.Webhook, error)
+}
+
+type webhookUpdater interface {
+	GetWebhook(id int64) (*models.Webhook, error)
+	UpdateWebhook(h *models.Webhook) error
+}
+
+type webhookDeleter interface {
+	DeleteWebhook(id int64) error
+}
+
+type deliveryLister interface {
+	GetWebhook(id int64) (*models.Webhook, error)
+	ListWebhookDeliveries(webhookID int64, page repository.Page) ([]*models.WebhookDelivery, error)
+}
+
+type redeliverer interface {
+	RedeliverWebhookDelivery(webhookID, deliveryID int64) (*models.WebhookDelivery, error)
+}
+
+type waker interface {
+	Wake()
+}
+
+// CreateWebhook registers an endpoint for the given events. The secret
+// payloads are signed with is only returned here.
+func CreateWebhook(log logger.Logger, webhookCreator webhookCreator, auditor auditor, cfg config.WebhooksConfig) http.HandlerFunc {
+	validate := util.NewCustomValidator()
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.admin.CreateWebhook"))
+
+		adminID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		var req webhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Info("error decoding request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			log.Info("error validation request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+		if err := checkWebhookURL(req.URL, cfg); err != nil {
+			util.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		secret := req.Secret
+		if secret == "" {
+			var err error
+			if secret, err = webhook.NewSecret(); err != nil {
+				log.Error("error generating webhook secret", sl.Error(err))
+				util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+				return
+			}
+		}
+
+		hook := &models.Webhook{
+			URL:       req.URL,
+			Secret:    secret,
+			Events:    uniqueEvents(req.Events),
+			Active:    true,
+			CreatedBy: adminID,
+		}
+		if err := webhookCreator.CreateWebhook(hook); err != nil {
+			log.Error("error creating webhook", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		auditWebhook(&log, auditor, adminID, models.AuditWebhookCreate, hook)
+
+		resp := webhookResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusCreated,
+			},
+			Webhook: hook,
+		}
+		log.Info("webhook created", slog.Int64("webhook_id", hook.ID))
+		if err := jsonutil.WriteJSON(w, http.StatusCreated, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// ListWebhooks returns all webhooks without their secrets.
+func ListWebhooks(log logger.Logger, webhookLister webhookLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.admin.ListWebhooks"))
+
+		hooks, err := webhookLister.ListWebhooks()
+		if err != nil {
+			log.Error("error listing webhooks", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if hooks == nil {
+			hooks = []*models.Webhook{}
+		}
+		for _, h := range hooks {
+			h.Secret = ""
+		}
+
+		resp := webhookListResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			Data: hooks,
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// UpdateWebhook changes the url or events of a webhook, or pauses it with
+// "active": false. Pending deliveries of a paused webhook fail.
+func UpdateWebhook(log logger.Logger, webhookUpdater webhookUpdater, auditor auditor, cfg config.WebhooksConfig) http.HandlerFunc {
+	validate := util.NewCustomValidator()
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.admin.UpdateWebhook"))
+
+		adminID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		webhookID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		var req webhookUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Info("error decoding request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			log.Info("error validation request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+		if req.URL != nil {
+			if err := checkWebhookURL(*req.URL, cfg); err != nil {
+				util.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		hook, err := webhookUpdater.GetWebhook(webhookID)
+		if err != nil {
+			util.RepositoryError(w, r, &log, err, "Webhook Not Found")
+			return
+		}
+		if req.URL != nil {
+			hook.URL = *req.URL
+		}
+		if req.Events != nil {
+			hook.Events = uniqueEvents(*req.Events)
+		}
+		if req.Active != nil {
+			hook.Active = *req.Active
+		}
+
+		if err := webhookUpdater.UpdateWebhook(hook); err != nil {
+			util.RepositoryError(w, r, &log, err, "Webhook Not Found")
+			return
+		}
+		hook.Secret = ""
+
+		auditWebhook(&log, auditor, adminID, models.AuditWebhookUpdate, hook)
+
+		resp := webhookResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			Webhook: hook,
+		}
+		log.Info("webhook updated", slog.Int64("webhook_id", webhookID))
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// DeleteWebhook removes a webhook together with its delivery log.
+func DeleteWebhook(log logger.Logger, webhookDeleter webhookDeleter, auditor auditor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.admin.DeleteWebhook"))
+
+		adminID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		webhookID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		if err := webhookDeleter.DeleteWebhook(webhookID); err != nil {
+			util.RepositoryError(w, r, &log, err, "Webhook Not Found")
+			return
+		}
+
+		auditWebhook(&log, auditor, adminID, models.AuditWebhookDelete, &models.Webhook{ID: webhookID})
+
+		log.Info("webhook deleted", slog.Int64("webhook_id", webhookID))
+		if err := jsonutil.WriteJSON(w, http.StatusOK, response.BaseResponse{Status: http.StatusOK}); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// WebhookDeliveries returns the delivery log of a webhook, newest first:
+// pending deliveries and finished ones within webhooks.retention.
+func WebhookDeliveries(log logger.Logger, deliveryLister deliveryLister, cursors *cursor.Codec) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.admin.WebhookDeliveries"))
+
+		webhookID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		limit, cur, err := cursors.Query(r.URL.Query(), deliveryLimit)
+		if err != nil {
+			log.Info("invalid page query", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if _, err := deliveryLister.GetWebhook(webhookID); err != nil {
+			util.RepositoryError(w, r, &log, err, "Webhook Not Found")
+			return
+		}
+
+		deliveries, err := deliveryLister.ListWebhookDeliveries(webhookID, cursor.Page(cur, limit))
+		if err != nil {
+			log.Error("error listing webhook deliveries", sl.Error(err), slog.Int64("webhook_id", webhookID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		deliveries, nextCursor, prevCursor := cursor.Paginate(cursors, cur, limit, deliveries, func(d *models.WebhookDelivery) (time.Time, int64) {
+			return d.CreatedAt, d.ID
+		})
+		if deliveries == nil {
+			deliveries = []*models.WebhookDelivery{}
+		}
+
+		resp := deliveryListResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			WebhookID: webhookID,
+			Data:      deliveries,
+
+			NextCursor: nextCursor,
+			PrevCursor: prevCursor,
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// Redeliver queues the payload of an earlier delivery again, as a new
+// delivery with a new id that refers to the original one.
+func Redeliver(log logger.Logger, redeliverer redeliverer, waker waker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.admin.Redeliver"))
+
+		webhookID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+		deliveryID, err := strconv.ParseInt(r.PathValue("delivery_id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		delivery, err := redeliverer.RedeliverWebhookDelivery(webhookID, deliveryID)
+		if err != nil {
+			util.RepositoryError(w, r, &log, err, "Delivery Not Found")
+			return
+		}
+		waker.Wake()
+
+		resp := deliveryResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusAccepted,
+			},
+			Delivery: delivery,
+		}
+		log.Info("webhook delivery queued again", slog.Int64("delivery_id", deliveryID), slog.Int64("redelivery_id", delivery.ID))
+		if err := jsonutil.WriteJSON(w, http.StatusAccepted, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// checkWebhookURL accepts https URLs, and http ones with webhooks.allow_private.
+func checkWebhookURL(rawURL string, cfg config.WebhooksConfig) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.New("invalid url")
+	}
+	if err := safehttp.CheckURL(u, cfg.AllowPrivate); err != nil {
+		return errors.New("url must be https")
+	}
+	return nil
+}
+
+// uniqueEvents returns events without duplicates, in the order of models.WebhookEvents.
+func uniqueEvents(events []string) []string {
+	var unique []string
+	for _, e := range models.WebhookEvents {
+		if slices.Contains(events, e) {
+			unique = append(unique, e)
+		}
+	}
+	return unique
+}
+
+func auditWebhook(log logger.Logger, auditor auditor, adminID int64, action string, hook *models.Webhook) {
+	var details string
+	if hook.URL != "" {
+		details = hook.URL + " " + strings.Join(hook.Events, ",")
+		if !hook.Active {
+			details += " (paused)"
+		}
+	}
+	entry := &models.AuditEntry{
+		ActorID:    &adminID,
+		Action:     action,
+		TargetType: models.TargetWebhook,
+		TargetID:   hook.ID,
+		Details:    details,
+	}
+	if err := auditor.AddAuditEntry(entry); err != nil {
+		log.Error("failed to write audit entry", sl.Error(err))
+	}
+}
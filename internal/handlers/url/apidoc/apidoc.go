@@ -0,0 +1,48 @@
+package apidoc
+
+import (
+	"log/slog"
+	"net/http"
+
+	"blog/internal/api/httpcache"
+	"blog/internal/api/openapi"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type documenter interface {
+	Document() ([]byte, error)
+}
+
+// Spec serves the OpenAPI document of the routes of this instance.
+func Spec(log logger.Logger, doc documenter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.apidoc.Spec"))
+
+		body, err := doc.Document()
+		if err != nil {
+			log.Error("error rendering OpenAPI document", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if err := httpcache.Write(w, r, http.StatusOK, "application/json; charset=utf-8", body, httpcache.Validators{}); err != nil {
+			log.Error("error writing OpenAPI document", sl.Error(err))
+		}
+	}
+}
+
+// Operations documents the document itself.
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Pattern: "GET /api/openapi.json",
+			ID:      "getOpenAPI",
+			Summary: "This document",
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "OpenAPI 3.1 document", &openapi.Schema{Type: "object"}),
+				openapi.Empty(http.StatusNotModified, "If-None-Match matched"),
+			},
+		},
+	}
+}
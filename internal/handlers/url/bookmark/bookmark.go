@@ -0,0 +1,256 @@
+package bookmark
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type saveRequest struct {
+	CollectionID *int64 `json:"collection_id"` // optional, null keeps the bookmark outside of collections
+}
+
+type bookmarkResponse struct {
+	response.BaseResponse
+	PostID       int64  `json:"post_id"`
+	Bookmarked   bool   `json:"bookmarked"`
+	CollectionID *int64 `json:"collection_id,omitempty"`
+}
+
+type bookmarkInfo struct {
+	BookmarkID   int64     `json:"bookmark_id"`
+	CollectionID *int64    `json:"collection_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	PostID       int64     `json:"post_id"`
+	Title        string    `json:"title"`
+	AuthorID     int64     `json:"author_id"`
+	Username     string    `json:"username"`
+}
+
+type bookmarkListResponse struct {
+	response.BaseResponse
+	Data       []bookmarkInfo `json:"data"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+type bookmarkSaver interface {
+	SaveBookmark(bookmark *models.Bookmark) error
+}
+
+type bookmarkDeleter interface {
+	DeleteBookmark(userID, postID int64) (bool, error)
+}
+
+type bookmarkLister interface {
+	ListBookmarks(userID int64, collectionID *int64, beforeID int64, limit int) ([]*models.Bookmark, error)
+}
+
+type postGetter interface {
+	GetPostByID(id int64) (*models.Post, error)
+}
+
+type usersGetter interface {
+	GetUsersByIDs(ids []int64) (map[int64]*models.User, error)
+}
+
+// Save bookmarks a post for the caller. Saving an already bookmarked post
+// moves it to the given collection.
+func Save(log logger.Logger, bookmarkSaver bookmarkSaver, postGetter postGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.bookmark.Save"))
+
+		userID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		postID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		var req saveRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			log.Info("error decoding request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		post, err := postGetter.GetPostByID(postID)
+		if err == nil && post.HiddenAt != nil {
+			err = repository.ErrNotExists
+		}
+		if err != nil {
+			util.RepositoryError(w, r, &log, err, "Post Not Found")
+			return
+		}
+
+		bookmark := &models.Bookmark{
+			UserID:       userID,
+			PostID:       postID,
+			CollectionID: req.CollectionID,
+		}
+		if err := bookmarkSaver.SaveBookmark(bookmark); err != nil {
+			if errors.Is(err, repository.ErrForeignKeyFailed) {
+				util.ErrorResponse(w, r, http.StatusNotFound, "Collection Not Found")
+				return
+			}
+			log.Error("error saving bookmark", sl.Error(err), slog.Int64("post_id", postID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		resp := bookmarkResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			PostID:       postID,
+			Bookmarked:   true,
+			CollectionID: bookmark.CollectionID,
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// Delete removes a bookmark. Removing a missing bookmark is not an error.
+func Delete(log logger.Logger, bookmarkDeleter bookmarkDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.bookmark.Delete"))
+
+		userID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		postID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		if _, err := bookmarkDeleter.DeleteBookmark(userID, postID); err != nil {
+			log.Error("error deleting bookmark", sl.Error(err), slog.Int64("post_id", postID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		resp := bookmarkResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			PostID: postID,
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// List returns the caller's bookmarks, newest first. Pages are linked by an
+// opaque cursor instead of offsets, so adding bookmarks doesn't shift them.
+func List(log logger.Logger, bookmarkLister bookmarkLister, usersGetter usersGetter, cursors *cursor.Codec) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.bookmark.List"))
+
+		userID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		query := r.URL.Query()
+
+		limit, beforeID, err := cursors.QueryID(query, cursor.DefaultLimit)
+		if err != nil {
+			log.Info("invalid page query", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var collectionID *int64
+		if qcollection := query.Get("collection_id"); qcollection != "" {
+			id, err := strconv.ParseInt(qcollection, 10, 64)
+			if err != nil {
+				util.ErrorResponse(w, r, http.StatusBadRequest, "Invalid collection_id parameter")
+				return
+			}
+			collectionID = &id
+		}
+
+		// one extra row tells whether there is a next page
+		bookmarks, err := bookmarkLister.ListBookmarks(userID, collectionID, beforeID, limit+1)
+		if err != nil {
+			log.Error("error listing bookmarks", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		var nextCursor string
+		if len(bookmarks) > limit {
+			bookmarks = bookmarks[:limit]
+			nextCursor = cursors.EncodeID(bookmarks[limit-1].ID)
+		}
+
+		authorIDs := make([]int64, 0, len(bookmarks))
+		for _, b := range bookmarks {
+			authorIDs = append(authorIDs, b.Post.AuthorID)
+		}
+		users, err := usersGetter.GetUsersByIDs(authorIDs)
+		if err != nil {
+			log.Error("error getting post authors", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		data := make([]bookmarkInfo, 0, len(bookmarks))
+		for _, b := range bookmarks {
+			info := bookmarkInfo{
+				BookmarkID:   b.ID,
+				CollectionID: b.CollectionID,
+				CreatedAt:    b.CreatedAt,
+				PostID:       b.PostID,
+				Title:        b.Post.Title,
+				AuthorID:     b.Post.AuthorID,
+			}
+			if user, ok := users[b.Post.AuthorID]; ok {
+				info.Username = user.Username
+			}
+			data = append(data, info)
+		}
+
+		resp := bookmarkListResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			Data:       data,
+			NextCursor: nextCursor,
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package bookmark
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type collectionRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=64"`
+}
+
+type collectionResponse struct {
+	response.BaseResponse
+	Collection *models.BookmarkCollection `json:"collection"`
+}
+
+type collectionListResponse struct {
+	response.BaseResponse
+	Data []*models.BookmarkCollection `json:"data"`
+}
+
+type collectionCreator interface {
+	CreateCollection(collection *models.BookmarkCollection) (int64, error)
+}
+
+type collectionLister interface {
+	ListCollections(userID int64) ([]*models.BookmarkCollection, error)
+}
+
+type collectionDeleter interface {
+	DeleteCollection(id, userID int64) error
+}
+
+// CreateCollection creates a named reading list. Names are unique per user.
+func CreateCollection(log logger.Logger, collectionCreator collectionCreator) http.HandlerFunc {
+	validate := util.NewCustomValidator()
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.bookmark.CreateCollection"))
+
+		userID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		var req collectionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Info("error decoding request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Info("error validation request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		collection := &models.BookmarkCollection{
+			UserID: userID,
+			Name:   req.Name,
+		}
+		if _, err := collectionCreator.CreateCollection(collection); err != nil {
+			util.RepositoryError(w, r, &log, err, "collection already exists")
+			return
+		}
+
+		resp := collectionResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusCreated,
+			},
+			Collection: collection,
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusCreated, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+func ListCollections(log logger.Logger, collectionLister collectionLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.bookmark.ListCollections"))
+
+		userID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		collections, err := collectionLister.ListCollections(userID)
+		if err != nil {
+			log.Error("error listing collections", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if collections == nil {
+			collections = []*models.BookmarkCollection{}
+		}
+
+		resp := collectionListResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			Data: collections,
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// DeleteCollection removes a reading list, its bookmarks stay without a collection.
+func DeleteCollection(log logger.Logger, collectionDeleter collectionDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.bookmark.DeleteCollection"))
+
+		userID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		collectionID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		if err := collectionDeleter.DeleteCollection(collectionID, userID); err != nil {
+			util.RepositoryError(w, r, &log, err, "Collection Not Found")
+			return
+		}
+
+		resp := response.BaseResponse{Status: http.StatusOK}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package bookmark
+
+import (
+	"net/http"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/openapi"
+	"blog/internal/api/response"
+)
+
+// Operations documents the bookmark and collection routes.
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Pattern:      "PUT /api/post/{id}/bookmark",
+			ID:           "bookmarkPost",
+			Summary:      "Bookmark a post, or move the bookmark to another collection",
+			Auth:         openapi.Required,
+			Body:         saveRequest{},
+			BodyOptional: true,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "bookmarked", bookmarkResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id or body"),
+				openapi.Error(http.StatusNotFound, "no such post or collection"),
+			},
+		},
+		{
+			Pattern: "DELETE /api/post/{id}/bookmark",
+			ID:      "unbookmarkPost",
+			Summary: "Remove the bookmark of a post",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "not bookmarked", bookmarkResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+			},
+		},
+		{
+			Pattern: "GET /api/bookmarks",
+			ID:      "listBookmarks",
+			Summary: "List the bookmarks of the caller, newest first",
+			Auth:    openapi.Required,
+			Params: append(cursor.Params(cursor.DefaultLimit),
+				openapi.Query("collection_id", openapi.Integer(), "only the bookmarks of this collection")),
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "a page of bookmarks", bookmarkListResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid limit, collection_id or cursor"),
+			},
+		},
+		{
+			Pattern: "POST /api/bookmarks/collections",
+			ID:      "createBookmarkCollection",
+			Summary: "Create a bookmark collection",
+			Auth:    openapi.Required,
+			Body:    collectionRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusCreated, "collection created", collectionResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid name"),
+				openapi.Error(http.StatusConflict, "the caller has a collection of that name"),
+			},
+		},
+		{
+			Pattern: "GET /api/bookmarks/collections",
+			ID:      "listBookmarkCollections",
+			Summary: "List the bookmark collections of the caller",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "the collections", collectionListResponse{}),
+			},
+		},
+		{
+			Pattern:     "DELETE /api/bookmarks/collections/{id}",
+			ID:          "deleteBookmarkCollection",
+			Summary:     "Delete a bookmark collection",
+			Description: "Its bookmarks are kept outside of collections.",
+			Auth:        openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "collection deleted", response.BaseResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				openapi.Error(http.StatusNotFound, "no such collection of the caller"),
+			},
+		},
+	}
+}
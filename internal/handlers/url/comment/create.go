@@ -1,65 +1,111 @@
 package comment
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"log/slog"
 	"net/http"
 
-	"github.com/go-playground/validator/v10"
-
 	"blog/internal/api/jsonutil"
 	"blog/internal/api/response"
+	"blog/internal/config"
+	"blog/internal/events"
 	"blog/internal/middlewares/auth"
 	"blog/internal/models"
 	"blog/internal/repository"
+	"blog/internal/spam"
 	"blog/internal/util"
 	"blog/internal/util/logger"
 	"blog/internal/util/logger/sl"
 )
 
 type CreateRequest struct {
-	Content string `json:"content" validate:"required,min=10,max=1024"`
-	PostID  int64  `json:"post_id"`
+	Content  string `json:"content" validate:"required,min=10,max=1024"`
+	PostID   int64  `json:"post_id"`
+	ParentID *int64 `json:"parent_id,omitempty"`
 }
 
 type CreateResponse struct {
 	response.BaseResponse
-	CommentID int64 `json:"comment_id,omitempty"`
-	PostID    int64 `json:"post_id,omitempty"`
-	AuthorID  int64 `json:"author_id,omitempty"`
+	CommentID int64  `json:"comment_id,omitempty"`
+	PostID    int64  `json:"post_id,omitempty"`
+	AuthorID  int64  `json:"author_id,omitempty"`
+	ParentID  int64  `json:"parent_id,omitempty"`
+	Moderated string `json:"moderation_status,omitempty"`
 }
 
 type commentCreator interface {
 	CreateComment(comment *models.Comment) (int64, error)
+	GetCommentByID(id int64) (*models.Comment, error)
+	HasApprovedComment(authorID int64) (bool, error)
+}
+
+type postGetter interface {
+	GetPostByID(id int64) (*models.Post, error)
+}
+
+type mentionSyncer interface {
+	Sync(targetType string, targetID int64, content string) error
+}
+
+type eventPublisher interface {
+	Publish(ctx context.Context, e events.Event)
+}
+
+type spamScorer interface {
+	Score(comment *models.Comment) (spam.Result, error)
+	Status(score float64) string
 }
 
-func Create(log logger.Logger, commentCreator commentCreator) http.HandlerFunc {
-	validate := validator.New()
+// Create adds a comment to a post, or a reply when parent_id is set.
+// Replies nested deeper than cfg.MaxDepth are rejected. Depending on the
+// moderation mode of the post and the spam score the comment is published,
+// queued for approval or filed as spam.
+func Create(log logger.Logger, commentCreator commentCreator, postGetter postGetter, spamScorer spamScorer, mentions mentionSyncer, publisher eventPublisher, cfg config.CommentsConfig) http.HandlerFunc {
+	validate := util.NewCustomValidator()
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := log.With("fn", "handlers.url.comment.Create")
 		if r.Method != http.MethodPost {
-			util.ErrorResponse(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			util.ErrorResponse(w, r, http.StatusMethodNotAllowed, "Method Not Allowed")
 			return
 		}
 
 		authorID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
 		if !ok {
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
 
 		var req CreateRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			log.Info("error decoding request", sl.Error(err))
-			util.ErrorResponse(w, http.StatusBadRequest, "EOF")
+			util.ValidationError(w, r, err)
 			return
 		}
 
 		if err := validate.Struct(req); err != nil {
-			validationErrors := err.(validator.ValidationErrors)
-			log.Info("error validation request", sl.Error(validationErrors))
-			util.ErrorResponse(w, http.StatusBadRequest, "invalid json format")
+			log.Info("error validation request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		post, err := postGetter.GetPostByID(req.PostID)
+		if err == nil && post.HiddenAt != nil {
+			err = repository.ErrNotExists
+		}
+		if err != nil {
+			util.RepositoryError(w, r, &log, err, "Post Not Found")
+			return
+		}
+		if post.CommentsLocked {
+			util.ErrorResponse(w, r, http.StatusForbidden, "comments are locked")
+			return
+		}
+
+		status, err := moderationStatus(commentCreator, post, authorID, cfg.Moderation)
+		if err != nil {
+			log.Error("failed to resolve moderation status", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
 
@@ -67,19 +113,59 @@ func Create(log logger.Logger, commentCreator commentCreator) http.HandlerFunc {
 			Content:  req.Content,
 			PostID:   req.PostID,
 			AuthorID: authorID,
+			Status:   status,
 		}
 
-		commentID, err := commentCreator.CreateComment(comment)
-		if err != nil {
-			if errors.Is(err, repository.ErrForeignKeyFailed) {
-				log.Error("author or post does not exist", sl.Error(err))
-				util.ErrorResponse(w, http.StatusBadRequest, "author or post does not exist")
+		if req.ParentID != nil {
+			parent, err := commentCreator.GetCommentByID(*req.ParentID)
+			if err != nil {
+				util.RepositoryError(w, r, &log, err, "Parent Comment Not Found")
+				return
+			}
+			if parent.PostID != req.PostID || parent.Status != models.CommentStatusApproved {
+				util.ErrorResponse(w, r, http.StatusBadRequest, "parent comment does not belong to the post")
 				return
 			}
-			log.Error("failed creating comment", sl.Error(err))
-			util.ErrorResponse(w, http.StatusBadRequest, "internal server error")
+			if parent.Depth+1 > cfg.MaxDepth {
+				log.Info("reply depth limit reached", slog.Int64("parent_id", parent.ID), slog.Int("max_depth", cfg.MaxDepth))
+				util.ErrorResponse(w, r, http.StatusBadRequest, "reply depth limit reached")
+				return
+			}
+			comment.ParentID = &parent.ID
+			comment.Depth = parent.Depth + 1
+		}
+
+		// post authors are trusted on their own posts
+		if post.AuthorID != authorID {
+			res, err := spamScorer.Score(comment)
+			if err != nil {
+				// scoring is advisory, fall back to the moderation mode
+				log.Error("failed to score comment", sl.Error(err))
+			}
+			comment.SpamScore = res.Score
+			switch spamScorer.Status(res.Score) {
+			case models.CommentStatusSpam:
+				comment.Status = models.CommentStatusSpam
+			case models.CommentStatusPending:
+				comment.Status = models.CommentStatusPending
+			}
+			if len(res.Signals) > 0 {
+				log.Info("comment spam score", slog.Float64("score", res.Score), slog.Any("signals", res.Signals))
+			}
+		}
+
+		commentID, err := commentCreator.CreateComment(comment)
+		if err != nil {
+			util.RepositoryError(w, r, &log, err, "author or post does not exist")
 			return
 		}
+		// stored for pending comments too, they notify once approved
+		if err := mentions.Sync(models.TargetComment, commentID, comment.Content); err != nil {
+			log.Error("failed to link mentions", sl.Error(err), slog.Int64("comment_id", commentID))
+		}
+		if comment.Status == models.CommentStatusApproved {
+			publisher.Publish(r.Context(), events.CommentPublished{Comment: comment})
+		}
 
 		resp := CreateResponse{
 			BaseResponse: response.BaseResponse{
@@ -88,6 +174,14 @@ func Create(log logger.Logger, commentCreator commentCreator) http.HandlerFunc {
 			CommentID: commentID,
 			PostID:    req.PostID,
 			AuthorID:  authorID,
+			Moderated: comment.Status,
+		}
+		if comment.Status == models.CommentStatusSpam {
+			// do not tell spammers they were caught
+			resp.Moderated = models.CommentStatusPending
+		}
+		if comment.ParentID != nil {
+			resp.ParentID = *comment.ParentID
 		}
 
 		log.Info("created comment", slog.Int64("post_id", req.PostID))
@@ -99,3 +193,29 @@ func Create(log logger.Logger, commentCreator commentCreator) http.HandlerFunc {
 		}
 	}
 }
+
+// moderationStatus decides whether a new comment is published right away or
+// waits in the moderation queue. Post authors are never moderated on their own posts.
+func moderationStatus(approvals commentCreator, post *models.Post, authorID int64, globalMode string) (string, error) {
+	mode := post.CommentMode
+	if mode == "" {
+		mode = globalMode
+	}
+	if post.AuthorID == authorID {
+		return models.CommentStatusApproved, nil
+	}
+
+	switch mode {
+	case models.ModerationAll:
+		return models.CommentStatusPending, nil
+	case models.ModerationFirstTime:
+		approved, err := approvals.HasApprovedComment(authorID)
+		if err != nil {
+			return "", err
+		}
+		if !approved {
+			return models.CommentStatusPending, nil
+		}
+	}
+	return models.CommentStatusApproved, nil
+}
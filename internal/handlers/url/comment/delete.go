@@ -1,7 +1,6 @@
 package comment
 
 import (
-	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -9,7 +8,6 @@ import (
 	"blog/internal/api/jsonutil"
 	"blog/internal/api/response"
 	"blog/internal/middlewares/auth"
-	"blog/internal/repository"
 	"blog/internal/util"
 	"blog/internal/util/logger"
 	"blog/internal/util/logger/sl"
@@ -32,26 +30,20 @@ func Delete(log logger.Logger, commentDeleter commentDeleter) http.HandlerFunc {
 
 		authorID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
 		if !ok {
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
 
 		commentID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 		if err != nil {
 			log.Info("invalid path value", sl.Error(err))
-			util.ErrorResponse(w, http.StatusBadRequest, "Bad Request")
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
 			return
 		}
 
 		commentAuthorID, err := commentDeleter.GetCommentAuthorID(commentID)
 		if err != nil {
-			if errors.Is(err, repository.ErrNotExists) {
-				log.Info("comment is not exists", slog.Int64("comment_id", commentID))
-				util.ErrorResponse(w, http.StatusNotFound, "Not Found")
-				return
-			}
-			log.Error("comment delete error", slog.Int64("comment_id", commentID), sl.Error(err))
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			util.RepositoryError(w, r, &log, err, "Not Found")
 			return
 
 		} else if commentAuthorID != authorID {
@@ -60,22 +52,13 @@ func Delete(log logger.Logger, commentDeleter commentDeleter) http.HandlerFunc {
 				slog.Int64("comment_author_id", commentAuthorID),
 				slog.Int64("comment_id", commentID))
 
-			util.ErrorResponse(w, http.StatusForbidden, "Forbidden")
+			util.ErrorResponse(w, r, http.StatusForbidden, "Forbidden")
 			return
 		}
 
 		err = commentDeleter.DeleteComment(commentID)
 		if err != nil {
-			if errors.Is(err, repository.ErrNotExists) {
-				log.Info("comment not found", slog.Int64("comment_id", commentID), sl.Error(err))
-				util.ErrorResponse(w, http.StatusNotFound, "Comment Not Found")
-				return
-			}
-			log.Info("comment not found",
-				slog.Int64("comment_id", commentID),
-				sl.Error(err))
-
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			util.RepositoryError(w, r, &log, err, "Comment Not Found")
 			return
 		}
 
@@ -7,8 +7,10 @@ import (
 	"strconv"
 	"time"
 
-	"blog/internal/api/jsonutil"
+	"blog/internal/api/cursor"
+	"blog/internal/api/httpcache"
 	"blog/internal/api/response"
+	"blog/internal/middlewares/auth"
 	"blog/internal/models"
 	"blog/internal/repository"
 	"blog/internal/util"
@@ -20,76 +22,79 @@ type commentInfo struct {
 	CommentID int64      `json:"comment_id,omitempty"`
 	Content   string     `json:"content"`
 	PostID    int64      `json:"post_id"`
+	ParentID  *int64     `json:"parent_id,omitempty"`
+	Depth     int        `json:"depth"`
 	AuthorID  int64      `json:"author_id"`
 	Username  string     `json:"username"`
+	Version   int64      `json:"version"`
 	CreatedAt *time.Time `json:"created_at,omitempty"`
+
+	Reactions map[string]int64 `json:"reactions"`
 }
 
 type commenttListResponse struct {
 	response.BaseResponse
-	Data []commentInfo `json:"data"`
+	Data       []commentInfo `json:"data"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	PrevCursor string        `json:"prev_cursor,omitempty"`
 }
 
 type commentGetter interface {
-	ListComments(limit int, offset int, postID int64) ([]*models.Comment, error)
+	ListComments(page repository.Page, postID, viewerID int64) ([]*models.Comment, error)
 }
 
 type userGetter interface {
 	GetUserByID(id int64) (*models.User, error)
 }
 
-func GetList(log logger.Logger, commentGetter commentGetter, userGetter userGetter) http.HandlerFunc {
+func GetList(log logger.Logger, commentGetter commentGetter, userGetter userGetter, reactions reactionCounter, cursors *cursor.Codec) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := log.With("fn", "handlers.url.comment.GetList")
 
-		qlimit := r.URL.Query().Get("limit")
-		qoffset := r.URL.Query().Get("offset")
-		qpostID := r.URL.Query().Get("post_id")
-
-		limit := 10 // default
-		if qlimit != "" {
-			l, err := strconv.ParseInt(qlimit, 10, 64)
-			if err != nil || l < 1 {
-				log.Info("error parsing limit query", sl.Error(err))
-				util.ErrorResponse(w, http.StatusBadRequest, "Invalid limit parameter")
-				return
-			}
-			limit = int(l)
+		limit, cur, err := cursors.Query(r.URL.Query(), 10)
+		if err != nil {
+			log.Info("invalid page query", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
 		}
 
-		offset := 0 // default
-		if qoffset != "" {
-			o, err := strconv.ParseInt(qoffset, 10, 64)
-			if err != nil || o < 0 {
-				log.Info("error parsing offset query", sl.Error(err))
-				util.ErrorResponse(w, http.StatusBadRequest, "Invalid offset parameter")
-				return
-			}
-			offset = int(o)
-		}
 		var postID int64
-		if qpostID != "" {
+		if qpostID := r.URL.Query().Get("post_id"); qpostID != "" {
 			o, err := strconv.ParseInt(qpostID, 10, 64)
 			if err != nil || o < 0 {
 				log.Info("error parsing post_id query", sl.Error(err))
-				util.ErrorResponse(w, http.StatusBadRequest, "Invalid post id parametet")
+				util.ErrorResponse(w, r, http.StatusBadRequest, "Invalid post id parametet")
 				return
 			}
 			postID = o
 		}
-		// TODO: пж почини этот говнокод, n+1 запросы к бд это 90iq
-		comments, err := commentGetter.ListComments(int(limit), int(offset), postID)
+		viewerID, _ := r.Context().Value(auth.UserIDCtxKey).(int64)
+		comments, err := commentGetter.ListComments(cursor.Page(cur, limit), postID, viewerID)
 		if err != nil {
-			log.Error("error get comments",
-				sl.Error(err),
-				slog.Int("limit", limit),
-				slog.Int("offset", offset))
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			log.Error("error get comments", sl.Error(err), slog.Int("limit", limit))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
+		comments, nextCursor, prevCursor := cursor.Paginate(cursors, cur, limit, comments, commentKey)
+
+		commentIDs := make([]int64, 0, len(comments))
+		for _, comment := range comments {
+			commentIDs = append(commentIDs, comment.ID)
+		}
+		summaries, err := reactions.ReactionSummaries(models.TargetComment, commentIDs)
+		if err != nil {
+			log.Error("error getting reaction counts", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
 		// TODO: пж почини этот говнокод, n+1 запросы к бд это 90iq
+		lastModified := models.LastReactionAt(summaries)
 		responseData := make([]commentInfo, 0, len(comments))
 		for _, comment := range comments {
+			if comment.UpdatedAt.After(lastModified) {
+				lastModified = comment.UpdatedAt
+			}
 			user, err := userGetter.GetUserByID(comment.AuthorID)
 			if err != nil {
 				if errors.Is(err, repository.ErrNotExists) {
@@ -107,9 +112,13 @@ func GetList(log logger.Logger, commentGetter commentGetter, userGetter userGett
 				CommentID: comment.ID,
 				Content:   comment.Content,
 				PostID:    comment.PostID,
+				ParentID:  comment.ParentID,
+				Depth:     comment.Depth,
 				AuthorID:  comment.AuthorID,
 				Username:  user.Username,
+				Version:   comment.Version,
 				CreatedAt: &comment.CreatedAt,
+				Reactions: summaries[comment.ID].CountMap(),
 			})
 		}
 
@@ -117,12 +126,19 @@ func GetList(log logger.Logger, commentGetter commentGetter, userGetter userGett
 			BaseResponse: response.BaseResponse{
 				Status: http.StatusOK,
 			},
-			Data: responseData,
+			Data:       responseData,
+			NextCursor: nextCursor,
+			PrevCursor: prevCursor,
 		}
-		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+		validators := httpcache.Validators{LastModified: lastModified}
+		if err := httpcache.WriteJSON(w, r, http.StatusOK, resp, validators); err != nil {
 			log.Error("failed to write JSON response", sl.Error(err))
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		}
 
 	}
 }
+
+func commentKey(comment *models.Comment) (time.Time, int64) {
+	return comment.CreatedAt, comment.ID
+}
@@ -0,0 +1,106 @@
+package comment
+
+import (
+	"net/http"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/etag"
+	"blog/internal/api/openapi"
+)
+
+// Operations documents the comment routes.
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Pattern:     "POST /api/comment",
+			ID:          "createComment",
+			Summary:     "Comment on a post or reply to a comment",
+			Description: "Depending on the comment mode of the post and the spam score the comment is published right away or held for moderation, see moderation_status.",
+			Auth:        openapi.Required,
+			Body:        CreateRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusCreated, "comment created", CreateResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid content, unknown post or parent, or reply depth limit reached"),
+				openapi.Error(http.StatusForbidden, "comments of the post are locked"),
+				openapi.Error(http.StatusUnprocessableEntity, "the post was deleted while commenting"),
+			},
+		},
+		{
+			Pattern: "GET /api/comment/{id}",
+			Summary: "Read a comment",
+			Auth:    openapi.Optional,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "the comment", readResponse{}),
+				openapi.Empty(http.StatusNotModified, "If-None-Match matched"),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				openapi.Error(http.StatusNotFound, "no such comment"),
+			},
+		},
+		{
+			Pattern: "PATCH /api/comment/{id}",
+			ID:      "updateComment",
+			Summary: "Edit a comment",
+			Auth:    openapi.Required,
+			Params: []openapi.Param{
+				openapi.Header(etag.HeaderIfMatch, openapi.String(), "ETag of the version the edit is based on"),
+			},
+			Body: updateRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusAccepted, "comment updated", updateResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id or content"),
+				openapi.Error(http.StatusForbidden, "not the author"),
+				openapi.Error(http.StatusNotFound, "no such comment"),
+				openapi.Error(http.StatusPreconditionFailed, "the comment was changed since the If-Match version"),
+			},
+		},
+		{
+			Pattern: "DELETE /api/comment/{id}",
+			ID:      "deleteComment",
+			Summary: "Move a comment to the trash",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusAccepted, "comment trashed", deleteResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				openapi.Error(http.StatusForbidden, "not the author"),
+				openapi.Error(http.StatusNotFound, "no such comment"),
+			},
+		},
+		{
+			Pattern: "POST /api/comment/{id}/restore",
+			Summary: "Restore a comment from the trash",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "comment restored", restoreResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				openapi.Error(http.StatusNotFound, "no comment of the caller in the trash"),
+			},
+		},
+		{
+			Pattern: "GET /api/post/{id}/comments",
+			ID:      "getPostComments",
+			Summary: "Read the comment thread of a post",
+			Auth:    openapi.Optional,
+			Params: []openapi.Param{
+				openapi.Query("view", openapi.Enum(viewTree, viewFlat), "nested replies or a flat list, tree by default"),
+				openapi.Query("sort", openapi.Enum(sortOldest, sortTop), "oldest first or most reactions first, oldest by default"),
+			},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "the thread and the webmentions of the post", threadResponse{}),
+				openapi.Empty(http.StatusNotModified, "If-None-Match matched"),
+				openapi.Error(http.StatusBadRequest, "invalid id, view or sort"),
+			},
+		},
+		{
+			Pattern: "GET /api/comments",
+			ID:      "listComments",
+			Summary: "List the newest comments",
+			Params: append(cursor.Params(10),
+				openapi.Query("post_id", openapi.Integer(), "only the comments of this post")),
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "a page of comments", commenttListResponse{}),
+				openapi.Empty(http.StatusNotModified, "If-None-Match matched"),
+				openapi.Error(http.StatusBadRequest, "invalid limit, cursor or post_id"),
+			},
+		},
+	}
+}
@@ -0,0 +1,151 @@
+package comment
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"blog/internal/api/etag"
+	"blog/internal/api/httpcache"
+	"blog/internal/api/response"
+	"blog/internal/mention"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type readResponse struct {
+	response.BaseResponse
+	CommentID int64     `json:"comment_id"`
+	Content   string    `json:"content"`
+	PostID    int64     `json:"post_id"`
+	AuthorID  int64     `json:"author_id"`
+	Username  string    `json:"username"`
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ContentHTML string            `json:"content_html"`
+	Mentions    []*models.Mention `json:"mentions"`
+	Reactions   map[string]int64  `json:"reactions"`
+}
+
+type commentReader interface {
+	GetCommentByID(id int64) (*models.Comment, error)
+}
+
+type shadowBanChecker interface {
+	ShadowBannedUsers(ids []int64) (map[int64]bool, error)
+}
+
+type mentionLister interface {
+	Mentions(targetType string, ids []int64) (map[int64][]*models.Mention, error)
+}
+
+type reactionCounter interface {
+	ReactionSummaries(targetType string, ids []int64) (map[int64]*models.ReactionSummary, error)
+}
+
+func Read(log logger.Logger, commentReader commentReader, userGetter userGetter, shadowBans shadowBanChecker, mentions mentionLister, reactions reactionCounter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.comment.Read"))
+
+		commentID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		comment, err := commentReader.GetCommentByID(commentID)
+		if err == nil && comment.Status != models.CommentStatusApproved {
+			err = repository.ErrNotExists
+		}
+		if err != nil {
+			util.RepositoryError(w, r, &log, err, "Comment Not Found")
+			return
+		}
+
+		viewerID, _ := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if comment.AuthorID != viewerID {
+			banned, err := shadowBans.ShadowBannedUsers([]int64{comment.AuthorID})
+			if err != nil {
+				log.Error("error checking shadow bans", sl.Error(err), slog.Int64("comment_id", commentID))
+				util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+				return
+			}
+			if banned[comment.AuthorID] {
+				util.ErrorResponse(w, r, http.StatusNotFound, "Comment Not Found")
+				return
+			}
+		}
+
+		username := ""
+		user, err := userGetter.GetUserByID(comment.AuthorID)
+		if err != nil {
+			if !errors.Is(err, repository.ErrNotExists) {
+				log.Error("error getting author",
+					slog.Int64("user_id", comment.AuthorID),
+					sl.Error(err))
+			}
+		} else {
+			username = user.Username
+		}
+
+		mentionsByComment, err := mentions.Mentions(models.TargetComment, []int64{comment.ID})
+		if err != nil {
+			log.Error("error getting mentions", sl.Error(err), slog.Int64("comment_id", commentID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		commentMentions := mentionsByComment[comment.ID]
+		if commentMentions == nil {
+			commentMentions = []*models.Mention{}
+		}
+
+		summaries, err := reactions.ReactionSummaries(models.TargetComment, []int64{comment.ID})
+		if err != nil {
+			log.Error("error getting reaction counts", sl.Error(err), slog.Int64("comment_id", commentID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		summary := summaries[comment.ID]
+
+		resp := readResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			CommentID:   comment.ID,
+			Content:     comment.Content,
+			PostID:      comment.PostID,
+			AuthorID:    comment.AuthorID,
+			Username:    username,
+			Version:     comment.Version,
+			CreatedAt:   comment.CreatedAt,
+			UpdatedAt:   comment.UpdatedAt,
+			ContentHTML: mention.HTML(comment.Content, commentMentions),
+			Mentions:    commentMentions,
+			Reactions:   summary.CountMap(),
+		}
+
+		validators := httpcache.Validators{
+			ETag:         etag.Version(comment.Version),
+			LastModified: comment.UpdatedAt,
+		}
+		if summary != nil {
+			validators.ETag = etag.VersionWith(comment.Version, summary.Revision)
+			if summary.UpdatedAt.After(validators.LastModified) {
+				validators.LastModified = summary.UpdatedAt
+			}
+		}
+		if err := httpcache.WriteJSON(w, r, http.StatusOK, resp, validators); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package comment
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/middlewares/auth"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type restoreResponse struct {
+	response.BaseResponse
+	CommentID int64 `json:"comment_id,omitempty"`
+}
+
+type commentRestorer interface {
+	RestoreComment(id, authorID int64) error
+}
+
+// Restore takes one of the caller's comments out of the trash.
+func Restore(log logger.Logger, commentRestorer commentRestorer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.comment.Restore"))
+
+		authorID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			log.Error("author id not found in context or invalid type", slog.String("user_id_ctx_key", auth.UserIDCtxKey))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		commentID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value for comment id", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		if err := commentRestorer.RestoreComment(commentID, authorID); err != nil {
+			util.RepositoryError(w, r, &log, err, "Comment Not Found In Trash")
+			return
+		}
+
+		resp := restoreResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			CommentID: commentID,
+		}
+
+		log.Info("comment restored", slog.Int64("comment_id", commentID))
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package comment
+
+import (
+	"cmp"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"blog/internal/api/httpcache"
+	"blog/internal/api/response"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+const (
+	viewTree = "tree"
+	viewFlat = "flat"
+
+	sortOldest = "oldest"
+	sortTop    = "top"
+
+	deletedPlaceholder = "[deleted]"
+	removedPlaceholder = "[removed]"
+)
+
+type threadNode struct {
+	CommentID  int64             `json:"comment_id"`
+	ParentID   *int64            `json:"parent_id,omitempty"`
+	Content    string            `json:"content"`
+	Mentions   []*models.Mention `json:"mentions,omitempty"`
+	AuthorID   int64             `json:"author_id,omitempty"`
+	Username   string            `json:"username,omitempty"`
+	Depth      int               `json:"depth"`
+	ReplyCount int               `json:"reply_count"`
+	Deleted    bool              `json:"deleted,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	Reactions  map[string]int64  `json:"reactions"`
+	Replies    []*threadNode     `json:"replies,omitempty"`
+
+	reactionTotal int64
+}
+
+type threadResponse struct {
+	response.BaseResponse
+	PostID int64         `json:"post_id"`
+	View   string        `json:"view"`
+	Sort   string        `json:"sort"`
+	Data   []*threadNode `json:"data"`
+
+	Webmentions []*models.Webmention `json:"webmentions"`
+}
+
+type threadGetter interface {
+	ListThread(postID int64) ([]*models.Comment, error)
+}
+
+type webmentionLister interface {
+	ListWebmentions(postID int64) ([]*models.Webmention, error)
+}
+
+type usersGetter interface {
+	GetUsersByIDs(ids []int64) (map[int64]*models.User, error)
+}
+
+// Thread returns the comments of a post as a nested tree (?view=tree, default)
+// or as a depth-first flattened list (?view=flat). Siblings are ordered oldest
+// first, or by total reactions with ?sort=top. Deleted or moderated comments
+// that still have replies are kept as "[deleted]" / "[removed]" placeholders.
+// Comments of shadow-banned users are treated as removed for everyone but them.
+// Verified webmentions of the post are listed next to the comments.
+func Thread(log logger.Logger, threadGetter threadGetter, usersGetter usersGetter, shadowBans shadowBanChecker, mentions mentionLister,
+	reactions reactionCounter, webmentions webmentionLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.comment.Thread"))
+
+		postID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		view := r.URL.Query().Get("view")
+		if view == "" {
+			view = viewTree
+		}
+		if view != viewTree && view != viewFlat {
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Invalid view parameter")
+			return
+		}
+
+		order := r.URL.Query().Get("sort")
+		if order == "" {
+			order = sortOldest
+		}
+		if order != sortOldest && order != sortTop {
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Invalid sort parameter")
+			return
+		}
+
+		comments, err := threadGetter.ListThread(postID)
+		if err != nil {
+			log.Error("error listing thread", sl.Error(err), slog.Int64("post_id", postID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		authorIDs := make([]int64, 0, len(comments))
+		commentIDs := make([]int64, 0, len(comments))
+		for _, c := range comments {
+			authorIDs = append(authorIDs, c.AuthorID)
+			commentIDs = append(commentIDs, c.ID)
+		}
+
+		summaries, err := reactions.ReactionSummaries(models.TargetComment, commentIDs)
+		if err != nil {
+			log.Error("error getting reaction counts", sl.Error(err), slog.Int64("post_id", postID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		mentionsByComment, err := mentions.Mentions(models.TargetComment, commentIDs)
+		if err != nil {
+			log.Error("error getting mentions", sl.Error(err), slog.Int64("post_id", postID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		received, err := webmentions.ListWebmentions(postID)
+		if err != nil {
+			log.Error("error listing webmentions", sl.Error(err), slog.Int64("post_id", postID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if received == nil {
+			received = []*models.Webmention{}
+		}
+
+		lastModified := models.LastReactionAt(summaries)
+		for _, m := range received {
+			if m.UpdatedAt.After(lastModified) {
+				lastModified = m.UpdatedAt
+			}
+		}
+		for _, c := range comments {
+			if c.UpdatedAt.After(lastModified) {
+				lastModified = c.UpdatedAt
+			}
+			if c.DeletedAt != nil && c.DeletedAt.After(lastModified) {
+				lastModified = *c.DeletedAt
+			}
+		}
+		users, err := usersGetter.GetUsersByIDs(authorIDs)
+		if err != nil {
+			log.Error("error getting comment authors", sl.Error(err), slog.Int64("post_id", postID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		banned, err := shadowBans.ShadowBannedUsers(authorIDs)
+		if err != nil {
+			log.Error("error checking shadow bans", sl.Error(err), slog.Int64("post_id", postID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		viewerID, _ := r.Context().Value(auth.UserIDCtxKey).(int64)
+		delete(banned, viewerID)
+
+		roots := buildThread(comments, users, banned, mentionsByComment, summaries)
+		if order == sortTop {
+			sortByReactions(roots)
+		}
+		if view == viewFlat {
+			roots = flattenThread(roots, make([]*threadNode, 0, len(comments)))
+		}
+		if roots == nil {
+			roots = []*threadNode{}
+		}
+
+		resp := threadResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			PostID: postID,
+			View:   view,
+			Sort:   order,
+			Data:   roots,
+
+			Webmentions: received,
+		}
+		validators := httpcache.Validators{LastModified: lastModified}
+		if err := httpcache.WriteJSON(w, r, http.StatusOK, resp, validators); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// buildThread links comments (in creation order) into trees and prunes deleted
+// comments that have no remaining replies. Comments of hidden authors count as removed.
+func buildThread(comments []*models.Comment, users map[int64]*models.User, hiddenAuthors map[int64]bool,
+	mentions map[int64][]*models.Mention, summaries map[int64]*models.ReactionSummary) []*threadNode {
+	nodes := make(map[int64]*threadNode, len(comments))
+	var roots []*threadNode
+
+	for _, c := range comments {
+		node := &threadNode{
+			CommentID: c.ID,
+			ParentID:  c.ParentID,
+			Content:   c.Content,
+			Mentions:  mentions[c.ID],
+			AuthorID:  c.AuthorID,
+			Depth:     c.Depth,
+			CreatedAt: c.CreatedAt,
+			Reactions: summaries[c.ID].CountMap(),
+		}
+		if s := summaries[c.ID]; s != nil {
+			node.reactionTotal = s.Total
+		}
+		if user, ok := users[c.AuthorID]; ok {
+			node.Username = user.Username
+		}
+		if c.DeletedAt != nil || c.Status != models.CommentStatusApproved || hiddenAuthors[c.AuthorID] {
+			node.Deleted = true
+			node.Content = deletedPlaceholder
+			if c.DeletedAt == nil {
+				node.Content = removedPlaceholder
+			}
+			node.AuthorID = 0
+			node.Username = ""
+			node.Mentions = nil
+			node.Reactions = map[string]int64{}
+			node.reactionTotal = 0
+		}
+		nodes[c.ID] = node
+
+		var parent *threadNode
+		if c.ParentID != nil {
+			parent = nodes[*c.ParentID]
+		}
+		if parent != nil {
+			parent.Replies = append(parent.Replies, node)
+		} else {
+			node.ParentID = nil
+			roots = append(roots, node)
+		}
+	}
+
+	return prune(roots)
+}
+
+func prune(nodes []*threadNode) []*threadNode {
+	kept := nodes[:0]
+	for _, n := range nodes {
+		n.Replies = prune(n.Replies)
+		n.ReplyCount = len(n.Replies)
+		if n.Deleted && n.ReplyCount == 0 {
+			continue
+		}
+		kept = append(kept, n)
+	}
+	return kept
+}
+
+// sortByReactions orders siblings by total reactions, keeping creation order on ties.
+func sortByReactions(nodes []*threadNode) {
+	slices.SortStableFunc(nodes, func(a, b *threadNode) int {
+		return cmp.Compare(b.reactionTotal, a.reactionTotal)
+	})
+	for _, n := range nodes {
+		sortByReactions(n.Replies)
+	}
+}
+
+func flattenThread(nodes []*threadNode, out []*threadNode) []*threadNode {
+	for _, n := range nodes {
+		replies := n.Replies
+		n.Replies = nil
+		out = append(out, n)
+		out = flattenThread(replies, out)
+	}
+	return out
+}
@@ -2,21 +2,19 @@ package comment
 
 import (
 	"encoding/json"
-	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
 
+	"blog/internal/api/etag"
 	"blog/internal/api/jsonutil"
 	"blog/internal/api/response"
+	"blog/internal/events"
 	"blog/internal/middlewares/auth"
 	"blog/internal/models"
-	"blog/internal/repository"
 	"blog/internal/util"
 	"blog/internal/util/logger"
 	"blog/internal/util/logger/sl"
-
-	"github.com/go-playground/validator/v10"
 )
 
 type updateRequest struct {
@@ -29,15 +27,16 @@ type updateResponse struct {
 	CommentID int64 `json:"comment_id,omitempty"`
 	AuthorID  int64 `json:"author_id"`
 	PostID    int64 `json:"post_id"`
+	Version   int64 `json:"version,omitempty"`
 }
 
 type commentUpdater interface {
 	UpdateComment(comment *models.Comment) error
-	GetCommentAuthorID(commentID int64) (int64, error)
+	GetCommentByID(id int64) (*models.Comment, error)
 }
 
-func Update(log logger.Logger, commentUpdater commentUpdater) http.HandlerFunc {
-	validate := validator.New()
+func Update(log logger.Logger, commentUpdater commentUpdater, mentions mentionSyncer, publisher eventPublisher) http.HandlerFunc {
+	validate := util.NewCustomValidator()
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.url.comment.Update"
 
@@ -45,41 +44,36 @@ func Update(log logger.Logger, commentUpdater commentUpdater) http.HandlerFunc {
 
 		authorID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
 		if !ok {
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
 
 		commentID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 		if err != nil {
 			log.Info("invalid path value", sl.Error(err))
-			util.ErrorResponse(w, http.StatusBadRequest, "Bad Request")
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
 			return
 		}
 
 		var req updateRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			log.Info("error decoding request", sl.Error(err))
-			util.ErrorResponse(w, http.StatusBadRequest, "EOF")
+			util.ValidationError(w, r, err)
 			return
 		}
 
 		if err := validate.Struct(req); err != nil {
-			validationErrors := err.(validator.ValidationErrors)
-			log.Info("error validation request", sl.Error(validationErrors))
-			util.ErrorResponse(w, http.StatusBadRequest, "Invalid Json Format")
+			log.Info("error validation request", sl.Error(err))
+			util.ValidationError(w, r, err)
 			return
 		}
 
-		commentAuthorID, err := commentUpdater.GetCommentAuthorID(commentID)
+		comment, err := commentUpdater.GetCommentByID(commentID)
 		if err != nil {
-			if errors.Is(err, repository.ErrNotExists) {
-				log.Info("comment author not found", sl.Error(err), slog.Int64("comment_id", commentID))
-				util.ErrorResponse(w, http.StatusNotFound, "Not Found")
-			}
-			log.Error("error get author id", sl.Error(err))
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			util.RepositoryError(w, r, &log, err, "Not Found")
 			return
 		}
+		commentAuthorID := comment.AuthorID
 
 		if commentAuthorID != authorID {
 			log.Info("forbidden: user is not the author",
@@ -87,7 +81,16 @@ func Update(log logger.Logger, commentUpdater commentUpdater) http.HandlerFunc {
 				slog.Int64("comment_author_id", commentAuthorID),
 				slog.Int64("author_id", authorID))
 
-			util.ErrorResponse(w, http.StatusForbidden, "Forbidden")
+			util.ErrorResponse(w, r, http.StatusForbidden, "Forbidden")
+			return
+		}
+
+		if !etag.MatchVersion(r.Header.Get(etag.HeaderIfMatch), comment.Version) {
+			log.Info("if-match precondition failed",
+				slog.Int64("comment_id", commentID),
+				slog.Int64("version", comment.Version),
+				slog.String("if_match", r.Header.Get(etag.HeaderIfMatch)))
+			util.ErrorResponse(w, r, http.StatusPreconditionFailed, "Precondition Failed")
 			return
 		}
 
@@ -96,29 +99,23 @@ func Update(log logger.Logger, commentUpdater commentUpdater) http.HandlerFunc {
 			Content:  req.Content,
 			PostID:   req.PostID,
 			AuthorID: commentAuthorID,
+			Version:  comment.Version,
 		}
 
-		err = commentUpdater.UpdateComment(newComment)
-		if err != nil {
-			if errors.Is(err, repository.ErrNotExists) {
-				log.Info("post is not exists", sl.Error(err),
-					slog.Int64("post_id", req.PostID),
-					slog.Int64("author_id", authorID),
-					slog.Int64("comment_author_id", commentAuthorID))
-
-				util.ErrorResponse(w, http.StatusNotFound, "Not Found")
-				return
-			}
-
-			log.Error("error update post", sl.Error(err),
-				slog.Int64("post_id", req.PostID),
-				slog.Int64("author_id", authorID),
-				slog.Int64("comment_author_id", commentAuthorID))
-
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+		if err := commentUpdater.UpdateComment(newComment); err != nil {
+			util.RepositoryError(w, r, &log, err, "the comment was deleted or changed in the meantime")
 			return
 		}
 
+		if err := mentions.Sync(models.TargetComment, commentID, newComment.Content); err != nil {
+			log.Error("failed to link mentions", sl.Error(err), slog.Int64("comment_id", commentID))
+		}
+		if comment.Status == models.CommentStatusApproved {
+			comment.Content = newComment.Content
+			comment.Version = newComment.Version
+			publisher.Publish(r.Context(), events.CommentUpdated{Comment: comment})
+		}
+
 		resp := updateResponse{
 			BaseResponse: response.BaseResponse{
 				Status: http.StatusAccepted,
@@ -126,8 +123,10 @@ func Update(log logger.Logger, commentUpdater commentUpdater) http.HandlerFunc {
 			CommentID: commentID,
 			AuthorID:  authorID,
 			PostID:    req.PostID,
+			Version:   newComment.Version,
 		}
 
+		w.Header().Set(etag.HeaderETag, etag.Version(newComment.Version))
 		log.Info("comment updated",
 			slog.Int64("post_id", req.PostID),
 			slog.Int64("comment_id", commentID))
@@ -0,0 +1,125 @@
+package feed
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type postInfo struct {
+	PostID    int64            `json:"post_id"`
+	Title     string           `json:"title"`
+	Content   string           `json:"content"`
+	Tags      []string         `json:"tags"`
+	AuthorID  int64            `json:"author_id"`
+	Username  string           `json:"username"`
+	Version   int64            `json:"version"`
+	CreatedAt time.Time        `json:"created_at"`
+	Reactions map[string]int64 `json:"reactions"`
+}
+
+type homeResponse struct {
+	response.BaseResponse
+	Data       []postInfo `json:"data"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+type pager interface {
+	Page(ctx context.Context, userID, beforeID int64, limit int) ([]*models.Post, int64, error)
+}
+
+type usersGetter interface {
+	GetUsersByIDs(ids []int64) (map[int64]*models.User, error)
+}
+
+type reactionCounter interface {
+	ReactionSummaries(targetType string, ids []int64) (map[int64]*models.ReactionSummary, error)
+}
+
+// Home returns the posts of the authors the caller follows, newest first.
+func Home(log logger.Logger, pager pager, usersGetter usersGetter, reactions reactionCounter, cursors *cursor.Codec) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.feed.Home"))
+
+		userID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		limit, beforeID, err := cursors.QueryID(r.URL.Query(), cursor.DefaultLimit)
+		if err != nil {
+			log.Info("invalid page query", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		posts, next, err := pager.Page(r.Context(), userID, beforeID, limit)
+		if err != nil {
+			log.Error("error building feed", sl.Error(err), slog.Int64("user_id", userID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		postIDs := make([]int64, 0, len(posts))
+		authorIDs := make([]int64, 0, len(posts))
+		for _, post := range posts {
+			postIDs = append(postIDs, post.ID)
+			authorIDs = append(authorIDs, post.AuthorID)
+		}
+		users, err := usersGetter.GetUsersByIDs(authorIDs)
+		if err != nil {
+			log.Error("error getting post authors", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		summaries, err := reactions.ReactionSummaries(models.TargetPost, postIDs)
+		if err != nil {
+			log.Error("error getting reaction counts", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		data := make([]postInfo, 0, len(posts))
+		for _, post := range posts {
+			info := postInfo{
+				PostID:    post.ID,
+				Title:     post.Title,
+				Content:   post.Content,
+				Tags:      append([]string{}, post.Tags...),
+				AuthorID:  post.AuthorID,
+				Version:   post.Version,
+				CreatedAt: post.CreatedAt,
+				Reactions: summaries[post.ID].CountMap(),
+			}
+			if user, ok := users[post.AuthorID]; ok {
+				info.Username = user.Username
+			}
+			data = append(data, info)
+		}
+
+		resp := homeResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			Data: data,
+		}
+		if next > 0 {
+			resp.NextCursor = cursors.EncodeID(next)
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
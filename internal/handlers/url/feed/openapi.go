@@ -0,0 +1,25 @@
+package feed
+
+import (
+	"net/http"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/openapi"
+)
+
+// Operations documents the home feed route.
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Pattern: "GET /api/feed",
+			ID:      "getHomeFeed",
+			Summary: "Posts of the users the caller follows, newest first",
+			Auth:    openapi.Required,
+			Params:  cursor.Params(cursor.DefaultLimit),
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "a page of the feed", homeResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid limit or cursor"),
+			},
+		},
+	}
+}
@@ -0,0 +1,241 @@
+package follow
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/events"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type followResponse struct {
+	response.BaseResponse
+	UserID    int64 `json:"user_id"`
+	Following bool  `json:"following"`
+	Followers int64 `json:"followers"`
+}
+
+type followInfo struct {
+	UserID     int64     `json:"user_id"`
+	Username   string    `json:"username"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type followListResponse struct {
+	response.BaseResponse
+	UserID int64        `json:"user_id"`
+	Total  int64        `json:"total"`
+	Data   []followInfo `json:"data"`
+
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+type followStore interface {
+	Follow(followerID, followeeID int64) (bool, error)
+	Unfollow(followerID, followeeID int64) (bool, error)
+	CountFollows(userID int64) (followers, following int64, err error)
+}
+
+type followLister interface {
+	ListFollowers(userID int64, page repository.Page) ([]*models.Follow, error)
+	ListFollowing(userID int64, page repository.Page) ([]*models.Follow, error)
+	CountFollows(userID int64) (followers, following int64, err error)
+}
+
+type usersGetter interface {
+	GetUsersByIDs(ids []int64) (map[int64]*models.User, error)
+}
+
+type eventPublisher interface {
+	Publish(ctx context.Context, e events.Event)
+}
+
+// Follow subscribes the caller to a user's posts. Following twice is a no-op.
+func Follow(log logger.Logger, follows followStore, publisher eventPublisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.follow.Follow"))
+		change(w, r, &log, follows, publisher, true)
+	}
+}
+
+// Unfollow removes a subscription. Unfollowing a user one doesn't follow is a no-op.
+func Unfollow(log logger.Logger, follows followStore, publisher eventPublisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.follow.Unfollow"))
+		change(w, r, &log, follows, publisher, false)
+	}
+}
+
+func change(w http.ResponseWriter, r *http.Request, log logger.Logger, follows followStore, publisher eventPublisher, follow bool) {
+	followerID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+	if !ok {
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	followeeID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Info("invalid path value", sl.Error(err))
+		util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+		return
+	}
+	if followeeID == followerID {
+		util.ErrorResponse(w, r, http.StatusBadRequest, "you can't follow yourself")
+		return
+	}
+
+	var changed bool
+	if follow {
+		changed, err = follows.Follow(followerID, followeeID)
+	} else {
+		changed, err = follows.Unfollow(followerID, followeeID)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrForeignKeyFailed) {
+			util.ErrorResponse(w, r, http.StatusNotFound, "User Not Found")
+			return
+		}
+		log.Error("error changing follow", sl.Error(err), slog.Int64("followee_id", followeeID))
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if changed && follow {
+		publisher.Publish(r.Context(), events.UserFollowed{FollowerID: followerID, FolloweeID: followeeID})
+	} else if changed {
+		publisher.Publish(r.Context(), events.UserUnfollowed{FollowerID: followerID, FolloweeID: followeeID})
+	}
+
+	followers, _, err := follows.CountFollows(followeeID)
+	if err != nil {
+		log.Error("error counting followers", sl.Error(err), slog.Int64("followee_id", followeeID))
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	resp := followResponse{
+		BaseResponse: response.BaseResponse{
+			Status: http.StatusOK,
+		},
+		UserID:    followeeID,
+		Following: follow,
+		Followers: followers,
+	}
+	if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+		log.Error("json writer error", sl.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// Followers lists who follows a user, the latest first.
+func Followers(log logger.Logger, follows followLister, usersGetter usersGetter, cursors *cursor.Codec) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.follow.Followers"))
+		list(w, r, &log, follows, usersGetter, cursors, true)
+	}
+}
+
+// Following lists whom a user follows, the latest first.
+func Following(log logger.Logger, follows followLister, usersGetter usersGetter, cursors *cursor.Codec) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.follow.Following"))
+		list(w, r, &log, follows, usersGetter, cursors, false)
+	}
+}
+
+func list(w http.ResponseWriter, r *http.Request, log logger.Logger, follows followLister, usersGetter usersGetter,
+	cursors *cursor.Codec, followers bool) {
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Info("invalid path value", sl.Error(err))
+		util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	limit, cur, err := cursors.Query(r.URL.Query(), cursor.DefaultLimit)
+	if err != nil {
+		log.Info("invalid page query", sl.Error(err))
+		util.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// the other side of each follow is the user to show, and its id the key
+	other := func(f *models.Follow) int64 {
+		if followers {
+			return f.FollowerID
+		}
+		return f.FolloweeID
+	}
+	var page []*models.Follow
+	if followers {
+		page, err = follows.ListFollowers(userID, cursor.Page(cur, limit))
+	} else {
+		page, err = follows.ListFollowing(userID, cursor.Page(cur, limit))
+	}
+	if err != nil {
+		log.Error("error listing follows", sl.Error(err), slog.Int64("user_id", userID))
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	page, nextCursor, prevCursor := cursor.Paginate(cursors, cur, limit, page, func(f *models.Follow) (time.Time, int64) {
+		return f.CreatedAt, other(f)
+	})
+
+	followerCount, followingCount, err := follows.CountFollows(userID)
+	if err != nil {
+		log.Error("error counting follows", sl.Error(err), slog.Int64("user_id", userID))
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	ids := make([]int64, 0, len(page))
+	for _, f := range page {
+		ids = append(ids, other(f))
+	}
+	users, err := usersGetter.GetUsersByIDs(ids)
+	if err != nil {
+		log.Error("error getting users", sl.Error(err))
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	data := make([]followInfo, 0, len(page))
+	for i, f := range page {
+		info := followInfo{UserID: ids[i], FollowedAt: f.CreatedAt}
+		if user, ok := users[ids[i]]; ok {
+			info.Username = user.Username
+		}
+		data = append(data, info)
+	}
+
+	resp := followListResponse{
+		BaseResponse: response.BaseResponse{
+			Status: http.StatusOK,
+		},
+		UserID: userID,
+		Total:  followingCount,
+		Data:   data,
+
+		NextCursor: nextCursor,
+		PrevCursor: prevCursor,
+	}
+	if followers {
+		resp.Total = followerCount
+	}
+	if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+		log.Error("failed to write JSON response", sl.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,56 @@
+package follow
+
+import (
+	"net/http"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/openapi"
+)
+
+// Operations documents the follow routes.
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Pattern: "PUT /api/user/{id}/follow",
+			ID:      "followUser",
+			Summary: "Follow a user",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "following, changed is false if already followed", followResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id or the caller's own id"),
+				openapi.Error(http.StatusNotFound, "no such user"),
+			},
+		},
+		{
+			Pattern: "DELETE /api/user/{id}/follow",
+			ID:      "unfollowUser",
+			Summary: "Stop following a user",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "not following, changed is false if not followed before", followResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id or the caller's own id"),
+				openapi.Error(http.StatusNotFound, "no such user"),
+			},
+		},
+		{
+			Pattern: "GET /api/user/{id}/followers",
+			ID:      "listFollowers",
+			Summary: "List the followers of a user",
+			Params:  cursor.Params(cursor.DefaultLimit),
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "a page of followers", followListResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id, limit or cursor"),
+			},
+		},
+		{
+			Pattern: "GET /api/user/{id}/following",
+			ID:      "listFollowing",
+			Summary: "List the users a user follows",
+			Params:  cursor.Params(cursor.DefaultLimit),
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "a page of followed users", followListResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id, limit or cursor"),
+			},
+		},
+	}
+}
@@ -0,0 +1,82 @@
+package graph
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+
+	"blog/internal/api/jsonutil"
+	graphs "blog/internal/graph"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+const maxQueryBody = 1 << 20
+
+type executor interface {
+	Prepare(req graphs.Request) (*graphs.Operation, []gqlerrors.FormattedError)
+	Execute(r *http.Request, op *graphs.Operation) *graphql.Result
+}
+
+// errorsResponse answers requests that were rejected before they ran, it
+// has no data at all.
+type errorsResponse struct {
+	Errors []gqlerrors.FormattedError `json:"errors"`
+}
+
+// Query serves GraphQL requests, a JSON body on POST or the query,
+// operationName and variables parameters on GET. GET only runs queries,
+// mutations have to be posted. Requests that aren't GraphQL at all get a
+// problem, everything else 200 with data and errors.
+func Query(log logger.Logger, exec executor) http.HandlerFunc {
+	validate := util.NewCustomValidator()
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.graph.Query"))
+
+		var req graphs.Request
+		if r.Method == http.MethodGet {
+			q := r.URL.Query()
+			req.Query = q.Get("query")
+			req.OperationName = q.Get("operationName")
+			if vars := q.Get("variables"); vars != "" {
+				if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+					log.Info("invalid variables parameter", sl.Error(err))
+					util.ErrorResponse(w, r, http.StatusBadRequest, "variables must be a JSON object")
+					return
+				}
+			}
+		} else if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxQueryBody)).Decode(&req); err != nil {
+			log.Info("error decoding request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			log.Info("request validation failed", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		op, errs := exec.Prepare(req)
+		if errs != nil {
+			log.Info("query rejected", slog.String("error", errs[0].Message))
+			if err := jsonutil.WriteJSON(w, http.StatusOK, errorsResponse{Errors: errs}); err != nil {
+				log.Error("json writer error", sl.Error(err))
+			}
+			return
+		}
+		if op.Mutation() && r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			util.ErrorResponse(w, r, http.StatusMethodNotAllowed, "mutations have to be sent with POST")
+			return
+		}
+
+		result := exec.Execute(r, op)
+		if err := jsonutil.WriteJSON(w, http.StatusOK, result); err != nil {
+			log.Error("json writer error", sl.Error(err))
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package graph
+
+import (
+	"net/http"
+
+	"blog/internal/api/openapi"
+	graphs "blog/internal/graph"
+)
+
+// Operations documents the GraphQL endpoint. The schema itself is
+// described by introspection, not here.
+func Operations() []openapi.Operation {
+	result := &openapi.Schema{
+		Type:        "object",
+		Description: "data with the requested fields and errors, each with message, locations, path and extensions. Errors of mutations carry the status, type and invalid fields of the REST route in extensions.",
+	}
+	responses := []openapi.Response{
+		openapi.JSON(http.StatusOK, "the result, also when the query failed", result),
+		openapi.Error(http.StatusBadRequest, "no query, or a body or variables that aren't JSON"),
+	}
+
+	return []openapi.Operation{
+		{
+			Pattern:     "POST /graphql",
+			ID:          "graphql",
+			Summary:     "Run a GraphQL query or mutation",
+			Description: "Posts, comments and their authors in one request. Queries nested too deep or asking for too many items are rejected before they run.",
+			Auth:        openapi.Optional,
+			Body:        graphs.Request{},
+			Responses:   responses,
+		},
+		{
+			Pattern: "GET /graphql",
+			ID:      "graphqlQuery",
+			Summary: "Run a GraphQL query",
+			Auth:    openapi.Optional,
+			Params: []openapi.Param{
+				{Name: "query", In: "query", Required: true, Schema: openapi.String(), Description: "the GraphQL document"},
+				openapi.Query("operationName", openapi.String(), "operation to run when the document has several"),
+				openapi.Query("variables", openapi.String(), "JSON object of the variables"),
+			},
+			Responses: append(responses,
+				openapi.Error(http.StatusMethodNotAllowed, "the operation is a mutation")),
+		},
+	}
+}
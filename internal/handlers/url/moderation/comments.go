@@ -0,0 +1,237 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/events"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+// queueLimit is the page size of the moderation queues.
+const queueLimit = 50
+
+var actionStatus = map[string]string{
+	"approve": models.CommentStatusApproved,
+	"reject":  models.CommentStatusRejected,
+	"spam":    models.CommentStatusSpam,
+}
+
+type queueItem struct {
+	CommentID int64     `json:"comment_id"`
+	PostID    int64     `json:"post_id"`
+	ParentID  *int64    `json:"parent_id,omitempty"`
+	Content   string    `json:"content"`
+	AuthorID  int64     `json:"author_id"`
+	Username  string    `json:"username"`
+	Status    string    `json:"status"`
+	SpamScore float64   `json:"spam_score"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type queueResponse struct {
+	response.BaseResponse
+	Data []queueItem `json:"data"`
+
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+type actionRequest struct {
+	CommentIDs []int64 `json:"comment_ids" validate:"required,min=1,max=500"`
+	Action     string  `json:"action" validate:"required,oneof=approve reject spam"`
+}
+
+type actionResponse struct {
+	response.BaseResponse
+	Action  string  `json:"action"`
+	Updated []int64 `json:"updated"`
+}
+
+type queueGetter interface {
+	ListCommentsByStatus(status string, page repository.Page) ([]*models.Comment, error)
+}
+
+type usersGetter interface {
+	GetUsersByIDs(ids []int64) (map[int64]*models.User, error)
+}
+
+type statusSetter interface {
+	SetCommentStatus(ids []int64, status string) ([]int64, error)
+	GetCommentByID(id int64) (*models.Comment, error)
+}
+
+type eventPublisher interface {
+	Publish(ctx context.Context, e events.Event)
+}
+
+type spamTrainer interface {
+	Learn(comment *models.Comment, spam bool) error
+}
+
+// Queue lists comments in a moderation status (?status=pending by default), oldest first.
+func Queue(log logger.Logger, queueGetter queueGetter, usersGetter usersGetter, cursors *cursor.Codec) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.moderation.Queue"))
+
+		status := r.URL.Query().Get("status")
+		if status == "" {
+			status = models.CommentStatusPending
+		}
+		switch status {
+		case models.CommentStatusPending, models.CommentStatusApproved, models.CommentStatusRejected, models.CommentStatusSpam:
+		default:
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Invalid status parameter")
+			return
+		}
+
+		limit, cur, err := cursors.Query(r.URL.Query(), queueLimit)
+		if err != nil {
+			log.Info("invalid page query", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		comments, err := queueGetter.ListCommentsByStatus(status, cursor.Page(cur, limit))
+		if err != nil {
+			log.Error("error listing moderation queue", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		comments, nextCursor, prevCursor := cursor.Paginate(cursors, cur, limit, comments, func(c *models.Comment) (time.Time, int64) {
+			return c.CreatedAt, c.ID
+		})
+
+		authorIDs := make([]int64, 0, len(comments))
+		for _, c := range comments {
+			authorIDs = append(authorIDs, c.AuthorID)
+		}
+		users, err := usersGetter.GetUsersByIDs(authorIDs)
+		if err != nil {
+			log.Error("error getting comment authors", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		data := make([]queueItem, 0, len(comments))
+		for _, c := range comments {
+			item := queueItem{
+				CommentID: c.ID,
+				PostID:    c.PostID,
+				ParentID:  c.ParentID,
+				Content:   c.Content,
+				AuthorID:  c.AuthorID,
+				Status:    c.Status,
+				SpamScore: c.SpamScore,
+				CreatedAt: c.CreatedAt,
+			}
+			if user, ok := users[c.AuthorID]; ok {
+				item.Username = user.Username
+			}
+			data = append(data, item)
+		}
+
+		resp := queueResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			Data: data,
+
+			NextCursor: nextCursor,
+			PrevCursor: prevCursor,
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// Act applies a moderation action to a batch of comments. Approved and spam
+// decisions are fed to the spam classifier as ham and spam samples, approved
+// comments are published like comments that needed no review.
+func Act(log logger.Logger, statusSetter statusSetter, spamTrainer spamTrainer, auditor auditor, publisher eventPublisher) http.HandlerFunc {
+	validate := util.NewCustomValidator()
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.moderation.Act"))
+
+		moderatorID, _ := r.Context().Value(auth.UserIDCtxKey).(int64)
+
+		var req actionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Info("error decoding request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Info("error validation request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		updated, err := statusSetter.SetCommentStatus(req.CommentIDs, actionStatus[req.Action])
+		if err != nil {
+			log.Error("error applying moderation action", sl.Error(err), slog.String("action", req.Action))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if updated == nil {
+			updated = []int64{}
+		}
+
+		for _, id := range updated {
+			audit(&log, auditor, &models.AuditEntry{
+				ActorID:    &moderatorID,
+				Action:     models.AuditCommentModerate,
+				TargetType: models.TargetComment,
+				TargetID:   id,
+				Details:    req.Action,
+			})
+		}
+
+		if req.Action == "approve" || req.Action == "spam" {
+			for _, id := range updated {
+				c, err := statusSetter.GetCommentByID(id)
+				if err != nil {
+					log.Error("failed to get comment for spam training", sl.Error(err), slog.Int64("comment_id", id))
+					continue
+				}
+				if err := spamTrainer.Learn(c, req.Action == "spam"); err != nil {
+					log.Error("failed to train spam classifier", sl.Error(err), slog.Int64("comment_id", id))
+				}
+				if req.Action == "approve" {
+					publisher.Publish(r.Context(), events.CommentPublished{Comment: c})
+				}
+			}
+		}
+
+		log.Info("moderation action applied",
+			slog.Int64("moderator_id", moderatorID),
+			slog.String("action", req.Action),
+			slog.Int("count", len(updated)))
+
+		resp := actionResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			Action:  req.Action,
+			Updated: updated,
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
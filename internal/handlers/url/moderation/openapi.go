@@ -0,0 +1,84 @@
+package moderation
+
+import (
+	"net/http"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/openapi"
+	"blog/internal/models"
+)
+
+// Operations documents the moderation queues and the report route.
+func Operations() []openapi.Operation {
+	notModerator := openapi.Error(http.StatusForbidden, "not a moderator or admin")
+	return []openapi.Operation{
+		{
+			Pattern: "GET /api/moderation/comments",
+			ID:      "listModerationComments",
+			Summary: "List comments by moderation status",
+			Auth:    openapi.Required,
+			Params: append([]openapi.Param{
+				openapi.Query("status", openapi.Enum(models.CommentStatusPending, models.CommentStatusApproved,
+					models.CommentStatusRejected, models.CommentStatusSpam), "pending by default"),
+			}, cursor.Params(queueLimit)...),
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "the comments, oldest first", queueResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid status, limit or cursor"),
+				notModerator,
+			},
+		},
+		{
+			Pattern:     "POST /api/moderation/comments",
+			ID:          "moderateComments",
+			Summary:     "Approve, reject or mark comments as spam",
+			Description: "Comments approved or marked as spam train the spam classifier.",
+			Auth:        openapi.Required,
+			Body:        actionRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "number of comments changed", actionResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid action or comment ids"),
+				notModerator,
+			},
+		},
+		{
+			Pattern: "GET /api/moderation/reports",
+			ID:      "listReports",
+			Summary: "List reported content with open reports, first reported first",
+			Auth:    openapi.Required,
+			Params:  cursor.Params(queueLimit),
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "the reported content", reportQueueResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid limit or cursor"),
+				notModerator,
+			},
+		},
+		{
+			Pattern:     "POST /api/moderation/reports/resolve",
+			ID:          "resolveReports",
+			Summary:     "Resolve the open reports of a post or comment",
+			Description: "dismiss shows hidden content again, remove trashes it, warn and suspend sanction its author.",
+			Auth:        openapi.Required,
+			Body:        resolveRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "reports resolved", resolveResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid target or action"),
+				openapi.Error(http.StatusForbidden, "not a moderator or admin, or the author can't be sanctioned by the caller"),
+				openapi.Error(http.StatusNotFound, "no such content or no open reports"),
+			},
+		},
+		{
+			Pattern:     "POST /api/report",
+			ID:          "reportContent",
+			Summary:     "Report a post or comment",
+			Description: "Content reported by enough users is hidden until a moderator resolves the reports.",
+			Auth:        openapi.Required,
+			Body:        reportRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusCreated, "report filed", reportResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid target or reason, or the caller's own content"),
+				openapi.Error(http.StatusNotFound, "no such content"),
+				openapi.Error(http.StatusConflict, "already reported by the caller"),
+			},
+		},
+	}
+}
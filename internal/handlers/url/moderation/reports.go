@@ -0,0 +1,492 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/config"
+	"blog/internal/events"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/repository/redisrepo"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type reportRequest struct {
+	TargetType string `json:"target_type" validate:"required,oneof=post comment"`
+	TargetID   int64  `json:"target_id" validate:"required,gt=0"`
+	Reason     string `json:"reason" validate:"required,oneof=spam harassment hate violence sexual misinformation off_topic other"`
+	Details    string `json:"details" validate:"max=500"`
+}
+
+type reportResponse struct {
+	response.BaseResponse
+	ReportID int64 `json:"report_id"`
+}
+
+type reportQueueItem struct {
+	*models.ReportTarget
+	AuthorID int64 `json:"author_id,omitempty"`
+	Hidden   bool  `json:"hidden"`
+	Deleted  bool  `json:"deleted,omitempty"`
+}
+
+type reportQueueResponse struct {
+	response.BaseResponse
+	Data []reportQueueItem `json:"data"`
+
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+type resolveRequest struct {
+	TargetType   string `json:"target_type" validate:"required,oneof=post comment"`
+	TargetID     int64  `json:"target_id" validate:"required,gt=0"`
+	Action       string `json:"action" validate:"required,oneof=dismiss remove warn suspend"`
+	Note         string `json:"note" validate:"max=500"`
+	DurationDays int    `json:"duration_days" validate:"gte=0,lte=3650"` // suspend only, 0 means reports.suspend_days
+}
+
+type resolveResponse struct {
+	response.BaseResponse
+	TargetType string `json:"target_type"`
+	TargetID   int64  `json:"target_id"`
+	Action     string `json:"action"`
+	Resolved   int64  `json:"resolved_reports"`
+	SanctionID int64  `json:"sanction_id,omitempty"`
+}
+
+type reportStore interface {
+	CreateReport(report *models.Report) (int64, error)
+	CountOpenReporters(targetType string, targetID int64) (int, error)
+	ListOpenReportTargets(page repository.Page) ([]*models.ReportTarget, error)
+	ResolveReports(targetType string, targetID int64, status string, resolverID int64, resolution string) (int64, error)
+}
+
+type postStore interface {
+	GetPostByID(id int64) (*models.Post, error)
+	SetPostHidden(id int64, hidden bool) error
+}
+
+type auditor interface {
+	AddAuditEntry(entry *models.AuditEntry) error
+}
+
+type sanctioner interface {
+	CreateSanction(sanction *models.Sanction) (int64, error)
+}
+
+type userGetter interface {
+	GetUserByID(id int64) (*models.User, error)
+}
+
+// content looks up, hides and removes reported posts and comments. Hidden
+// posts are kept out of public reads, hidden comments go back to pending.
+type content struct {
+	posts     postStore
+	comments  statusSetter
+	publisher eventPublisher
+	rdb       *redisrepo.RedisRepo
+}
+
+// lookup returns the author of the target and whether it is publicly visible.
+func (c content) lookup(targetType string, id int64) (authorID int64, visible bool, err error) {
+	if targetType == models.TargetPost {
+		post, err := c.posts.GetPostByID(id)
+		if err != nil {
+			return 0, false, err
+		}
+		return post.AuthorID, post.HiddenAt == nil, nil
+	}
+
+	comment, err := c.comments.GetCommentByID(id)
+	if err != nil {
+		return 0, false, err
+	}
+	return comment.AuthorID, comment.Status == models.CommentStatusApproved, nil
+}
+
+func (c content) setPostHidden(ctx context.Context, id int64, hidden bool) error {
+	if err := c.posts.SetPostHidden(id, hidden); err != nil {
+		return err
+	}
+	c.publisher.Publish(ctx, events.PostVisibilityChanged{PostID: id})
+	return c.rdb.Delete(ctx, redisrepo.PostKey(id))
+}
+
+func (c content) hide(ctx context.Context, targetType string, id int64) error {
+	if targetType == models.TargetPost {
+		return c.setPostHidden(ctx, id, true)
+	}
+	_, err := c.comments.SetCommentStatus([]int64{id}, models.CommentStatusPending)
+	return err
+}
+
+// unhide reverts hide after the reports were dismissed.
+func (c content) unhide(ctx context.Context, targetType string, id int64) error {
+	if targetType == models.TargetPost {
+		return c.setPostHidden(ctx, id, false)
+	}
+	comment, err := c.comments.GetCommentByID(id)
+	if err != nil {
+		return err
+	}
+	if comment.Status != models.CommentStatusPending {
+		return nil
+	}
+	_, err = c.comments.SetCommentStatus([]int64{id}, models.CommentStatusApproved)
+	return err
+}
+
+func (c content) remove(ctx context.Context, targetType string, id int64) error {
+	if targetType == models.TargetPost {
+		return c.setPostHidden(ctx, id, true)
+	}
+	_, err := c.comments.SetCommentStatus([]int64{id}, models.CommentStatusRejected)
+	return err
+}
+
+// audit records an action and only logs when that fails, the action itself already happened.
+func audit(log logger.Logger, auditor auditor, entry *models.AuditEntry) {
+	if err := auditor.AddAuditEntry(entry); err != nil {
+		log.Error("failed to write audit entry", sl.Error(err), slog.String("action", entry.Action))
+	}
+}
+
+// Report flags a post or comment. Every user can have one open report per
+// target; once cfg.HideThreshold distinct users reported it, it is hidden
+// until a moderator resolves the reports.
+func Report(log logger.Logger, reports reportStore, posts postStore, comments statusSetter, auditor auditor, publisher eventPublisher, rdb *redisrepo.RedisRepo, cfg config.ReportsConfig) http.HandlerFunc {
+	validate := util.NewCustomValidator()
+	content := content{posts: posts, comments: comments, publisher: publisher, rdb: rdb}
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.moderation.Report"))
+
+		reporterID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		var req reportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Info("error decoding request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Info("error validation request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		authorID, visible, err := content.lookup(req.TargetType, req.TargetID)
+		if err != nil && !errors.Is(err, repository.ErrNotExists) {
+			log.Error("error getting reported content", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if err != nil || !visible {
+			util.ErrorResponse(w, r, http.StatusNotFound, "Not Found")
+			return
+		}
+		if authorID == reporterID {
+			util.ErrorResponse(w, r, http.StatusBadRequest, "cannot report your own content")
+			return
+		}
+
+		report := &models.Report{
+			ReporterID: reporterID,
+			TargetType: req.TargetType,
+			TargetID:   req.TargetID,
+			Reason:     req.Reason,
+			Details:    req.Details,
+		}
+		if _, err := reports.CreateReport(report); err != nil {
+			util.RepositoryError(w, r, &log, err, "already reported")
+			return
+		}
+
+		reporters, err := reports.CountOpenReporters(req.TargetType, req.TargetID)
+		if err != nil {
+			log.Error("error counting reporters", sl.Error(err))
+		} else if cfg.HideThreshold > 0 && reporters >= cfg.HideThreshold {
+			if err := content.hide(r.Context(), req.TargetType, req.TargetID); err != nil {
+				log.Error("error hiding reported content", sl.Error(err))
+			} else {
+				log.Info("reported content hidden",
+					slog.String("target_type", req.TargetType),
+					slog.Int64("target_id", req.TargetID),
+					slog.Int("reporters", reporters))
+				audit(&log, auditor, &models.AuditEntry{
+					Action:     models.AuditContentHide,
+					TargetType: req.TargetType,
+					TargetID:   req.TargetID,
+					Details:    fmt.Sprintf("hidden after %d reports", reporters),
+				})
+			}
+		}
+
+		resp := reportResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusCreated,
+			},
+			ReportID: report.ID,
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusCreated, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// ReportQueue lists reported posts and comments with open reports, the first reported first.
+func ReportQueue(log logger.Logger, reports reportStore, posts postStore, comments statusSetter, cursors *cursor.Codec) http.HandlerFunc {
+	content := content{posts: posts, comments: comments}
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.moderation.ReportQueue"))
+
+		limit, cur, err := cursors.Query(r.URL.Query(), queueLimit)
+		if err != nil {
+			log.Info("invalid page query", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		targets, err := reports.ListOpenReportTargets(cursor.Page(cur, limit))
+		if err != nil {
+			log.Error("error listing reports", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		targets, nextCursor, prevCursor := cursor.Paginate(cursors, cur, limit, targets, func(t *models.ReportTarget) (time.Time, int64) {
+			return t.FirstReportedAt, t.FirstReportID
+		})
+
+		data := make([]reportQueueItem, 0, len(targets))
+		for _, t := range targets {
+			item := reportQueueItem{ReportTarget: t}
+			authorID, visible, err := content.lookup(t.TargetType, t.TargetID)
+			switch {
+			case errors.Is(err, repository.ErrNotExists):
+				item.Deleted = true
+			case err != nil:
+				log.Error("error getting reported content", sl.Error(err))
+				util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+				return
+			default:
+				item.AuthorID = authorID
+				item.Hidden = !visible
+			}
+			data = append(data, item)
+		}
+
+		resp := reportQueueResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			Data: data,
+
+			NextCursor: nextCursor,
+			PrevCursor: prevCursor,
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// ResolveReport closes all open reports on a post or comment. dismiss makes
+// hidden content visible again, remove takes it down, warn and suspend take it
+// down and sanction its author as well. Moderators can't sanction themselves
+// or admins, and only admins can sanction other moderators.
+func ResolveReport(log logger.Logger, reports reportStore, posts postStore, comments statusSetter, users userGetter, sanctioner sanctioner, auditor auditor, publisher eventPublisher, rdb *redisrepo.RedisRepo, cfg config.ReportsConfig) http.HandlerFunc {
+	validate := util.NewCustomValidator()
+	content := content{posts: posts, comments: comments, publisher: publisher, rdb: rdb}
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.moderation.ResolveReport"))
+
+		moderatorID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		var req resolveRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Info("error decoding request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Info("error validation request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		// content deleted by its author in the meantime can still have its reports closed
+		authorID, _, err := content.lookup(req.TargetType, req.TargetID)
+		exists := err == nil
+		if err != nil && !errors.Is(err, repository.ErrNotExists) {
+			log.Error("error getting reported content", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		sanctions := req.Action == "warn" || req.Action == "suspend"
+		if !exists && sanctions {
+			util.ErrorResponse(w, r, http.StatusNotFound, "content not found")
+			return
+		}
+		if sanctions {
+			role, _ := r.Context().Value(auth.UserRoleCtxKey).(string)
+			detail, err := mayNotSanction(users, moderatorID, role, authorID)
+			if err != nil {
+				log.Error("error getting author", sl.Error(err), slog.Int64("user_id", authorID))
+				util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+				return
+			}
+			if detail != "" {
+				log.Info("sanction refused", slog.Int64("moderator_id", moderatorID), slog.Int64("user_id", authorID), slog.String("reason", detail))
+				util.ErrorResponse(w, r, http.StatusForbidden, detail)
+				return
+			}
+		}
+
+		status := models.ReportStatusResolved
+		if req.Action == "dismiss" {
+			status = models.ReportStatusDismissed
+		}
+		resolution := req.Action
+		if req.Note != "" {
+			resolution += ": " + req.Note
+		}
+
+		resolved, err := reports.ResolveReports(req.TargetType, req.TargetID, status, moderatorID, resolution)
+		if err != nil {
+			util.RepositoryError(w, r, &log, err, "no open reports")
+			return
+		}
+
+		resp := resolveResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			TargetType: req.TargetType,
+			TargetID:   req.TargetID,
+			Action:     req.Action,
+			Resolved:   resolved,
+		}
+
+		entry := &models.AuditEntry{
+			ActorID:    &moderatorID,
+			Action:     models.AuditContentRemove,
+			TargetType: req.TargetType,
+			TargetID:   req.TargetID,
+			Details:    resolution,
+		}
+		if req.Action == "dismiss" {
+			entry.Action = models.AuditReportDismiss
+			err = nil
+			if exists {
+				err = content.unhide(r.Context(), req.TargetType, req.TargetID)
+			}
+		} else if exists {
+			err = content.remove(r.Context(), req.TargetType, req.TargetID)
+		}
+		if err != nil {
+			log.Error("error applying report resolution", sl.Error(err), slog.String("action", req.Action))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		audit(&log, auditor, entry)
+
+		if sanctions {
+			reason := req.Note
+			if reason == "" {
+				reason = fmt.Sprintf("reported %s %d", req.TargetType, req.TargetID)
+			}
+			sanction := &models.Sanction{
+				UserID:   authorID,
+				Kind:     models.SanctionWarn,
+				Reason:   reason,
+				IssuedBy: moderatorID,
+			}
+			action := models.AuditUserWarn
+			if req.Action == "suspend" {
+				days := req.DurationDays
+				if days == 0 {
+					days = cfg.SuspendDays
+				}
+				expiresAt := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+				sanction.Kind = models.SanctionSuspend
+				sanction.ExpiresAt = &expiresAt
+				action = models.AuditUserSuspend
+			}
+
+			if _, err := sanctioner.CreateSanction(sanction); err != nil {
+				log.Error("error creating sanction", sl.Error(err), slog.Int64("user_id", authorID))
+				util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+				return
+			}
+			resp.SanctionID = sanction.ID
+
+			details := reason
+			if sanction.ExpiresAt != nil {
+				details += ", until " + sanction.ExpiresAt.UTC().Format(time.RFC3339)
+			}
+			audit(&log, auditor, &models.AuditEntry{
+				ActorID:    &moderatorID,
+				Action:     action,
+				TargetType: models.TargetUser,
+				TargetID:   authorID,
+				Details:    details,
+			})
+		}
+
+		log.Info("reports resolved",
+			slog.Int64("moderator_id", moderatorID),
+			slog.String("action", req.Action),
+			slog.String("target_type", req.TargetType),
+			slog.Int64("target_id", req.TargetID))
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// mayNotSanction tells why the acting moderator may not sanction the user,
+// "" if they may. Deleted accounts can still be sanctioned.
+func mayNotSanction(users userGetter, actorID int64, actorRole string, userID int64) (string, error) {
+	if userID == actorID {
+		return "you can't sanction yourself", nil
+	}
+	user, err := users.GetUserByID(userID)
+	if errors.Is(err, repository.ErrNotExists) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case user.Role == models.RoleAdmin:
+		return "admins can't be sanctioned", nil
+	case user.Role == models.RoleModerator && actorRole != models.RoleAdmin:
+		return "only admins can sanction moderators", nil
+	}
+	return "", nil
+}
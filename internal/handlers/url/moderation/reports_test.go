@@ -0,0 +1,66 @@
+package moderation
+
+import (
+	"errors"
+	"testing"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+)
+
+type usersByID map[int64]*models.User
+
+func (u usersByID) GetUserByID(id int64) (*models.User, error) {
+	if user, ok := u[id]; ok {
+		return user, nil
+	}
+	return nil, repository.ErrNotExists
+}
+
+func TestMayNotSanction(t *testing.T) {
+	users := usersByID{
+		1: {ID: 1, Role: models.RoleAdmin},
+		2: {ID: 2, Role: models.RoleModerator},
+		3: {ID: 3, Role: models.RoleModerator},
+		4: {ID: 4, Role: models.RoleUser},
+	}
+
+	tests := []struct {
+		name      string
+		actorID   int64
+		actorRole string
+		userID    int64
+		refused   bool
+	}{
+		{"moderator sanctions user", 2, models.RoleModerator, 4, false},
+		{"moderator sanctions themselves", 2, models.RoleModerator, 2, true},
+		{"moderator sanctions admin", 2, models.RoleModerator, 1, true},
+		{"moderator sanctions moderator", 2, models.RoleModerator, 3, true},
+		{"admin sanctions moderator", 1, models.RoleAdmin, 3, false},
+		{"admin sanctions themselves", 1, models.RoleAdmin, 1, true},
+		{"deleted author", 2, models.RoleModerator, 9, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detail, err := mayNotSanction(users, tt.actorID, tt.actorRole, tt.userID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if refused := detail != ""; refused != tt.refused {
+				t.Fatalf("refused = %v (%q), want %v", refused, detail, tt.refused)
+			}
+		})
+	}
+}
+
+type brokenUsers struct{}
+
+func (brokenUsers) GetUserByID(id int64) (*models.User, error) {
+	return nil, errors.New("database is locked")
+}
+
+func TestMayNotSanctionLookupError(t *testing.T) {
+	if _, err := mayNotSanction(brokenUsers{}, 2, models.RoleModerator, 4); err == nil {
+		t.Fatal("lookup error was swallowed")
+	}
+}
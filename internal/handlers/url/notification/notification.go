@@ -0,0 +1,234 @@
+package notification
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type notificationInfo struct {
+	NotificationID int64      `json:"notification_id"`
+	Kind           string     `json:"kind"`
+	ActorID        int64      `json:"actor_id"`
+	ActorUsername  string     `json:"actor_username"`
+	PostID         *int64     `json:"post_id,omitempty"`
+	CommentID      *int64     `json:"comment_id,omitempty"`
+	Read           bool       `json:"read"`
+	ReadAt         *time.Time `json:"read_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type listResponse struct {
+	response.BaseResponse
+	Unread     int64              `json:"unread"`
+	Data       []notificationInfo `json:"data"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+type markReadRequest struct {
+	IDs []int64 `json:"ids" validate:"required,min=1,max=100"`
+}
+
+type markReadResponse struct {
+	response.BaseResponse
+	Marked int64 `json:"marked"`
+	Unread int64 `json:"unread"`
+}
+
+type notificationLister interface {
+	ListNotifications(userID int64, unreadOnly bool, beforeID int64, limit int) ([]*models.Notification, error)
+	CountUnread(userID int64) (int64, error)
+}
+
+type notificationMarker interface {
+	MarkRead(userID int64, ids []int64) (int64, error)
+	MarkAllRead(userID int64) (int64, error)
+	CountUnread(userID int64) (int64, error)
+}
+
+type usersGetter interface {
+	GetUsersByIDs(ids []int64) (map[int64]*models.User, error)
+}
+
+// List returns the caller's notifications, newest first, with the number of
+// unread ones. ?unread=true leaves out the ones already read.
+func List(log logger.Logger, notifications notificationLister, usersGetter usersGetter, cursors *cursor.Codec) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.notification.List"))
+
+		userID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		query := r.URL.Query()
+
+		limit, beforeID, err := cursors.QueryID(query, cursor.DefaultLimit)
+		if err != nil {
+			log.Info("invalid page query", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var unreadOnly bool
+		if qunread := query.Get("unread"); qunread != "" {
+			u, err := strconv.ParseBool(qunread)
+			if err != nil {
+				util.ErrorResponse(w, r, http.StatusBadRequest, "Invalid unread parameter")
+				return
+			}
+			unreadOnly = u
+		}
+
+		// one extra row tells whether there is a next page
+		page, err := notifications.ListNotifications(userID, unreadOnly, beforeID, limit+1)
+		if err != nil {
+			log.Error("error listing notifications", sl.Error(err), slog.Int64("user_id", userID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		var nextCursor string
+		if len(page) > limit {
+			page = page[:limit]
+			nextCursor = cursors.EncodeID(page[limit-1].ID)
+		}
+
+		unread, err := notifications.CountUnread(userID)
+		if err != nil {
+			log.Error("error counting unread notifications", sl.Error(err), slog.Int64("user_id", userID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		actorIDs := make([]int64, 0, len(page))
+		for _, n := range page {
+			actorIDs = append(actorIDs, n.ActorID)
+		}
+		users, err := usersGetter.GetUsersByIDs(actorIDs)
+		if err != nil {
+			log.Error("error getting actors", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		data := make([]notificationInfo, 0, len(page))
+		for _, n := range page {
+			info := notificationInfo{
+				NotificationID: n.ID,
+				Kind:           n.Kind,
+				ActorID:        n.ActorID,
+				PostID:         n.PostID,
+				CommentID:      n.CommentID,
+				Read:           n.ReadAt != nil,
+				ReadAt:         n.ReadAt,
+				CreatedAt:      n.CreatedAt,
+			}
+			if user, ok := users[n.ActorID]; ok {
+				info.ActorUsername = user.Username
+			}
+			data = append(data, info)
+		}
+
+		resp := listResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			Unread:     unread,
+			Data:       data,
+			NextCursor: nextCursor,
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// MarkRead marks the given notifications of the caller as read.
+func MarkRead(log logger.Logger, notifications notificationMarker) http.HandlerFunc {
+	validate := util.NewCustomValidator()
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.notification.MarkRead"))
+
+		userID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		var req markReadRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Info("error decoding request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			log.Info("error validation request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		marked, err := notifications.MarkRead(userID, req.IDs)
+		if err != nil {
+			log.Error("error marking notifications read", sl.Error(err), slog.Int64("user_id", userID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		writeMarked(w, r, &log, notifications, userID, marked)
+	}
+}
+
+// MarkAllRead marks every notification of the caller as read.
+func MarkAllRead(log logger.Logger, notifications notificationMarker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.notification.MarkAllRead"))
+
+		userID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		marked, err := notifications.MarkAllRead(userID)
+		if err != nil {
+			log.Error("error marking notifications read", sl.Error(err), slog.Int64("user_id", userID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		writeMarked(w, r, &log, notifications, userID, marked)
+	}
+}
+
+func writeMarked(w http.ResponseWriter, r *http.Request, log logger.Logger, notifications notificationMarker, userID, marked int64) {
+	unread, err := notifications.CountUnread(userID)
+	if err != nil {
+		log.Error("error counting unread notifications", sl.Error(err), slog.Int64("user_id", userID))
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	resp := markReadResponse{
+		BaseResponse: response.BaseResponse{
+			Status: http.StatusOK,
+		},
+		Marked: marked,
+		Unread: unread,
+	}
+	if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+		log.Error("json writer error", sl.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
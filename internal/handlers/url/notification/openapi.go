@@ -0,0 +1,46 @@
+package notification
+
+import (
+	"net/http"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/openapi"
+)
+
+// Operations documents the notification routes.
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Pattern: "GET /api/notifications",
+			ID:      "listNotifications",
+			Summary: "List the notifications of the caller, newest first",
+			Auth:    openapi.Required,
+			Params: append(cursor.Params(cursor.DefaultLimit),
+				openapi.Query("unread", openapi.Boolean(), "only unread notifications")),
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "a page of notifications and the unread count", listResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid limit, unread or cursor"),
+			},
+		},
+		{
+			Pattern: "POST /api/notifications/read",
+			ID:      "markNotificationsRead",
+			Summary: "Mark notifications as read",
+			Auth:    openapi.Required,
+			Body:    markReadRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "marked count and the unread count left", markReadResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid ids"),
+			},
+		},
+		{
+			Pattern: "POST /api/notifications/read-all",
+			ID:      "markAllNotificationsRead",
+			Summary: "Mark all notifications as read",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "marked count", markReadResponse{}),
+			},
+		},
+	}
+}
@@ -0,0 +1,112 @@
+package post
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/httpcache"
+	"blog/internal/api/response"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type authorPostsResponse struct {
+	response.BaseResponse
+	AuthorID int64      `json:"author_id"`
+	Username string     `json:"username"`
+	Data     []postInfo `json:"data"`
+
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+type authorPostsGetter interface {
+	GetPostsByAuthor(authorID int64, page repository.Page) ([]*models.Post, error)
+}
+
+// ByAuthor lists the visible posts of one author, newest first.
+func ByAuthor(log logger.Logger, postsGetter authorPostsGetter, userGetter userGetter, reactions reactionCounter, cursors *cursor.Codec) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.post.ByAuthor"))
+
+		authorID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		limit, cur, err := cursors.Query(r.URL.Query(), cursor.DefaultLimit)
+		if err != nil {
+			log.Info("invalid page query", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		author, err := userGetter.GetUserByID(authorID)
+		if err != nil {
+			util.RepositoryError(w, r, &log, err, "User Not Found")
+			return
+		}
+
+		posts, err := postsGetter.GetPostsByAuthor(authorID, cursor.Page(cur, limit))
+		if err != nil {
+			log.Error("error getting posts", sl.Error(err), slog.Int64("user_id", authorID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		posts, nextCursor, prevCursor := cursor.Paginate(cursors, cur, limit, posts, postKey)
+
+		postIDs := make([]int64, 0, len(posts))
+		for _, post := range posts {
+			postIDs = append(postIDs, post.ID)
+		}
+		summaries, err := reactions.ReactionSummaries(models.TargetPost, postIDs)
+		if err != nil {
+			log.Error("error getting reaction counts", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		lastModified := models.LastReactionAt(summaries)
+		data := make([]postInfo, 0, len(posts))
+		for _, post := range posts {
+			if post.UpdatedAt.After(lastModified) {
+				lastModified = post.UpdatedAt
+			}
+			data = append(data, postInfo{
+				PostID:    post.ID,
+				Title:     post.Title,
+				Content:   post.Content,
+				Tags:      tagList(post.Tags),
+				AuthorID:  post.AuthorID,
+				Username:  author.Username,
+				Version:   post.Version,
+				CreatedAt: &post.CreatedAt,
+				Reactions: summaries[post.ID].CountMap(),
+			})
+		}
+
+		resp := authorPostsResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			AuthorID: authorID,
+			Username: author.Username,
+			Data:     data,
+
+			NextCursor: nextCursor,
+			PrevCursor: prevCursor,
+		}
+		validators := httpcache.Validators{LastModified: lastModified}
+		if err := httpcache.WriteJSON(w, r, http.StatusOK, resp, validators); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package post
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/middlewares/auth"
+	"blog/internal/repository/redisrepo"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type commentSettingsRequest struct {
+	CommentMode    string `json:"comment_mode" validate:"omitempty,oneof=open first_time all"`
+	CommentsLocked bool   `json:"comments_locked"`
+}
+
+type commentSettingsResponse struct {
+	response.BaseResponse
+	PostID         int64  `json:"post_id"`
+	CommentMode    string `json:"comment_mode,omitempty"`
+	CommentsLocked bool   `json:"comments_locked"`
+}
+
+type commentSettingsUpdater interface {
+	UpdateCommentSettings(postID, authorID int64, mode string, locked bool) error
+}
+
+// CommentSettings lets the author of a post override the moderation mode of its
+// comments (an empty mode falls back to the global one) and lock new comments.
+func CommentSettings(log logger.Logger, updater commentSettingsUpdater, rdb *redisrepo.RedisRepo) http.HandlerFunc {
+	validate := util.NewCustomValidator()
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.post.CommentSettings"))
+
+		authorID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			log.Error("author id not found in context or invalid type", slog.String("user_id_ctx_key", auth.UserIDCtxKey))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		postID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		var req commentSettingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Info("error decoding request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Info("error validation request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		err = updater.UpdateCommentSettings(postID, authorID, req.CommentMode, req.CommentsLocked)
+		if err != nil {
+			util.RepositoryError(w, r, &log, err, "Not Found")
+			return
+		}
+
+		if err := rdb.Delete(r.Context(), redisrepo.PostKey(postID)); err != nil {
+			log.Error("failed to invalidate cached post", sl.Error(err), slog.Int64("post_id", postID))
+		}
+
+		resp := commentSettingsResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			PostID:         postID,
+			CommentMode:    req.CommentMode,
+			CommentsLocked: req.CommentsLocked,
+		}
+
+		log.Info("comment settings updated", slog.Int64("post_id", postID))
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
@@ -1,26 +1,25 @@
 package post
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"log/slog"
 	"net/http"
 
-	"github.com/go-playground/validator/v10"
-
 	"blog/internal/api/jsonutil"
 	"blog/internal/api/response"
+	"blog/internal/events"
 	"blog/internal/middlewares/auth"
 	"blog/internal/models"
-	"blog/internal/repository"
 	"blog/internal/util"
 	"blog/internal/util/logger"
 	"blog/internal/util/logger/sl"
 )
 
 type CreateRequest struct {
-	Title   string `json:"title" validate:"required,min=3,max=255"`
-	Content string `json:"content" validate:"required,min=10"`
+	Title   string   `json:"title" validate:"required,min=3,max=255"`
+	Content string   `json:"content" validate:"required,min=10"`
+	Tags    []string `json:"tags" validate:"max=10,dive,tag,max=50"`
 }
 
 type CreateResponse struct {
@@ -32,34 +31,41 @@ type PostCreator interface {
 	CreatePost(post *models.Post) (int64, error) // post.title, post.content, post.authorID
 }
 
-func Create(log logger.Logger, creator PostCreator) http.HandlerFunc {
-	validate := validator.New()
+type mentionSyncer interface {
+	Sync(targetType string, targetID int64, content string) error
+}
+
+type eventPublisher interface {
+	Publish(ctx context.Context, e events.Event)
+}
+
+func Create(log logger.Logger, creator PostCreator, mentions mentionSyncer, publisher eventPublisher) http.HandlerFunc {
+	validate := util.NewCustomValidator()
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := log.With(slog.String("fn", "handlers.url.post.Create"))
 		if r.Method != http.MethodPost {
-			util.ErrorResponse(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			util.ErrorResponse(w, r, http.StatusMethodNotAllowed, "Method Not Allowed")
 			return
 		}
 
 		authorID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
 		if !ok {
 			log.Error("author id not found in context or invalid type", slog.String("user_id_ctx_key", auth.UserIDCtxKey))
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
 
 		var req CreateRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			log.Info("error decoding request payload", sl.Error(err))
-			util.ErrorResponse(w, http.StatusBadRequest, "invalid request payload")
+			util.ValidationError(w, r, err)
 			return
 		}
 
 		if err := validate.Struct(req); err != nil {
-			validationErrors := err.(validator.ValidationErrors)
-			log.Info("request validation failed", sl.Error(validationErrors))
-			util.ErrorResponse(w, http.StatusBadRequest, "invalid request format")
+			log.Info("request validation failed", sl.Error(err))
+			util.ValidationError(w, r, err)
 			return
 		}
 
@@ -67,21 +73,21 @@ func Create(log logger.Logger, creator PostCreator) http.HandlerFunc {
 			Title:    req.Title,
 			Content:  req.Content,
 			AuthorID: authorID,
+			Tags:     models.NormalizeTags(req.Tags),
 		}
 
 		postID, err := creator.CreatePost(post)
 		if err != nil {
-			if errors.Is(err, repository.ErrForeignKeyFailed) {
-				log.Info("author for post does not exist", sl.Error(err))
-				util.ErrorResponse(w, http.StatusBadRequest, "author does not exist")
-				return
-			}
-
-			log.Error("failed to create post", sl.Error(err))
-			util.ErrorResponse(w, http.StatusBadRequest, "internal server error")
+			util.RepositoryError(w, r, &log, err, "author does not exist")
 			return
 		}
 
+		// unresolved mentions only cost the links, the post is saved either way
+		if err := mentions.Sync(models.TargetPost, postID, post.Content); err != nil {
+			log.Error("failed to link mentions", sl.Error(err), slog.Int64("post_id", postID))
+		}
+		publisher.Publish(r.Context(), events.PostPublished{Post: post})
+
 		resp := CreateResponse{
 			BaseResponse: response.BaseResponse{
 				Status: http.StatusAccepted,
@@ -1,16 +1,16 @@
 package post
 
 import (
-	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
 
 	"blog/internal/api/jsonutil"
 	"blog/internal/api/response"
+	"blog/internal/events"
 	"blog/internal/middlewares/auth"
 	"blog/internal/models"
-	"blog/internal/repository"
+	"blog/internal/repository/redisrepo"
 	"blog/internal/util"
 	"blog/internal/util/logger"
 	"blog/internal/util/logger/sl"
@@ -26,33 +26,27 @@ type postDeleter interface {
 	DeletePost(id int64) error
 }
 
-func Delete(log logger.Logger, postDeleter postDeleter) http.HandlerFunc {
+func Delete(log logger.Logger, postDeleter postDeleter, publisher eventPublisher, rdb *redisrepo.RedisRepo) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := log.With("fn", "handlers.url.post.Delete")
 
 		authorID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
 		if !ok {
 			log.Error("author id not found in context or invalid type", slog.String("user_id_ctx_key", auth.UserIDCtxKey))
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
 
 		postID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 		if err != nil {
 			log.Info("invalid path value for post id", sl.Error(err))
-			util.ErrorResponse(w, http.StatusBadRequest, "Bad Request")
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
 			return
 		}
 
 		post, err := postDeleter.GetPostByID(postID)
 		if err != nil {
-			if errors.Is(err, repository.ErrNotExists) {
-				log.Info("post to delete not found", slog.Int64("post_id", postID), sl.Error(err))
-				util.ErrorResponse(w, http.StatusNotFound, "Post not found")
-				return
-			}
-			log.Error("failed to retrieve post for deletion", slog.Int64("post_id", postID), sl.Error(err))
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			util.RepositoryError(w, r, &log, err, "Post not found")
 			return
 		}
 
@@ -63,22 +57,21 @@ func Delete(log logger.Logger, postDeleter postDeleter) http.HandlerFunc {
 				slog.Int64("post_author_id", post.AuthorID),
 				slog.Int64("author_id", authorID))
 
-			util.ErrorResponse(w, http.StatusForbidden, "Forbidden")
+			util.ErrorResponse(w, r, http.StatusForbidden, "Forbidden")
 			return
 		}
 
 		err = postDeleter.DeletePost(postID)
 		if err != nil {
-			if errors.Is(err, repository.ErrNotExists) {
-				log.Info("post is not exists", slog.Int64("post_id", postID))
-				util.ErrorResponse(w, http.StatusNotFound, "Not Found")
-				return
-			}
-			log.Error("post delete error", slog.Int64("post_id", postID), sl.Error(err))
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			util.RepositoryError(w, r, &log, err, "Not Found")
 			return
 		}
 
+		if err := rdb.Delete(r.Context(), redisrepo.PostKey(postID)); err != nil {
+			log.Error("failed to invalidate cached post", sl.Error(err), slog.Int64("post_id", postID))
+		}
+		publisher.Publish(r.Context(), events.PostVisibilityChanged{PostID: postID})
+
 		resp := deleteResponse{
 			BaseResponse: response.BaseResponse{
 				Status: http.StatusOK,
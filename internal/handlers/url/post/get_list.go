@@ -4,10 +4,10 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
-	"strconv"
 	"time"
 
-	"blog/internal/api/jsonutil"
+	"blog/internal/api/cursor"
+	"blog/internal/api/httpcache"
 	"blog/internal/api/response"
 	"blog/internal/models"
 	"blog/internal/repository"
@@ -21,63 +21,68 @@ type postInfo struct {
 	PostID    int64      `json:"post_id,omitempty"`
 	Title     string     `json:"title"`
 	Content   string     `json:"content"`
+	Tags      []string   `json:"tags"`
 	AuthorID  int64      `json:"author_id"`
 	Username  string     `json:"username"`
+	Version   int64      `json:"version"`
 	CreatedAt *time.Time `json:"created_at,omitempty"`
+
+	Reactions map[string]int64 `json:"reactions"`
 }
 
 type postListResponse struct {
 	response.BaseResponse
-	Data []postInfo `json:"data"`
+	Data       []postInfo `json:"data"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+	PrevCursor string     `json:"prev_cursor,omitempty"`
 }
 
 type postsGetter interface {
-	ListPosts(limit, offset int) ([]*models.Post, error)
+	ListPosts(page repository.Page) ([]*models.Post, error)
 }
 type userGetter interface {
 	GetUserByID(id int64) (*models.User, error)
 }
 
-func GetList(log logger.Logger, postsGetter postsGetter, userGetter userGetter) http.HandlerFunc {
+// GetList returns the newest posts. Pages are linked by signed next/prev
+// cursors on (created_at, id), so new posts don't shift them.
+func GetList(log logger.Logger, postsGetter postsGetter, userGetter userGetter, reactions reactionCounter, cursors *cursor.Codec) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := log.With("fn", "handlers.url.post.GetList")
 
-		qlimit := r.URL.Query().Get("limit")
-		qoffset := r.URL.Query().Get("offset")
-
-		limit := 10 // default
-		if qlimit != "" {
-			l, err := strconv.ParseInt(qlimit, 10, 64)
-			if err != nil || l < 1 {
-				util.ErrorResponse(w, http.StatusBadRequest, "Invalid limit parameter")
-				return
-			}
-			limit = int(l)
+		limit, cur, err := cursors.Query(r.URL.Query(), 10)
+		if err != nil {
+			log.Info("invalid page query", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
 		}
 
-		offset := 0 // default
-		if qoffset != "" {
-			o, err := strconv.ParseInt(qoffset, 10, 64)
-			if err != nil || o < 0 {
-				util.ErrorResponse(w, http.StatusBadRequest, "Invalid offset parameter")
-				return
-			}
-			offset = int(o)
+		posts, err := postsGetter.ListPosts(cursor.Page(cur, limit))
+		if err != nil {
+			log.Error("error get posts", sl.Error(err), slog.Int("limit", limit))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
 		}
+		posts, nextCursor, prevCursor := cursor.Paginate(cursors, cur, limit, posts, postKey)
 
-		// TODO: пж почини этот говнокод
-		posts, err := postsGetter.ListPosts(limit, offset)
+		postIDs := make([]int64, 0, len(posts))
+		for _, post := range posts {
+			postIDs = append(postIDs, post.ID)
+		}
+		summaries, err := reactions.ReactionSummaries(models.TargetPost, postIDs)
 		if err != nil {
-			log.Error("error get posts",
-				sl.Error(err),
-				slog.Int("limit", limit),
-				slog.Int("offset", offset))
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			log.Error("error getting reaction counts", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
+
 		// TODO: ADD REDIS for cache
+		lastModified := models.LastReactionAt(summaries)
 		responseData := make([]postInfo, 0, len(posts))
 		for _, post := range posts {
+			if post.UpdatedAt.After(lastModified) {
+				lastModified = post.UpdatedAt
+			}
 			user, err := userGetter.GetUserByID(post.AuthorID)
 			if err != nil {
 				if errors.Is(err, repository.ErrNotExists) {
@@ -95,9 +100,12 @@ func GetList(log logger.Logger, postsGetter postsGetter, userGetter userGetter)
 				PostID:    post.ID,
 				Title:     post.Title,
 				Content:   post.Content,
+				Tags:      tagList(post.Tags),
 				AuthorID:  post.AuthorID,
 				Username:  user.Username,
+				Version:   post.Version,
 				CreatedAt: &post.CreatedAt,
+				Reactions: summaries[post.ID].CountMap(),
 			})
 		}
 
@@ -105,13 +113,20 @@ func GetList(log logger.Logger, postsGetter postsGetter, userGetter userGetter)
 			BaseResponse: response.BaseResponse{
 				Status: http.StatusOK,
 			},
-			Data: responseData,
+			Data:       responseData,
+			NextCursor: nextCursor,
+			PrevCursor: prevCursor,
 		}
 
-		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+		validators := httpcache.Validators{LastModified: lastModified}
+		if err := httpcache.WriteJSON(w, r, http.StatusOK, resp, validators); err != nil {
 			log.Error("failed to write JSON response", sl.Error(err))
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		}
 
 	}
 }
+
+func postKey(post *models.Post) (time.Time, int64) {
+	return post.CreatedAt, post.ID
+}
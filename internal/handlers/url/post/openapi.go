@@ -0,0 +1,115 @@
+package post
+
+import (
+	"net/http"
+
+	"blog/internal/api/cursor"
+	"blog/internal/api/etag"
+	"blog/internal/api/openapi"
+)
+
+// Operations documents the post routes.
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Pattern: "POST /api/post",
+			ID:      "createPost",
+			Summary: "Publish a post",
+			Auth:    openapi.Required,
+			Body:    CreateRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusCreated, "post created", CreateResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid title or content"),
+				openapi.Error(http.StatusUnprocessableEntity, "the author was deleted"),
+			},
+		},
+		{
+			Pattern: "GET /api/post/{id}",
+			Summary: "Read a post",
+			Auth:    openapi.Optional,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "the post, bookmarked is only set for signed in callers", readResponse{}),
+				openapi.Empty(http.StatusNotModified, "If-None-Match matched"),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				openapi.Error(http.StatusNotFound, "no such post"),
+			},
+		},
+		{
+			Pattern:     "PATCH /api/post/{id}",
+			ID:          "updatePost",
+			Summary:     "Edit a post",
+			Description: "The previous version is kept as a revision.",
+			Auth:        openapi.Required,
+			Params: []openapi.Param{
+				openapi.Header(etag.HeaderIfMatch, openapi.String(), "ETag of the version the edit is based on"),
+			},
+			Body: updateRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusAccepted, "post updated", updateResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id, title or content"),
+				openapi.Error(http.StatusForbidden, "not the author"),
+				openapi.Error(http.StatusNotFound, "no such post"),
+				openapi.Error(http.StatusPreconditionFailed, "the post was changed since the If-Match version"),
+			},
+		},
+		{
+			Pattern:     "DELETE /api/post/{id}",
+			ID:          "deletePost",
+			Summary:     "Move a post to the trash",
+			Description: "Trashed posts can be restored until they are purged.",
+			Auth:        openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusAccepted, "post trashed", deleteResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				openapi.Error(http.StatusForbidden, "not the author"),
+				openapi.Error(http.StatusNotFound, "no such post"),
+			},
+		},
+		{
+			Pattern: "POST /api/post/{id}/restore",
+			Summary: "Restore a post from the trash",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "post restored", restoreResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				openapi.Error(http.StatusNotFound, "no post of the caller in the trash"),
+			},
+		},
+		{
+			Pattern:     "PATCH /api/post/{id}/comment-settings",
+			ID:          "updatePostCommentSettings",
+			Summary:     "Change how comments of a post are moderated",
+			Description: "An empty comment_mode falls back to the global mode.",
+			Auth:        openapi.Required,
+			Body:        commentSettingsRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "settings changed", commentSettingsResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id or comment mode"),
+				openapi.Error(http.StatusNotFound, "no post of the caller"),
+			},
+		},
+		{
+			Pattern: "GET /api/posts",
+			ID:      "listPosts",
+			Summary: "List the newest posts",
+			Params:  cursor.Params(10),
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "a page of posts", postListResponse{}),
+				openapi.Empty(http.StatusNotModified, "If-None-Match matched"),
+				openapi.Error(http.StatusBadRequest, "invalid limit or cursor"),
+			},
+		},
+		{
+			Pattern: "GET /api/user/{id}/posts",
+			ID:      "listUserPosts",
+			Summary: "List the posts of a user",
+			Params:  cursor.Params(cursor.DefaultLimit),
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "a page of posts", authorPostsResponse{}),
+				openapi.Empty(http.StatusNotModified, "If-None-Match matched"),
+				openapi.Error(http.StatusBadRequest, "invalid id, limit or cursor"),
+				openapi.Error(http.StatusNotFound, "no such user"),
+			},
+		},
+	}
+}
@@ -1,15 +1,18 @@
 package post
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
-	"blog/internal/api/jsonutil"
+	"blog/internal/api/etag"
+	"blog/internal/api/httpcache"
 	"blog/internal/api/response"
+	"blog/internal/mention"
+	"blog/internal/middlewares/auth"
 	"blog/internal/models"
 	"blog/internal/repository"
 	"blog/internal/repository/redisrepo"
@@ -20,11 +23,18 @@ import (
 
 type readResponse struct {
 	response.BaseResponse
-	ID       int64  `json:"post_id,omitempty"`
-	Title    string `json:"title"`
-	Content  string `json:"content"`
-	AuthorID int64  `json:"author_id"`
-	Username string `json:"username"`
+	ID       int64    `json:"post_id,omitempty"`
+	Title    string   `json:"title"`
+	Content  string   `json:"content"`
+	AuthorID int64    `json:"author_id"`
+	Username string   `json:"username"`
+	Version  int64    `json:"version"`
+	Tags     []string `json:"tags"`
+
+	ContentHTML string            `json:"content_html"`
+	Mentions    []*models.Mention `json:"mentions"`
+	Reactions   map[string]int64  `json:"reactions"`
+	Bookmarked  bool              `json:"bookmarked"`
 }
 
 type postReader interface {
@@ -35,7 +45,23 @@ type userGetterReader interface {
 	GetUserByID(id int64) (*models.User, error)
 }
 
-func Read(log logger.Logger, postReader postReader, userGetter userGetterReader, rdb *redisrepo.RedisRepo) http.HandlerFunc {
+type reactionCounter interface {
+	ReactionSummaries(targetType string, ids []int64) (map[int64]*models.ReactionSummary, error)
+}
+
+type mentionLister interface {
+	Mentions(targetType string, ids []int64) (map[int64][]*models.Mention, error)
+}
+
+type bookmarkChecker interface {
+	BookmarkedPosts(userID int64, postIDs []int64) (map[int64]bool, error)
+}
+
+// Read returns a post with its mentions, reaction counts and, for signed in
+// callers, whether they bookmarked it. Reactions and bookmarks change
+// independently of the post, so they are never cached in Redis and are part
+// of the ETag.
+func Read(log logger.Logger, postReader postReader, userGetter userGetterReader, mentions mentionLister, reactions reactionCounter, bookmarks bookmarkChecker, rdb *redisrepo.RedisRepo) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		log := log.With("fn", "handlers.url.post.Read")
@@ -43,16 +69,47 @@ func Read(log logger.Logger, postReader postReader, userGetter userGetterReader,
 		postID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 		if err != nil {
 			log.Info("invalid path value", sl.Error(err))
-			util.ErrorResponse(w, http.StatusBadRequest, "Bad Request")
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
 			return
 		}
 
-		postKey := fmt.Sprintf("post:%d", postID)
+		postKey := redisrepo.PostKey(postID)
 		var cachedPost redisrepo.PostModel
 
+		summaries, err := reactions.ReactionSummaries(models.TargetPost, []int64{postID})
+		if err != nil {
+			log.Error("error getting reaction counts", sl.Error(err), slog.Int64("postID", postID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		summary := summaries[postID]
+
+		mentionsByPost, err := mentions.Mentions(models.TargetPost, []int64{postID})
+		if err != nil {
+			log.Error("error getting mentions", sl.Error(err), slog.Int64("postID", postID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		postMentions := mentionsByPost[postID]
+		if postMentions == nil {
+			postMentions = []*models.Mention{}
+		}
+
+		var bookmarked bool
+		if viewerID, ok := ctx.Value(auth.UserIDCtxKey).(int64); ok {
+			marked, err := bookmarks.BookmarkedPosts(viewerID, []int64{postID})
+			if err != nil {
+				log.Error("error checking bookmark", sl.Error(err), slog.Int64("postID", postID))
+				util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+				return
+			}
+			bookmarked = marked[postID]
+		}
+
 		err = rdb.Get(ctx, postKey, &cachedPost)
 		if err == nil {
 			log.Info("sending cached post", slog.Int64("postID", postID))
+			validators := readValidators(cachedPost.Version, cachedPost.UpdatedAt, summary, bookmarked)
 			resp := readResponse{
 				BaseResponse: response.BaseResponse{Status: http.StatusOK},
 				ID:           cachedPost.ID,
@@ -60,8 +117,14 @@ func Read(log logger.Logger, postReader postReader, userGetter userGetterReader,
 				Content:      cachedPost.Content,
 				AuthorID:     cachedPost.AuthorID,
 				Username:     cachedPost.Username,
+				Version:      cachedPost.Version,
+				Tags:         tagList(cachedPost.Tags),
+				ContentHTML:  mention.HTML(cachedPost.Content, postMentions),
+				Mentions:     postMentions,
+				Reactions:    summary.CountMap(),
+				Bookmarked:   bookmarked,
 			}
-			if writeErr := jsonutil.WriteJSON(w, http.StatusOK, resp); writeErr != nil {
+			if writeErr := httpcache.WriteJSON(w, r, http.StatusOK, resp, validators); writeErr != nil {
 				log.Error("json writer error (cached response)", sl.Error(writeErr))
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			}
@@ -74,13 +137,13 @@ func Read(log logger.Logger, postReader postReader, userGetter userGetterReader,
 
 		post, err := postReader.GetPostByID(postID)
 		if err != nil {
-			if errors.Is(err, repository.ErrNotExists) {
-				log.Info("post not found in DB", sl.Error(err), slog.Int64("postID", postID))
-				util.ErrorResponse(w, http.StatusNotFound, "Page Not Found")
-				return
-			}
-			log.Error("error getting post by id from DB", sl.Error(err), slog.Int64("postID", postID))
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			util.RepositoryError(w, r, &log, err, "Page Not Found")
+			return
+		}
+
+		if post.HiddenAt != nil {
+			log.Info("post is hidden", slog.Int64("postID", postID))
+			util.ErrorResponse(w, r, http.StatusNotFound, "Page Not Found")
 			return
 		}
 
@@ -111,20 +174,31 @@ func Read(log logger.Logger, postReader postReader, userGetter userGetterReader,
 			Content:  post.Content,
 			AuthorID: post.AuthorID,
 			Username: username,
+			Version:  post.Version,
+			Tags:     tagList(post.Tags),
+
+			ContentHTML: mention.HTML(post.Content, postMentions),
+			Mentions:    postMentions,
+			Reactions:   summary.CountMap(),
+			Bookmarked:  bookmarked,
 		}
 
 		postToCache := redisrepo.PostModel{
 			Post: models.Post{
-				ID:       post.ID,
-				Title:    post.Title,
-				Content:  post.Content,
-				AuthorID: post.AuthorID,
+				ID:        post.ID,
+				Title:     post.Title,
+				Content:   post.Content,
+				AuthorID:  post.AuthorID,
+				Version:   post.Version,
+				CreatedAt: post.CreatedAt,
+				UpdatedAt: post.UpdatedAt,
+				Tags:      post.Tags,
 			},
 			Username: username,
 		}
 
 		go func() {
-			setErr := rdb.Set(ctx, postKey, postToCache, 12*time.Hour)
+			setErr := rdb.Set(context.WithoutCancel(ctx), postKey, postToCache, 12*time.Hour)
 			if setErr != nil {
 				log.Error("failed to set post to redis cache", sl.Error(setErr), slog.Int64("postID", postID))
 			} else {
@@ -132,9 +206,34 @@ func Read(log logger.Logger, postReader postReader, userGetter userGetterReader,
 			}
 		}()
 
-		if writeErr := jsonutil.WriteJSON(w, http.StatusOK, resp); writeErr != nil {
+		validators := readValidators(post.Version, post.UpdatedAt, summary, bookmarked)
+		if writeErr := httpcache.WriteJSON(w, r, http.StatusOK, resp, validators); writeErr != nil {
 			log.Error("json writer error (direct response)", sl.Error(writeErr))
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		}
 	}
 }
+
+// tagList returns the tags of a post as a list, empty rather than null.
+func tagList(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// readValidators builds the validators of a post representation: reactions
+// and the caller's bookmark change the ETag without a new post version.
+func readValidators(version int64, updatedAt time.Time, summary *models.ReactionSummary, bookmarked bool) httpcache.Validators {
+	v := httpcache.Validators{ETag: etag.Version(version), LastModified: updatedAt}
+	if summary != nil {
+		v.ETag = etag.VersionWith(version, summary.Revision)
+		if summary.UpdatedAt.After(updatedAt) {
+			v.LastModified = summary.UpdatedAt
+		}
+	}
+	if bookmarked {
+		v.ETag = etag.Variant(v.ETag, "b")
+	}
+	return v
+}
@@ -0,0 +1,64 @@
+package post
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/events"
+	"blog/internal/middlewares/auth"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type restoreResponse struct {
+	response.BaseResponse
+	PostID int64 `json:"post_id,omitempty"`
+}
+
+type postRestorer interface {
+	RestorePost(id, authorID int64) error
+}
+
+// Restore takes one of the caller's posts out of the trash.
+func Restore(log logger.Logger, postRestorer postRestorer, publisher eventPublisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.post.Restore"))
+
+		authorID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			log.Error("author id not found in context or invalid type", slog.String("user_id_ctx_key", auth.UserIDCtxKey))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		postID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value for post id", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		if err := postRestorer.RestorePost(postID, authorID); err != nil {
+			util.RepositoryError(w, r, &log, err, "Post Not Found In Trash")
+			return
+		}
+		publisher.Publish(r.Context(), events.PostVisibilityChanged{PostID: postID})
+
+		resp := restoreResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			PostID: postID,
+		}
+
+		log.Info("post restored", slog.Int64("post_id", postID))
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
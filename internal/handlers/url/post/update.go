@@ -2,31 +2,32 @@ package post
 
 import (
 	"encoding/json"
-	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
 
-	"github.com/go-playground/validator/v10"
-
+	"blog/internal/api/etag"
 	"blog/internal/api/jsonutil"
 	"blog/internal/api/response"
+	"blog/internal/events"
 	"blog/internal/middlewares/auth"
 	"blog/internal/models"
-	"blog/internal/repository"
+	"blog/internal/repository/redisrepo"
 	"blog/internal/util"
 	"blog/internal/util/logger"
 	"blog/internal/util/logger/sl"
 )
 
 type updateRequest struct {
-	Title   string `json:"title" validate:"required,min=3,max=255"`
-	Content string `json:"content" validate:"required,min=10"`
+	Title   string   `json:"title" validate:"required,min=3,max=255"`
+	Content string   `json:"content" validate:"required,min=10"`
+	Tags    []string `json:"tags" validate:"max=10,dive,tag,max=50"` // left unchanged when missing
 }
 
 type updateResponse struct {
 	response.BaseResponse
-	PostID int64 `json:"post_id,omitempty"`
+	PostID  int64 `json:"post_id,omitempty"`
+	Version int64 `json:"version,omitempty"`
 }
 
 type postUpdater interface {
@@ -34,45 +35,40 @@ type postUpdater interface {
 	UpdatePost(post *models.Post) error
 }
 
-func Update(log logger.Logger, postUpdater postUpdater) http.HandlerFunc {
-	validate := validator.New()
+func Update(log logger.Logger, postUpdater postUpdater, mentions mentionSyncer, publisher eventPublisher, rdb *redisrepo.RedisRepo) http.HandlerFunc {
+	validate := util.NewCustomValidator()
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := log.With(slog.String("fn", "handlers.url.post.Update"))
 
 		authorID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
 		if !ok {
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
 
 		postID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 		if err != nil {
 			log.Info("invalid path value", sl.Error(err))
-			util.ErrorResponse(w, http.StatusBadRequest, "Bad Request")
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
 			return
 		}
 
 		var req updateRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			log.Info("error decoding request", sl.Error(err))
-			// TODO: add error handler for jsonutil
-			util.ErrorResponse(w, http.StatusBadRequest, "EOF")
+			util.ValidationError(w, r, err)
 			return
 		}
 
 		if err := validate.Struct(req); err != nil {
-			validationErrors := err.(validator.ValidationErrors)
-			log.Info("error validation request", sl.Error(validationErrors))
-			util.ErrorResponse(w, http.StatusBadRequest, "Invalid Json Format")
+			log.Info("error validation request", sl.Error(err))
+			util.ValidationError(w, r, err)
 			return
 		}
 		post, err := postUpdater.GetPostByID(postID)
 		if err != nil {
-			if errors.Is(err, repository.ErrNotExists) {
-				log.Info("post is not exists", sl.Error(err), slog.Int64("post_id", postID))
-				util.ErrorResponse(w, http.StatusNotFound, "Not Found")
-				return
-			}
+			util.RepositoryError(w, r, &log, err, "Not Found")
+			return
 		}
 
 		if post.AuthorID != authorID {
@@ -81,36 +77,53 @@ func Update(log logger.Logger, postUpdater postUpdater) http.HandlerFunc {
 				slog.Int64("post_author_id", post.AuthorID),
 				slog.Int64("author_id", authorID))
 
-			util.ErrorResponse(w, http.StatusForbidden, "Forbidden")
+			util.ErrorResponse(w, r, http.StatusForbidden, "Forbidden")
+			return
+		}
+
+		if !etag.MatchVersion(r.Header.Get(etag.HeaderIfMatch), post.Version) {
+			log.Info("if-match precondition failed",
+				slog.Int64("post_id", postID),
+				slog.Int64("version", post.Version),
+				slog.String("if_match", r.Header.Get(etag.HeaderIfMatch)))
+			util.ErrorResponse(w, r, http.StatusPreconditionFailed, "Precondition Failed")
 			return
 		}
 
 		newPost := &models.Post{
-			ID:       postID,
-			Title:    req.Title,
-			Content:  req.Title,
-			AuthorID: authorID,
+			ID:        postID,
+			Title:     req.Title,
+			Content:   req.Content,
+			AuthorID:  authorID,
+			Version:   post.Version,
+			CreatedAt: post.CreatedAt,
+		}
+		if req.Tags != nil {
+			newPost.Tags = models.NormalizeTags(req.Tags)
 		}
 
-		err = postUpdater.UpdatePost(newPost)
-		if err != nil {
-			if errors.Is(err, repository.ErrNotExists) {
-				log.Info("post is not exists", sl.Error(err), slog.Int64("post_id", postID))
-				util.ErrorResponse(w, http.StatusNotFound, "Not Found")
-				return
-			}
-			log.Error("error update post", sl.Error(err), slog.Int64("post_id", postID))
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+		if err := postUpdater.UpdatePost(newPost); err != nil {
+			util.RepositoryError(w, r, &log, err, "the post was deleted or changed in the meantime")
 			return
 		}
 
+		if err := rdb.Delete(r.Context(), redisrepo.PostKey(postID)); err != nil {
+			log.Error("failed to invalidate cached post", sl.Error(err), slog.Int64("post_id", postID))
+		}
+		if err := mentions.Sync(models.TargetPost, postID, newPost.Content); err != nil {
+			log.Error("failed to link mentions", sl.Error(err), slog.Int64("post_id", postID))
+		}
+		publisher.Publish(r.Context(), events.PostUpdated{Post: newPost})
+
 		resp := updateResponse{
 			BaseResponse: response.BaseResponse{
 				Status: http.StatusAccepted,
 			},
-			PostID: postID,
+			PostID:  postID,
+			Version: newPost.Version,
 		}
 		log.Info("post updated", slog.Int64("post_id", postID))
+		w.Header().Set(etag.HeaderETag, etag.Version(newPost.Version))
 		err = jsonutil.WriteJSON(w, http.StatusAccepted, resp)
 		if err != nil {
 			log.Error("json writer error", sl.Error(err))
@@ -0,0 +1,51 @@
+package reaction
+
+import (
+	"net/http"
+
+	"blog/internal/api/openapi"
+	"blog/internal/config"
+)
+
+// Operations documents the reaction routes of posts and comments, the
+// emoji are the configured ones.
+func Operations(cfg config.ReactionsConfig) []openapi.Operation {
+	emoji := openapi.Enum(cfg.Emoji...)
+	body := &openapi.Schema{
+		Type:       "object",
+		Properties: map[string]*openapi.Schema{"emoji": emoji},
+		Required:   []string{"emoji"},
+	}
+
+	var ops []openapi.Operation
+	for _, t := range []struct{ target, name string }{{"post", "Post"}, {"comment", "Comment"}} {
+		target := t.target
+		ops = append(ops,
+			openapi.Operation{
+				Pattern: "POST /api/" + target + "/{id}/reactions",
+				ID:      "add" + t.name + "Reaction",
+				Summary: "React to a " + target,
+				Auth:    openapi.Required,
+				Body:    body,
+				Responses: []openapi.Response{
+					openapi.JSON(http.StatusOK, "the reaction counts, changed is false if the reaction was there already", reactionResponse{}),
+					openapi.Error(http.StatusBadRequest, "invalid id or unsupported emoji"),
+					openapi.Error(http.StatusNotFound, "no such "+target),
+				},
+			},
+			openapi.Operation{
+				Pattern: "DELETE /api/" + target + "/{id}/reactions/{emoji}",
+				ID:      "remove" + t.name + "Reaction",
+				Summary: "Take back a reaction to a " + target,
+				Auth:    openapi.Required,
+				Params:  []openapi.Param{openapi.Path("emoji", emoji, "")},
+				Responses: []openapi.Response{
+					openapi.JSON(http.StatusOK, "the reaction counts, changed is false if there was no such reaction", reactionResponse{}),
+					openapi.Error(http.StatusBadRequest, "invalid id or unsupported emoji"),
+					openapi.Error(http.StatusNotFound, "no such "+target),
+				},
+			},
+		)
+	}
+	return ops
+}
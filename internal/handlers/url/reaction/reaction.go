@@ -0,0 +1,180 @@
+package reaction
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/config"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type reactionRequest struct {
+	Emoji string `json:"emoji" validate:"required"`
+}
+
+type reactionResponse struct {
+	response.BaseResponse
+	TargetType string           `json:"target_type"`
+	TargetID   int64            `json:"target_id"`
+	Emoji      string           `json:"emoji"`
+	Changed    bool             `json:"changed"`
+	Reactions  map[string]int64 `json:"reactions"`
+	Total      int64            `json:"total"`
+}
+
+type reactionStore interface {
+	AddReaction(reaction *models.Reaction) (bool, error)
+	RemoveReaction(reaction *models.Reaction) (bool, error)
+	ReactionSummaries(targetType string, ids []int64) (map[int64]*models.ReactionSummary, error)
+}
+
+type postGetter interface {
+	GetPostByID(id int64) (*models.Post, error)
+}
+
+type commentGetter interface {
+	GetCommentByID(id int64) (*models.Comment, error)
+}
+
+// Targets resolves the posts and comments users can react to.
+type Targets struct {
+	Posts    postGetter
+	Comments commentGetter
+}
+
+// visible returns ErrNotExists for deleted, hidden or unapproved content.
+func (t Targets) visible(targetType string, id int64) error {
+	switch targetType {
+	case models.TargetPost:
+		post, err := t.Posts.GetPostByID(id)
+		if err != nil {
+			return err
+		}
+		if post.HiddenAt != nil {
+			return repository.ErrNotExists
+		}
+	case models.TargetComment:
+		comment, err := t.Comments.GetCommentByID(id)
+		if err != nil {
+			return err
+		}
+		if comment.Status != models.CommentStatusApproved {
+			return repository.ErrNotExists
+		}
+	default:
+		return repository.ErrNotExists
+	}
+	return nil
+}
+
+// Add reacts to a post or comment with one of the configured emoji. Reacting
+// twice with the same emoji is a no-op, so clients can safely retry.
+func Add(log logger.Logger, reactions reactionStore, targets Targets, targetType string, cfg config.ReactionsConfig) http.HandlerFunc {
+	validate := util.NewCustomValidator()
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.reaction.Add"))
+
+		var req reactionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Info("error decoding request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Info("error validation request", sl.Error(err))
+			util.ValidationError(w, r, err)
+			return
+		}
+
+		react(w, r, &log, reactions, targets, targetType, req.Emoji, cfg, reactions.AddReaction)
+	}
+}
+
+// Remove takes a reaction back. Removing a reaction that doesn't exist is a no-op.
+func Remove(log logger.Logger, reactions reactionStore, targets Targets, targetType string, cfg config.ReactionsConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.reaction.Remove"))
+
+		react(w, r, &log, reactions, targets, targetType, r.PathValue("emoji"), cfg, reactions.RemoveReaction)
+	}
+}
+
+func react(w http.ResponseWriter, r *http.Request, log logger.Logger, reactions reactionStore, targets Targets,
+	targetType, emoji string, cfg config.ReactionsConfig, apply func(*models.Reaction) (bool, error)) {
+	userID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+	if !ok {
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	targetID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Info("invalid path value", sl.Error(err))
+		util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	if !slices.Contains(cfg.Emoji, emoji) {
+		util.ErrorResponse(w, r, http.StatusBadRequest, "unsupported reaction")
+		return
+	}
+
+	if err := targets.visible(targetType, targetID); err != nil {
+		util.RepositoryError(w, r, log, err, "Not Found")
+		return
+	}
+
+	reaction := &models.Reaction{
+		UserID:     userID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Emoji:      emoji,
+	}
+	changed, err := apply(reaction)
+	if err != nil {
+		log.Error("error saving reaction", sl.Error(err), slog.Int64("target_id", targetID))
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	summaries, err := reactions.ReactionSummaries(targetType, []int64{targetID})
+	if err != nil {
+		log.Error("error getting reaction counts", sl.Error(err), slog.Int64("target_id", targetID))
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	summary := summaries[targetID]
+
+	resp := reactionResponse{
+		BaseResponse: response.BaseResponse{
+			Status: http.StatusOK,
+		},
+		TargetType: targetType,
+		TargetID:   targetID,
+		Emoji:      emoji,
+		Changed:    changed,
+		Reactions:  summary.CountMap(),
+	}
+	if summary != nil {
+		resp.Total = summary.Total
+	}
+
+	if changed {
+		log.Info("reaction changed", slog.String("target_type", targetType), slog.Int64("target_id", targetID))
+	}
+	if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+		log.Error("json writer error", sl.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,74 @@
+package revision
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/models"
+	"blog/internal/util"
+	"blog/internal/util/diff"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type diffResponse struct {
+	response.BaseResponse
+	From        int64  `json:"from"`
+	To          int64  `json:"to"`
+	TitleDiff   string `json:"title_diff"`
+	ContentDiff string `json:"content_diff"`
+}
+
+// Diff returns a unified diff between two revisions of a post: ?from={revision_id}&to={revision_id}.
+func Diff(log logger.Logger, postGetter postGetter, revisionGetter revisionGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.revision.Diff"))
+
+		post, ok := authorizedPost(&log, w, r, postGetter)
+		if !ok {
+			return
+		}
+
+		fromID, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		if err != nil {
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Invalid from parameter")
+			return
+		}
+		toID, err := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		if err != nil {
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Invalid to parameter")
+			return
+		}
+
+		revs := make([]*models.PostRevision, 0, 2)
+		for _, id := range []int64{fromID, toID} {
+			rev, err := revisionGetter.GetRevision(post.ID, id)
+			if err != nil {
+				util.RepositoryError(w, r, &log, err, "Revision Not Found")
+				return
+			}
+			revs = append(revs, rev)
+		}
+		from, to := revs[0], revs[1]
+
+		fromName := fmt.Sprintf("revision/%d", from.ID)
+		toName := fmt.Sprintf("revision/%d", to.ID)
+		resp := diffResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			From:        from.ID,
+			To:          to.ID,
+			TitleDiff:   diff.Unified(fromName, toName, from.Title, to.Title, diff.DefaultContext),
+			ContentDiff: diff.Unified(fromName, toName, from.Content, to.Content, diff.DefaultContext),
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
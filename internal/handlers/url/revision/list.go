@@ -0,0 +1,167 @@
+package revision
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type revisionInfo struct {
+	RevisionID int64     `json:"revision_id"`
+	PostID     int64     `json:"post_id"`
+	EditorID   int64     `json:"editor_id"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content,omitempty"`
+	Tags       []string  `json:"tags"` // null in revisions stored before tags were tracked
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type revisionListResponse struct {
+	response.BaseResponse
+	Data []revisionInfo `json:"data"`
+}
+
+type revisionResponse struct {
+	response.BaseResponse
+	Revision revisionInfo `json:"revision"`
+}
+
+type postGetter interface {
+	GetPostByID(id int64) (*models.Post, error)
+}
+
+type revisionLister interface {
+	ListRevisions(postID int64) ([]*models.PostRevision, error)
+}
+
+type revisionGetter interface {
+	GetRevision(postID, revisionID int64) (*models.PostRevision, error)
+}
+
+func List(log logger.Logger, postGetter postGetter, revisionLister revisionLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.revision.List"))
+
+		post, ok := authorizedPost(&log, w, r, postGetter)
+		if !ok {
+			return
+		}
+
+		revisions, err := revisionLister.ListRevisions(post.ID)
+		if err != nil {
+			log.Error("error listing revisions", sl.Error(err), slog.Int64("post_id", post.ID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		data := make([]revisionInfo, 0, len(revisions))
+		for _, rev := range revisions {
+			data = append(data, revisionInfo{
+				RevisionID: rev.ID,
+				PostID:     rev.PostID,
+				EditorID:   rev.EditorID,
+				Title:      rev.Title,
+				Tags:       rev.Tags,
+				CreatedAt:  rev.CreatedAt,
+			})
+		}
+
+		resp := revisionListResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			Data: data,
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+func Get(log logger.Logger, postGetter postGetter, revisionGetter revisionGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.revision.Get"))
+
+		post, ok := authorizedPost(&log, w, r, postGetter)
+		if !ok {
+			return
+		}
+
+		revisionID, err := strconv.ParseInt(r.PathValue("revision_id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value for revision id", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		rev, err := revisionGetter.GetRevision(post.ID, revisionID)
+		if err != nil {
+			util.RepositoryError(w, r, &log, err, "Revision Not Found")
+			return
+		}
+
+		resp := revisionResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			Revision: revisionInfo{
+				RevisionID: rev.ID,
+				PostID:     rev.PostID,
+				EditorID:   rev.EditorID,
+				Title:      rev.Title,
+				Content:    rev.Content,
+				Tags:       rev.Tags,
+				CreatedAt:  rev.CreatedAt,
+			},
+		}
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// authorizedPost loads the post from the path and checks that the caller is its author.
+// On failure the error response is already written.
+func authorizedPost(log logger.Logger, w http.ResponseWriter, r *http.Request, postGetter postGetter) (*models.Post, bool) {
+	userID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+	if !ok {
+		log.Error("user id not found in context or invalid type", slog.String("user_id_ctx_key", auth.UserIDCtxKey))
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return nil, false
+	}
+
+	postID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		log.Info("invalid path value for post id", sl.Error(err))
+		util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+		return nil, false
+	}
+
+	post, err := postGetter.GetPostByID(postID)
+	if err != nil {
+		util.RepositoryError(w, r, log, err, "Post Not Found")
+		return nil, false
+	}
+
+	if post.AuthorID != userID {
+		log.Info("forbidden: user is not the author",
+			slog.Int64("post_id", postID),
+			slog.Int64("post_author_id", post.AuthorID),
+			slog.Int64("user_id", userID))
+		util.ErrorResponse(w, r, http.StatusForbidden, "Forbidden")
+		return nil, false
+	}
+
+	return post, true
+}
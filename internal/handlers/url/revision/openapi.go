@@ -0,0 +1,73 @@
+package revision
+
+import (
+	"net/http"
+
+	"blog/internal/api/etag"
+	"blog/internal/api/openapi"
+)
+
+// Operations documents the revision routes, they are open to the author of
+// the post only.
+func Operations() []openapi.Operation {
+	notAuthor := openapi.Error(http.StatusForbidden, "not the author of the post")
+	return []openapi.Operation{
+		{
+			Pattern: "GET /api/post/{id}/revisions",
+			ID:      "listPostRevisions",
+			Summary: "List the earlier versions of a post",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "revisions, newest first", revisionListResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				notAuthor,
+				openapi.Error(http.StatusNotFound, "no such post"),
+			},
+		},
+		{
+			Pattern: "GET /api/post/{id}/revisions/diff",
+			ID:      "diffPostRevisions",
+			Summary: "Compare two revisions of a post",
+			Auth:    openapi.Required,
+			Params: []openapi.Param{
+				{Name: "from", In: "query", Required: true, Schema: openapi.Integer(), Description: "id of the older revision"},
+				{Name: "to", In: "query", Required: true, Schema: openapi.Integer(), Description: "id of the newer revision"},
+			},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "line diff of title and content", diffResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id, from or to"),
+				notAuthor,
+				openapi.Error(http.StatusNotFound, "no such post or revision"),
+			},
+		},
+		{
+			Pattern: "GET /api/post/{id}/revisions/{revision_id}",
+			ID:      "getPostRevision",
+			Summary: "Read a revision of a post",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "the revision", revisionResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				notAuthor,
+				openapi.Error(http.StatusNotFound, "no such post or revision"),
+			},
+		},
+		{
+			Pattern:     "POST /api/post/{id}/revisions/{revision_id}/restore",
+			ID:          "restorePostRevision",
+			Summary:     "Make a revision the current version of a post",
+			Description: "The current version is kept as a revision itself.",
+			Auth:        openapi.Required,
+			Params: []openapi.Param{
+				openapi.Header(etag.HeaderIfMatch, openapi.String(), "ETag of the version the restore is based on"),
+			},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusAccepted, "revision restored", restoreResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid id"),
+				notAuthor,
+				openapi.Error(http.StatusNotFound, "no such post or revision"),
+				openapi.Error(http.StatusPreconditionFailed, "the post was changed since the If-Match version"),
+			},
+		},
+	}
+}
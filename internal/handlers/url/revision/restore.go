@@ -0,0 +1,110 @@
+package revision
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"blog/internal/api/etag"
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/events"
+	"blog/internal/models"
+	"blog/internal/repository/redisrepo"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type restoreResponse struct {
+	response.BaseResponse
+	PostID       int64 `json:"post_id"`
+	RestoredFrom int64 `json:"restored_from"`
+}
+
+type postRestorer interface {
+	GetPostByID(id int64) (*models.Post, error)
+	UpdatePost(post *models.Post) error
+}
+
+type mentionSyncer interface {
+	Sync(targetType string, targetID int64, content string) error
+}
+
+type eventPublisher interface {
+	Publish(ctx context.Context, e events.Event)
+}
+
+// Restore writes the title, content and tags of an old revision back to the
+// post, revisions stored before tags were tracked leave the tags unchanged.
+// The restore itself is recorded as a new revision, so nothing is lost.
+func Restore(log logger.Logger, postRestorer postRestorer, revisionGetter revisionGetter, mentions mentionSyncer, publisher eventPublisher, rdb *redisrepo.RedisRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.revision.Restore"))
+
+		post, ok := authorizedPost(&log, w, r, postRestorer)
+		if !ok {
+			return
+		}
+
+		if !etag.MatchVersion(r.Header.Get(etag.HeaderIfMatch), post.Version) {
+			util.ErrorResponse(w, r, http.StatusPreconditionFailed, "Precondition Failed")
+			return
+		}
+
+		revisionID, err := strconv.ParseInt(r.PathValue("revision_id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value for revision id", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		rev, err := revisionGetter.GetRevision(post.ID, revisionID)
+		if err != nil {
+			util.RepositoryError(w, r, &log, err, "Revision Not Found")
+			return
+		}
+
+		restored := &models.Post{
+			ID:        post.ID,
+			Title:     rev.Title,
+			Content:   rev.Content,
+			Tags:      rev.Tags,
+			AuthorID:  post.AuthorID,
+			Version:   post.Version,
+			CreatedAt: post.CreatedAt,
+		}
+		if err := postRestorer.UpdatePost(restored); err != nil {
+			util.RepositoryError(w, r, &log, err, "the post was deleted or changed in the meantime")
+			return
+		}
+
+		if restored.Tags == nil {
+			restored.Tags = post.Tags
+		}
+
+		if err := rdb.Delete(r.Context(), redisrepo.PostKey(post.ID)); err != nil {
+			log.Error("failed to invalidate cached post", sl.Error(err), slog.Int64("post_id", post.ID))
+		}
+		if err := mentions.Sync(models.TargetPost, post.ID, restored.Content); err != nil {
+			log.Error("failed to link mentions", sl.Error(err), slog.Int64("post_id", post.ID))
+		}
+		publisher.Publish(r.Context(), events.PostUpdated{Post: restored})
+
+		resp := restoreResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusAccepted,
+			},
+			PostID:       post.ID,
+			RestoredFrom: rev.ID,
+		}
+
+		w.Header().Set(etag.HeaderETag, etag.Version(restored.Version))
+		log.Info("revision restored", slog.Int64("post_id", post.ID), slog.Int64("revision_id", rev.ID))
+		if err := jsonutil.WriteJSON(w, http.StatusAccepted, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package sitemap
+
+import (
+	"net/http"
+
+	"blog/internal/api/openapi"
+)
+
+// Operations documents the sitemap and robots.txt routes.
+func Operations() []openapi.Operation {
+	xml := &openapi.Schema{Type: "string", Description: "sitemaps.org XML document"}
+	return []openapi.Operation{
+		{
+			Pattern: "GET /sitemap.xml",
+			ID:      "getSitemap",
+			Summary: "The sitemap, an index of the parts when there are many posts",
+			Responses: []openapi.Response{
+				openapi.Content(http.StatusOK, "the sitemap", "application/xml", xml),
+				openapi.Empty(http.StatusNotModified, "If-None-Match or If-Modified-Since matched"),
+			},
+		},
+		{
+			Pattern: "GET /sitemaps/{name}",
+			ID:      "getSitemapPart",
+			Summary: "A part of the sitemap index",
+			Params:  []openapi.Param{openapi.Path("name", openapi.String(), "posts-{n}.xml, as listed in the index")},
+			Responses: []openapi.Response{
+				openapi.Content(http.StatusOK, "the part", "application/xml", xml),
+				openapi.Empty(http.StatusNotModified, "If-None-Match or If-Modified-Since matched"),
+				openapi.Error(http.StatusNotFound, "no such part"),
+			},
+		},
+		{
+			Pattern: "GET /robots.txt",
+			ID:      "getRobots",
+			Summary: "robots.txt pointing to the sitemap",
+			Responses: []openapi.Response{
+				openapi.Content(http.StatusOK, "robots.txt", "text/plain", openapi.String()),
+				openapi.Empty(http.StatusNotModified, "If-None-Match matched"),
+			},
+		},
+	}
+}
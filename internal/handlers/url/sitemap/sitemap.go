@@ -0,0 +1,90 @@
+package sitemap
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"blog/internal/api/httpcache"
+	"blog/internal/sitemap"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+const (
+	xmlContentType  = "application/xml; charset=utf-8"
+	textContentType = "text/plain; charset=utf-8"
+)
+
+type rootRenderer interface {
+	Root() (*sitemap.Document, error)
+}
+
+type partRenderer interface {
+	Part(n int) (*sitemap.Document, bool, error)
+}
+
+// Root serves sitemap.xml.
+func Root(log logger.Logger, sitemaps rootRenderer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.sitemap.Root"))
+
+		doc, err := sitemaps.Root()
+		if err != nil {
+			log.Error("error rendering sitemap", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		writeDocument(w, r, &log, doc)
+	}
+}
+
+// Part serves one of the sitemaps listed by the sitemap index,
+// /sitemaps/posts-{n}.xml.
+func Part(log logger.Logger, sitemaps partRenderer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.sitemap.Part"))
+
+		name, ok := strings.CutPrefix(r.PathValue("name"), "posts-")
+		if ok {
+			name, ok = strings.CutSuffix(name, ".xml")
+		}
+		n, err := strconv.Atoi(name)
+		if !ok || err != nil || n < 1 {
+			util.ErrorResponse(w, r, http.StatusNotFound, "Sitemap Not Found")
+			return
+		}
+
+		doc, found, err := sitemaps.Part(n)
+		if err != nil {
+			log.Error("error rendering sitemap", sl.Error(err), slog.Int("part", n))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if !found {
+			util.ErrorResponse(w, r, http.StatusNotFound, "Sitemap Not Found")
+			return
+		}
+		writeDocument(w, r, &log, doc)
+	}
+}
+
+// Robots serves robots.txt, it only changes with the config.
+func Robots(log logger.Logger, body []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.sitemap.Robots"))
+
+		if err := httpcache.Write(w, r, http.StatusOK, textContentType, body, httpcache.Validators{}); err != nil {
+			log.Error("failed to write robots.txt", sl.Error(err))
+		}
+	}
+}
+
+func writeDocument(w http.ResponseWriter, r *http.Request, log logger.Logger, doc *sitemap.Document) {
+	validators := httpcache.Validators{LastModified: doc.LastMod}
+	if err := httpcache.Write(w, r, http.StatusOK, xmlContentType, doc.Body, validators); err != nil {
+		log.Error("failed to write sitemap", sl.Error(err))
+	}
+}
@@ -0,0 +1,51 @@
+package stream
+
+import (
+	"net/http"
+	"strconv"
+
+	"blog/internal/api/openapi"
+	"blog/internal/config"
+)
+
+// Operations documents the server-sent event streams.
+func Operations(cfg config.StreamConfig) []openapi.Operation {
+	params := []openapi.Param{
+		openapi.Header("Last-Event-ID", openapi.Integer(), "id of the last event received, the missed events are sent first"),
+		openapi.Query("last_event_id", openapi.Integer(), "the same, for clients that can't set headers"),
+	}
+	events := &openapi.Schema{
+		Type:        "string",
+		Description: "text/event-stream, every event has an id, a type in event and JSON in data. reset means events were missed and the page should be reloaded.",
+	}
+	busy := openapi.Error(http.StatusServiceUnavailable,
+		"more than "+strconv.Itoa(cfg.MaxClients)+" open streams, try again after Retry-After")
+
+	return []openapi.Operation{
+		{
+			Pattern:     "GET /api/posts/stream",
+			ID:          "streamPosts",
+			Summary:     "Follow new, edited and deleted posts",
+			Description: "Event types post.created, post.updated, post.deleted and reset.",
+			Params:      params,
+			Responses: []openapi.Response{
+				openapi.Content(http.StatusOK, "the stream", "text/event-stream", events),
+				openapi.Error(http.StatusBadRequest, "invalid Last-Event-ID"),
+				busy,
+			},
+		},
+		{
+			Pattern:     "GET /api/post/{id}/comments/stream",
+			ID:          "streamPostComments",
+			Summary:     "Follow new and edited comments of a post",
+			Description: "Event types comment.created, comment.updated and reset.",
+			Params:      params,
+			Responses: []openapi.Response{
+				openapi.Content(http.StatusOK, "the stream", "text/event-stream", events),
+				openapi.Error(http.StatusBadRequest, "invalid id or Last-Event-ID"),
+				openapi.Error(http.StatusNotFound, "no such post"),
+				busy,
+			},
+		},
+	}
+}
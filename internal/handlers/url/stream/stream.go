@@ -0,0 +1,154 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/models"
+	"blog/internal/stream"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type listener interface {
+	Listen(topic string, lastID int64) (*stream.Listener, []stream.Event, bool, error)
+	Unlisten(l *stream.Listener)
+}
+
+type postsGetter interface {
+	GetPostsByIDs(ids []int64) (map[int64]*models.Post, error)
+}
+
+// Posts streams new, edited and deleted posts as server-sent events.
+func Posts(log logger.Logger, hub listener, cfg config.StreamConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.stream.Posts"))
+		serve(w, r, &log, hub, stream.PostsTopic, cfg)
+	}
+}
+
+// Comments streams new and edited comments of a visible post as
+// server-sent events.
+func Comments(log logger.Logger, posts postsGetter, hub listener, cfg config.StreamConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.stream.Comments"))
+
+		postID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+		found, err := posts.GetPostsByIDs([]int64{postID})
+		if err != nil {
+			log.Error("error getting post", sl.Error(err), slog.Int64("post_id", postID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if _, ok := found[postID]; !ok {
+			util.ErrorResponse(w, r, http.StatusNotFound, "Post Not Found")
+			return
+		}
+		serve(w, r, &log, hub, stream.CommentsTopic(postID), cfg)
+	}
+}
+
+// serve writes the events of topic until the client goes away, falls
+// behind or the server shuts down. The client resumes with the id of the
+// last event it got in the Last-Event-ID header, or ?last_event_id= where
+// the header can't be set. A "reset" event means events were missed and
+// what the client shows should be loaded again.
+func serve(w http.ResponseWriter, r *http.Request, log logger.Logger, hub listener, topic string, cfg config.StreamConfig) {
+	lastID := int64(0)
+	rawID := r.Header.Get("Last-Event-ID")
+	if rawID == "" {
+		rawID = r.URL.Query().Get("last_event_id")
+	}
+	if rawID != "" {
+		id, err := strconv.ParseInt(rawID, 10, 64)
+		if err != nil || id < 0 {
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+		lastID = id
+	}
+
+	l, missed, reset, err := hub.Listen(topic, lastID)
+	if err != nil {
+		if errors.Is(err, stream.ErrTooManyClients) || errors.Is(err, stream.ErrClosed) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(cfg.Retry.Seconds())))
+			util.ErrorResponse(w, r, http.StatusServiceUnavailable, "Too many streams, try again later")
+			return
+		}
+		log.Error("error listening to stream", sl.Error(err))
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	defer hub.Unlisten(l)
+
+	// the server timeouts are meant for ordinary requests: without lifting
+	// the read deadline the request context is cancelled once it passes,
+	// and the write deadline is moved forward for every event instead
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		log.Warn("can't lift read deadline, the stream ends with it", sl.Error(err))
+	}
+	write := func(msg string) bool {
+		if err := rc.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return false
+		}
+		if _, err := fmt.Fprint(w, msg); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	opening := fmt.Sprintf("retry: %d\n\n", cfg.Retry.Milliseconds())
+	if reset {
+		opening += "event: reset\ndata: {}\n\n"
+	}
+	for _, ev := range missed {
+		opening += format(ev)
+	}
+	if !write(opening) {
+		return
+	}
+	log.Debug("stream opened", slog.String("topic", topic), slog.Int64("last_event_id", lastID),
+		slog.Int("replayed", len(missed)), slog.Bool("reset", reset))
+
+	heartbeat := time.NewTicker(cfg.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if !write(": ping\n\n") {
+				return
+			}
+		case ev, ok := <-l.C:
+			if !ok {
+				// dropped, the client reconnects and resumes
+				return
+			}
+			if !write(format(ev)) {
+				return
+			}
+		}
+	}
+}
+
+func format(ev stream.Event) string {
+	return fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, ev.Data)
+}
@@ -0,0 +1,55 @@
+package syndication
+
+import (
+	"net/http"
+	"strings"
+
+	"blog/internal/api/openapi"
+	"blog/internal/syndication"
+)
+
+// Operations documents the site, author and tag feeds of the given formats.
+func Operations(formats ...syndication.Format) []openapi.Operation {
+	var ops []openapi.Operation
+	for _, format := range formats {
+		mediaType, _, _ := strings.Cut(format.ContentType(), ";")
+		feed := &openapi.Schema{Type: "string", Description: string(format) + " feed document"}
+		if format == syndication.JSON {
+			feed = &openapi.Schema{Type: "object", Description: "JSON Feed 1.1 document"}
+		}
+		ops = append(ops,
+			openapi.Operation{
+				Pattern: "GET /feed." + string(format),
+				ID:      "getSiteFeed" + strings.ToUpper(string(format)),
+				Summary: "Newest posts of the blog as " + string(format) + " feed",
+				Responses: []openapi.Response{
+					openapi.Content(http.StatusOK, "the feed", mediaType, feed),
+					openapi.Empty(http.StatusNotModified, "If-None-Match or If-Modified-Since matched"),
+				},
+			},
+			openapi.Operation{
+				Pattern: "GET /user/{id}/feed." + string(format),
+				ID:      "getUserFeed" + strings.ToUpper(string(format)),
+				Summary: "Newest posts of a user as " + string(format) + " feed",
+				Responses: []openapi.Response{
+					openapi.Content(http.StatusOK, "the feed", mediaType, feed),
+					openapi.Empty(http.StatusNotModified, "If-None-Match or If-Modified-Since matched"),
+					openapi.Error(http.StatusBadRequest, "invalid id"),
+					openapi.Error(http.StatusNotFound, "no such user"),
+				},
+			},
+			openapi.Operation{
+				Pattern: "GET /tag/{tag}/feed." + string(format),
+				ID:      "getTagFeed" + strings.ToUpper(string(format)),
+				Summary: "Newest posts with a tag as " + string(format) + " feed",
+				Responses: []openapi.Response{
+					openapi.Content(http.StatusOK, "the feed", mediaType, feed),
+					openapi.Empty(http.StatusNotModified, "If-None-Match or If-Modified-Since matched"),
+					openapi.Error(http.StatusBadRequest, "invalid tag"),
+					openapi.Error(http.StatusNotFound, "no visible post has the tag"),
+				},
+			},
+		)
+	}
+	return ops
+}
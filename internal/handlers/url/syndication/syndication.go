@@ -0,0 +1,217 @@
+package syndication
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog/internal/api/httpcache"
+	"blog/internal/config"
+	"blog/internal/mention"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/syndication"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+const summaryLength = 280
+
+// siteCreated dates the tag URIs of the site and tag feeds, it must never change.
+var siteCreated = time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+// tagRe matches a lower cased post tag, see util.NewCustomValidator.
+var tagRe = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type postsLister interface {
+	ListPosts(page repository.Page) ([]*models.Post, error)
+}
+
+type authorPostsLister interface {
+	GetPostsByAuthor(authorID int64, page repository.Page) ([]*models.Post, error)
+}
+
+type tagPostsLister interface {
+	GetPostsByTag(tag string, page repository.Page) ([]*models.Post, error)
+}
+
+type usersGetter interface {
+	GetUserByID(id int64) (*models.User, error)
+	GetUsersByIDs(ids []int64) (map[int64]*models.User, error)
+}
+
+type mentionLister interface {
+	Mentions(targetType string, ids []int64) (map[int64][]*models.Mention, error)
+}
+
+// Site serves the newest posts of the whole blog as a feed of the given format.
+func Site(log logger.Logger, posts postsLister, users usersGetter, mentions mentionLister, cfg config.SyndicationConfig, format syndication.Format) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.syndication.Site"), slog.String("format", string(format)))
+
+		list, err := posts.ListPosts(repository.Page{Limit: cfg.Items})
+		if err != nil {
+			log.Error("error listing posts", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		feed := &syndication.Feed{
+			ID:          syndication.TagURI(cfg.BaseURL, siteCreated, "feed"),
+			Title:       cfg.Title,
+			Description: cfg.Description,
+			HomeURL:     absURL(cfg.BaseURL, "/api/posts"),
+			FeedURL:     absURL(cfg.BaseURL, "/feed."+string(format)),
+		}
+		writeFeed(w, r, &log, feed, list, users, mentions, cfg, format)
+	}
+}
+
+// Author serves the newest posts of one user as a feed of the given format.
+func Author(log logger.Logger, posts authorPostsLister, users usersGetter, mentions mentionLister, cfg config.SyndicationConfig, format syndication.Format) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.syndication.Author"), slog.String("format", string(format)))
+
+		authorID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			log.Info("invalid path value", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		author, err := users.GetUserByID(authorID)
+		if err != nil {
+			util.RepositoryError(w, r, &log, err, "User Not Found")
+			return
+		}
+
+		list, err := posts.GetPostsByAuthor(authorID, repository.Page{Limit: cfg.Items})
+		if err != nil {
+			log.Error("error listing posts", sl.Error(err), slog.Int64("user_id", authorID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		feed := &syndication.Feed{
+			ID:          syndication.TagURI(cfg.BaseURL, author.CreatedAt, fmt.Sprintf("user/%d/feed", author.ID)),
+			Title:       fmt.Sprintf("%s: %s", cfg.Title, author.Username),
+			Description: fmt.Sprintf("posts by %s", author.Username),
+			HomeURL:     absURL(cfg.BaseURL, fmt.Sprintf("/api/user/%d/posts", author.ID)),
+			FeedURL:     absURL(cfg.BaseURL, fmt.Sprintf("/user/%d/feed.%s", author.ID, format)),
+			Updated:     author.CreatedAt,
+		}
+		writeFeed(w, r, &log, feed, list, users, mentions, cfg, format)
+	}
+}
+
+// Tag serves the newest posts with a tag as a feed of the given format.
+// Tags nothing visible is tagged with are not found.
+func Tag(log logger.Logger, posts tagPostsLister, users usersGetter, mentions mentionLister, cfg config.SyndicationConfig, format syndication.Format) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.syndication.Tag"), slog.String("format", string(format)))
+
+		tag := strings.ToLower(r.PathValue("tag"))
+		if !tagRe.MatchString(tag) {
+			log.Info("invalid path value", slog.String("tag", tag))
+			util.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		list, err := posts.GetPostsByTag(tag, repository.Page{Limit: cfg.Items})
+		if err != nil {
+			log.Error("error listing posts", sl.Error(err), slog.String("tag", tag))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if len(list) == 0 {
+			util.ErrorResponse(w, r, http.StatusNotFound, "Tag Not Found")
+			return
+		}
+
+		feed := &syndication.Feed{
+			ID:          syndication.TagURI(cfg.BaseURL, siteCreated, "tag/"+tag+"/feed"),
+			Title:       fmt.Sprintf("%s: #%s", cfg.Title, tag),
+			Description: fmt.Sprintf("posts tagged %s", tag),
+			HomeURL:     absURL(cfg.BaseURL, "/api/posts"),
+			FeedURL:     absURL(cfg.BaseURL, fmt.Sprintf("/tag/%s/feed.%s", tag, format)),
+		}
+		writeFeed(w, r, &log, feed, list, users, mentions, cfg, format)
+	}
+}
+
+// writeFeed fills the feed with posts and writes it. Last-Modified is the
+// newest post update; a deleted post changes the body and so the ETag.
+func writeFeed(w http.ResponseWriter, r *http.Request, log logger.Logger, feed *syndication.Feed, posts []*models.Post, users usersGetter, mentions mentionLister, cfg config.SyndicationConfig, format syndication.Format) {
+	postIDs := make([]int64, 0, len(posts))
+	authorIDs := make([]int64, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+		authorIDs = append(authorIDs, post.AuthorID)
+	}
+
+	authors, err := users.GetUsersByIDs(authorIDs)
+	if err != nil {
+		log.Error("error getting authors", sl.Error(err))
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	mentionsByPost, err := mentions.Mentions(models.TargetPost, postIDs)
+	if err != nil {
+		log.Error("error getting mentions", sl.Error(err))
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	for _, post := range posts {
+		if post.UpdatedAt.After(feed.Updated) {
+			feed.Updated = post.UpdatedAt
+		}
+
+		postMentions := mentionsByPost[post.ID]
+		for _, m := range postMentions {
+			m.URL = absURL(cfg.BaseURL, m.URL)
+		}
+
+		item := syndication.Item{
+			ID:          syndication.TagURI(cfg.BaseURL, post.CreatedAt, fmt.Sprintf("post/%d", post.ID)),
+			URL:         absURL(cfg.BaseURL, fmt.Sprintf("/api/post/%d", post.ID)),
+			Title:       post.Title,
+			ContentHTML: mention.HTML(post.Content, postMentions),
+			Summary:     syndication.Summary(post.Content, summaryLength),
+			Tags:        post.Tags,
+			Published:   post.CreatedAt,
+			Updated:     post.UpdatedAt,
+		}
+		if author, ok := authors[post.AuthorID]; ok {
+			item.AuthorName = author.Username
+			item.AuthorURL = absURL(cfg.BaseURL, fmt.Sprintf("/api/user/%d/posts", author.ID))
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	body, err := syndication.Render(feed, format)
+	if err != nil {
+		log.Error("error rendering feed", sl.Error(err))
+		util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	validators := httpcache.Validators{LastModified: feed.Updated}
+	if err := httpcache.Write(w, r, http.StatusOK, format.ContentType(), body, validators); err != nil {
+		log.Error("failed to write feed", sl.Error(err))
+	}
+}
+
+// absURL resolves a path against the public origin of the blog. Feed
+// readers resolve relative links inconsistently, so feeds only use absolute ones.
+func absURL(baseURL, path string) string {
+	if !strings.HasPrefix(path, "/") {
+		return path
+	}
+	return strings.TrimSuffix(baseURL, "/") + path
+}
@@ -0,0 +1,102 @@
+package trash
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/middlewares/auth"
+	"blog/internal/models"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type postInfo struct {
+	PostID    int64     `json:"post_id"`
+	Title     string    `json:"title"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+type commentInfo struct {
+	CommentID int64     `json:"comment_id"`
+	PostID    int64     `json:"post_id"`
+	Content   string    `json:"content"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+type listResponse struct {
+	response.BaseResponse
+	Posts    []postInfo    `json:"posts"`
+	Comments []commentInfo `json:"comments"`
+}
+
+type deletedPostsGetter interface {
+	ListDeletedPosts(authorID int64) ([]*models.Post, error)
+}
+
+type deletedCommentsGetter interface {
+	ListDeletedComments(authorID int64) ([]*models.Comment, error)
+}
+
+// List shows the caller's trashed posts and comments, with the time each will be purged.
+func List(log logger.Logger, postsGetter deletedPostsGetter, commentsGetter deletedCommentsGetter, retention time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.trash.List"))
+
+		userID, ok := r.Context().Value(auth.UserIDCtxKey).(int64)
+		if !ok {
+			log.Error("user id not found in context or invalid type", slog.String("user_id_ctx_key", auth.UserIDCtxKey))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		posts, err := postsGetter.ListDeletedPosts(userID)
+		if err != nil {
+			log.Error("error listing deleted posts", sl.Error(err), slog.Int64("user_id", userID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		comments, err := commentsGetter.ListDeletedComments(userID)
+		if err != nil {
+			log.Error("error listing deleted comments", sl.Error(err), slog.Int64("user_id", userID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		resp := listResponse{
+			BaseResponse: response.BaseResponse{
+				Status: http.StatusOK,
+			},
+			Posts:    make([]postInfo, 0, len(posts)),
+			Comments: make([]commentInfo, 0, len(comments)),
+		}
+		for _, post := range posts {
+			resp.Posts = append(resp.Posts, postInfo{
+				PostID:    post.ID,
+				Title:     post.Title,
+				DeletedAt: *post.DeletedAt,
+				PurgeAt:   post.DeletedAt.Add(retention),
+			})
+		}
+		for _, comment := range comments {
+			resp.Comments = append(resp.Comments, commentInfo{
+				CommentID: comment.ID,
+				PostID:    comment.PostID,
+				Content:   comment.Content,
+				DeletedAt: *comment.DeletedAt,
+				PurgeAt:   comment.DeletedAt.Add(retention),
+			})
+		}
+
+		if err := jsonutil.WriteJSON(w, http.StatusOK, resp); err != nil {
+			log.Error("failed to write JSON response", sl.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
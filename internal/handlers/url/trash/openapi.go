@@ -0,0 +1,22 @@
+package trash
+
+import (
+	"net/http"
+
+	"blog/internal/api/openapi"
+)
+
+// Operations documents the trash route.
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Pattern: "GET /api/me/trash",
+			ID:      "listTrash",
+			Summary: "List the trashed posts and comments of the caller",
+			Auth:    openapi.Required,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusOK, "trashed content with the time it will be purged", listResponse{}),
+			},
+		},
+	}
+}
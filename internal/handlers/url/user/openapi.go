@@ -0,0 +1,37 @@
+package user
+
+import (
+	"net/http"
+
+	"blog/internal/api/openapi"
+)
+
+// Operations documents the account routes.
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Pattern: "POST /api/user/signup",
+			ID:      "signUp",
+			Summary: "Create an account",
+			Body:    SignUpRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusCreated, "account created", SignUpResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid username, email or password"),
+				openapi.Error(http.StatusConflict, "email or username taken"),
+			},
+		},
+		{
+			Pattern: "POST /api/user/signin",
+			ID:      "signIn",
+			Summary: "Get a token",
+			Body:    SignInRequest{},
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusAccepted, "token for the Authorization header", SignInResponse{}),
+				openapi.Error(http.StatusBadRequest, "invalid email or password"),
+				openapi.Error(http.StatusUnauthorized, "wrong password"),
+				openapi.Error(http.StatusForbidden, "the account is suspended or banned"),
+				openapi.Error(http.StatusNotFound, "no account with that email"),
+			},
+		},
+	}
+}
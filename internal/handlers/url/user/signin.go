@@ -6,11 +6,10 @@ import (
 	"log/slog"
 	"net/http"
 
-	"github.com/go-playground/validator/v10"
-
 	"blog/internal/api/jsonutil"
 	"blog/internal/api/response"
 	"blog/internal/handlers/auth"
+	authmw "blog/internal/middlewares/auth"
 	"blog/internal/models"
 	"blog/internal/repository"
 	"blog/internal/util"
@@ -33,27 +32,30 @@ type UserGetter interface {
 	GetUserByEmail(email string) (*models.User, error)
 }
 
-func SignInHandler(log logger.Logger, userGetter UserGetter) http.HandlerFunc {
+type SanctionChecker interface {
+	ActiveSanction(userID int64, kinds ...string) (*models.Sanction, error)
+}
+
+func SignInHandler(log logger.Logger, userGetter UserGetter, sanctions SanctionChecker) http.HandlerFunc {
 	cstValidator := util.NewCustomValidator()
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := log.With(slog.String("fn", "handlers.url.user.SignIn"))
 
 		if r.Method != http.MethodPost {
-			util.ErrorResponse(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			util.ErrorResponse(w, r, http.StatusMethodNotAllowed, "Method Not Allowed")
 			return
 		}
 
 		var req SignInRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			log.Info("decoding error", sl.Error(err))
-			util.ErrorResponse(w, http.StatusBadRequest, "Invalid Request")
+			util.ValidationError(w, r, err)
 			return
 		}
 
 		if err := cstValidator.Struct(req); err != nil {
-			validationErrors := err.(validator.ValidationErrors)
-			log.Info("validation failed", sl.Error(validationErrors))
-			util.ErrorResponse(w, http.StatusBadRequest, "Validation Error")
+			log.Info("validation failed", sl.Error(err))
+			util.ValidationError(w, r, err)
 			return
 		}
 
@@ -61,25 +63,37 @@ func SignInHandler(log logger.Logger, userGetter UserGetter) http.HandlerFunc {
 		if err != nil {
 			if errors.Is(err, repository.ErrNotExists) {
 				log.Info("user not found", slog.String("email", req.Email))
-				util.ErrorResponse(w, http.StatusNotFound, "User Not Found")
+				util.ErrorResponse(w, r, http.StatusNotFound, "User Not Found")
 				return
 			}
 
 			log.Error("get user error", sl.Error(err))
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
 
 		if !util.CheckPasswordHash(req.Password, user.Password) {
 			log.Info("invalid password", slog.String("email", req.Email))
-			util.ErrorResponse(w, http.StatusUnauthorized, "Invalid Credentials")
+			util.ErrorResponse(w, r, http.StatusUnauthorized, "Invalid Credentials")
+			return
+		}
+
+		sanction, err := authmw.Blocked(sanctions, user.ID)
+		if err != nil {
+			log.Error("error checking sanctions", sl.Error(err), slog.Int64("user_id", user.ID))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if sanction != nil {
+			log.Info("blocked user tried to sign in", slog.Int64("user_id", user.ID), slog.String("kind", sanction.Kind))
+			util.ErrorResponse(w, r, http.StatusForbidden, authmw.SanctionMessage(sanction))
 			return
 		}
 
 		token, err := auth.GenerateToken(user.ID, user.Username, user.Password)
 		if err != nil {
 			log.Error("error generation token", sl.Error(err), slog.Int64("user_id", user.ID))
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
 
@@ -1,19 +1,17 @@
 package user
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"log/slog"
 	"net/http"
 	// "time"
 
-	"github.com/go-playground/validator/v10"
-
 	"blog/internal/api/jsonutil"
 	"blog/internal/api/response"
+	"blog/internal/events"
 	// "blog/internal/handlers/url/token"
 	"blog/internal/models"
-	"blog/internal/repository"
 	"blog/internal/util"
 	"blog/internal/util/logger"
 	"blog/internal/util/logger/sl"
@@ -39,44 +37,37 @@ type UserCreator interface {
 	CreateUser(user *models.User) (int64, error)
 }
 
+type eventPublisher interface {
+	Publish(ctx context.Context, e events.Event)
+}
+
 func CreateUser(user *models.User, userCreator UserCreator) (int64, error) {
 	hash := util.GeneratePasswordHash(user.Password)
 	user.Password = hash
 
-	userID, err := userCreator.CreateUser(user)
-	if err != nil {
-		if errors.Is(err, repository.ErrEmailAlreadyExists) {
-			return 0, err
-		} else if errors.Is(err, repository.ErrUsernameAlreadyExists) {
-			return 0, err
-		}
-		return 0, err
-	}
-
-	return userID, nil
+	return userCreator.CreateUser(user)
 }
 
-func SignUpHandler(log logger.Logger, userCreator UserCreator) http.HandlerFunc {
+func SignUpHandler(log logger.Logger, userCreator UserCreator, publisher eventPublisher) http.HandlerFunc {
 	cstValidator := util.NewCustomValidator()
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := log.With(slog.String("fn", "handlers.url.user.SignUp"))
 
 		if r.Method != http.MethodPost {
-			util.ErrorResponse(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			util.ErrorResponse(w, r, http.StatusMethodNotAllowed, "Method Not Allowed")
 			return
 		}
 
 		var req SignUpRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			log.Info("error decoding request", sl.Error(err))
-			util.ErrorResponse(w, http.StatusBadRequest, "Invalid Request")
+			util.ValidationError(w, r, err)
 			return
 		}
 
 		if err := cstValidator.Struct(req); err != nil {
-			validationErrors := err.(validator.ValidationErrors)
-			log.Info("validation failed", sl.Error(validationErrors))
-			util.ErrorResponse(w, http.StatusBadRequest, "Validation Error")
+			log.Info("validation failed", sl.Error(err))
+			util.ValidationError(w, r, err)
 			return
 		}
 
@@ -88,20 +79,11 @@ func SignUpHandler(log logger.Logger, userCreator UserCreator) http.HandlerFunc
 
 		userID, err := CreateUser(user, userCreator)
 		if err != nil {
-			if errors.Is(err, repository.ErrEmailAlreadyExists) {
-				log.Info("email exists", slog.String("email", req.Email))
-				util.ErrorResponse(w, http.StatusConflict, "Email Already Exists")
-				return
-			} else if errors.Is(err, repository.ErrUsernameAlreadyExists) {
-				log.Info("username exists", slog.String("username", req.Username))
-				util.ErrorResponse(w, http.StatusConflict, "Username Already Exists")
-				return
-			}
-
-			log.Error("create user error", sl.Error(err))
-			util.ErrorResponse(w, http.StatusInternalServerError, "Internal Server Error")
+			// the sentinel says whether the email or the username is taken
+			util.RepositoryError(w, r, &log, err, err.Error())
 			return
 		}
+		publisher.Publish(r.Context(), events.UserCreated{UserID: userID})
 
 		resp := SignUpResponse{
 			BaseResponse: response.BaseResponse{
@@ -0,0 +1,33 @@
+package webmention
+
+import (
+	"net/http"
+
+	"blog/internal/api/openapi"
+	"blog/internal/api/response"
+)
+
+// Operations documents the webmention endpoint.
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Pattern:     "POST /webmention",
+			ID:          "receiveWebmention",
+			Summary:     "Send a webmention for a post",
+			Description: "The mention is queued, source is fetched and checked for a link to target in the background.",
+			Body: &openapi.Schema{
+				Type: "object",
+				Properties: map[string]*openapi.Schema{
+					"source": {Type: "string", Format: "uri", Description: "URL of the page mentioning the post"},
+					"target": {Type: "string", Format: "uri", Description: "URL of the post, /api/post/{id} or /ap/posts/{id}"},
+				},
+				Required: []string{"source", "target"},
+			},
+			BodyType: openapi.ContentForm,
+			Responses: []openapi.Response{
+				openapi.JSON(http.StatusAccepted, "queued for verification", response.BaseResponse{}),
+				openapi.Error(http.StatusBadRequest, "source not http(s), target not a post of this blog"),
+			},
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package webmention
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"blog/internal/api/jsonutil"
+	"blog/internal/api/response"
+	"blog/internal/util"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+	webmentions "blog/internal/webmention"
+)
+
+const maxFormBody = 8 << 10
+
+type receiver interface {
+	Receive(source, target string) error
+}
+
+// Receive accepts a webmention, source and target as form values. It's
+// only queued here, source is fetched and checked for a link to target by
+// the webmention job.
+func Receive(log logger.Logger, receiver receiver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(slog.String("fn", "handlers.url.webmention.Receive"))
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxFormBody)
+		if err := r.ParseForm(); err != nil {
+			util.ErrorResponse(w, r, http.StatusBadRequest, "invalid form body")
+			return
+		}
+		source, target := r.PostForm.Get("source"), r.PostForm.Get("target")
+
+		if err := receiver.Receive(source, target); err != nil {
+			if errors.Is(err, webmentions.ErrInvalid) {
+				log.Info("rejected webmention", sl.Error(err), slog.String("source", source), slog.String("target", target))
+				util.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+				return
+			}
+			log.Error("error queueing webmention", sl.Error(err))
+			util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		log.Info("webmention queued", slog.String("source", source), slog.String("target", target))
+		resp := response.BaseResponse{Status: http.StatusAccepted}
+		if err := jsonutil.WriteJSON(w, http.StatusAccepted, resp); err != nil {
+			log.Error("json writer error", sl.Error(err))
+		}
+	}
+}
+
+// Advertise sets the Link header that points senders to the endpoint.
+func Advertise(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	link := "<" + endpoint + `>; rel="webmention"`
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", link)
+		next(w, r)
+	}
+}
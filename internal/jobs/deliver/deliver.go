@@ -0,0 +1,86 @@
+package deliver
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/federation"
+	"blog/internal/jobs/retry"
+	"blog/internal/models"
+	"blog/internal/util/logger"
+)
+
+type queue interface {
+	DueDeliveries(now time.Time, limit int) ([]*models.Delivery, error)
+	RetryDelivery(id int64, next time.Time, lastError string) error
+	DeleteDelivery(id int64) error
+}
+
+type sender interface {
+	Deliver(ctx context.Context, userID int64, inbox string, activity []byte) error
+}
+
+// Job posts queued ActivityPub activities to remote inboxes. Failed
+// deliveries are retried with exponential backoff, rejected ones and those
+// failing max_attempts times are dropped.
+type Job struct {
+	*retry.Runner[delivery]
+}
+
+// New returns the job, wake signals that new deliveries were queued.
+func New(log logger.Logger, queue queue, sender sender, wake <-chan struct{}, cfg config.FederationConfig) *Job {
+	return &Job{retry.New(log, deliveries{queue}, activities{sender}, wake, retry.Config{
+		Name:         "deliver",
+		Interval:     cfg.DeliveryInterval,
+		MaxAttempts:  cfg.MaxAttempts,
+		RetryBackoff: cfg.RetryBackoff,
+		Permanent:    federation.ErrRejected,
+	})}
+}
+
+type delivery struct {
+	*models.Delivery
+}
+
+func (d delivery) Failures() int {
+	return d.Attempts
+}
+
+func (d delivery) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int64("delivery_id", d.ID), slog.String("inbox", d.Inbox), slog.Int("attempts", d.Attempts))
+}
+
+type deliveries struct {
+	queue queue
+}
+
+func (q deliveries) Due(now time.Time, limit int) ([]delivery, error) {
+	due, err := q.queue.DueDeliveries(now, limit)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]delivery, 0, len(due))
+	for _, d := range due {
+		items = append(items, delivery{d})
+	}
+	return items, nil
+}
+
+func (q deliveries) Retry(d delivery, next time.Time, err error) error {
+	return q.queue.RetryDelivery(d.ID, next, err.Error())
+}
+
+// Finish drops the delivery, whether it succeeded or not.
+func (q deliveries) Finish(d delivery, err error) error {
+	return q.queue.DeleteDelivery(d.ID)
+}
+
+type activities struct {
+	sender sender
+}
+
+func (a activities) Process(ctx context.Context, d delivery) error {
+	return a.sender.Deliver(ctx, d.UserID, d.Inbox, d.Activity)
+}
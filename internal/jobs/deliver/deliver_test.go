@@ -0,0 +1,123 @@
+package deliver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/events"
+	"blog/internal/federation"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/repository/sqliterepo"
+	"blog/internal/util/logger"
+)
+
+// newTestJob returns a job with one delivery queued for an inbox on
+// localhost that answers with status, and the number of requests it got.
+func newTestJob(t *testing.T, status int) (*Job, repository.FederationRepository, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	inbox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if _, err := federation.ParseSignature(r, body); err != nil {
+			t.Errorf("delivery: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(inbox.Close)
+
+	log := logger.NewLogger(nil)
+	db, err := sqliterepo.New("file:"+filepath.Join(t.TempDir(), "blog.db")+"?_foreign_keys=on", log)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	repo := sqliterepo.NewSQLiteRepository(log, db)
+	for _, init := range []func() error{
+		repo.User().InitUserDatabase,
+		repo.Post().InitPostDatabase,
+		repo.Federation().InitFederationDatabase,
+	} {
+		if err := init(); err != nil {
+			t.Fatalf("init database: %v", err)
+		}
+	}
+	userID, err := repo.User().CreateUser(&models.User{Username: "alice", Email: "alice@example.com", Password: "x"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	cfg := config.FederationConfig{
+		AllowInsecure: true,
+		Timeout:       5 * time.Second,
+		MaxAttempts:   3,
+		RetryBackoff:  time.Hour,
+	}
+	fed, err := federation.New(log, repo.Federation(), repo.User(), repo.Post(), nil, nil, nil, nil,
+		events.NewBus(log), "https://blog.example", cfg, config.CommentsConfig{})
+	if err != nil {
+		t.Fatalf("new service: %v", err)
+	}
+	if err := repo.Federation().EnqueueDeliveries(userID, []string{inbox.URL + "/inbox"}, []byte(`{"type":"Create"}`)); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	return New(log, repo.Federation(), fed, nil, cfg), repo.Federation(), &requests
+}
+
+// queued returns the deliveries left in the queue, due or not.
+func queued(t *testing.T, queue repository.FederationRepository) []*models.Delivery {
+	t.Helper()
+	due, err := queue.DueDeliveries(time.Now().Add(365*24*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("due deliveries: %v", err)
+	}
+	return due
+}
+
+func TestDeliveryOutcome(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		retried bool
+	}{
+		{"accepted", http.StatusAccepted, false},
+		{"gone", http.StatusGone, false},
+		{"unauthorized", http.StatusUnauthorized, false},
+		{"too many requests", http.StatusTooManyRequests, true},
+		{"server error", http.StatusBadGateway, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, queue, requests := newTestJob(t, tt.status)
+			start := time.Now()
+			job.Drain(context.Background())
+
+			if n := requests.Load(); n != 1 {
+				t.Fatalf("inbox got %d requests, want 1", n)
+			}
+			left := queued(t, queue)
+			if retried := len(left) == 1; retried != tt.retried {
+				t.Fatalf("retried = %v, want %v (queue %+v)", retried, tt.retried, left)
+			}
+			if !tt.retried {
+				return
+			}
+			if left[0].Attempts != 1 || left[0].LastError == "" {
+				t.Fatalf("delivery %+v, want one failed attempt recorded", left[0])
+			}
+			// retry_backoff is an hour
+			if diff := left[0].NextAttemptAt.Sub(start.Add(time.Hour)); diff < -2*time.Second || diff > 2*time.Second {
+				t.Fatalf("next attempt at %v, want an hour after %v", left[0].NextAttemptAt, start)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package purge
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type postPurger interface {
+	PurgeDeletedPosts(before time.Time) (int64, error)
+}
+
+type commentPurger interface {
+	PurgeDeletedComments(before time.Time) (int64, error)
+}
+
+// Job permanently removes trashed posts and comments once they are older than the retention period.
+type Job struct {
+	log       logger.Logger
+	posts     postPurger
+	comments  commentPurger
+	retention time.Duration
+	interval  time.Duration
+}
+
+func New(log logger.Logger, posts postPurger, comments commentPurger, retention, interval time.Duration) *Job {
+	return &Job{
+		log:       log,
+		posts:     posts,
+		comments:  comments,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Run purges once immediately and then on every interval until ctx is done.
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.purge()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *Job) purge() {
+	log := j.log.With(slog.String("fn", "jobs.purge.Run"))
+	before := time.Now().Add(-j.retention)
+
+	comments, err := j.comments.PurgeDeletedComments(before)
+	if err != nil {
+		log.Error("failed to purge comments", sl.Error(err))
+	}
+	posts, err := j.posts.PurgeDeletedPosts(before)
+	if err != nil {
+		log.Error("failed to purge posts", sl.Error(err))
+	}
+
+	if posts > 0 || comments > 0 {
+		log.Info("trash purged", slog.Int64("posts", posts), slog.Int64("comments", comments))
+	}
+}
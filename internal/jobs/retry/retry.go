@@ -0,0 +1,148 @@
+// Package retry runs the queues of the background jobs that talk to other
+// servers. Due items are attempted right away, on every interval and on
+// wake-ups. Failed attempts are retried with exponential backoff, items
+// failing for good or max_attempts times are given up on. The queues are in
+// the database, so pending items survive restarts.
+package retry
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+const (
+	batchSize  = 50             // due items attempted per round
+	maxBackoff = 24 * time.Hour // longest wait between two attempts
+)
+
+// Item is a queued piece of work, logged with its LogValue.
+type Item interface {
+	slog.LogValuer
+	// Failures is how many attempts failed before.
+	Failures() int
+}
+
+type Queue[T Item] interface {
+	// Due returns up to limit items whose next attempt is due.
+	Due(now time.Time, limit int) ([]T, error)
+	// Retry counts a failed attempt and schedules the next one.
+	Retry(item T, next time.Time, err error) error
+	// Finish takes an item off the queue, err is why it was given up on
+	// and nil if it succeeded.
+	Finish(item T, err error) error
+}
+
+type Processor[T Item] interface {
+	Process(ctx context.Context, item T) error
+}
+
+type Config struct {
+	Name         string        // of the job in logs, like "deliver"
+	Interval     time.Duration // how often the queue is checked for due items
+	MaxAttempts  int           // an item is given up on after this many failures
+	RetryBackoff time.Duration // wait after the first failure, doubled after each further one
+	Permanent    error         // failures wrapping it are not retried
+	Tick         func()        // called on every interval as well, for housekeeping, may be nil
+}
+
+// Runner attempts the items of a queue with a processor.
+type Runner[T Item] struct {
+	log       logger.Logger
+	queue     Queue[T]
+	processor Processor[T]
+	wake      <-chan struct{}
+	cfg       Config
+}
+
+// New returns a runner, wake signals that new items were queued.
+func New[T Item](log logger.Logger, queue Queue[T], processor Processor[T], wake <-chan struct{}, cfg Config) *Runner[T] {
+	return &Runner[T]{
+		log:       log,
+		queue:     queue,
+		processor: processor,
+		wake:      wake,
+		cfg:       cfg,
+	}
+}
+
+// Run processes what is due immediately, then on every interval and on
+// wake-ups until ctx is done.
+func (r *Runner[T]) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.Drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if r.cfg.Tick != nil {
+				r.cfg.Tick()
+			}
+		case <-r.wake:
+		}
+	}
+}
+
+// Drain attempts everything that is due until the queue has nothing due
+// left or ctx is done.
+func (r *Runner[T]) Drain(ctx context.Context) {
+	log := r.log.With(slog.String("fn", "jobs."+r.cfg.Name+".Run"))
+
+	for ctx.Err() == nil {
+		due, err := r.queue.Due(time.Now(), batchSize)
+		if err != nil {
+			log.Error("failed to list due items", sl.Error(err))
+			return
+		}
+		for _, item := range due {
+			r.attempt(ctx, item)
+		}
+		if len(due) < batchSize {
+			return
+		}
+	}
+}
+
+func (r *Runner[T]) attempt(ctx context.Context, item T) {
+	err := r.processor.Process(ctx, item)
+	if err != nil && ctx.Err() != nil {
+		// shutting down, the attempt doesn't count
+		return
+	}
+	// logged after the attempt, items may record its outcome
+	log := r.log.With(slog.String("fn", "jobs."+r.cfg.Name+".Run"), slog.Attr{Key: "item", Value: item.LogValue()})
+	if err == nil || errors.Is(err, r.cfg.Permanent) || item.Failures()+1 >= r.cfg.MaxAttempts {
+		if err != nil {
+			log.Warn("given up", sl.Error(err))
+		} else {
+			log.Debug("done")
+		}
+		if err := r.queue.Finish(item, err); err != nil {
+			log.Error("failed to finish item", sl.Error(err))
+		}
+		return
+	}
+
+	next := time.Now().Add(backoff(r.cfg.RetryBackoff, item.Failures()))
+	log.Info("failed, retrying", sl.Error(err), slog.Time("next_attempt_at", next))
+	if err := r.queue.Retry(item, next, err); err != nil {
+		log.Error("failed to reschedule item", sl.Error(err))
+	}
+}
+
+// backoff is the wait after a failed attempt: base after the first failure,
+// doubled for every earlier one and at most a day.
+func backoff(base time.Duration, failures int) time.Duration {
+	if failures >= 20 {
+		return maxBackoff
+	}
+	return min(base<<failures, maxBackoff)
+}
@@ -0,0 +1,176 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"blog/internal/util/logger"
+)
+
+var errRejected = errors.New("rejected")
+
+type item struct {
+	id       int
+	failures int
+	next     time.Time
+	finished bool
+	err      error
+}
+
+func (i *item) Failures() int        { return i.failures }
+func (i *item) LogValue() slog.Value { return slog.IntValue(i.id) }
+
+// queue keeps its items in memory, processing fails with the errors in
+// results one after another and succeeds after them.
+type queue struct {
+	items    []*item
+	results  []error
+	attempts int
+}
+
+func (q *queue) Due(now time.Time, limit int) ([]*item, error) {
+	var due []*item
+	for _, it := range q.items {
+		if !it.finished && !it.next.After(now) && len(due) < limit {
+			due = append(due, it)
+		}
+	}
+	return due, nil
+}
+
+func (q *queue) Retry(it *item, next time.Time, err error) error {
+	it.failures++
+	it.next = next
+	it.err = err
+	return nil
+}
+
+func (q *queue) Finish(it *item, err error) error {
+	it.finished = true
+	it.err = err
+	return nil
+}
+
+func (q *queue) Process(ctx context.Context, it *item) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	q.attempts++
+	if q.attempts <= len(q.results) {
+		return q.results[q.attempts-1]
+	}
+	return nil
+}
+
+func newRunner(q *queue) *Runner[*item] {
+	return New(logger.NewLogger(nil), q, q, nil, Config{
+		Name:         "test",
+		Interval:     time.Minute,
+		MaxAttempts:  3,
+		RetryBackoff: time.Minute,
+		Permanent:    errRejected,
+	})
+}
+
+func TestAttempt(t *testing.T) {
+	failed := errors.New("status 503")
+	tests := []struct {
+		name     string
+		results  []error
+		failures int // before the attempt
+		finished bool
+		err      error
+	}{
+		{"success", nil, 0, true, nil},
+		{"failure", []error{failed}, 0, false, failed},
+		{"rejected", []error{fmt.Errorf("%w: status 410", errRejected)}, 0, true, errRejected},
+		{"last attempt", []error{failed}, 2, true, failed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := &item{id: 1, failures: tt.failures}
+			q := &queue{items: []*item{it}, results: tt.results}
+			newRunner(q).Drain(context.Background())
+
+			if it.finished != tt.finished || !errors.Is(it.err, tt.err) {
+				t.Fatalf("finished %v with %v, want %v with %v", it.finished, it.err, tt.finished, tt.err)
+			}
+			if !tt.finished && it.failures != tt.failures+1 {
+				t.Fatalf("failures = %d, want %d", it.failures, tt.failures+1)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	failed := errors.New("status 503")
+	it := &item{id: 1}
+	q := &queue{items: []*item{it}, results: []error{failed, failed, failed}}
+	r := newRunner(q)
+
+	// the wait starts at retry_backoff and doubles after every failure
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute} {
+		start := time.Now()
+		r.Drain(context.Background())
+		if it.finished || it.failures != i+1 {
+			t.Fatalf("attempt %d: finished %v, %d failures", i+1, it.finished, it.failures)
+		}
+		if wait := it.next.Sub(start); wait < want || wait > want+time.Second {
+			t.Fatalf("attempt %d: next attempt in %v, want %v", i+1, wait, want)
+		}
+		// not due yet
+		r.Drain(context.Background())
+		if q.attempts != i+1 {
+			t.Fatalf("attempted %d times before it was due", q.attempts)
+		}
+		it.next = time.Time{}
+	}
+
+	r.Drain(context.Background())
+	if !it.finished || q.attempts != 3 {
+		t.Fatalf("finished %v after %d attempts, want given up after 3", it.finished, q.attempts)
+	}
+}
+
+func TestBackoffLimit(t *testing.T) {
+	for failures, want := range map[int]time.Duration{
+		0:  time.Minute,
+		3:  8 * time.Minute,
+		11: maxBackoff,
+		70: maxBackoff,
+	} {
+		if got := backoff(time.Minute, failures); got != want {
+			t.Errorf("backoff after %d failures = %v, want %v", failures, got, want)
+		}
+	}
+}
+
+func TestShutdownDoesNotCount(t *testing.T) {
+	it := &item{id: 1}
+	q := &queue{items: []*item{it}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	newRunner(q).attempt(ctx, it)
+	if it.finished || it.failures != 0 {
+		t.Fatalf("finished %v, %d failures, want the item untouched", it.finished, it.failures)
+	}
+}
+
+func TestDrainsEveryBatch(t *testing.T) {
+	q := &queue{}
+	for i := range 2*batchSize + 1 {
+		q.items = append(q.items, &item{id: i})
+	}
+	newRunner(q).Drain(context.Background())
+
+	for _, it := range q.items {
+		if !it.finished {
+			t.Fatalf("item %d left in the queue", it.id)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/jobs/retry"
+	"blog/internal/models"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+	webhooks "blog/internal/webhook"
+)
+
+type queue interface {
+	DueWebhookDeliveries(now time.Time, limit int) ([]*models.WebhookDelivery, error)
+	CompleteWebhookDelivery(id int64, status string, responseStatus int, lastError string) error
+	RetryWebhookDelivery(id int64, next time.Time, responseStatus int, lastError string) error
+	PurgeWebhookDeliveries(before time.Time) (int64, error)
+}
+
+type sender interface {
+	Send(ctx context.Context, d *models.WebhookDelivery) (int, error)
+}
+
+// Job posts queued webhook payloads. Failed deliveries are retried with
+// exponential backoff and marked failed when rejected or after max_attempts
+// attempts. Finished deliveries stay in the log for the retention period.
+type Job struct {
+	*retry.Runner[*delivery]
+}
+
+// New returns the job, wake signals that new deliveries were queued.
+func New(log logger.Logger, queue queue, sender sender, wake <-chan struct{}, cfg config.WebhooksConfig) *Job {
+	q := deliveries{log: log, queue: queue, retention: cfg.Retention}
+	return &Job{retry.New(log, q, payloads{sender}, wake, retry.Config{
+		Name:         "webhook",
+		Interval:     cfg.Interval,
+		MaxAttempts:  cfg.MaxAttempts,
+		RetryBackoff: cfg.RetryBackoff,
+		Permanent:    webhooks.ErrRejected,
+		Tick:         q.purge,
+	})}
+}
+
+// delivery carries the response status of the attempt to the log.
+type delivery struct {
+	*models.WebhookDelivery
+	status int
+}
+
+func (d *delivery) Failures() int {
+	return d.Attempts
+}
+
+func (d *delivery) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int64("delivery_id", d.ID), slog.Int64("webhook_id", d.WebhookID),
+		slog.String("event", d.Event), slog.Int("attempts", d.Attempts), slog.Int("response_status", d.status))
+}
+
+type deliveries struct {
+	log       logger.Logger
+	queue     queue
+	retention time.Duration
+}
+
+func (q deliveries) Due(now time.Time, limit int) ([]*delivery, error) {
+	due, err := q.queue.DueWebhookDeliveries(now, limit)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*delivery, 0, len(due))
+	for _, d := range due {
+		items = append(items, &delivery{WebhookDelivery: d})
+	}
+	return items, nil
+}
+
+func (q deliveries) Retry(d *delivery, next time.Time, err error) error {
+	return q.queue.RetryWebhookDelivery(d.ID, next, d.status, err.Error())
+}
+
+// Finish keeps the delivery in the log as succeeded or failed.
+func (q deliveries) Finish(d *delivery, err error) error {
+	if err != nil {
+		return q.queue.CompleteWebhookDelivery(d.ID, models.DeliveryFailed, d.status, err.Error())
+	}
+	return q.queue.CompleteWebhookDelivery(d.ID, models.DeliverySucceeded, d.status, "")
+}
+
+func (q deliveries) purge() {
+	n, err := q.queue.PurgeWebhookDeliveries(time.Now().Add(-q.retention))
+	if err != nil {
+		q.log.Error("failed to purge webhook deliveries", slog.String("fn", "jobs.webhook.purge"), sl.Error(err))
+		return
+	}
+	if n > 0 {
+		q.log.Info("purged webhook deliveries", slog.String("fn", "jobs.webhook.purge"), slog.Int64("count", n))
+	}
+}
+
+type payloads struct {
+	sender sender
+}
+
+func (p payloads) Process(ctx context.Context, d *delivery) error {
+	var err error
+	d.status, err = p.sender.Send(ctx, d.WebhookDelivery)
+	return err
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/models"
+	"blog/internal/util/logger"
+	webhooks "blog/internal/webhook"
+)
+
+// deliveryLog is a queue of one delivery recording what became of it.
+type deliveryLog struct {
+	delivery *models.WebhookDelivery
+	done     bool
+}
+
+func (l *deliveryLog) DueWebhookDeliveries(now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	if l.done || l.delivery.NextAttemptAt.After(now) {
+		return nil, nil
+	}
+	return []*models.WebhookDelivery{l.delivery}, nil
+}
+
+func (l *deliveryLog) CompleteWebhookDelivery(id int64, status string, responseStatus int, lastError string) error {
+	l.done = true
+	l.delivery.Status, l.delivery.ResponseStatus, l.delivery.LastError = status, responseStatus, lastError
+	return nil
+}
+
+func (l *deliveryLog) RetryWebhookDelivery(id int64, next time.Time, responseStatus int, lastError string) error {
+	l.delivery.Attempts++
+	l.delivery.NextAttemptAt = &next
+	l.delivery.ResponseStatus, l.delivery.LastError = responseStatus, lastError
+	return nil
+}
+
+func (l *deliveryLog) PurgeWebhookDeliveries(before time.Time) (int64, error) {
+	return 0, nil
+}
+
+type receiver struct {
+	status int
+	err    error
+}
+
+func (r receiver) Send(ctx context.Context, d *models.WebhookDelivery) (int, error) {
+	return r.status, r.err
+}
+
+func TestDeliveryLog(t *testing.T) {
+	tests := []struct {
+		name     string
+		receiver receiver
+		status   string // models.DeliveryPending while retried
+	}{
+		{"succeeded", receiver{http.StatusNoContent, nil}, models.DeliverySucceeded},
+		{"rejected", receiver{http.StatusGone, fmt.Errorf("%w: status 410", webhooks.ErrRejected)}, models.DeliveryFailed},
+		{"retried", receiver{http.StatusBadGateway, errors.New("status 502")}, models.DeliveryPending},
+		{"no response", receiver{0, errors.New("connection refused")}, models.DeliveryPending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			l := &deliveryLog{delivery: &models.WebhookDelivery{ID: 1, WebhookID: 1, Status: models.DeliveryPending, NextAttemptAt: &now}}
+			cfg := config.WebhooksConfig{Interval: time.Minute, MaxAttempts: 3, RetryBackoff: time.Minute}
+			New(logger.NewLogger(nil), l, tt.receiver, nil, cfg).Drain(context.Background())
+
+			d := l.delivery
+			if d.Status != tt.status || d.ResponseStatus != tt.receiver.status {
+				t.Fatalf("delivery %s with response %d, want %s with %d", d.Status, d.ResponseStatus, tt.status, tt.receiver.status)
+			}
+			if (tt.receiver.err != nil) != (d.LastError != "") {
+				t.Fatalf("last error %q for %v", d.LastError, tt.receiver.err)
+			}
+			if tt.status == models.DeliveryPending && d.Attempts != 1 {
+				t.Fatalf("attempts = %d, want 1", d.Attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package webmention
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/jobs/retry"
+	"blog/internal/models"
+	"blog/internal/util/logger"
+	webmentions "blog/internal/webmention"
+)
+
+type queue interface {
+	DueWebmentionJobs(now time.Time, limit int) ([]*models.WebmentionJob, error)
+	RetryWebmentionJob(id int64, next time.Time, lastError string) error
+	DeleteWebmentionJob(id int64) error
+}
+
+type processor interface {
+	Process(ctx context.Context, job *models.WebmentionJob) error
+}
+
+// Job sends and verifies queued webmentions. Failed jobs are retried with
+// exponential backoff, rejected ones and those failing max_attempts times
+// are dropped.
+type Job struct {
+	*retry.Runner[job]
+}
+
+// New returns the job, wake signals that new jobs were queued.
+func New(log logger.Logger, queue queue, processor processor, wake <-chan struct{}, cfg config.WebmentionConfig) *Job {
+	return &Job{retry.New(log, jobs{queue}, mentions{processor}, wake, retry.Config{
+		Name:         "webmention",
+		Interval:     cfg.Interval,
+		MaxAttempts:  cfg.MaxAttempts,
+		RetryBackoff: cfg.RetryBackoff,
+		Permanent:    webmentions.ErrRejected,
+	})}
+}
+
+type job struct {
+	*models.WebmentionJob
+}
+
+func (j job) Failures() int {
+	return j.Attempts
+}
+
+func (j job) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int64("job_id", j.ID), slog.String("kind", j.Kind),
+		slog.String("source", j.Source), slog.String("target", j.Target), slog.Int("attempts", j.Attempts))
+}
+
+type jobs struct {
+	queue queue
+}
+
+func (q jobs) Due(now time.Time, limit int) ([]job, error) {
+	due, err := q.queue.DueWebmentionJobs(now, limit)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]job, 0, len(due))
+	for _, j := range due {
+		items = append(items, job{j})
+	}
+	return items, nil
+}
+
+func (q jobs) Retry(j job, next time.Time, err error) error {
+	return q.queue.RetryWebmentionJob(j.ID, next, err.Error())
+}
+
+// Finish drops the job, whether it succeeded or not.
+func (q jobs) Finish(j job, err error) error {
+	return q.queue.DeleteWebmentionJob(j.ID)
+}
+
+type mentions struct {
+	processor processor
+}
+
+func (m mentions) Process(ctx context.Context, j job) error {
+	return m.processor.Process(ctx, j.WebmentionJob)
+}
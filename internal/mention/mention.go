@@ -0,0 +1,179 @@
+package mention
+
+import (
+	"html"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"blog/internal/config"
+	"blog/internal/models"
+	"blog/internal/util/logger"
+)
+
+// same limits as the username validator used on sign up
+const (
+	minUsernameLength = 5
+	maxUsernameLength = 30
+)
+
+var mentionRe = regexp.MustCompile(`@([A-Za-z0-9]+)`)
+
+// Match is an @username found in a text. Offsets are in characters, End is
+// exclusive and the span includes the "@".
+type Match struct {
+	Username string
+	Start    int
+	End      int
+}
+
+// Parse finds the @username mentions in content. An "@" preceded by a letter,
+// digit or another "@" is not a mention, so e-mail addresses are left alone,
+// and names that can't be usernames are skipped.
+func Parse(content string) []Match {
+	var matches []Match
+	for _, loc := range mentionRe.FindAllStringSubmatchIndex(content, -1) {
+		start, end := loc[0], loc[1]
+		name := content[loc[2]:loc[3]]
+		if len(name) < minUsernameLength || len(name) > maxUsernameLength {
+			continue
+		}
+		if prev, _ := utf8.DecodeLastRuneInString(content[:start]); prev != utf8.RuneError &&
+			(unicode.IsLetter(prev) || unicode.IsDigit(prev) || prev == '@' || prev == '_') {
+			continue
+		}
+		runeStart := utf8.RuneCountInString(content[:start])
+		matches = append(matches, Match{
+			Username: name,
+			Start:    runeStart,
+			End:      runeStart + utf8.RuneCountInString(content[start:end]),
+		})
+	}
+	return matches
+}
+
+type userResolver interface {
+	GetUsersByUsernames(usernames []string) (map[string]*models.User, error)
+}
+
+type store interface {
+	ReplaceMentions(targetType string, targetID int64, mentions []*models.Mention) error
+	ListMentions(targetType string, ids []int64) (map[int64][]*models.Mention, error)
+}
+
+// Linker resolves the mentions of posts and comments against the user table
+// when they are written and hands them out, with profile links, when they are
+// read. Mentions of unknown users stay plain text.
+type Linker struct {
+	log   logger.Logger
+	users userResolver
+	store store
+	cfg   config.MentionsConfig
+}
+
+func New(log logger.Logger, users userResolver, store store, cfg config.MentionsConfig) *Linker {
+	return &Linker{
+		log:   log,
+		users: users,
+		store: store,
+		cfg:   cfg,
+	}
+}
+
+// Sync parses content and replaces the stored mentions of the post or
+// comment. Only the first cfg.MaxPerEntry distinct users are linked.
+func (l *Linker) Sync(targetType string, targetID int64, content string) error {
+	matches := Parse(content)
+
+	names := make([]string, 0, len(matches))
+	seen := make(map[string]bool, len(matches))
+	for _, m := range matches {
+		key := strings.ToLower(m.Username)
+		if seen[key] {
+			continue
+		}
+		if len(names) == l.cfg.MaxPerEntry {
+			break
+		}
+		seen[key] = true
+		names = append(names, m.Username)
+	}
+
+	users, err := l.users.GetUsersByUsernames(names)
+	if err != nil {
+		return err
+	}
+
+	mentions := make([]*models.Mention, 0, len(matches))
+	for _, m := range matches {
+		key := strings.ToLower(m.Username)
+		user, ok := users[key]
+		if !ok || !seen[key] {
+			continue
+		}
+		mentions = append(mentions, &models.Mention{
+			TargetType: targetType,
+			TargetID:   targetID,
+			UserID:     user.ID,
+			Username:   user.Username,
+			Start:      m.Start,
+			End:        m.End,
+		})
+	}
+	if err := l.store.ReplaceMentions(targetType, targetID, mentions); err != nil {
+		return err
+	}
+	l.log.Debug("mentions linked",
+		slog.String("target_type", targetType),
+		slog.Int64("target_id", targetID),
+		slog.Int("mentions", len(mentions)))
+	return nil
+}
+
+// Mentions returns the stored mentions of the given posts or comments with
+// their profile links.
+func (l *Linker) Mentions(targetType string, ids []int64) (map[int64][]*models.Mention, error) {
+	mentions, err := l.store.ListMentions(targetType, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, list := range mentions {
+		for _, m := range list {
+			m.URL = l.profileURL(m)
+		}
+	}
+	return mentions, nil
+}
+
+func (l *Linker) profileURL(m *models.Mention) string {
+	return strings.NewReplacer(
+		"{id}", strconv.FormatInt(m.UserID, 10),
+		"{username}", m.Username,
+	).Replace(l.cfg.ProfileURL)
+}
+
+// HTML renders content as escaped HTML with mentions turned into profile
+// links. Mentions that don't fit the content are ignored.
+func HTML(content string, mentions []*models.Mention) string {
+	runes := []rune(content)
+
+	var b strings.Builder
+	pos := 0
+	for _, m := range mentions {
+		if m.Start < pos || m.End > len(runes) || m.Start >= m.End {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:m.Start])))
+		b.WriteString(`<a class="mention" href="`)
+		b.WriteString(html.EscapeString(m.URL))
+		b.WriteString(`">`)
+		b.WriteString(html.EscapeString(string(runes[m.Start:m.End])))
+		b.WriteString(`</a>`)
+		pos = m.End
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:])))
+	return b.String()
+}
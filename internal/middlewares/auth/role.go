@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util"
+)
+
+const UserRoleCtxKey = "user_role"
+
+type userGetter interface {
+	GetUserByID(id int64) (*models.User, error)
+}
+
+// RequireRole only lets through users having one of the given roles.
+// It must be wrapped by AuthMiddleware, which puts the user id into the context.
+func RequireRole(userGetter userGetter, roles ...string) func(http.Handler) http.HandlerFunc {
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := r.Context().Value(UserIDCtxKey).(int64)
+			if !ok {
+				util.ErrorResponse(w, r, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+
+			user, err := userGetter.GetUserByID(userID)
+			if err != nil {
+				if errors.Is(err, repository.ErrNotExists) {
+					util.ErrorResponse(w, r, http.StatusUnauthorized, "Unauthorized")
+					return
+				}
+				util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+				return
+			}
+
+			if !slices.Contains(roles, user.Role) {
+				util.ErrorResponse(w, r, http.StatusForbidden, "Forbidden")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserRoleCtxKey, user.Role)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+	}
+}
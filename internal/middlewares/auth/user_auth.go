@@ -2,11 +2,15 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"blog/internal/handlers/auth"
+	"blog/internal/models"
+	"blog/internal/repository"
 	"blog/internal/util"
 )
 
@@ -15,30 +19,99 @@ const (
 	UserIDCtxKey = "user_id"
 )
 
-func AuthMiddleware(next http.Handler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		header := r.Header.Get(authHeader)
-		if header == " " {
-			util.ErrorResponse(w, http.StatusUnauthorized, "Auth Header Empty")
-			return
+type sanctionChecker interface {
+	ActiveSanction(userID int64, kinds ...string) (*models.Sanction, error)
+}
+
+// AuthMiddleware authenticates requests by their bearer token. Tokens of
+// suspended or banned users are rejected until the sanction expires or is revoked.
+func AuthMiddleware(sanctions sanctionChecker) func(http.Handler) http.HandlerFunc {
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get(authHeader)
+			if header == " " {
+				util.ErrorResponse(w, r, http.StatusUnauthorized, "Auth Header Empty")
+				return
+			}
+
+			headerParts := strings.Split(header, " ")
+			if len(headerParts) != 2 {
+				util.ErrorResponse(w, r, http.StatusUnauthorized, "Invalid Auth Header")
+				return
+			}
+
+			userID, err := auth.ParseToken(headerParts[1])
+			if err != nil {
+				fmt.Println(err)
+				util.ErrorResponse(w, r, http.StatusUnauthorized, "Invalid Auth Token")
+				return
+			}
+
+			sanction, err := Blocked(sanctions, userID)
+			if err != nil {
+				util.ErrorResponse(w, r, http.StatusInternalServerError, "Internal Server Error")
+				return
+			}
+			if sanction != nil {
+				util.ErrorResponse(w, r, http.StatusForbidden, SanctionMessage(sanction))
+				return
+			}
+
+			userID = int64(userID)
+			ctx := context.WithValue(r.Context(), UserIDCtxKey, userID)
+			ctxR := r.WithContext(ctx)
+			next.ServeHTTP(w, ctxR)
 		}
+	}
+}
 
-		headerParts := strings.Split(header, " ")
-		if len(headerParts) != 2 {
-			util.ErrorResponse(w, http.StatusUnauthorized, "Invalid Auth Header")
-			return
+// OptionalAuth is AuthMiddleware for public routes: requests without a valid
+// token, or from blocked users, go through anonymously without a user id.
+func OptionalAuth(sanctions sanctionChecker) func(http.Handler) http.HandlerFunc {
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			headerParts := strings.Split(r.Header.Get(authHeader), " ")
+			if len(headerParts) != 2 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, err := auth.ParseToken(headerParts[1])
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if sanction, err := Blocked(sanctions, userID); err != nil || sanction != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDCtxKey, userID)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		}
+	}
+}
 
-		userID, err := auth.ParseToken(headerParts[1])
-		if err != nil {
-			fmt.Println(err)
-			util.ErrorResponse(w, http.StatusUnauthorized, "Invalid Auth Token")
-			return
+// Blocked returns the suspension or ban that keeps the user out, or nil.
+func Blocked(sanctions sanctionChecker, userID int64) (*models.Sanction, error) {
+	sanction, err := sanctions.ActiveSanction(userID, models.SanctionSuspend, models.SanctionBan)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotExists) {
+			return nil, nil
 		}
+		return nil, err
+	}
+	return sanction, nil
+}
 
-		userID = int64(userID)
-		ctx := context.WithValue(r.Context(), UserIDCtxKey, userID)
-		ctxR := r.WithContext(ctx)
-		next.ServeHTTP(w, ctxR)
+// SanctionMessage describes a suspension or ban to the affected user.
+func SanctionMessage(s *models.Sanction) string {
+	verb := "suspended"
+	if s.Kind == models.SanctionBan {
+		verb = "banned"
+	}
+	if s.ExpiresAt == nil {
+		return fmt.Sprintf("account %s: %s", verb, s.Reason)
 	}
+	return fmt.Sprintf("account %s until %s: %s", verb, s.ExpiresAt.UTC().Format(time.RFC3339), s.Reason)
 }
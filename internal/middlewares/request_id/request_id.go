@@ -37,3 +37,10 @@ func Get(ctx context.Context) string {
 	}
 	return ""
 }
+
+// NewContext returns ctx with a new request id, for calls that don't come
+// through RequestID, e.g. over gRPC.
+func NewContext(ctx context.Context) (context.Context, string) {
+	requestID := generateID()
+	return context.WithValue(ctx, requestIDKey, requestID), requestID
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// AuditEntry records a moderation or administration action. ActorID is nil
+// for actions taken by the system, e.g. hiding content after too many reports.
+type AuditEntry struct {
+	ID         int64     `json:"id"`
+	ActorID    *int64    `json:"actor_id,omitempty"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"target_type"`
+	TargetID   int64     `json:"target_id"`
+	Details    string    `json:"details,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Audit actions.
+const (
+	AuditCommentModerate = "comment.moderate"
+	AuditContentHide     = "content.hide"
+	AuditContentRemove   = "content.remove"
+	AuditReportDismiss   = "report.dismiss"
+	AuditUserRole        = "user.role"
+	AuditUserWarn        = "user.warn"
+	AuditUserSuspend     = "user.suspend"
+	AuditUserBan         = "user.ban"
+	AuditUserShadowBan   = "user.shadow_ban"
+	AuditSanctionRevoke  = "sanction.revoke"
+	AuditWebhookCreate   = "webhook.create"
+	AuditWebhookUpdate   = "webhook.update"
+	AuditWebhookDelete   = "webhook.delete"
+)
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type Bookmark struct {
+	ID           int64     `json:"bookmark_id"`
+	UserID       int64     `json:"user_id"`
+	PostID       int64     `json:"post_id"`
+	CollectionID *int64    `json:"collection_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	Post *Post `json:"-"` // filled by listings
+}
+
+// BookmarkCollection is a named reading list. Bookmarks outside of any
+// collection have no collection id.
+type BookmarkCollection struct {
+	ID        int64     `json:"collection_id"`
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Bookmarks int64     `json:"bookmarks"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -2,11 +2,24 @@ package models
 
 import "time"
 
+const (
+	CommentStatusPending  = "pending"
+	CommentStatusApproved = "approved"
+	CommentStatusRejected = "rejected"
+	CommentStatusSpam     = "spam"
+)
+
 type Comment struct {
-	ID        int64     `json:"id"`
-	Content   string    `json:"content"`
-	PostID    int64     `json:"post_id"`
-	AuthorID  int64     `json:"author_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        int64      `json:"id"`
+	Content   string     `json:"content"`
+	PostID    int64      `json:"post_id"`
+	ParentID  *int64     `json:"parent_id,omitempty"`
+	Depth     int        `json:"depth"`
+	Status    string     `json:"status"`
+	AuthorID  int64      `json:"author_id"`
+	SpamScore float64    `json:"spam_score"`
+	Version   int64      `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
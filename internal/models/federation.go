@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// ActorKey is the key pair a local user signs ActivityPub requests with.
+type ActorKey struct {
+	UserID        int64
+	PrivateKeyPEM string
+	PublicKeyPEM  string
+}
+
+// RemoteActor is an ActivityPub actor of another server. Actors that reply
+// to posts get a local user of role RoleRemote to author their comments.
+type RemoteActor struct {
+	ID           int64
+	ActorID      string // IRI of the actor document
+	Username     string // preferredUsername
+	Inbox        string
+	SharedInbox  string
+	KeyID        string
+	PublicKeyPEM string
+	UserID       *int64
+	FetchedAt    time.Time
+}
+
+// DeliveryInbox returns the inbox activities for the actor are posted to.
+func (a *RemoteActor) DeliveryInbox() string {
+	if a.SharedInbox != "" {
+		return a.SharedInbox
+	}
+	return a.Inbox
+}
+
+// Delivery is an activity waiting to be posted to a remote inbox, signed
+// with the key of UserID.
+type Delivery struct {
+	ID            int64
+	UserID        int64
+	Inbox         string
+	Activity      []byte
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+// Mention is an @username in the content of a post or comment that resolved
+// to a user. Start and End are offsets in characters (unicode code points)
+// into the content, End is exclusive and the span includes the "@".
+type Mention struct {
+	TargetType string `json:"-"`
+	TargetID   int64  `json:"-"`
+	UserID     int64  `json:"user_id"`
+	Username   string `json:"username"`
+	Start      int    `json:"start"`
+	End        int    `json:"end"`
+	URL        string `json:"url"`
+}
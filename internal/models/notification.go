@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	NotificationComment = "comment" // someone commented on your post
+	NotificationReply   = "reply"   // someone replied to your comment
+	NotificationMention = "mention" // someone @mentioned you
+	NotificationFollow  = "follow"  // someone followed you
+)
+
+// Notification is an entry in a user's inbox. ActorID is the user who caused
+// it; PostID and CommentID point at the content it is about, if any.
+type Notification struct {
+	ID        int64      `json:"notification_id"`
+	UserID    int64      `json:"user_id"`
+	Kind      string     `json:"kind"`
+	ActorID   int64      `json:"actor_id"`
+	PostID    *int64     `json:"post_id,omitempty"`
+	CommentID *int64     `json:"comment_id,omitempty"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -1,14 +1,40 @@
 package models
 
 import (
+	"slices"
+	"strings"
 	"time"
 )
 
+// Comment moderation modes. A post with an empty CommentMode uses the global mode.
+const (
+	ModerationOpen      = "open"       // comments are published immediately
+	ModerationFirstTime = "first_time" // first comment of a user needs approval
+	ModerationAll       = "all"        // every comment needs approval
+)
+
 type Post struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	AuthorID  int64     `json:"author_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             int64      `json:"id"`
+	Title          string     `json:"title"`
+	Content        string     `json:"content"`
+	AuthorID       int64      `json:"author_id"`
+	Version        int64      `json:"version"`
+	CommentMode    string     `json:"comment_mode,omitempty"`
+	CommentsLocked bool       `json:"comments_locked"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	HiddenAt       *time.Time `json:"hidden_at,omitempty"` // set when hidden by reports or a moderator
+	Tags           []string   `json:"tags,omitempty"`      // lower case, sorted; nil leaves them unchanged on update
+}
+
+// NormalizeTags lower cases tags and drops duplicates. The result is never
+// nil, so an empty list clears the tags of a post.
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		normalized = append(normalized, strings.ToLower(strings.TrimSpace(tag)))
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
 }
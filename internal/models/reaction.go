@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+type Reaction struct {
+	UserID     int64     `json:"user_id"`
+	TargetType string    `json:"target_type"`
+	TargetID   int64     `json:"target_id"`
+	Emoji      string    `json:"emoji"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ReactionSummary holds the precomputed reaction counts of a post or comment.
+// Revision grows with every change and is used to build entity tags.
+type ReactionSummary struct {
+	Counts    map[string]int64 `json:"counts"`
+	Total     int64            `json:"total"`
+	Revision  int64            `json:"-"`
+	UpdatedAt time.Time        `json:"-"`
+}
+
+// LastReactionAt returns the latest reaction change among the summaries, or the zero time.
+func LastReactionAt(summaries map[int64]*ReactionSummary) time.Time {
+	var last time.Time
+	for _, s := range summaries {
+		if s.UpdatedAt.After(last) {
+			last = s.UpdatedAt
+		}
+	}
+	return last
+}
+
+// CountMap returns the per-emoji counts, never nil, so responses always carry an object.
+func (s *ReactionSummary) CountMap() map[string]int64 {
+	if s == nil || s.Counts == nil {
+		return map[string]int64{}
+	}
+	return s.Counts
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+const (
+	TargetPost    = "post"
+	TargetComment = "comment"
+	TargetUser    = "user"
+)
+
+// Report reasons.
+const (
+	ReasonSpam           = "spam"
+	ReasonHarassment     = "harassment"
+	ReasonHate           = "hate"
+	ReasonViolence       = "violence"
+	ReasonSexual         = "sexual"
+	ReasonMisinformation = "misinformation"
+	ReasonOffTopic       = "off_topic"
+	ReasonOther          = "other"
+)
+
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusResolved  = "resolved"
+	ReportStatusDismissed = "dismissed"
+)
+
+type Report struct {
+	ID         int64      `json:"id"`
+	ReporterID int64      `json:"reporter_id"`
+	TargetType string     `json:"target_type"`
+	TargetID   int64      `json:"target_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details,omitempty"`
+	Status     string     `json:"status"`
+	ResolvedBy *int64     `json:"resolved_by,omitempty"`
+	Resolution string     `json:"resolution,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// ReportTarget aggregates the open reports of a single post or comment.
+type ReportTarget struct {
+	TargetType      string         `json:"target_type"`
+	TargetID        int64          `json:"target_id"`
+	Reporters       int            `json:"reporters"`
+	Reasons         map[string]int `json:"reasons"`
+	FirstReportedAt time.Time      `json:"first_reported_at"`
+	LastReportedAt  time.Time      `json:"last_reported_at"`
+	FirstReportID   int64          `json:"-"` // with FirstReportedAt the position in the queue
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type PostRevision struct {
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	EditorID  int64     `json:"editor_id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Tags      []string  `json:"tags"` // nil in revisions stored before tags were tracked
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+const (
+	SanctionWarn      = "warn"
+	SanctionSuspend   = "suspend"    // temporary, the account can't sign in or use its tokens
+	SanctionBan       = "ban"        // like suspend, but may be permanent
+	SanctionShadowBan = "shadow_ban" // comments stay visible to their author only
+)
+
+type Sanction struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Kind      string     `json:"kind"`
+	Reason    string     `json:"reason"`
+	IssuedBy  int64      `json:"issued_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil for warnings and permanent sanctions
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy *int64     `json:"revoked_by,omitempty"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// SpamToken holds how many spam and ham training samples contained a token.
+type SpamToken struct {
+	Token string `json:"token"`
+	Spam  int64  `json:"spam"`
+	Ham   int64  `json:"ham"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Webmention job kinds.
+const (
+	WebmentionSend   = "send"   // notify the target of a link in one of our posts
+	WebmentionVerify = "verify" // check that a received source really links to our post
+)
+
+// Webmention is a verified mention of a post on another site. It's shown
+// next to the comments of the post.
+type Webmention struct {
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	Source    string    `json:"source"`
+	Title     string    `json:"title,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebmentionJob is a queued webmention to send or verify.
+type WebmentionJob struct {
+	ID            int64
+	Kind          string
+	Source        string
+	Target        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+}
@@ -0,0 +1,198 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"blog/internal/events"
+	"blog/internal/models"
+	"blog/internal/util/logger"
+)
+
+type notificationCreator interface {
+	CreateNotifications(notifications []*models.Notification) error
+}
+
+type postGetter interface {
+	GetPostByID(id int64) (*models.Post, error)
+}
+
+type commentGetter interface {
+	GetCommentByID(id int64) (*models.Comment, error)
+}
+
+type mentionLister interface {
+	ListMentions(targetType string, ids []int64) (map[int64][]*models.Mention, error)
+}
+
+type shadowBans interface {
+	ShadowBannedUsers(ids []int64) (map[int64]bool, error)
+}
+
+// Notifier turns events into inbox notifications. New notification types
+// subscribe another handler in Subscribe.
+type Notifier struct {
+	log        logger.Logger
+	store      notificationCreator
+	posts      postGetter
+	comments   commentGetter
+	mentions   mentionLister
+	shadowBans shadowBans
+}
+
+func New(log logger.Logger, store notificationCreator, posts postGetter, comments commentGetter, mentions mentionLister, shadowBans shadowBans) *Notifier {
+	return &Notifier{
+		log:        log,
+		store:      store,
+		posts:      posts,
+		comments:   comments,
+		mentions:   mentions,
+		shadowBans: shadowBans,
+	}
+}
+
+func (n *Notifier) Subscribe(bus *events.Bus) {
+	events.On(bus, n.postPublished)
+	events.On(bus, n.postUpdated)
+	events.On(bus, n.commentPublished)
+	events.On(bus, n.commentUpdated)
+	events.On(bus, n.userFollowed)
+}
+
+func (n *Notifier) postPublished(ctx context.Context, e events.PostPublished) error {
+	return n.postMentions(e.Post)
+}
+
+// postUpdated notifies users mentioned by an edit. Users mentioned before
+// already have their notification and don't get another one.
+func (n *Notifier) postUpdated(ctx context.Context, e events.PostUpdated) error {
+	return n.postMentions(e.Post)
+}
+
+func (n *Notifier) postMentions(post *models.Post) error {
+	mentions, err := n.mentions.ListMentions(models.TargetPost, []int64{post.ID})
+	if err != nil {
+		return fmt.Errorf("list mentions: %w", err)
+	}
+	return n.send(mentionNotifications(mentions[post.ID], post.AuthorID, &post.ID, nil, 0)...)
+}
+
+// commentPublished tells the parent comment's author about a reply, or the
+// post author about a top-level comment, and the mentioned users about the
+// mention.
+func (n *Notifier) commentPublished(ctx context.Context, e events.CommentPublished) error {
+	direct, err := n.commentNotification(e.Comment)
+	if err != nil {
+		return err
+	}
+	mentions, err := n.commentMentions(e.Comment, direct.UserID)
+	if err != nil {
+		return err
+	}
+	return n.send(append(mentions, direct)...)
+}
+
+func (n *Notifier) commentUpdated(ctx context.Context, e events.CommentUpdated) error {
+	direct, err := n.commentNotification(e.Comment)
+	if err != nil {
+		return err
+	}
+	mentions, err := n.commentMentions(e.Comment, direct.UserID)
+	if err != nil {
+		return err
+	}
+	return n.send(mentions...)
+}
+
+// commentMentions builds the mention notifications of a comment. The user
+// who already hears about the comment as a comment or reply is left out.
+func (n *Notifier) commentMentions(c *models.Comment, notifiedID int64) ([]*models.Notification, error) {
+	mentions, err := n.mentions.ListMentions(models.TargetComment, []int64{c.ID})
+	if err != nil {
+		return nil, fmt.Errorf("list mentions: %w", err)
+	}
+	return mentionNotifications(mentions[c.ID], c.AuthorID, &c.PostID, &c.ID, notifiedID), nil
+}
+
+func (n *Notifier) commentNotification(c *models.Comment) (*models.Notification, error) {
+	notification := &models.Notification{
+		Kind:      models.NotificationComment,
+		ActorID:   c.AuthorID,
+		PostID:    &c.PostID,
+		CommentID: &c.ID,
+	}
+
+	if c.ParentID != nil {
+		parent, err := n.comments.GetCommentByID(*c.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("get parent comment: %w", err)
+		}
+		notification.Kind = models.NotificationReply
+		notification.UserID = parent.AuthorID
+	} else {
+		post, err := n.posts.GetPostByID(c.PostID)
+		if err != nil {
+			return nil, fmt.Errorf("get post: %w", err)
+		}
+		notification.UserID = post.AuthorID
+	}
+	return notification, nil
+}
+
+// mentionNotifications builds one notification per mentioned user, skipping skipID.
+func mentionNotifications(mentions []*models.Mention, actorID int64, postID, commentID *int64, skipID int64) []*models.Notification {
+	seen := make(map[int64]bool, len(mentions))
+	notifications := make([]*models.Notification, 0, len(mentions))
+	for _, m := range mentions {
+		if seen[m.UserID] || m.UserID == skipID {
+			continue
+		}
+		seen[m.UserID] = true
+		notifications = append(notifications, &models.Notification{
+			UserID:    m.UserID,
+			Kind:      models.NotificationMention,
+			ActorID:   actorID,
+			PostID:    postID,
+			CommentID: commentID,
+		})
+	}
+	return notifications
+}
+
+func (n *Notifier) userFollowed(ctx context.Context, e events.UserFollowed) error {
+	return n.send(&models.Notification{
+		UserID:  e.FolloweeID,
+		Kind:    models.NotificationFollow,
+		ActorID: e.FollowerID,
+	})
+}
+
+// send stores notifications, dropping the ones users would get about
+// themselves and the ones caused by shadow-banned users.
+func (n *Notifier) send(notifications ...*models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	actorIDs := make([]int64, 0, len(notifications))
+	for _, notification := range notifications {
+		actorIDs = append(actorIDs, notification.ActorID)
+	}
+	banned, err := n.shadowBans.ShadowBannedUsers(actorIDs)
+	if err != nil {
+		return fmt.Errorf("check shadow bans: %w", err)
+	}
+
+	keep := notifications[:0]
+	for _, notification := range notifications {
+		if notification.UserID == notification.ActorID || banned[notification.ActorID] {
+			continue
+		}
+		keep = append(keep, notification)
+	}
+	if err := n.store.CreateNotifications(keep); err != nil {
+		return err
+	}
+	n.log.Debug("notifications sent", slog.Int("count", len(keep)))
+	return nil
+}
@@ -0,0 +1,20 @@
+package repository
+
+import "time"
+
+// Key is a position in a list ordered by (created_at, id), newest first
+// unless the list says otherwise.
+type Key struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// Page selects up to Limit rows of such a list: the ones after After, the
+// ones before Before, or the first ones when neither is set. In a list
+// ordered newest first the rows after a key are the older ones. Rows come
+// back in list order either way.
+type Page struct {
+	After  *Key
+	Before *Key
+	Limit  int
+}
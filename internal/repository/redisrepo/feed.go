@@ -0,0 +1,130 @@
+package redisrepo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// FeedKey is the sorted set of post ids in a user's home feed, scored by post id.
+func FeedKey(userID int64) string {
+	return fmt.Sprintf("feed:%d", userID)
+}
+
+// FeedPush adds a post to the feeds of the given users that are already
+// materialized, keeping at most maxItems entries in each. Missing feeds are
+// skipped: they are built from the database on their next read.
+func (rp *RedisRepo) FeedPush(ctx context.Context, userIDs []int64, postID int64, maxItems int) error {
+	if rp == nil || len(userIDs) == 0 {
+		return nil
+	}
+
+	exists := make([]*redis.IntCmd, len(userIDs))
+	_, err := rp.RDB.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range userIDs {
+			exists[i] = pipe.Exists(ctx, FeedKey(id))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis exists failed: %w", err)
+	}
+
+	_, err = rp.RDB.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range userIDs {
+			if exists[i].Val() == 0 {
+				continue
+			}
+			key := FeedKey(id)
+			pipe.ZAdd(ctx, key, redis.Z{Score: float64(postID), Member: postID})
+			pipe.ZRemRangeByRank(ctx, key, 0, int64(-maxItems-1))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis zadd failed: %w", err)
+	}
+	return nil
+}
+
+// FeedFill replaces a user's feed with the given post ids.
+func (rp *RedisRepo) FeedFill(ctx context.Context, userID int64, postIDs []int64, ttl time.Duration) error {
+	if rp == nil {
+		return nil
+	}
+
+	key := FeedKey(userID)
+	members := make([]redis.Z, len(postIDs))
+	for i, id := range postIDs {
+		members[i] = redis.Z{Score: float64(id), Member: id}
+	}
+	_, err := rp.RDB.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		if len(members) > 0 {
+			pipe.ZAdd(ctx, key, members...)
+			pipe.Expire(ctx, key, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis feed fill failed: %w", err)
+	}
+	return nil
+}
+
+// FeedRemove drops posts from a user's feed.
+func (rp *RedisRepo) FeedRemove(ctx context.Context, userID int64, postIDs []int64) error {
+	if rp == nil || len(postIDs) == 0 {
+		return nil
+	}
+
+	members := make([]any, len(postIDs))
+	for i, id := range postIDs {
+		members[i] = id
+	}
+	return rp.RDB.ZRem(ctx, FeedKey(userID), members...).Err()
+}
+
+// FeedPage returns up to limit post ids below beforeID (0 means from the
+// newest), newest first, and refreshes the feed's ttl. A feed that isn't
+// materialized yields KeyNotFound.
+func (rp *RedisRepo) FeedPage(ctx context.Context, userID, beforeID int64, limit int, ttl time.Duration) ([]int64, error) {
+	if rp == nil {
+		return nil, KeyNotFound
+	}
+
+	key := FeedKey(userID)
+	ok, err := rp.RDB.Expire(ctx, key, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis expire failed: %w", err)
+	}
+	if !ok {
+		return nil, KeyNotFound
+	}
+
+	max := "+inf"
+	if beforeID > 0 {
+		max = "(" + strconv.FormatInt(beforeID, 10)
+	}
+	members, err := rp.RDB.ZRevRangeByScore(ctx, key, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   max,
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis zrange failed: %w", err)
+	}
+
+	ids := make([]int64, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid feed member %q: %w", m, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
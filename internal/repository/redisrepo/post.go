@@ -22,6 +22,10 @@ type PostModel struct {
 	Username string `json:"username"`
 }
 
+func PostKey(postID int64) string {
+	return fmt.Sprintf("post:%d", postID)
+}
+
 type RedisRepo struct {
 	RDB *redis.Client
 }
@@ -41,7 +45,12 @@ func NewRedisClient(cfg *config.Config) (*RedisRepo, error) {
 	return &RedisRepo{RDB: rdb}, nil
 }
 
+// Set, Get and Delete are safe to call on a nil *RedisRepo, which behaves
+// as an always-empty cache when Redis is unavailable.
 func (rp *RedisRepo) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	if rp == nil {
+		return nil
+	}
 	data, err := json.Marshal(value)
 	if err != nil {
 		return fmt.Errorf("(redis) failed to marshal: %w", err)
@@ -55,6 +64,9 @@ func (rp *RedisRepo) Set(ctx context.Context, key string, value any, expiration
 }
 
 func (rp *RedisRepo) Get(ctx context.Context, key string, dest any) error {
+	if rp == nil {
+		return KeyNotFound
+	}
 	data, err := rp.RDB.Get(ctx, key).Bytes()
 	if err == redis.Nil {
 		return KeyNotFound
@@ -70,5 +82,8 @@ func (rp *RedisRepo) Get(ctx context.Context, key string, dest any) error {
 }
 
 func (rp *RedisRepo) Delete(ctx context.Context, key string) error {
+	if rp == nil {
+		return nil
+	}
 	return rp.RDB.Del(ctx, key).Err()
 }
@@ -0,0 +1,78 @@
+package redisrepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// StreamChannel is the pub/sub channel live events are fanned out on.
+	StreamChannel = "stream:events"
+	// StreamSeqKey is the counter event ids are taken from, shared by all instances.
+	StreamSeqKey = "stream:seq"
+)
+
+// StreamNextID returns the next event id.
+func (rp *RedisRepo) StreamNextID(ctx context.Context) (int64, error) {
+	id, err := rp.RDB.Incr(ctx, StreamSeqKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis incr failed: %w", err)
+	}
+	return id, nil
+}
+
+// StreamLastID returns the id of the newest event, 0 if there was none yet.
+func (rp *RedisRepo) StreamLastID(ctx context.Context) (int64, error) {
+	id, err := rp.RDB.Get(ctx, StreamSeqKey).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("redis get failed: %w", err)
+	}
+	return id, nil
+}
+
+// StreamPublish sends an encoded event to every instance.
+func (rp *RedisRepo) StreamPublish(ctx context.Context, payload []byte) error {
+	if err := rp.RDB.Publish(ctx, StreamChannel, payload).Err(); err != nil {
+		return fmt.Errorf("redis publish failed: %w", err)
+	}
+	return nil
+}
+
+// StreamSubscribe returns the events published by all instances until ctx
+// is done. The subscription is set up when it returns, so nothing
+// published afterwards is missed.
+func (rp *RedisRepo) StreamSubscribe(ctx context.Context) (<-chan []byte, error) {
+	sub := rp.RDB.Subscribe(ctx, StreamChannel)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, fmt.Errorf("redis subscribe failed: %w", err)
+	}
+
+	out := make(chan []byte, 64)
+	go func() {
+		defer close(out)
+		defer sub.Close()
+		messages := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case out <- []byte(msg.Payload):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
@@ -3,14 +3,17 @@ package repository
 import (
 	"blog/internal/models"
 	"errors"
+	"time"
 )
 
 var (
 	ErrNotExists             = errors.New("not found")
 	ErrOperationFailed       = errors.New("database operation failed")
 	ErrEmailAlreadyExists    = errors.New("email already exists")
-	ErrUsernameAlreadyExists = errors.New("userame already exists")
+	ErrUsernameAlreadyExists = errors.New("username already exists")
 	ErrForeignKeyFailed      = errors.New("foreign key not found")
+	ErrVersionConflict       = errors.New("version conflict")
+	ErrAlreadyExists         = errors.New("already exists")
 )
 
 type UserRepository interface {
@@ -18,7 +21,11 @@ type UserRepository interface {
 	CreateUser(user *models.User) (int64, error)
 	GetUserByID(id int64) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
+	GetUsersByIDs(ids []int64) (map[int64]*models.User, error)
+	GetUsersByUsernames(usernames []string) (map[string]*models.User, error)
 	UpdateUser(user *models.User) error
+	SetUserRole(id int64, role string) error
+	PromoteAdmins(emails []string) error
 	DeleteUser(id int64) error
 	ListUsers(limit, offset int) ([]*models.User, error)
 }
@@ -27,23 +34,186 @@ type PostRepository interface {
 	InitPostDatabase() error
 	CreatePost(post *models.Post) (int64, error)
 	GetPostByID(id int64) (*models.Post, error)
-	GetPostsByAuthor(authorID int64) ([]*models.Post, error)
+	GetPostsByAuthor(authorID int64, page Page) ([]*models.Post, error)
+	GetPostsByTag(tag string, page Page) ([]*models.Post, error)
 	UpdatePost(post *models.Post) error
 	DeletePost(id int64) error
-	ListPosts(limit, offset int) ([]*models.Post, error)
+	ListPosts(page Page) ([]*models.Post, error)
+	UpdateCommentSettings(postID, authorID int64, mode string, locked bool) error
+	SetPostHidden(id int64, hidden bool) error
+	RestorePost(id, authorID int64) error
+	ListDeletedPosts(authorID int64) ([]*models.Post, error)
+	PurgeDeletedPosts(before time.Time) (int64, error)
+	ListFeed(followerID, beforeID int64, limit int) ([]*models.Post, error)
+	GetPostsByIDs(ids []int64) (map[int64]*models.Post, error)
+	PostUpdateTimes() (map[int64]time.Time, error)
 }
 
 type CommentRepository interface {
 	InitCommentDatabase() error
 	CreateComment(comment *models.Comment) (int64, error)
+	GetCommentByID(id int64) (*models.Comment, error)
 	GetCommentAuthorID(commentID int64) (int64, error)
 	UpdateComment(comment *models.Comment) error
 	DeleteComment(id int64) error
-	ListComments(limit int, offset int, postID int64) ([]*models.Comment, error)
+	ListComments(page Page, postID, viewerID int64) ([]*models.Comment, error)
+	ListCommentsByPosts(postIDs []int64, viewerID int64, limit int) (map[int64][]*models.Comment, error)
+	RestoreComment(id, authorID int64) error
+	ListDeletedComments(authorID int64) ([]*models.Comment, error)
+	PurgeDeletedComments(before time.Time) (int64, error)
+	ListThread(postID int64) ([]*models.Comment, error)
+	HasApprovedComment(authorID int64) (bool, error)
+	ListCommentsByStatus(status string, page Page) ([]*models.Comment, error)
+	SetCommentStatus(ids []int64, status string) ([]int64, error)
+	CountCommentsSince(authorID int64, since time.Time) (int, error)
+	CountDuplicateComments(authorID int64, content string, since time.Time) (int, error)
+}
+
+type RevisionRepository interface {
+	InitRevisionDatabase() error
+	ListRevisions(postID int64) ([]*models.PostRevision, error)
+	GetRevision(postID, revisionID int64) (*models.PostRevision, error)
+}
+
+type SpamRepository interface {
+	InitSpamDatabase() error
+	TokenCounts(tokens []string) (map[string]*models.SpamToken, error)
+	TrainingTotals() (spam, ham int64, err error)
+	Train(commentID int64, tokens []string, spam bool) error
+}
+
+type ReportRepository interface {
+	InitReportDatabase() error
+	CreateReport(report *models.Report) (int64, error)
+	CountOpenReporters(targetType string, targetID int64) (int, error)
+	ListOpenReportTargets(page Page) ([]*models.ReportTarget, error)
+	ResolveReports(targetType string, targetID int64, status string, resolverID int64, resolution string) (int64, error)
+}
+
+type AuditRepository interface {
+	InitAuditDatabase() error
+	AddAuditEntry(entry *models.AuditEntry) error
+	ListAuditEntries(page Page) ([]*models.AuditEntry, error)
+}
+
+type SanctionRepository interface {
+	InitSanctionDatabase() error
+	CreateSanction(sanction *models.Sanction) (int64, error)
+	ListSanctions(userID int64) ([]*models.Sanction, error)
+	ActiveSanction(userID int64, kinds ...string) (*models.Sanction, error)
+	ShadowBannedUsers(ids []int64) (map[int64]bool, error)
+	RevokeSanction(id, revokedBy int64) (*models.Sanction, error)
+}
+
+type ReactionRepository interface {
+	InitReactionDatabase() error
+	AddReaction(reaction *models.Reaction) (bool, error)
+	RemoveReaction(reaction *models.Reaction) (bool, error)
+	ReactionSummaries(targetType string, ids []int64) (map[int64]*models.ReactionSummary, error)
+}
+
+type BookmarkRepository interface {
+	InitBookmarkDatabase() error
+	SaveBookmark(bookmark *models.Bookmark) error
+	DeleteBookmark(userID, postID int64) (bool, error)
+	ListBookmarks(userID int64, collectionID *int64, beforeID int64, limit int) ([]*models.Bookmark, error)
+	BookmarkedPosts(userID int64, postIDs []int64) (map[int64]bool, error)
+	CreateCollection(collection *models.BookmarkCollection) (int64, error)
+	ListCollections(userID int64) ([]*models.BookmarkCollection, error)
+	DeleteCollection(id, userID int64) error
+}
+
+type FollowRepository interface {
+	InitFollowDatabase() error
+	Follow(followerID, followeeID int64) (bool, error)
+	Unfollow(followerID, followeeID int64) (bool, error)
+	ListFollowers(userID int64, page Page) ([]*models.Follow, error)
+	ListFollowing(userID int64, page Page) ([]*models.Follow, error)
+	FollowerIDs(userID int64) ([]int64, error)
+	CountFollows(userID int64) (followers, following int64, err error)
+}
+
+type NotificationRepository interface {
+	InitNotificationDatabase() error
+	CreateNotifications(notifications []*models.Notification) error
+	ListNotifications(userID int64, unreadOnly bool, beforeID int64, limit int) ([]*models.Notification, error)
+	CountUnread(userID int64) (int64, error)
+	MarkRead(userID int64, ids []int64) (int64, error)
+	MarkAllRead(userID int64) (int64, error)
+}
+
+type MentionRepository interface {
+	InitMentionDatabase() error
+	ReplaceMentions(targetType string, targetID int64, mentions []*models.Mention) error
+	ListMentions(targetType string, ids []int64) (map[int64][]*models.Mention, error)
+}
+
+type FederationRepository interface {
+	InitFederationDatabase() error
+	ActorKey(userID int64) (*models.ActorKey, error)
+	SaveActorKey(key *models.ActorKey) (*models.ActorKey, error)
+	SaveRemoteActor(actor *models.RemoteActor) error
+	GetRemoteActor(actorID string) (*models.RemoteActor, error)
+	SetRemoteActorUser(id, userID int64) error
+	AddRemoteFollower(userID, remoteActorID int64) (bool, error)
+	RemoveRemoteFollower(userID, remoteActorID int64) (bool, error)
+	FollowerInboxes(userID int64) ([]string, error)
+	CountRemoteFollowers(userID int64) (int64, error)
+	SetPostFederated(postID, userID int64, deleted bool) error
+	FederatedPost(postID int64) (userID int64, deleted bool, err error)
+	SaveRemoteObject(objectID string, commentID int64) error
+	RemoteObjectComment(objectID string) (int64, error)
+	EnqueueDeliveries(userID int64, inboxes []string, activity []byte) error
+	DueDeliveries(now time.Time, limit int) ([]*models.Delivery, error)
+	RetryDelivery(id int64, next time.Time, lastError string) error
+	DeleteDelivery(id int64) error
+}
+
+type WebmentionRepository interface {
+	InitWebmentionDatabase() error
+	EnqueueWebmentionJobs(kind, source string, targets []string) error
+	DueWebmentionJobs(now time.Time, limit int) ([]*models.WebmentionJob, error)
+	RetryWebmentionJob(id int64, next time.Time, lastError string) error
+	DeleteWebmentionJob(id int64) error
+	SaveWebmention(m *models.Webmention) error
+	DeleteWebmention(postID int64, source string) (bool, error)
+	ListWebmentions(postID int64) ([]*models.Webmention, error)
+	ReplaceWebmentionTargets(postID int64, targets []string) ([]string, error)
+	WebmentionTargets(postID int64) ([]string, error)
+}
+
+type WebhookRepository interface {
+	InitWebhookDatabase() error
+	CreateWebhook(h *models.Webhook) error
+	GetWebhook(id int64) (*models.Webhook, error)
+	ListWebhooks() ([]*models.Webhook, error)
+	WebhooksFor(event string) ([]*models.Webhook, error)
+	UpdateWebhook(h *models.Webhook) error
+	DeleteWebhook(id int64) error
+	EnqueueWebhookDeliveries(event string, payload []byte, webhookIDs []int64) error
+	DueWebhookDeliveries(now time.Time, limit int) ([]*models.WebhookDelivery, error)
+	ListWebhookDeliveries(webhookID int64, page Page) ([]*models.WebhookDelivery, error)
+	CompleteWebhookDelivery(id int64, status string, responseStatus int, lastError string) error
+	RetryWebhookDelivery(id int64, next time.Time, responseStatus int, lastError string) error
+	RedeliverWebhookDelivery(webhookID, deliveryID int64) (*models.WebhookDelivery, error)
+	PurgeWebhookDeliveries(before time.Time) (int64, error)
 }
 
 type Repository interface {
 	User() UserRepository
 	Post() PostRepository
 	Comment() CommentRepository
+	Revision() RevisionRepository
+	Spam() SpamRepository
+	Report() ReportRepository
+	Audit() AuditRepository
+	Sanction() SanctionRepository
+	Reaction() ReactionRepository
+	Bookmark() BookmarkRepository
+	Follow() FollowRepository
+	Notification() NotificationRepository
+	Mention() MentionRepository
+	Federation() FederationRepository
+	Webmention() WebmentionRepository
+	Webhook() WebhookRepository
 }
@@ -0,0 +1,104 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type SQliteAuditRepo struct {
+	log logger.Logger
+	db  *sql.DB
+}
+
+// InitAuditDatabase creates the append-only audit_log table. Entries keep the
+// actor id even after the user is deleted, so there is no foreign key.
+func (r *SQliteAuditRepo) InitAuditDatabase() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor_id INTEGER,
+		action TEXT NOT NULL,
+		target_type TEXT NOT NULL,
+		target_id INTEGER NOT NULL,
+		details TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_type, target_id);
+	`
+	if _, err := r.db.Exec(stmt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SQliteAuditRepo) AddAuditEntry(entry *models.AuditEntry) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.AddAuditEntry"), slog.String("action", entry.Action))
+	query := `
+		INSERT INTO audit_log (actor_id, action, target_type, target_id, details)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRow(query,
+		entry.ActorID,
+		entry.Action,
+		entry.TargetType,
+		entry.TargetID,
+		entry.Details).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		log.Error("failed to add audit entry", sl.Error(err))
+		return fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// ListAuditEntries returns a page of the audit log, newest first.
+func (r *SQliteAuditRepo) ListAuditEntries(page repository.Page) ([]*models.AuditEntry, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListAuditEntries"))
+	query := `
+		SELECT id, actor_id, action, target_type, target_id, details, created_at
+		FROM audit_log
+	`
+	cond, tail, args := keyset(page, "created_at", "id")
+	if cond != "" {
+		query += ` WHERE ` + cond
+	}
+	query += tail
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to list audit entries", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(
+			&e.ID,
+			&e.ActorID,
+			&e.Action,
+			&e.TargetType,
+			&e.TargetID,
+			&e.Details,
+			&e.CreatedAt,
+		); err != nil {
+			log.Error("failed to scan audit entry", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		entries = append(entries, &e)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return keysetRows(page, entries), nil
+}
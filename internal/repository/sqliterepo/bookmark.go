@@ -0,0 +1,261 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type SQliteBookmarkRepo struct {
+	log logger.Logger
+	db  *sql.DB
+}
+
+func (r *SQliteBookmarkRepo) InitBookmarkDatabase() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS bookmark_collection (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, name),
+		FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS bookmark (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		post_id INTEGER NOT NULL,
+		collection_id INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, post_id),
+		FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+		FOREIGN KEY (post_id) REFERENCES post(id) ON DELETE CASCADE,
+		FOREIGN KEY (collection_id) REFERENCES bookmark_collection(id) ON DELETE SET NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_bookmark_user_id ON bookmark(user_id, id);
+	CREATE INDEX IF NOT EXISTS idx_bookmark_collection_id ON bookmark(collection_id);
+	`
+	_, err := r.db.Exec(stmt)
+	return err
+}
+
+// SaveBookmark bookmarks a post, or moves an existing bookmark to another
+// collection. The collection has to belong to the same user.
+func (r *SQliteBookmarkRepo) SaveBookmark(bookmark *models.Bookmark) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.SaveBookmark"),
+		slog.Int64("user_id", bookmark.UserID), slog.Int64("post_id", bookmark.PostID))
+
+	if bookmark.CollectionID != nil {
+		var exists bool
+		err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM bookmark_collection WHERE id = ? AND user_id = ?)`,
+			*bookmark.CollectionID, bookmark.UserID).Scan(&exists)
+		if err != nil {
+			log.Error("failed to check collection", sl.Error(err))
+			return fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+		}
+		if !exists {
+			return repository.ErrForeignKeyFailed
+		}
+	}
+
+	query := `
+		INSERT INTO bookmark (user_id, post_id, collection_id)
+		VALUES (?, ?, ?)
+		ON CONFLICT (user_id, post_id) DO UPDATE SET collection_id = excluded.collection_id
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRow(query, bookmark.UserID, bookmark.PostID, bookmark.CollectionID).Scan(&bookmark.ID, &bookmark.CreatedAt)
+	if err != nil {
+		if isForeignKeyErr(err) {
+			return repository.ErrForeignKeyFailed
+		}
+		log.Error("failed to save bookmark", sl.Error(err))
+		return fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// DeleteBookmark removes the bookmark and reports whether it existed.
+func (r *SQliteBookmarkRepo) DeleteBookmark(userID, postID int64) (bool, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.DeleteBookmark"),
+		slog.Int64("user_id", userID), slog.Int64("post_id", postID))
+
+	res, err := r.db.Exec(`DELETE FROM bookmark WHERE user_id = ? AND post_id = ?`, userID, postID)
+	if err != nil {
+		log.Error("failed to delete bookmark", sl.Error(err))
+		return false, fmt.Errorf("delete error: %w", repository.ErrOperationFailed)
+	}
+
+	deleted, _ := res.RowsAffected()
+	return deleted > 0, nil
+}
+
+// ListBookmarks returns the newest bookmarks of a user with ids below beforeID
+// (0 means from the start). Bookmarks of deleted or hidden posts are skipped.
+func (r *SQliteBookmarkRepo) ListBookmarks(userID int64, collectionID *int64, beforeID int64, limit int) ([]*models.Bookmark, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListBookmarks"), slog.Int64("user_id", userID))
+
+	query := `
+		SELECT b.id, b.user_id, b.post_id, b.collection_id, b.created_at,
+			p.title, p.author_id, p.version, p.created_at, p.updated_at
+		FROM bookmark b
+		JOIN post p ON p.id = b.post_id
+		WHERE b.user_id = ? AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+	`
+	args := []any{userID}
+	if collectionID != nil {
+		query += ` AND b.collection_id = ?`
+		args = append(args, *collectionID)
+	}
+	if beforeID > 0 {
+		query += ` AND b.id < ?`
+		args = append(args, beforeID)
+	}
+	query += ` ORDER BY b.id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to list bookmarks", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var bookmarks []*models.Bookmark
+	for rows.Next() {
+		b := &models.Bookmark{Post: &models.Post{}}
+		if err := rows.Scan(&b.ID, &b.UserID, &b.PostID, &b.CollectionID, &b.CreatedAt,
+			&b.Post.Title, &b.Post.AuthorID, &b.Post.Version, &b.Post.CreatedAt, &b.Post.UpdatedAt); err != nil {
+			log.Error("failed to scan bookmark", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		b.Post.ID = b.PostID
+		bookmarks = append(bookmarks, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return bookmarks, nil
+}
+
+// BookmarkedPosts reports which of the posts the user has bookmarked.
+func (r *SQliteBookmarkRepo) BookmarkedPosts(userID int64, postIDs []int64) (map[int64]bool, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.BookmarkedPosts"), slog.Int64("user_id", userID))
+	bookmarked := make(map[int64]bool, len(postIDs))
+	if len(postIDs) == 0 {
+		return bookmarked, nil
+	}
+
+	args := make([]any, 0, len(postIDs)+1)
+	args = append(args, userID)
+	for _, id := range postIDs {
+		args = append(args, id)
+	}
+	query := `SELECT post_id FROM bookmark WHERE user_id = ? AND post_id IN (?` + strings.Repeat(",?", len(postIDs)-1) + `)`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to query bookmarks", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			log.Error("failed to scan bookmark", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		bookmarked[id] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return bookmarked, nil
+}
+
+func (r *SQliteBookmarkRepo) CreateCollection(collection *models.BookmarkCollection) (int64, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.CreateCollection"), slog.Int64("user_id", collection.UserID))
+	query := `
+		INSERT INTO bookmark_collection (user_id, name)
+		VALUES (?, ?)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRow(query, collection.UserID, collection.Name).Scan(&collection.ID, &collection.CreatedAt)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
+			return 0, repository.ErrAlreadyExists
+		}
+		log.Error("failed to create collection", sl.Error(err))
+		return 0, fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+	}
+	return collection.ID, nil
+}
+
+// ListCollections returns the collections of a user by name, with the number of bookmarks in each.
+func (r *SQliteBookmarkRepo) ListCollections(userID int64) ([]*models.BookmarkCollection, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListCollections"), slog.Int64("user_id", userID))
+	query := `
+		SELECT c.id, c.user_id, c.name, c.created_at, COUNT(b.id)
+		FROM bookmark_collection c
+		LEFT JOIN bookmark b ON b.collection_id = c.id
+		WHERE c.user_id = ?
+		GROUP BY c.id
+		ORDER BY c.name
+	`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		log.Error("failed to list collections", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var collections []*models.BookmarkCollection
+	for rows.Next() {
+		var c models.BookmarkCollection
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.CreatedAt, &c.Bookmarks); err != nil {
+			log.Error("failed to scan collection", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		collections = append(collections, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return collections, nil
+}
+
+// DeleteCollection removes a collection of the user. Its bookmarks are kept
+// without a collection.
+func (r *SQliteBookmarkRepo) DeleteCollection(id, userID int64) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.DeleteCollection"), slog.Int64("collection_id", id))
+
+	res, err := r.db.Exec(`DELETE FROM bookmark_collection WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		log.Error("failed to delete collection", sl.Error(err))
+		return fmt.Errorf("delete error: %w", repository.ErrOperationFailed)
+	}
+
+	deleted, _ := res.RowsAffected()
+	if deleted == 0 {
+		return repository.ErrNotExists
+	}
+	return nil
+}
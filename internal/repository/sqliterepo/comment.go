@@ -5,10 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
-	"github.com/mattn/go-sqlite3"
-
 	"blog/internal/models"
 	"blog/internal/repository"
 	"blog/internal/util/logger"
@@ -26,11 +25,18 @@ func (r *SQliteCommentRepo) InitCommentDatabase() error {
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		content TEXT NOT NULL,
 		post_id INTEGER NOT NULL,
+		parent_id INTEGER,
+		depth INTEGER NOT NULL DEFAULT 0,
+		status TEXT NOT NULL DEFAULT 'approved',
+		spam_score REAL NOT NULL DEFAULT 0,
 		author_id INTEGER,
+		version INTEGER NOT NULL DEFAULT 1,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		deleted_at DATETIME,
 		FOREIGN KEY (post_id) REFERENCES post(id) ON DELETE CASCADE,
-		FOREIGN KEY (author_id) REFERENCES user(id) ON DELETE SET NULL
+		FOREIGN KEY (author_id) REFERENCES user(id) ON DELETE SET NULL,
+		FOREIGN KEY (parent_id) REFERENCES comment(id) ON DELETE SET NULL
 	);
 
 	`
@@ -38,26 +44,62 @@ func (r *SQliteCommentRepo) InitCommentDatabase() error {
 		return err
 	}
 
-	return nil
+	if err := addColumn(r.db, "comment", "version", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return err
+	}
+	if err := addColumn(r.db, "comment", "deleted_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := addColumn(r.db, "comment", "parent_id", "INTEGER REFERENCES comment(id) ON DELETE SET NULL"); err != nil {
+		return err
+	}
+	if err := addColumn(r.db, "comment", "depth", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	if err := addColumn(r.db, "comment", "status", "TEXT NOT NULL DEFAULT 'approved'"); err != nil {
+		return err
+	}
+	if err := addColumn(r.db, "comment", "spam_score", "REAL NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	_, err := r.db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_comment_post_id ON comment(post_id, created_at);
+		CREATE INDEX IF NOT EXISTS idx_comment_status ON comment(status, created_at);
+		CREATE INDEX IF NOT EXISTS idx_comment_author_id ON comment(author_id, created_at);
+	`)
+	return err
 }
 
 func (r *SQliteCommentRepo) CreateComment(comment *models.Comment) (int64, error) {
 	log := r.log.With("fn", "repository.sqliterepo.CreateComment")
 	query := `
-		INSERT INTO comment (content, post_id, author_id)
-		VALUES (?, ?, ?)
-		RETURNING id, created_at, updated_at
+		INSERT INTO comment (content, post_id, parent_id, depth, status, spam_score, author_id)
+		SELECT ?, ?, ?, ?, ?, ?, ?
+		WHERE EXISTS (SELECT 1 FROM post WHERE id = ? AND deleted_at IS NULL)
+		RETURNING id, version, created_at, updated_at
     	`
 
-	var id int64
+	var id, version int64
 	var createdAt, updatedAt time.Time
-	err := r.db.QueryRow(query, comment.Content, comment.PostID, comment.AuthorID).Scan(
+	err := r.db.QueryRow(query,
+		comment.Content,
+		comment.PostID,
+		comment.ParentID,
+		comment.Depth,
+		comment.Status,
+		comment.SpamScore,
+		comment.AuthorID,
+		comment.PostID).Scan(
 		&id,
+		&version,
 		&createdAt,
 		&updatedAt,
 	)
 	if err != nil {
-		if errors.Is(err, sqlite3.ErrConstraintForeignKey) {
+		// no row is inserted when the post does not exist or is in the trash
+		if errors.Is(err, sql.ErrNoRows) || isForeignKeyErr(err) {
 			return 0, repository.ErrForeignKeyFailed
 		}
 		log.Error("failed to create comment", sl.Error(err))
@@ -65,14 +107,48 @@ func (r *SQliteCommentRepo) CreateComment(comment *models.Comment) (int64, error
 	}
 
 	comment.ID = id
+	comment.Version = version
 	comment.CreatedAt = createdAt
 	comment.UpdatedAt = updatedAt
 	return id, nil
 }
 
+func (r *SQliteCommentRepo) GetCommentByID(id int64) (*models.Comment, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.GetCommentByID"), slog.Int64("comment_id", id))
+	query := `
+		SELECT id, content, post_id, parent_id, depth, status, author_id, version, created_at, updated_at
+		FROM comment
+		WHERE id = ? AND deleted_at IS NULL
+	`
+
+	var comment models.Comment
+	err := r.db.QueryRow(query, id).Scan(
+		&comment.ID,
+		&comment.Content,
+		&comment.PostID,
+		&comment.ParentID,
+		&comment.Depth,
+		&comment.Status,
+		&comment.AuthorID,
+		&comment.Version,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Info("comment not found")
+			return nil, repository.ErrNotExists
+		}
+		log.Error("failed to get comment", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+
+	return &comment, nil
+}
+
 func (r *SQliteCommentRepo) GetCommentAuthorID(commentID int64) (int64, error) {
 	log := r.log.With(slog.String("fn", "repository.sqliterepo.GetCommentAuthor"), slog.Int64("comment_id", commentID))
-	query := `SELECT author_id FROM comment WHERE id = ?`
+	query := `SELECT author_id FROM comment WHERE id = ? AND deleted_at IS NULL`
 
 	var authorID int64
 	err := r.db.QueryRow(query, commentID).Scan(&authorID)
@@ -88,20 +164,49 @@ func (r *SQliteCommentRepo) GetCommentAuthorID(commentID int64) (int64, error) {
 	return authorID, nil
 }
 
+// UpdateComment overwrites the content if comment.Version still matches the stored
+// version, and bumps the version. A stale version yields repository.ErrVersionConflict.
 func (r *SQliteCommentRepo) UpdateComment(comment *models.Comment) error {
 	log := r.log.With(slog.String("fn", "repository.sqliterepo.UpdateComment"))
 	query := `
 		UPDATE comment
-		SET content = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ? AND post_id = ? AND author_id = ?
+		SET content = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND post_id = ? AND author_id = ? AND version = ? AND deleted_at IS NULL
+		RETURNING version, updated_at
 	`
 
-	res, err := r.db.Exec(query,
+	var version int64
+	var updatedAt time.Time
+	err := r.db.QueryRow(query,
 		comment.Content,
 		comment.ID,
 		comment.PostID,
-		comment.AuthorID)
+		comment.AuthorID,
+		comment.Version).Scan(&version, &updatedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		var current int64
+		err = r.db.QueryRow(`SELECT version FROM comment WHERE id = ? AND post_id = ? AND author_id = ? AND deleted_at IS NULL`,
+			comment.ID,
+			comment.PostID,
+			comment.AuthorID).Scan(&current)
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Info("comment not found or author mismatch or post mismatch",
+				slog.Int64("id", comment.ID),
+				slog.Int64("post_id", comment.PostID),
+				slog.Int64("author_id", comment.AuthorID))
+
+			return repository.ErrNotExists
+		}
+		if err == nil {
+			log.Info("comment version conflict",
+				slog.Int64("id", comment.ID),
+				slog.Int64("expected_version", comment.Version),
+				slog.Int64("current_version", current))
 
+			return repository.ErrVersionConflict
+		}
+	}
 	if err != nil {
 		log.Error("update query failed",
 			sl.Error(err),
@@ -112,28 +217,15 @@ func (r *SQliteCommentRepo) UpdateComment(comment *models.Comment) error {
 		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
 	}
 
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		log.Error("rows affected check failed", sl.Error(err))
-
-		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
-	}
-
-	if rowsAffected == 0 {
-		log.Info("comment not found or author mismatch or post mismatch",
-			slog.Int64("id", comment.ID),
-			slog.Int64("post_id", comment.PostID),
-			slog.Int64("author_id", comment.AuthorID))
-
-		return repository.ErrNotExists
-	}
-
+	comment.Version = version
+	comment.UpdatedAt = updatedAt
 	return nil
 }
 
+// DeleteComment moves the comment to the trash. It stays restorable until purged.
 func (r *SQliteCommentRepo) DeleteComment(id int64) error {
 	log := r.log.With("fn", "repository.sqliterepo.DeleteComment")
-	query := `DELETE FROM comment WHERE id = ?`
+	query := `UPDATE comment SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL`
 	res, err := r.db.Exec(query, id)
 	if err != nil {
 		log.Error("failed to delete comment", sl.Error(err), slog.Int64("id", id))
@@ -149,16 +241,26 @@ func (r *SQliteCommentRepo) DeleteComment(id int64) error {
 	return nil
 }
 
-func (r *SQliteCommentRepo) ListComments(limit int, offset int, postID int64) ([]*models.Comment, error) {
+// ListComments returns a page of the published comments of a post, newest
+// first. Comments of shadow-banned users are left out unless they are the viewer's.
+func (r *SQliteCommentRepo) ListComments(page repository.Page, postID, viewerID int64) ([]*models.Comment, error) {
 	log := r.log.With("fn", "repository.sqliterepo.ListComment")
 	query := `
-		SELECT id, content, post_id, author_id, created_at, updated_at
+		SELECT id, content, post_id, parent_id, depth, status, author_id, version, created_at, updated_at
 		FROM comment
-		WHERE post_id = ?
-		ORDER BY created_at DESC
-		LIMIT ? OFFSET ?
-    	`
-	rows, err := r.db.Query(query, postID, limit, offset)
+		WHERE post_id = ? AND deleted_at IS NULL AND status = 'approved'
+			AND EXISTS (SELECT 1 FROM post WHERE post.id = comment.post_id AND post.deleted_at IS NULL)
+			AND (comment.author_id = ? OR NOT ` + fmt.Sprintf(activeSanctionCond, "comment.author_id", "'shadow_ban'") + `)
+	`
+	args := []any{postID, viewerID}
+	cond, tail, pageArgs := keyset(page, "created_at", "id")
+	if cond != "" {
+		query += ` AND ` + cond
+	}
+	query += tail
+	args = append(args, pageArgs...)
+
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		log.Error("failed to list comments", sl.Error(err))
 		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
@@ -172,7 +274,11 @@ func (r *SQliteCommentRepo) ListComments(limit int, offset int, postID int64) ([
 			&comment.ID,
 			&comment.Content,
 			&comment.PostID,
+			&comment.ParentID,
+			&comment.Depth,
+			&comment.Status,
 			&comment.AuthorID,
+			&comment.Version,
 			&comment.CreatedAt,
 			&comment.UpdatedAt,
 		); err != nil {
@@ -186,5 +292,329 @@ func (r *SQliteCommentRepo) ListComments(limit int, offset int, postID int64) ([
 		log.Error("iteration error", "error", err)
 		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
 	}
+	return keysetRows(page, comments), nil
+}
+
+// ListCommentsByPosts returns the newest limit comments of each post, the
+// ones ListComments would show on its first page, in one query.
+func (r *SQliteCommentRepo) ListCommentsByPosts(postIDs []int64, viewerID int64, limit int) (map[int64][]*models.Comment, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListCommentsByPosts"))
+	result := make(map[int64][]*models.Comment, len(postIDs))
+	if len(postIDs) == 0 {
+		return result, nil
+	}
+
+	args := make([]any, 0, len(postIDs)+2)
+	for _, id := range postIDs {
+		args = append(args, id)
+	}
+	args = append(args, viewerID, limit)
+	query := `
+		SELECT id, content, post_id, parent_id, depth, status, author_id, version, created_at, updated_at
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY post_id ORDER BY created_at DESC, id DESC) AS n
+			FROM comment
+			WHERE post_id IN (?` + strings.Repeat(",?", len(postIDs)-1) + `) AND deleted_at IS NULL AND status = 'approved'
+				AND EXISTS (SELECT 1 FROM post WHERE post.id = comment.post_id AND post.deleted_at IS NULL)
+				AND (comment.author_id = ? OR NOT ` + fmt.Sprintf(activeSanctionCond, "comment.author_id", "'shadow_ban'") + `)
+		)
+		WHERE n <= ?
+		ORDER BY post_id, created_at DESC, id DESC
+	`
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to list comments", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var comment models.Comment
+		if err := rows.Scan(
+			&comment.ID,
+			&comment.Content,
+			&comment.PostID,
+			&comment.ParentID,
+			&comment.Depth,
+			&comment.Status,
+			&comment.AuthorID,
+			&comment.Version,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+		); err != nil {
+			log.Error("failed to scan comment", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		result[comment.PostID] = append(result[comment.PostID], &comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return result, nil
+}
+
+func (r *SQliteCommentRepo) RestoreComment(id, authorID int64) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.RestoreComment"), slog.Int64("id", id))
+	query := `
+		UPDATE comment
+		SET deleted_at = NULL
+		WHERE id = ? AND author_id = ? AND deleted_at IS NOT NULL
+	`
+	res, err := r.db.Exec(query, id, authorID)
+	if err != nil {
+		log.Error("failed to restore comment", sl.Error(err))
+		return fmt.Errorf("restore error: %w", repository.ErrOperationFailed)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		log.Info("comment not found in trash", slog.Int64("author_id", authorID))
+		return repository.ErrNotExists
+	}
+	return nil
+}
+
+func (r *SQliteCommentRepo) ListDeletedComments(authorID int64) ([]*models.Comment, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListDeletedComments"), slog.Int64("author_id", authorID))
+	query := `
+		SELECT id, content, post_id, author_id, version, created_at, updated_at, deleted_at
+		FROM comment
+		WHERE author_id = ? AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+	`
+	rows, err := r.db.Query(query, authorID)
+	if err != nil {
+		log.Error("failed to list deleted comments", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var comments []*models.Comment
+	for rows.Next() {
+		var comment models.Comment
+		if err := rows.Scan(
+			&comment.ID,
+			&comment.Content,
+			&comment.PostID,
+			&comment.AuthorID,
+			&comment.Version,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+			&comment.DeletedAt,
+		); err != nil {
+			log.Error("failed to scan comment", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		comments = append(comments, &comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return comments, nil
+}
+
+// PurgeDeletedComments permanently removes comments trashed before the given time.
+// Comments that still have replies are kept as "[deleted]" placeholders of their thread.
+func (r *SQliteCommentRepo) PurgeDeletedComments(before time.Time) (int64, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.PurgeDeletedComments"))
+	query := `
+		DELETE FROM comment
+		WHERE deleted_at IS NOT NULL AND deleted_at < ?
+			AND NOT EXISTS (SELECT 1 FROM comment AS reply WHERE reply.parent_id = comment.id)
+	`
+
+	res, err := r.db.Exec(query, before.UTC().Format(time.DateTime))
+	if err != nil {
+		log.Error("failed to purge comments", sl.Error(err))
+		return 0, fmt.Errorf("purge error: %w", repository.ErrOperationFailed)
+	}
+
+	purged, _ := res.RowsAffected()
+	return purged, nil
+}
+
+// ListThread returns every comment of a post in creation order, including trashed
+// ones, so that replies to a deleted comment can still be attached to the thread.
+func (r *SQliteCommentRepo) ListThread(postID int64) ([]*models.Comment, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListThread"), slog.Int64("post_id", postID))
+	query := `
+		SELECT id, content, post_id, parent_id, depth, status, author_id, version, created_at, updated_at, deleted_at
+		FROM comment
+		WHERE post_id = ?
+			AND EXISTS (SELECT 1 FROM post WHERE post.id = comment.post_id AND post.deleted_at IS NULL)
+		ORDER BY created_at ASC, id ASC
+	`
+	rows, err := r.db.Query(query, postID)
+	if err != nil {
+		log.Error("failed to list thread", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var comments []*models.Comment
+	for rows.Next() {
+		var comment models.Comment
+		if err := rows.Scan(
+			&comment.ID,
+			&comment.Content,
+			&comment.PostID,
+			&comment.ParentID,
+			&comment.Depth,
+			&comment.Status,
+			&comment.AuthorID,
+			&comment.Version,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+			&comment.DeletedAt,
+		); err != nil {
+			log.Error("failed to scan comment", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		comments = append(comments, &comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
 	return comments, nil
 }
+
+// HasApprovedComment reports whether the user has at least one approved comment.
+func (r *SQliteCommentRepo) HasApprovedComment(authorID int64) (bool, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.HasApprovedComment"), slog.Int64("author_id", authorID))
+	query := `SELECT EXISTS (SELECT 1 FROM comment WHERE author_id = ? AND status = 'approved')`
+
+	var exists bool
+	if err := r.db.QueryRow(query, authorID).Scan(&exists); err != nil {
+		log.Error("failed to check approved comments", sl.Error(err))
+		return false, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return exists, nil
+}
+
+// ListCommentsByStatus returns a page of the comments with a moderation status, oldest first.
+func (r *SQliteCommentRepo) ListCommentsByStatus(status string, page repository.Page) ([]*models.Comment, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListCommentsByStatus"), slog.String("status", status))
+	query := `
+		SELECT id, content, post_id, parent_id, depth, status, spam_score, author_id, version, created_at, updated_at
+		FROM comment
+		WHERE status = ? AND deleted_at IS NULL
+	`
+	args := []any{status}
+	cond, tail, pageArgs := keysetOldestFirst(page, "created_at", "id")
+	if cond != "" {
+		query += ` AND ` + cond
+	}
+	query += tail
+	args = append(args, pageArgs...)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to list comments", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var comments []*models.Comment
+	for rows.Next() {
+		var comment models.Comment
+		if err := rows.Scan(
+			&comment.ID,
+			&comment.Content,
+			&comment.PostID,
+			&comment.ParentID,
+			&comment.Depth,
+			&comment.Status,
+			&comment.SpamScore,
+			&comment.AuthorID,
+			&comment.Version,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+		); err != nil {
+			log.Error("failed to scan comment", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		comments = append(comments, &comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return keysetRows(page, comments), nil
+}
+
+// SetCommentStatus moves the given comments to a moderation status and returns
+// the ids that were actually changed.
+func (r *SQliteCommentRepo) SetCommentStatus(ids []int64, status string) ([]int64, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.SetCommentStatus"), slog.String("status", status))
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, status)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	query := `
+		UPDATE comment
+		SET status = ?
+		WHERE deleted_at IS NULL AND id IN (?` + strings.Repeat(",?", len(ids)-1) + `)
+		RETURNING id
+	`
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to set comment status", sl.Error(err))
+		return nil, fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var changed []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			log.Error("failed to scan comment id", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		changed = append(changed, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return changed, nil
+}
+
+// CountCommentsSince returns how many comments the user has written since the given time.
+func (r *SQliteCommentRepo) CountCommentsSince(authorID int64, since time.Time) (int, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.CountCommentsSince"), slog.Int64("author_id", authorID))
+	query := `SELECT COUNT(*) FROM comment WHERE author_id = ? AND created_at >= ?`
+
+	var count int
+	if err := r.db.QueryRow(query, authorID, since.UTC().Format(time.DateTime)).Scan(&count); err != nil {
+		log.Error("failed to count comments", sl.Error(err))
+		return 0, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return count, nil
+}
+
+// CountDuplicateComments returns how many comments with the same content the
+// user has written since the given time.
+func (r *SQliteCommentRepo) CountDuplicateComments(authorID int64, content string, since time.Time) (int, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.CountDuplicateComments"), slog.Int64("author_id", authorID))
+	query := `SELECT COUNT(*) FROM comment WHERE author_id = ? AND content = ? AND created_at >= ?`
+
+	var count int
+	if err := r.db.QueryRow(query, authorID, content, since.UTC().Format(time.DateTime)).Scan(&count); err != nil {
+		log.Error("failed to count duplicate comments", sl.Error(err))
+		return 0, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return count, nil
+}
@@ -0,0 +1,393 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type SQliteFederationRepo struct {
+	log logger.Logger
+	db  *sql.DB
+}
+
+// InitFederationDatabase creates the ActivityPub tables: signing keys of
+// local users, known remote actors and which of them follow whom, posts
+// sent out as notes, remote notes stored as comments and the delivery queue.
+func (r *SQliteFederationRepo) InitFederationDatabase() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS ap_key (
+		user_id INTEGER PRIMARY KEY,
+		private_key TEXT NOT NULL,
+		public_key TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS ap_remote_actor (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor_id TEXT NOT NULL UNIQUE,
+		username TEXT NOT NULL,
+		inbox TEXT NOT NULL,
+		shared_inbox TEXT NOT NULL DEFAULT '',
+		key_id TEXT NOT NULL,
+		public_key TEXT NOT NULL,
+		user_id INTEGER,
+		fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE SET NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS ap_follower (
+		user_id INTEGER NOT NULL,
+		remote_actor_id INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, remote_actor_id),
+		FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+		FOREIGN KEY (remote_actor_id) REFERENCES ap_remote_actor(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS ap_post (
+		post_id INTEGER PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		deleted INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS ap_object (
+		object_id TEXT PRIMARY KEY,
+		comment_id INTEGER NOT NULL,
+		FOREIGN KEY (comment_id) REFERENCES comment(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS ap_delivery (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		inbox TEXT NOT NULL,
+		activity BLOB NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at DATETIME NOT NULL,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_ap_delivery_next_attempt_at ON ap_delivery(next_attempt_at);
+	`
+	_, err := r.db.Exec(stmt)
+	return err
+}
+
+// ActorKey returns the signing key of a user, repository.ErrNotExists if
+// none was generated yet.
+func (r *SQliteFederationRepo) ActorKey(userID int64) (*models.ActorKey, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ActorKey"), slog.Int64("user_id", userID))
+	key := &models.ActorKey{UserID: userID}
+	err := r.db.QueryRow(`SELECT private_key, public_key FROM ap_key WHERE user_id = ?`, userID).
+		Scan(&key.PrivateKeyPEM, &key.PublicKeyPEM)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotExists
+		}
+		log.Error("failed to get actor key", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return key, nil
+}
+
+// SaveActorKey stores the key unless the user already has one, which is
+// then returned instead. Concurrent first requests agree on a single key.
+func (r *SQliteFederationRepo) SaveActorKey(key *models.ActorKey) (*models.ActorKey, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.SaveActorKey"), slog.Int64("user_id", key.UserID))
+	query := `
+		INSERT INTO ap_key (user_id, private_key, public_key)
+		VALUES (?, ?, ?)
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := r.db.Exec(query, key.UserID, key.PrivateKeyPEM, key.PublicKeyPEM); err != nil {
+		if isForeignKeyErr(err) {
+			return nil, repository.ErrForeignKeyFailed
+		}
+		log.Error("failed to save actor key", sl.Error(err))
+		return nil, fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+	}
+	return r.ActorKey(key.UserID)
+}
+
+// SaveRemoteActor inserts or refreshes a remote actor by its IRI and sets
+// actor.ID. The local user of an actor is kept.
+func (r *SQliteFederationRepo) SaveRemoteActor(actor *models.RemoteActor) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.SaveRemoteActor"), slog.String("actor_id", actor.ActorID))
+	query := `
+		INSERT INTO ap_remote_actor (actor_id, username, inbox, shared_inbox, key_id, public_key, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (actor_id) DO UPDATE SET
+			username = excluded.username,
+			inbox = excluded.inbox,
+			shared_inbox = excluded.shared_inbox,
+			key_id = excluded.key_id,
+			public_key = excluded.public_key,
+			fetched_at = CURRENT_TIMESTAMP
+		RETURNING id, user_id, fetched_at
+	`
+	err := r.db.QueryRow(query, actor.ActorID, actor.Username, actor.Inbox, actor.SharedInbox, actor.KeyID, actor.PublicKeyPEM).
+		Scan(&actor.ID, &actor.UserID, &actor.FetchedAt)
+	if err != nil {
+		log.Error("failed to save remote actor", sl.Error(err))
+		return fmt.Errorf("upsert error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+func (r *SQliteFederationRepo) GetRemoteActor(actorID string) (*models.RemoteActor, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.GetRemoteActor"), slog.String("actor_id", actorID))
+	query := `
+		SELECT id, actor_id, username, inbox, shared_inbox, key_id, public_key, user_id, fetched_at
+		FROM ap_remote_actor
+		WHERE actor_id = ?
+	`
+	var actor models.RemoteActor
+	err := r.db.QueryRow(query, actorID).Scan(&actor.ID, &actor.ActorID, &actor.Username, &actor.Inbox,
+		&actor.SharedInbox, &actor.KeyID, &actor.PublicKeyPEM, &actor.UserID, &actor.FetchedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotExists
+		}
+		log.Error("failed to get remote actor", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return &actor, nil
+}
+
+// SetRemoteActorUser links a remote actor to the local user authoring its comments.
+func (r *SQliteFederationRepo) SetRemoteActorUser(id, userID int64) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.SetRemoteActorUser"), slog.Int64("id", id))
+	if _, err := r.db.Exec(`UPDATE ap_remote_actor SET user_id = ? WHERE id = ?`, userID, id); err != nil {
+		log.Error("failed to link remote actor", sl.Error(err))
+		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// AddRemoteFollower reports whether the follow is new.
+func (r *SQliteFederationRepo) AddRemoteFollower(userID, remoteActorID int64) (bool, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.AddRemoteFollower"), slog.Int64("user_id", userID))
+	res, err := r.db.Exec(`INSERT INTO ap_follower (user_id, remote_actor_id) VALUES (?, ?) ON CONFLICT DO NOTHING`, userID, remoteActorID)
+	if err != nil {
+		if isForeignKeyErr(err) {
+			return false, repository.ErrForeignKeyFailed
+		}
+		log.Error("failed to add remote follower", sl.Error(err))
+		return false, fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+	}
+	added, _ := res.RowsAffected()
+	return added > 0, nil
+}
+
+// RemoveRemoteFollower reports whether the follow existed.
+func (r *SQliteFederationRepo) RemoveRemoteFollower(userID, remoteActorID int64) (bool, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.RemoveRemoteFollower"), slog.Int64("user_id", userID))
+	res, err := r.db.Exec(`DELETE FROM ap_follower WHERE user_id = ? AND remote_actor_id = ?`, userID, remoteActorID)
+	if err != nil {
+		log.Error("failed to remove remote follower", sl.Error(err))
+		return false, fmt.Errorf("delete error: %w", repository.ErrOperationFailed)
+	}
+	removed, _ := res.RowsAffected()
+	return removed > 0, nil
+}
+
+// FollowerInboxes returns the distinct inboxes reaching all remote
+// followers of a user, shared inboxes where the servers have one.
+func (r *SQliteFederationRepo) FollowerInboxes(userID int64) ([]string, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.FollowerInboxes"), slog.Int64("user_id", userID))
+	query := `
+		SELECT DISTINCT CASE WHEN a.shared_inbox <> '' THEN a.shared_inbox ELSE a.inbox END
+		FROM ap_follower f
+		JOIN ap_remote_actor a ON a.id = f.remote_actor_id
+		WHERE f.user_id = ?
+	`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		log.Error("failed to list follower inboxes", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var inboxes []string
+	for rows.Next() {
+		var inbox string
+		if err := rows.Scan(&inbox); err != nil {
+			log.Error("failed to scan inbox", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		inboxes = append(inboxes, inbox)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return inboxes, nil
+}
+
+func (r *SQliteFederationRepo) CountRemoteFollowers(userID int64) (int64, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.CountRemoteFollowers"), slog.Int64("user_id", userID))
+	var count int64
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM ap_follower WHERE user_id = ?`, userID).Scan(&count); err != nil {
+		log.Error("failed to count remote followers", sl.Error(err))
+		return 0, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return count, nil
+}
+
+// SetPostFederated records that a post was sent out as a note, or deleted
+// again when deleted is set.
+func (r *SQliteFederationRepo) SetPostFederated(postID, userID int64, deleted bool) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.SetPostFederated"), slog.Int64("post_id", postID))
+	query := `
+		INSERT INTO ap_post (post_id, user_id, deleted)
+		VALUES (?, ?, ?)
+		ON CONFLICT (post_id) DO UPDATE SET deleted = excluded.deleted
+	`
+	if _, err := r.db.Exec(query, postID, userID, deleted); err != nil {
+		log.Error("failed to record federated post", sl.Error(err))
+		return fmt.Errorf("upsert error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// FederatedPost returns the author of a post sent out as a note and whether
+// it was deleted since, repository.ErrNotExists if it was never sent.
+func (r *SQliteFederationRepo) FederatedPost(postID int64) (userID int64, deleted bool, err error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.FederatedPost"), slog.Int64("post_id", postID))
+	err = r.db.QueryRow(`SELECT user_id, deleted FROM ap_post WHERE post_id = ?`, postID).Scan(&userID, &deleted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, repository.ErrNotExists
+		}
+		log.Error("failed to get federated post", sl.Error(err))
+		return 0, false, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return userID, deleted, nil
+}
+
+// SaveRemoteObject remembers the comment a remote note was stored as. A
+// note that is already known yields repository.ErrAlreadyExists.
+func (r *SQliteFederationRepo) SaveRemoteObject(objectID string, commentID int64) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.SaveRemoteObject"), slog.String("object_id", objectID))
+	res, err := r.db.Exec(`INSERT INTO ap_object (object_id, comment_id) VALUES (?, ?) ON CONFLICT DO NOTHING`, objectID, commentID)
+	if err != nil {
+		if isForeignKeyErr(err) {
+			return repository.ErrForeignKeyFailed
+		}
+		log.Error("failed to save remote object", sl.Error(err))
+		return fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+	}
+	if added, _ := res.RowsAffected(); added == 0 {
+		return repository.ErrAlreadyExists
+	}
+	return nil
+}
+
+// RemoteObjectComment returns the comment a remote note was stored as.
+func (r *SQliteFederationRepo) RemoteObjectComment(objectID string) (int64, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.RemoteObjectComment"), slog.String("object_id", objectID))
+	var commentID int64
+	err := r.db.QueryRow(`SELECT comment_id FROM ap_object WHERE object_id = ?`, objectID).Scan(&commentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, repository.ErrNotExists
+		}
+		log.Error("failed to get remote object", sl.Error(err))
+		return 0, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return commentID, nil
+}
+
+// EnqueueDeliveries queues the activity for every inbox, due right away.
+func (r *SQliteFederationRepo) EnqueueDeliveries(userID int64, inboxes []string, activity []byte) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.EnqueueDeliveries"), slog.Int64("user_id", userID))
+	if len(inboxes) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Error("failed to begin transaction", sl.Error(err))
+		return fmt.Errorf("transaction error: %w", repository.ErrOperationFailed)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(time.DateTime)
+	for _, inbox := range inboxes {
+		query := `INSERT INTO ap_delivery (user_id, inbox, activity, next_attempt_at) VALUES (?, ?, ?, ?)`
+		if _, err := tx.Exec(query, userID, inbox, activity, now); err != nil {
+			log.Error("failed to enqueue delivery", sl.Error(err), slog.String("inbox", inbox))
+			return fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("failed to commit deliveries", sl.Error(err))
+		return fmt.Errorf("commit error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// DueDeliveries returns up to limit deliveries whose next attempt is due,
+// the oldest first.
+func (r *SQliteFederationRepo) DueDeliveries(now time.Time, limit int) ([]*models.Delivery, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.DueDeliveries"))
+	query := `
+		SELECT id, user_id, inbox, activity, attempts, next_attempt_at, last_error, created_at
+		FROM ap_delivery
+		WHERE next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?
+	`
+	rows, err := r.db.Query(query, now.UTC().Format(time.DateTime), limit)
+	if err != nil {
+		log.Error("failed to list due deliveries", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.Delivery
+	for rows.Next() {
+		var d models.Delivery
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Inbox, &d.Activity, &d.Attempts, &d.NextAttemptAt, &d.LastError, &d.CreatedAt); err != nil {
+			log.Error("failed to scan delivery", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		deliveries = append(deliveries, &d)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return deliveries, nil
+}
+
+// RetryDelivery counts a failed attempt and schedules the next one.
+func (r *SQliteFederationRepo) RetryDelivery(id int64, next time.Time, lastError string) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.RetryDelivery"), slog.Int64("id", id))
+	query := `UPDATE ap_delivery SET attempts = attempts + 1, next_attempt_at = ?, last_error = ? WHERE id = ?`
+	if _, err := r.db.Exec(query, next.UTC().Format(time.DateTime), lastError, id); err != nil {
+		log.Error("failed to reschedule delivery", sl.Error(err))
+		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// DeleteDelivery removes a delivery that succeeded or was given up on.
+func (r *SQliteFederationRepo) DeleteDelivery(id int64) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.DeleteDelivery"), slog.Int64("id", id))
+	if _, err := r.db.Exec(`DELETE FROM ap_delivery WHERE id = ?`, id); err != nil {
+		log.Error("failed to delete delivery", sl.Error(err))
+		return fmt.Errorf("delete error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type SQliteFollowRepo struct {
+	log logger.Logger
+	db  *sql.DB
+}
+
+func (r *SQliteFollowRepo) InitFollowDatabase() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS follow (
+		follower_id INTEGER NOT NULL,
+		followee_id INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (follower_id, followee_id),
+		CHECK (follower_id <> followee_id),
+		FOREIGN KEY (follower_id) REFERENCES user(id) ON DELETE CASCADE,
+		FOREIGN KEY (followee_id) REFERENCES user(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_follow_followee_id ON follow(followee_id);
+	`
+	_, err := r.db.Exec(stmt)
+	return err
+}
+
+// Follow reports whether the follow is new. Following twice is a no-op, a
+// missing followee yields repository.ErrForeignKeyFailed.
+func (r *SQliteFollowRepo) Follow(followerID, followeeID int64) (bool, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.Follow"),
+		slog.Int64("follower_id", followerID), slog.Int64("followee_id", followeeID))
+	query := `
+		INSERT INTO follow (follower_id, followee_id)
+		VALUES (?, ?)
+		ON CONFLICT DO NOTHING
+	`
+	res, err := r.db.Exec(query, followerID, followeeID)
+	if err != nil {
+		if isForeignKeyErr(err) {
+			return false, repository.ErrForeignKeyFailed
+		}
+		log.Error("failed to follow", sl.Error(err))
+		return false, fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+	}
+
+	added, _ := res.RowsAffected()
+	return added > 0, nil
+}
+
+// Unfollow reports whether the follow existed.
+func (r *SQliteFollowRepo) Unfollow(followerID, followeeID int64) (bool, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.Unfollow"),
+		slog.Int64("follower_id", followerID), slog.Int64("followee_id", followeeID))
+
+	res, err := r.db.Exec(`DELETE FROM follow WHERE follower_id = ? AND followee_id = ?`, followerID, followeeID)
+	if err != nil {
+		log.Error("failed to unfollow", sl.Error(err))
+		return false, fmt.Errorf("delete error: %w", repository.ErrOperationFailed)
+	}
+
+	removed, _ := res.RowsAffected()
+	return removed > 0, nil
+}
+
+// ListFollowers returns a page of who follows the user, the latest follows
+// first. Pages are keyed by the follower id.
+func (r *SQliteFollowRepo) ListFollowers(userID int64, page repository.Page) ([]*models.Follow, error) {
+	return r.listFollows("repository.sqliterepo.ListFollowers", "followee_id", "follower_id", userID, page)
+}
+
+// ListFollowing returns a page of whom the user follows, the latest follows
+// first. Pages are keyed by the followee id.
+func (r *SQliteFollowRepo) ListFollowing(userID int64, page repository.Page) ([]*models.Follow, error) {
+	return r.listFollows("repository.sqliterepo.ListFollowing", "follower_id", "followee_id", userID, page)
+}
+
+// listFollows lists the follows whose user column is userID, the other
+// column is the id of the page keys.
+func (r *SQliteFollowRepo) listFollows(fn, user, other string, userID int64, page repository.Page) ([]*models.Follow, error) {
+	log := r.log.With(slog.String("fn", fn), slog.Int64("user_id", userID))
+	query := `
+		SELECT follower_id, followee_id, created_at
+		FROM follow
+		WHERE ` + user + ` = ?
+	`
+	args := []any{userID}
+	cond, tail, pageArgs := keyset(page, "created_at", other)
+	if cond != "" {
+		query += ` AND ` + cond
+	}
+	query += tail
+	args = append(args, pageArgs...)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to list follows", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var follows []*models.Follow
+	for rows.Next() {
+		var f models.Follow
+		if err := rows.Scan(&f.FollowerID, &f.FolloweeID, &f.CreatedAt); err != nil {
+			log.Error("failed to scan follow", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		follows = append(follows, &f)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return keysetRows(page, follows), nil
+}
+
+// FollowerIDs returns the ids of all followers of the user.
+func (r *SQliteFollowRepo) FollowerIDs(userID int64) ([]int64, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.FollowerIDs"), slog.Int64("user_id", userID))
+
+	rows, err := r.db.Query(`SELECT follower_id FROM follow WHERE followee_id = ?`, userID)
+	if err != nil {
+		log.Error("failed to list followers", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			log.Error("failed to scan follower", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return ids, nil
+}
+
+func (r *SQliteFollowRepo) CountFollows(userID int64) (followers, following int64, err error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.CountFollows"), slog.Int64("user_id", userID))
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM follow WHERE followee_id = ?),
+			(SELECT COUNT(*) FROM follow WHERE follower_id = ?)
+	`
+	if err := r.db.QueryRow(query, userID, userID).Scan(&followers, &following); err != nil {
+		log.Error("failed to count follows", sl.Error(err))
+		return 0, 0, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return followers, following, nil
+}
@@ -0,0 +1,128 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type SQliteMentionRepo struct {
+	log logger.Logger
+	db  *sql.DB
+}
+
+// InitMentionDatabase creates the mention table. Mentions of deleted users go
+// with them, the text then reads as a plain @username.
+func (r *SQliteMentionRepo) InitMentionDatabase() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS mention (
+		target_type TEXT NOT NULL,
+		target_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		start_pos INTEGER NOT NULL,
+		end_pos INTEGER NOT NULL,
+		PRIMARY KEY (target_type, target_id, start_pos),
+		FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+	);
+
+	CREATE TRIGGER IF NOT EXISTS trg_mention_post_delete AFTER DELETE ON post
+	BEGIN
+		DELETE FROM mention WHERE target_type = 'post' AND target_id = OLD.id;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS trg_mention_comment_delete AFTER DELETE ON comment
+	BEGIN
+		DELETE FROM mention WHERE target_type = 'comment' AND target_id = OLD.id;
+	END;
+	`
+	_, err := r.db.Exec(stmt)
+	return err
+}
+
+// ReplaceMentions stores the mentions of a post or comment in place of the
+// ones found in its previous content.
+func (r *SQliteMentionRepo) ReplaceMentions(targetType string, targetID int64, mentions []*models.Mention) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ReplaceMentions"),
+		slog.String("target_type", targetType), slog.Int64("target_id", targetID))
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Error("failed to begin transaction", sl.Error(err))
+		return fmt.Errorf("transaction error: %w", repository.ErrOperationFailed)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM mention WHERE target_type = ? AND target_id = ?`, targetType, targetID); err != nil {
+		log.Error("failed to delete mentions", sl.Error(err))
+		return fmt.Errorf("delete error: %w", repository.ErrOperationFailed)
+	}
+
+	for _, m := range mentions {
+		_, err := tx.Exec(`INSERT INTO mention (target_type, target_id, user_id, start_pos, end_pos) VALUES (?, ?, ?, ?, ?)`,
+			targetType, targetID, m.UserID, m.Start, m.End)
+		if err != nil {
+			if isForeignKeyErr(err) {
+				// the user was deleted since the username was resolved
+				continue
+			}
+			log.Error("failed to insert mention", sl.Error(err))
+			return fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("failed to commit transaction", sl.Error(err))
+		return fmt.Errorf("commit error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// ListMentions returns the mentions of the given posts or comments keyed by
+// their id, in order of appearance, with the current usernames.
+func (r *SQliteMentionRepo) ListMentions(targetType string, ids []int64) (map[int64][]*models.Mention, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListMentions"), slog.String("target_type", targetType))
+	mentions := make(map[int64][]*models.Mention, len(ids))
+	if len(ids) == 0 {
+		return mentions, nil
+	}
+
+	query := `
+		SELECT m.target_id, m.user_id, u.username, m.start_pos, m.end_pos
+		FROM mention m
+		JOIN user u ON u.id = m.user_id
+		WHERE m.target_type = ? AND m.target_id IN (?` + strings.Repeat(",?", len(ids)-1) + `)
+		ORDER BY m.target_id, m.start_pos
+	`
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, targetType)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to list mentions", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m := &models.Mention{TargetType: targetType}
+		if err := rows.Scan(&m.TargetID, &m.UserID, &m.Username, &m.Start, &m.End); err != nil {
+			log.Error("failed to scan mention", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		mentions[m.TargetID] = append(mentions[m.TargetID], m)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("rows iteration error", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return mentions, nil
+}
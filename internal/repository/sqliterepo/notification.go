@@ -0,0 +1,184 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type SQliteNotificationRepo struct {
+	log logger.Logger
+	db  *sql.DB
+}
+
+func (r *SQliteNotificationRepo) InitNotificationDatabase() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS notification (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		kind TEXT NOT NULL,
+		actor_id INTEGER NOT NULL,
+		post_id INTEGER,
+		comment_id INTEGER,
+		read_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+		FOREIGN KEY (actor_id) REFERENCES user(id) ON DELETE CASCADE,
+		FOREIGN KEY (post_id) REFERENCES post(id) ON DELETE CASCADE,
+		FOREIGN KEY (comment_id) REFERENCES comment(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_notification_user_id ON notification(user_id, id);
+	CREATE INDEX IF NOT EXISTS idx_notification_unread ON notification(user_id, id) WHERE read_at IS NULL;
+	-- the same event never notifies twice, e.g. a comment approved again or a re-follow
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_dedupe
+		ON notification(user_id, kind, actor_id, COALESCE(post_id, 0), COALESCE(comment_id, 0));
+	`
+	_, err := r.db.Exec(stmt)
+	return err
+}
+
+// CreateNotifications stores a batch of notifications. Duplicates of existing
+// notifications are skipped.
+func (r *SQliteNotificationRepo) CreateNotifications(notifications []*models.Notification) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.CreateNotifications"))
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Error("failed to begin transaction", sl.Error(err))
+		return fmt.Errorf("transaction error: %w", repository.ErrOperationFailed)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT OR IGNORE INTO notification (user_id, kind, actor_id, post_id, comment_id)
+		VALUES (?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		log.Error("failed to prepare statement", sl.Error(err))
+		return fmt.Errorf("prepare error: %w", repository.ErrOperationFailed)
+	}
+	defer stmt.Close()
+
+	for _, n := range notifications {
+		if _, err := stmt.Exec(n.UserID, n.Kind, n.ActorID, n.PostID, n.CommentID); err != nil {
+			if isForeignKeyErr(err) {
+				// the target went away in the meantime
+				log.Info("skipping notification", slog.Int64("user_id", n.UserID), slog.String("kind", n.Kind))
+				continue
+			}
+			log.Error("failed to insert notification", sl.Error(err))
+			return fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("failed to commit transaction", sl.Error(err))
+		return fmt.Errorf("commit error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// ListNotifications returns the newest notifications of a user with ids below
+// beforeID (0 means from the start).
+func (r *SQliteNotificationRepo) ListNotifications(userID int64, unreadOnly bool, beforeID int64, limit int) ([]*models.Notification, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListNotifications"), slog.Int64("user_id", userID))
+
+	query := `
+		SELECT id, user_id, kind, actor_id, post_id, comment_id, read_at, created_at
+		FROM notification
+		WHERE user_id = ?
+	`
+	args := []any{userID}
+	if unreadOnly {
+		query += ` AND read_at IS NULL`
+	}
+	if beforeID > 0 {
+		query += ` AND id < ?`
+		args = append(args, beforeID)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to list notifications", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var notifications []*models.Notification
+	for rows.Next() {
+		n := &models.Notification{}
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.ActorID, &n.PostID, &n.CommentID, &n.ReadAt, &n.CreatedAt); err != nil {
+			log.Error("failed to scan notification", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("rows iteration error", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return notifications, nil
+}
+
+func (r *SQliteNotificationRepo) CountUnread(userID int64) (int64, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.CountUnread"), slog.Int64("user_id", userID))
+
+	var count int64
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM notification WHERE user_id = ? AND read_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		log.Error("failed to count unread notifications", sl.Error(err))
+		return 0, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return count, nil
+}
+
+// MarkRead marks the given notifications of a user as read. Ids of other
+// users' notifications are ignored. It returns how many were unread.
+func (r *SQliteNotificationRepo) MarkRead(userID int64, ids []int64) (int64, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.MarkRead"), slog.Int64("user_id", userID))
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	query := `
+		UPDATE notification SET read_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND read_at IS NULL AND id IN (?` + strings.Repeat(",?", len(ids)-1) + `)
+	`
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, userID)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	res, err := r.db.Exec(query, args...)
+	if err != nil {
+		log.Error("failed to mark notifications read", sl.Error(err))
+		return 0, fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+	marked, _ := res.RowsAffected()
+	return marked, nil
+}
+
+// MarkAllRead marks every unread notification of a user as read and returns how many there were.
+func (r *SQliteNotificationRepo) MarkAllRead(userID int64) (int64, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.MarkAllRead"), slog.Int64("user_id", userID))
+
+	res, err := r.db.Exec(`UPDATE notification SET read_at = CURRENT_TIMESTAMP WHERE user_id = ? AND read_at IS NULL`, userID)
+	if err != nil {
+		log.Error("failed to mark notifications read", sl.Error(err))
+		return 0, fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+	marked, _ := res.RowsAffected()
+	return marked, nil
+}
@@ -0,0 +1,51 @@
+package sqliterepo
+
+import (
+	"fmt"
+	"slices"
+
+	"blog/internal/repository"
+)
+
+// sqliteTime matches how CURRENT_TIMESTAMP stores times, so keys compare as text.
+const sqliteTime = "2006-01-02 15:04:05.999999999"
+
+// keyset turns a page into a WHERE condition (empty for the first page) and
+// an ORDER BY ... LIMIT clause over the given created_at and id columns.
+// Pages before a key are read oldest first and have to go through
+// keysetRows to come back newest first.
+func keyset(page repository.Page, createdAt, id string) (cond string, tail string, args []any) {
+	switch {
+	case page.After != nil:
+		cond = fmt.Sprintf("(%s, %s) < (?, ?)", createdAt, id)
+		args = append(args, page.After.CreatedAt.UTC().Format(sqliteTime), page.After.ID)
+	case page.Before != nil:
+		cond = fmt.Sprintf("(%s, %s) > (?, ?)", createdAt, id)
+		args = append(args, page.Before.CreatedAt.UTC().Format(sqliteTime), page.Before.ID)
+		return cond, fmt.Sprintf("ORDER BY %s ASC, %s ASC LIMIT ?", createdAt, id), append(args, page.Limit)
+	}
+	return cond, fmt.Sprintf("ORDER BY %s DESC, %s DESC LIMIT ?", createdAt, id), append(args, page.Limit)
+}
+
+// keysetOldestFirst is keyset for lists ordered oldest first: the rows after
+// a key are the newer ones, the rows before it the older ones.
+func keysetOldestFirst(page repository.Page, createdAt, id string) (cond string, tail string, args []any) {
+	switch {
+	case page.After != nil:
+		cond = fmt.Sprintf("(%s, %s) > (?, ?)", createdAt, id)
+		args = append(args, page.After.CreatedAt.UTC().Format(sqliteTime), page.After.ID)
+	case page.Before != nil:
+		cond = fmt.Sprintf("(%s, %s) < (?, ?)", createdAt, id)
+		args = append(args, page.Before.CreatedAt.UTC().Format(sqliteTime), page.Before.ID)
+		return cond, fmt.Sprintf("ORDER BY %s DESC, %s DESC LIMIT ?", createdAt, id), append(args, page.Limit)
+	}
+	return cond, fmt.Sprintf("ORDER BY %s ASC, %s ASC LIMIT ?", createdAt, id), append(args, page.Limit)
+}
+
+// keysetRows puts rows read by keyset or keysetOldestFirst in list order.
+func keysetRows[T any](page repository.Page, rows []T) []T {
+	if page.After == nil && page.Before != nil {
+		slices.Reverse(rows)
+	}
+	return rows
+}
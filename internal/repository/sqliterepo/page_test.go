@@ -0,0 +1,231 @@
+package sqliterepo
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+)
+
+func TestKeyset(t *testing.T) {
+	at := time.Date(2025, 6, 27, 14, 39, 55, 500000000, time.FixedZone("", 3600))
+	key := &repository.Key{CreatedAt: at, ID: 7}
+	tests := []struct {
+		name   string
+		keyset func(repository.Page, string, string) (string, string, []any)
+		page   repository.Page
+		cond   string
+		tail   string
+		args   []any
+	}{
+		{
+			name:   "first page",
+			keyset: keyset,
+			page:   repository.Page{Limit: 3},
+			tail:   "ORDER BY created_at DESC, id DESC LIMIT ?",
+			args:   []any{3},
+		},
+		{
+			name:   "after",
+			keyset: keyset,
+			page:   repository.Page{After: key, Limit: 3},
+			cond:   "(created_at, id) < (?, ?)",
+			tail:   "ORDER BY created_at DESC, id DESC LIMIT ?",
+			args:   []any{"2025-06-27 13:39:55.5", int64(7), 3},
+		},
+		{
+			name:   "before",
+			keyset: keyset,
+			page:   repository.Page{Before: key, Limit: 3},
+			cond:   "(created_at, id) > (?, ?)",
+			tail:   "ORDER BY created_at ASC, id ASC LIMIT ?",
+			args:   []any{"2025-06-27 13:39:55.5", int64(7), 3},
+		},
+		{
+			name:   "oldest first, first page",
+			keyset: keysetOldestFirst,
+			page:   repository.Page{Limit: 3},
+			tail:   "ORDER BY created_at ASC, id ASC LIMIT ?",
+			args:   []any{3},
+		},
+		{
+			name:   "oldest first, after",
+			keyset: keysetOldestFirst,
+			page:   repository.Page{After: key, Limit: 3},
+			cond:   "(created_at, id) > (?, ?)",
+			tail:   "ORDER BY created_at ASC, id ASC LIMIT ?",
+			args:   []any{"2025-06-27 13:39:55.5", int64(7), 3},
+		},
+		{
+			name:   "oldest first, before",
+			keyset: keysetOldestFirst,
+			page:   repository.Page{Before: key, Limit: 3},
+			cond:   "(created_at, id) < (?, ?)",
+			tail:   "ORDER BY created_at DESC, id DESC LIMIT ?",
+			args:   []any{"2025-06-27 13:39:55.5", int64(7), 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond, tail, args := tt.keyset(tt.page, "created_at", "id")
+			if cond != tt.cond || tail != tt.tail || !slices.Equal(args, tt.args) {
+				t.Fatalf("keyset = %q, %q, %v, want %q, %q, %v", cond, tail, args, tt.cond, tt.tail, tt.args)
+			}
+		})
+	}
+}
+
+func postIDs(posts []*models.Post) []int64 {
+	ids := make([]int64, 0, len(posts))
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+// TestKeysetPages pages through the posts to the oldest and back to the
+// newest, across pages ending between posts created in the same second.
+func TestKeysetPages(t *testing.T) {
+	log := logger.NewLogger(nil)
+	db, err := New("file:"+filepath.Join(t.TempDir(), "blog.db"), log)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	posts := &SQlitePostRepo{log: log, db: db}
+	if err := posts.InitPostDatabase(); err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+
+	// ids 1 to 7, two posts a second as CURRENT_TIMESTAMP stores them
+	for i := range 7 {
+		_, err := db.Exec(`INSERT INTO post (title, content, author_id, created_at) VALUES ('t', 'c', 1, ?)`,
+			time.Date(2025, 6, 27, 12, 0, i/2, 0, time.UTC).Format(sqliteTime))
+		if err != nil {
+			t.Fatalf("insert post: %v", err)
+		}
+	}
+	key := func(p *models.Post) *repository.Key {
+		return &repository.Key{CreatedAt: p.CreatedAt, ID: p.ID}
+	}
+
+	const limit = 3
+	want := [][]int64{{7, 6, 5}, {4, 3, 2}, {1}}
+	var forward [][]int64
+	page := repository.Page{Limit: limit}
+	for {
+		got, err := posts.ListPosts(page)
+		if err != nil {
+			t.Fatalf("ListPosts: %v", err)
+		}
+		if len(got) == 0 {
+			break
+		}
+		forward = append(forward, postIDs(got))
+		page = repository.Page{After: key(got[len(got)-1]), Limit: limit}
+	}
+	if !slices.EqualFunc(forward, want, slices.Equal) {
+		t.Fatalf("paged forward through %v, want %v", forward, want)
+	}
+
+	// back from the oldest post, every page newest first
+	want = [][]int64{{4, 3, 2}, {7, 6, 5}}
+	var backward [][]int64
+	page = repository.Page{Before: &repository.Key{CreatedAt: time.Date(2025, 6, 27, 12, 0, 0, 0, time.UTC), ID: 1}, Limit: limit}
+	for {
+		got, err := posts.ListPosts(page)
+		if err != nil {
+			t.Fatalf("ListPosts: %v", err)
+		}
+		if len(got) == 0 {
+			break
+		}
+		backward = append(backward, postIDs(got))
+		page = repository.Page{Before: key(got[0]), Limit: limit}
+	}
+	if !slices.EqualFunc(backward, want, slices.Equal) {
+		t.Fatalf("paged back through %v, want %v", backward, want)
+	}
+}
+
+// TestReportQueuePages pages through the report queue, whose keys are the
+// first open report of each target, to its end and back.
+func TestReportQueuePages(t *testing.T) {
+	log := logger.NewLogger(nil)
+	db, err := New("file:"+filepath.Join(t.TempDir(), "blog.db"), log)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	reports := &SQliteReportRepo{log: log, db: db}
+	if err := reports.InitReportDatabase(); err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+
+	// posts 1 to 5 reported in that order, two a second, later reports of
+	// post 1 don't move it
+	report := func(reporterID, postID int64, sec int) {
+		_, err := db.Exec(`INSERT INTO report (reporter_id, target_type, target_id, reason, created_at) VALUES (?, 'post', ?, 'spam', ?)`,
+			reporterID, postID, time.Date(2025, 6, 27, 12, 0, sec, 0, time.UTC).Format(sqliteTime))
+		if err != nil {
+			t.Fatalf("insert report: %v", err)
+		}
+	}
+	for i := range 5 {
+		report(1, int64(i+1), i/2)
+	}
+	report(2, 1, 9)
+	report(3, 1, 9)
+
+	targetIDs := func(targets []*models.ReportTarget) []int64 {
+		ids := make([]int64, 0, len(targets))
+		for _, t := range targets {
+			ids = append(ids, t.TargetID)
+		}
+		return ids
+	}
+	key := func(t *models.ReportTarget) *repository.Key {
+		return &repository.Key{CreatedAt: t.FirstReportedAt, ID: t.FirstReportID}
+	}
+
+	const limit = 2
+	var forward [][]int64
+	var last *models.ReportTarget
+	page := repository.Page{Limit: limit}
+	for {
+		got, err := reports.ListOpenReportTargets(page)
+		if err != nil {
+			t.Fatalf("ListOpenReportTargets: %v", err)
+		}
+		if len(got) == 0 {
+			break
+		}
+		forward = append(forward, targetIDs(got))
+		last = got[len(got)-1]
+		page = repository.Page{After: key(last), Limit: limit}
+	}
+	if want := [][]int64{{1, 2}, {3, 4}, {5}}; !slices.EqualFunc(forward, want, slices.Equal) {
+		t.Fatalf("paged forward through %v, want %v", forward, want)
+	}
+
+	var backward [][]int64
+	page = repository.Page{Before: key(last), Limit: limit}
+	for {
+		got, err := reports.ListOpenReportTargets(page)
+		if err != nil {
+			t.Fatalf("ListOpenReportTargets: %v", err)
+		}
+		if len(got) == 0 {
+			break
+		}
+		backward = append(backward, targetIDs(got))
+		page = repository.Page{Before: key(got[0]), Limit: limit}
+	}
+	if want := [][]int64{{3, 4}, {1, 2}}; !slices.EqualFunc(backward, want, slices.Equal) {
+		t.Fatalf("paged back through %v, want %v", backward, want)
+	}
+}
@@ -5,10 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
-	"github.com/mattn/go-sqlite3"
-
 	"blog/internal/models"
 	"blog/internal/repository"
 	"blog/internal/util/logger"
@@ -27,24 +26,51 @@ func (r *SQlitePostRepo) InitPostDatabase() error {
 		title TEXT NOT NULL,
 		content TEXT NOT NULL,
 		author_id INTEGER NOT NULL,
+		version INTEGER NOT NULL DEFAULT 1,
+		comment_mode TEXT NOT NULL DEFAULT '',
+		comments_locked INTEGER NOT NULL DEFAULT 0,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		deleted_at DATETIME,
+		hidden_at DATETIME,
 		FOREIGN KEY (author_id) REFERENCES user(id)
 	);
+	CREATE INDEX IF NOT EXISTS idx_post_author_id ON post(author_id, id);
+	CREATE INDEX IF NOT EXISTS idx_post_created_at ON post(created_at, id);
+
+	CREATE TABLE IF NOT EXISTS post_tag (
+		post_id INTEGER NOT NULL,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (post_id, tag),
+		FOREIGN KEY (post_id) REFERENCES post(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_post_tag_tag ON post_tag(tag, post_id);
 	`
 	if _, err := r.db.Exec(stmt); err != nil {
 		return err
 	}
 
-	return nil
+	if err := addColumn(r.db, "post", "version", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return err
+	}
+	if err := addColumn(r.db, "post", "deleted_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := addColumn(r.db, "post", "comment_mode", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumn(r.db, "post", "comments_locked", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	return addColumn(r.db, "post", "hidden_at", "DATETIME")
 }
 
 func (r *SQlitePostRepo) GetPostByID(id int64) (*models.Post, error) {
 	log := r.log.With("fn", "repository.sqliterepo.GetPostByID")
 	query := `
-		SELECT id, title, content, author_id, created_at, updated_at
+		SELECT id, title, content, author_id, version, comment_mode, comments_locked, created_at, updated_at, hidden_at
 		FROM post
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL
     	`
 
 	var post models.Post
@@ -54,8 +80,12 @@ func (r *SQlitePostRepo) GetPostByID(id int64) (*models.Post, error) {
 		&post.Title,
 		&post.Content,
 		&post.AuthorID,
+		&post.Version,
+		&post.CommentMode,
+		&post.CommentsLocked,
 		&post.CreatedAt,
 		&post.UpdatedAt,
+		&post.HiddenAt,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -66,6 +96,10 @@ func (r *SQlitePostRepo) GetPostByID(id int64) (*models.Post, error) {
 		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
 	}
 
+	if post.Tags, err = postTags(r.db, id); err != nil {
+		log.Error("failed to get tags", sl.Error(err), slog.Int64("id", id))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
 	return &post, nil
 }
 
@@ -74,43 +108,102 @@ func (r *SQlitePostRepo) CreatePost(post *models.Post) (int64, error) {
 	query := `
 		INSERT INTO post (title, content, author_id)
 		VALUES (?, ?, ?)
-		RETURNING id, created_at, updated_at
+		RETURNING id, version, created_at, updated_at
     	`
 
-	var id int64
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Error("failed to begin transaction", sl.Error(err))
+		return 0, repository.ErrOperationFailed
+	}
+	defer tx.Rollback()
+
+	var id, version int64
 	var createdAt, updatedAt time.Time
-	err := r.db.QueryRow(query, post.Title, post.Content, post.AuthorID).Scan(
+	err = tx.QueryRow(query, post.Title, post.Content, post.AuthorID).Scan(
 		&id,
+		&version,
 		&createdAt,
 		&updatedAt,
 	)
 	if err != nil {
-		if errors.Is(err, sqlite3.ErrConstraintForeignKey) {
+		if isForeignKeyErr(err) {
 			return 0, repository.ErrForeignKeyFailed
 		}
 		log.Error("failed to create post", sl.Error(err))
 		return 0, repository.ErrOperationFailed
 	}
 
+	if err := setPostTags(tx, id, post.Tags); err != nil {
+		log.Error("failed to set tags", sl.Error(err), slog.Int64("id", id))
+		return 0, repository.ErrOperationFailed
+	}
+	if err := insertRevision(tx, id, post.AuthorID); err != nil {
+		log.Error("failed to create initial revision", sl.Error(err), slog.Int64("id", id))
+		return 0, repository.ErrOperationFailed
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("failed to commit post", sl.Error(err))
+		return 0, repository.ErrOperationFailed
+	}
+
 	post.ID = id
+	post.Version = version
 	post.CreatedAt = createdAt
 	post.UpdatedAt = updatedAt
 	return id, nil
 }
 
+// UpdatePost overwrites title, content and, unless nil, tags if post.Version still
+// matches the stored version, and bumps the version. A stale version yields
+// repository.ErrVersionConflict.
 func (r *SQlitePostRepo) UpdatePost(post *models.Post) error {
 	log := r.log.With(slog.String("fn", "repository.sqliterepo.UpdatePost"))
 	query := `
 		UPDATE post
-		SET title = ?, content = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ? AND author_id = ?`
+		SET title = ?, content = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND author_id = ? AND version = ? AND deleted_at IS NULL
+		RETURNING version, updated_at`
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+	defer tx.Rollback()
 
-	res, err := r.db.Exec(query,
+	if err := insertBaseRevision(tx, post.ID); err != nil {
+		log.Error("failed to create base revision", "error", err, "id", post.ID)
+		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+
+	var version int64
+	var updatedAt time.Time
+	err = tx.QueryRow(query,
 		post.Title,
 		post.Content,
 		post.ID,
-		post.AuthorID)
+		post.AuthorID,
+		post.Version).Scan(&version, &updatedAt)
 
+	if errors.Is(err, sql.ErrNoRows) {
+		var current int64
+		err = tx.QueryRow(`SELECT version FROM post WHERE id = ? AND author_id = ? AND deleted_at IS NULL`, post.ID, post.AuthorID).Scan(&current)
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Info("post not found or author mismatch",
+				"id", post.ID,
+				"author_id", post.AuthorID)
+			return repository.ErrNotExists
+		}
+		if err == nil {
+			log.Info("post version conflict",
+				"id", post.ID,
+				"expected_version", post.Version,
+				"current_version", current)
+			return repository.ErrVersionConflict
+		}
+	}
 	if err != nil {
 		log.Error("update query failed",
 			"error", err,
@@ -119,24 +212,31 @@ func (r *SQlitePostRepo) UpdatePost(post *models.Post) error {
 		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
 	}
 
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		log.Error("rows affected check failed", "error", err)
+	if post.Tags != nil {
+		if err := setPostTags(tx, post.ID, post.Tags); err != nil {
+			log.Error("failed to set tags", "error", err, "id", post.ID)
+			return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+		}
+	}
+	if err := insertRevision(tx, post.ID, post.AuthorID); err != nil {
+		log.Error("failed to create revision", "error", err, "id", post.ID)
 		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
 	}
 
-	if rowsAffected == 0 {
-		log.Info("post not found or author mismatch",
-			"id", post.ID,
-			"author_id", post.AuthorID)
-		return repository.ErrNotExists
+	if err := tx.Commit(); err != nil {
+		log.Error("failed to commit update", "error", err, "id", post.ID)
+		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
 	}
+
+	post.Version = version
+	post.UpdatedAt = updatedAt
 	return nil
 }
 
+// DeletePost moves the post to the trash. It stays restorable until purged.
 func (r *SQlitePostRepo) DeletePost(id int64) error {
 	log := r.log.With("fn", "repository.sqliterepo.DeletePost")
-	query := `DELETE FROM post WHERE id = ?`
+	query := `UPDATE post SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL`
 	res, err := r.db.Exec(query, id)
 	if err != nil {
 		log.Error("failed to delete post", "error", err, "id", id)
@@ -151,17 +251,184 @@ func (r *SQlitePostRepo) DeletePost(id int64) error {
 	return nil
 }
 
-func (r *SQlitePostRepo) GetPostsByAuthor(authorID int64) ([]*models.Post, error) {
-	log := r.log.With("fn", "repository.sqliterepo.GetPostsByAuthor")
+// GetPostsByAuthor returns a page of the visible posts of an author.
+func (r *SQlitePostRepo) GetPostsByAuthor(authorID int64, page repository.Page) ([]*models.Post, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.GetPostsByAuthor"), slog.Int64("author_id", authorID))
 	query := `
-		SELECT id, title, content, author_id, created_at, updated_at
+		SELECT id, title, content, author_id, version, comment_mode, comments_locked, created_at, updated_at, hidden_at
 		FROM post
-		WHERE author_id = ?
-		ORDER BY created_at DESC
-    	`
+		WHERE author_id = ? AND deleted_at IS NULL AND hidden_at IS NULL
+	`
+	args := []any{authorID}
+	cond, tail, pageArgs := keyset(page, "created_at", "id")
+	if cond != "" {
+		query += ` AND ` + cond
+	}
+	query += tail
+	args = append(args, pageArgs...)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to fetch posts", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	posts, err := collectPosts(&log, rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadPostTags(r.db, posts); err != nil {
+		log.Error("failed to get tags", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return keysetRows(page, posts), nil
+}
+
+// ListPosts returns a page of the visible posts, newest first.
+func (r *SQlitePostRepo) ListPosts(page repository.Page) ([]*models.Post, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListPosts"))
+	query := `
+		SELECT id, title, content, author_id, version, comment_mode, comments_locked, created_at, updated_at, hidden_at
+		FROM post
+		WHERE deleted_at IS NULL AND hidden_at IS NULL
+	`
+	cond, tail, args := keyset(page, "created_at", "id")
+	if cond != "" {
+		query += ` AND ` + cond
+	}
+	query += tail
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to list posts", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	posts, err := collectPosts(&log, rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadPostTags(r.db, posts); err != nil {
+		log.Error("failed to get tags", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return keysetRows(page, posts), nil
+}
+
+// GetPostsByTag returns a page of the visible posts with the tag.
+func (r *SQlitePostRepo) GetPostsByTag(tag string, page repository.Page) ([]*models.Post, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.GetPostsByTag"), slog.String("tag", tag))
+	query := `
+		SELECT post.id, title, content, author_id, version, comment_mode, comments_locked, created_at, updated_at, hidden_at
+		FROM post
+		JOIN post_tag ON post_tag.post_id = post.id
+		WHERE post_tag.tag = ? AND deleted_at IS NULL AND hidden_at IS NULL
+	`
+	args := []any{tag}
+	cond, tail, pageArgs := keyset(page, "post.created_at", "post.id")
+	if cond != "" {
+		query += ` AND ` + cond
+	}
+	query += tail
+	args = append(args, pageArgs...)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to fetch posts", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	posts, err := collectPosts(&log, rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadPostTags(r.db, posts); err != nil {
+		log.Error("failed to get tags", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return keysetRows(page, posts), nil
+}
+
+// UpdateCommentSettings sets the moderation mode and lock state of comments on a post.
+// It does not bump the post version, since the post content is unchanged.
+func (r *SQlitePostRepo) UpdateCommentSettings(postID, authorID int64, mode string, locked bool) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.UpdateCommentSettings"), slog.Int64("id", postID))
+	query := `
+		UPDATE post
+		SET comment_mode = ?, comments_locked = ?
+		WHERE id = ? AND author_id = ? AND deleted_at IS NULL
+	`
+	res, err := r.db.Exec(query, mode, locked, postID, authorID)
+	if err != nil {
+		log.Error("failed to update comment settings", sl.Error(err))
+		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		log.Info("post not found or author mismatch", slog.Int64("author_id", authorID))
+		return repository.ErrNotExists
+	}
+	return nil
+}
+
+// SetPostHidden hides a post from public reads or makes it visible again.
+func (r *SQlitePostRepo) SetPostHidden(id int64, hidden bool) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.SetPostHidden"), slog.Int64("id", id))
+	query := `
+		UPDATE post
+		SET hidden_at = CASE WHEN ? THEN COALESCE(hidden_at, CURRENT_TIMESTAMP) END
+		WHERE id = ? AND deleted_at IS NULL
+	`
+	res, err := r.db.Exec(query, hidden, id)
+	if err != nil {
+		log.Error("failed to set post visibility", sl.Error(err))
+		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		log.Info("post not found")
+		return repository.ErrNotExists
+	}
+	return nil
+}
+
+func (r *SQlitePostRepo) RestorePost(id, authorID int64) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.RestorePost"), slog.Int64("id", id))
+	query := `
+		UPDATE post
+		SET deleted_at = NULL
+		WHERE id = ? AND author_id = ? AND deleted_at IS NOT NULL
+	`
+	res, err := r.db.Exec(query, id, authorID)
+	if err != nil {
+		log.Error("failed to restore post", sl.Error(err))
+		return fmt.Errorf("restore error: %w", repository.ErrOperationFailed)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		log.Info("post not found in trash", slog.Int64("author_id", authorID))
+		return repository.ErrNotExists
+	}
+	return nil
+}
+
+func (r *SQlitePostRepo) ListDeletedPosts(authorID int64) ([]*models.Post, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListDeletedPosts"), slog.Int64("author_id", authorID))
+	query := `
+		SELECT id, title, content, author_id, version, created_at, updated_at, deleted_at
+		FROM post
+		WHERE author_id = ? AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+	`
 	rows, err := r.db.Query(query, authorID)
 	if err != nil {
-		log.Error("failed to fetch posts", "error", err, "author_id", authorID)
+		log.Error("failed to list deleted posts", sl.Error(err))
 		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
 	}
 	defer rows.Close()
@@ -174,37 +441,115 @@ func (r *SQlitePostRepo) GetPostsByAuthor(authorID int64) ([]*models.Post, error
 			&post.Title,
 			&post.Content,
 			&post.AuthorID,
+			&post.Version,
 			&post.CreatedAt,
 			&post.UpdatedAt,
+			&post.DeletedAt,
 		); err != nil {
-			log.Error("failed to scan post", "error", err)
+			log.Error("failed to scan post", sl.Error(err))
 			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
 		}
 		posts = append(posts, &post)
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Error("iteration error", "error", err)
+		log.Error("iteration error", sl.Error(err))
 		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
 	}
 	return posts, nil
 }
 
-func (r *SQlitePostRepo) ListPosts(limit, offset int) ([]*models.Post, error) {
-	log := r.log.With("fn", "repository.sqliterepo.ListPosts")
+// PurgeDeletedPosts permanently removes posts trashed before the given time,
+// together with their comments and revisions.
+func (r *SQlitePostRepo) PurgeDeletedPosts(before time.Time) (int64, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.PurgeDeletedPosts"))
+	query := `DELETE FROM post WHERE deleted_at IS NOT NULL AND deleted_at < ?`
+
+	res, err := r.db.Exec(query, before.UTC().Format(time.DateTime))
+	if err != nil {
+		log.Error("failed to purge posts", sl.Error(err))
+		return 0, fmt.Errorf("purge error: %w", repository.ErrOperationFailed)
+	}
+
+	purged, _ := res.RowsAffected()
+	return purged, nil
+}
+
+// ListFeed returns the newest visible posts of the authors the user follows,
+// with ids below beforeID (0 means from the start).
+func (r *SQlitePostRepo) ListFeed(followerID, beforeID int64, limit int) ([]*models.Post, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListFeed"), slog.Int64("follower_id", followerID))
+	query := `
+		SELECT p.id, p.title, p.content, p.author_id, p.version, p.comment_mode, p.comments_locked, p.created_at, p.updated_at, p.hidden_at
+		FROM post p
+		JOIN follow f ON f.followee_id = p.author_id
+		WHERE f.follower_id = ? AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+	`
+	args := []any{followerID}
+	if beforeID > 0 {
+		query += ` AND p.id < ?`
+		args = append(args, beforeID)
+	}
+	query += ` ORDER BY p.id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to list feed", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	posts, err := collectPosts(&log, rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadPostTags(r.db, posts); err != nil {
+		log.Error("failed to get tags", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return posts, nil
+}
+
+// GetPostsByIDs returns the visible posts among ids. Deleted and hidden posts are missing from the map.
+func (r *SQlitePostRepo) GetPostsByIDs(ids []int64) (map[int64]*models.Post, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.GetPostsByIDs"))
+	result := make(map[int64]*models.Post, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
 	query := `
-		SELECT id, title, content, author_id, created_at, updated_at
+		SELECT id, title, content, author_id, version, comment_mode, comments_locked, created_at, updated_at, hidden_at
 		FROM post
-		ORDER BY created_at DESC
-		LIMIT ? OFFSET ?
-    	`
-	rows, err := r.db.Query(query, limit, offset)
+		WHERE id IN (?` + strings.Repeat(",?", len(ids)-1) + `) AND deleted_at IS NULL AND hidden_at IS NULL
+	`
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		log.Error("failed to list posts", "error", err)
+		log.Error("failed to get posts", sl.Error(err))
 		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
 	}
 	defer rows.Close()
 
+	posts, err := collectPosts(&log, rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadPostTags(r.db, posts); err != nil {
+		log.Error("failed to get tags", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	for _, post := range posts {
+		result[post.ID] = post
+	}
+	return result, nil
+}
+
+func collectPosts(log logger.Logger, rows *sql.Rows) ([]*models.Post, error) {
 	var posts []*models.Post
 	for rows.Next() {
 		var post models.Post
@@ -213,18 +558,117 @@ func (r *SQlitePostRepo) ListPosts(limit, offset int) ([]*models.Post, error) {
 			&post.Title,
 			&post.Content,
 			&post.AuthorID,
+			&post.Version,
+			&post.CommentMode,
+			&post.CommentsLocked,
 			&post.CreatedAt,
 			&post.UpdatedAt,
+			&post.HiddenAt,
 		); err != nil {
-			log.Error("failed to scan post", "error", err)
+			log.Error("failed to scan post", sl.Error(err))
 			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
 		}
 		posts = append(posts, &post)
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Error("iteration error", "error", err)
+		log.Error("iteration error", sl.Error(err))
 		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
 	}
 	return posts, nil
 }
+
+// PostUpdateTimes returns when each visible post was last updated, keyed by id.
+func (r *SQlitePostRepo) PostUpdateTimes() (map[int64]time.Time, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.PostUpdateTimes"))
+	query := `SELECT id, updated_at FROM post WHERE deleted_at IS NULL AND hidden_at IS NULL`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		log.Error("failed to list post update times", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	times := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			id        int64
+			updatedAt time.Time
+		)
+		if err := rows.Scan(&id, &updatedAt); err != nil {
+			log.Error("failed to scan post update time", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		times[id] = updatedAt
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return times, nil
+}
+
+// postTags returns the tags of a post, sorted.
+func postTags(db *sql.DB, postID int64) ([]string, error) {
+	rows, err := db.Query(`SELECT tag FROM post_tag WHERE post_id = ? ORDER BY tag`, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// loadPostTags sets the tags of a page of posts with one query.
+func loadPostTags(db *sql.DB, posts []*models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	args := make([]any, len(posts))
+	byID := make(map[int64]*models.Post, len(posts))
+	for i, post := range posts {
+		args[i] = post.ID
+		byID[post.ID] = post
+	}
+	rows, err := db.Query(`SELECT post_id, tag FROM post_tag WHERE post_id IN (?`+strings.Repeat(",?", len(posts)-1)+`) ORDER BY post_id, tag`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			postID int64
+			tag    string
+		)
+		if err := rows.Scan(&postID, &tag); err != nil {
+			return err
+		}
+		if post, ok := byID[postID]; ok {
+			post.Tags = append(post.Tags, tag)
+		}
+	}
+	return rows.Err()
+}
+
+// setPostTags replaces the tags of a post.
+func setPostTags(tx *sql.Tx, postID int64, tags []string) error {
+	if _, err := tx.Exec(`DELETE FROM post_tag WHERE post_id = ?`, postID); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO post_tag (post_id, tag) VALUES (?, ?)`, postID, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
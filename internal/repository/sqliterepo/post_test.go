@@ -0,0 +1,131 @@
+package sqliterepo
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+)
+
+func newTestPostRepo(t *testing.T) *SQlitePostRepo {
+	t.Helper()
+	log := logger.NewLogger(nil)
+	db, err := New("file:"+filepath.Join(t.TempDir(), "blog.db"), log)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	posts := &SQlitePostRepo{log: log, db: db}
+	if err := posts.InitPostDatabase(); err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+	if err := (&SQliteRevisionRepo{log: log, db: db}).InitRevisionDatabase(); err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+	return posts
+}
+
+// TestListedPostsHaveTags checks the lists load the tags of every post on
+// the page, not only GetPostByID.
+func TestListedPostsHaveTags(t *testing.T) {
+	posts := newTestPostRepo(t)
+	want := map[int64][]string{}
+	for _, tags := range [][]string{{"go", "sqlite"}, nil, {"go"}} {
+		id, err := posts.CreatePost(&models.Post{Title: "t", Content: "content", AuthorID: 1, Tags: tags})
+		if err != nil {
+			t.Fatalf("CreatePost: %v", err)
+		}
+		want[id] = tags
+	}
+	check := func(name string, got []*models.Post) {
+		t.Helper()
+		if len(got) == 0 {
+			t.Fatalf("%s: no posts", name)
+		}
+		for _, p := range got {
+			if !slices.Equal(p.Tags, want[p.ID]) {
+				t.Errorf("%s: post %d has tags %v, want %v", name, p.ID, p.Tags, want[p.ID])
+			}
+		}
+	}
+
+	page := repository.Page{Limit: 10}
+	list, err := posts.ListPosts(page)
+	if err != nil {
+		t.Fatalf("ListPosts: %v", err)
+	}
+	check("ListPosts", list)
+
+	list, err = posts.GetPostsByTag("go", page)
+	if err != nil {
+		t.Fatalf("GetPostsByTag: %v", err)
+	}
+	check("GetPostsByTag", list)
+
+	list, err = posts.GetPostsByAuthor(1, page)
+	if err != nil {
+		t.Fatalf("GetPostsByAuthor: %v", err)
+	}
+	check("GetPostsByAuthor", list)
+
+	byID, err := posts.GetPostsByIDs([]int64{1, 2, 3})
+	if err != nil {
+		t.Fatalf("GetPostsByIDs: %v", err)
+	}
+	check("GetPostsByIDs", slices.Collect(maps.Values(byID)))
+}
+
+// TestRevisionsKeepTags checks every revision snapshots the tags the post
+// had after the change, and that revisions from before tags were tracked
+// have none rather than empty ones.
+func TestRevisionsKeepTags(t *testing.T) {
+	posts := newTestPostRepo(t)
+	revisions := &SQliteRevisionRepo{log: posts.log, db: posts.db}
+
+	post := &models.Post{Title: "t", Content: "content", AuthorID: 1, Tags: []string{"go"}}
+	if _, err := posts.CreatePost(post); err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	post.Tags = []string{"go", "sqlite"}
+	if err := posts.UpdatePost(post); err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+	post.Tags = []string{}
+	if err := posts.UpdatePost(post); err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+	// an edit leaving the tags unchanged
+	post.Tags = nil
+	if err := posts.UpdatePost(post); err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+	if _, err := posts.db.Exec(`INSERT INTO post_revision (post_id, editor_id, title, content) VALUES (?, 1, 't', 'c')`, post.ID); err != nil {
+		t.Fatalf("insert untracked revision: %v", err)
+	}
+
+	list, err := revisions.ListRevisions(post.ID)
+	if err != nil {
+		t.Fatalf("ListRevisions: %v", err)
+	}
+	want := [][]string{nil, {}, {}, {"go", "sqlite"}, {"go"}}
+	if len(list) != len(want) {
+		t.Fatalf("%d revisions, want %d", len(list), len(want))
+	}
+	for i, rev := range list {
+		if !slices.Equal(rev.Tags, want[i]) || (rev.Tags == nil) != (want[i] == nil) {
+			t.Errorf("revision %d has tags %#v, want %#v", rev.ID, rev.Tags, want[i])
+		}
+	}
+
+	rev, err := revisions.GetRevision(post.ID, list[3].ID)
+	if err != nil {
+		t.Fatalf("GetRevision: %v", err)
+	}
+	if !slices.Equal(rev.Tags, []string{"go", "sqlite"}) {
+		t.Fatalf("GetRevision has tags %v, want [go sqlite]", rev.Tags)
+	}
+}
@@ -0,0 +1,206 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type SQliteReactionRepo struct {
+	log logger.Logger
+	db  *sql.DB
+}
+
+// InitReactionDatabase creates the reaction table together with the counters
+// read by ReactionSummaries. The counters are maintained by triggers, so reads
+// never have to count reactions.
+func (r *SQliteReactionRepo) InitReactionDatabase() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS reaction (
+		user_id INTEGER NOT NULL,
+		target_type TEXT NOT NULL,
+		target_id INTEGER NOT NULL,
+		emoji TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, target_type, target_id, emoji),
+		FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_reaction_target ON reaction(target_type, target_id);
+
+	CREATE TABLE IF NOT EXISTS reaction_count (
+		target_type TEXT NOT NULL,
+		target_id INTEGER NOT NULL,
+		emoji TEXT NOT NULL,
+		count INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (target_type, target_id, emoji)
+	);
+
+	CREATE TABLE IF NOT EXISTS reaction_state (
+		target_type TEXT NOT NULL,
+		target_id INTEGER NOT NULL,
+		revision INTEGER NOT NULL DEFAULT 0,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (target_type, target_id)
+	);
+
+	CREATE TRIGGER IF NOT EXISTS trg_reaction_insert AFTER INSERT ON reaction
+	BEGIN
+		INSERT INTO reaction_count (target_type, target_id, emoji, count)
+		VALUES (NEW.target_type, NEW.target_id, NEW.emoji, 1)
+		ON CONFLICT (target_type, target_id, emoji) DO UPDATE SET count = count + 1;
+		INSERT INTO reaction_state (target_type, target_id, revision, updated_at)
+		VALUES (NEW.target_type, NEW.target_id, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (target_type, target_id) DO UPDATE SET revision = revision + 1, updated_at = CURRENT_TIMESTAMP;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS trg_reaction_delete AFTER DELETE ON reaction
+	BEGIN
+		UPDATE reaction_count SET count = count - 1
+		WHERE target_type = OLD.target_type AND target_id = OLD.target_id AND emoji = OLD.emoji;
+		DELETE FROM reaction_count
+		WHERE target_type = OLD.target_type AND target_id = OLD.target_id AND emoji = OLD.emoji AND count <= 0;
+		UPDATE reaction_state SET revision = revision + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE target_type = OLD.target_type AND target_id = OLD.target_id;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS trg_reaction_post_delete AFTER DELETE ON post
+	BEGIN
+		DELETE FROM reaction WHERE target_type = 'post' AND target_id = OLD.id;
+		DELETE FROM reaction_state WHERE target_type = 'post' AND target_id = OLD.id;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS trg_reaction_comment_delete AFTER DELETE ON comment
+	BEGIN
+		DELETE FROM reaction WHERE target_type = 'comment' AND target_id = OLD.id;
+		DELETE FROM reaction_state WHERE target_type = 'comment' AND target_id = OLD.id;
+	END;
+	`
+	_, err := r.db.Exec(stmt)
+	return err
+}
+
+// AddReaction stores the reaction and reports whether it is new. Adding a
+// reaction the user already has is a no-op.
+func (r *SQliteReactionRepo) AddReaction(reaction *models.Reaction) (bool, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.AddReaction"),
+		slog.String("target_type", reaction.TargetType), slog.Int64("target_id", reaction.TargetID))
+	query := `
+		INSERT INTO reaction (user_id, target_type, target_id, emoji)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT DO NOTHING
+	`
+	res, err := r.db.Exec(query, reaction.UserID, reaction.TargetType, reaction.TargetID, reaction.Emoji)
+	if err != nil {
+		if isForeignKeyErr(err) {
+			return false, repository.ErrForeignKeyFailed
+		}
+		log.Error("failed to add reaction", sl.Error(err))
+		return false, fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+	}
+
+	added, _ := res.RowsAffected()
+	return added > 0, nil
+}
+
+// RemoveReaction deletes the reaction and reports whether it existed.
+func (r *SQliteReactionRepo) RemoveReaction(reaction *models.Reaction) (bool, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.RemoveReaction"),
+		slog.String("target_type", reaction.TargetType), slog.Int64("target_id", reaction.TargetID))
+	query := `
+		DELETE FROM reaction
+		WHERE user_id = ? AND target_type = ? AND target_id = ? AND emoji = ?
+	`
+	res, err := r.db.Exec(query, reaction.UserID, reaction.TargetType, reaction.TargetID, reaction.Emoji)
+	if err != nil {
+		log.Error("failed to remove reaction", sl.Error(err))
+		return false, fmt.Errorf("delete error: %w", repository.ErrOperationFailed)
+	}
+
+	removed, _ := res.RowsAffected()
+	return removed > 0, nil
+}
+
+// ReactionSummaries returns the reaction counts of the given targets. Targets
+// nobody ever reacted to are missing from the map.
+func (r *SQliteReactionRepo) ReactionSummaries(targetType string, ids []int64) (map[int64]*models.ReactionSummary, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ReactionSummaries"), slog.String("target_type", targetType))
+	summaries := make(map[int64]*models.ReactionSummary, len(ids))
+	if len(ids) == 0 {
+		return summaries, nil
+	}
+
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, targetType)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	in := "?" + strings.Repeat(",?", len(ids)-1)
+
+	query := `
+		SELECT target_id, revision, updated_at
+		FROM reaction_state
+		WHERE target_type = ? AND target_id IN (` + in + `)
+	`
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to query reaction state", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s := &models.ReactionSummary{Counts: make(map[string]int64)}
+		var id int64
+		if err := rows.Scan(&id, &s.Revision, &s.UpdatedAt); err != nil {
+			log.Error("failed to scan reaction state", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		summaries[id] = s
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	if len(summaries) == 0 {
+		return summaries, nil
+	}
+
+	query = `
+		SELECT target_id, emoji, count
+		FROM reaction_count
+		WHERE target_type = ? AND target_id IN (` + in + `)
+	`
+	rows, err = r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to query reaction counts", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id    int64
+			emoji string
+			count int64
+		)
+		if err := rows.Scan(&id, &emoji, &count); err != nil {
+			log.Error("failed to scan reaction count", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		if s, ok := summaries[id]; ok {
+			s.Counts[emoji] = count
+			s.Total += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return summaries, nil
+}
@@ -0,0 +1,170 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type SQliteReportRepo struct {
+	log logger.Logger
+	db  *sql.DB
+}
+
+// InitReportDatabase creates the report table. A reporter can have only one
+// open report per post or comment.
+func (r *SQliteReportRepo) InitReportDatabase() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS report (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		reporter_id INTEGER NOT NULL,
+		target_type TEXT NOT NULL,
+		target_id INTEGER NOT NULL,
+		reason TEXT NOT NULL,
+		details TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL DEFAULT 'open',
+		resolved_by INTEGER,
+		resolution TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		resolved_at DATETIME,
+		FOREIGN KEY (reporter_id) REFERENCES user(id) ON DELETE CASCADE,
+		FOREIGN KEY (resolved_by) REFERENCES user(id) ON DELETE SET NULL
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_report_open_reporter ON report(reporter_id, target_type, target_id) WHERE status = 'open';
+	CREATE INDEX IF NOT EXISTS idx_report_target ON report(target_type, target_id, status);
+	`
+	if _, err := r.db.Exec(stmt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SQliteReportRepo) CreateReport(report *models.Report) (int64, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.CreateReport"))
+	query := `
+		INSERT INTO report (reporter_id, target_type, target_id, reason, details)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id, status, created_at
+	`
+	err := r.db.QueryRow(query,
+		report.ReporterID,
+		report.TargetType,
+		report.TargetID,
+		report.Reason,
+		report.Details).Scan(
+		&report.ID,
+		&report.Status,
+		&report.CreatedAt,
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
+			return 0, repository.ErrAlreadyExists
+		}
+		log.Error("failed to create report", sl.Error(err))
+		return 0, fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+	}
+	return report.ID, nil
+}
+
+// CountOpenReporters returns how many distinct users have an open report on the target.
+func (r *SQliteReportRepo) CountOpenReporters(targetType string, targetID int64) (int, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.CountOpenReporters"))
+	query := `
+		SELECT COUNT(DISTINCT reporter_id)
+		FROM report
+		WHERE target_type = ? AND target_id = ? AND status = 'open'
+	`
+	var count int
+	if err := r.db.QueryRow(query, targetType, targetID).Scan(&count); err != nil {
+		log.Error("failed to count reporters", sl.Error(err))
+		return 0, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return count, nil
+}
+
+// ListOpenReportTargets returns a page of the reported posts and comments,
+// keyed by their first open report and the first reported first. Further
+// reports don't move a target, so pages don't shift while they come in.
+func (r *SQliteReportRepo) ListOpenReportTargets(page repository.Page) ([]*models.ReportTarget, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListOpenReportTargets"))
+	query := `
+		SELECT target_type, target_id, COUNT(DISTINCT reporter_id), GROUP_CONCAT(reason), MIN(created_at), MAX(created_at), MIN(id)
+		FROM report
+		WHERE status = 'open'
+		GROUP BY target_type, target_id
+	`
+	cond, tail, args := keysetOldestFirst(page, "MIN(created_at)", "MIN(id)")
+	if cond != "" {
+		query += ` HAVING ` + cond
+	}
+	query += tail
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to list reports", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var targets []*models.ReportTarget
+	for rows.Next() {
+		var (
+			t                 models.ReportTarget
+			reasons           string
+			firstRep, lastRep string
+		)
+		if err := rows.Scan(&t.TargetType, &t.TargetID, &t.Reporters, &reasons, &firstRep, &lastRep, &t.FirstReportID); err != nil {
+			log.Error("failed to scan report target", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		t.Reasons = make(map[string]int)
+		for _, reason := range strings.Split(reasons, ",") {
+			t.Reasons[reason]++
+		}
+		// aggregates lose the column type, so the timestamps come back as text
+		t.FirstReportedAt, _ = time.Parse(time.DateTime, firstRep)
+		t.LastReportedAt, _ = time.Parse(time.DateTime, lastRep)
+		targets = append(targets, &t)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return keysetRows(page, targets), nil
+}
+
+// ResolveReports closes all open reports on the target and returns how many were closed.
+func (r *SQliteReportRepo) ResolveReports(targetType string, targetID int64, status string, resolverID int64, resolution string) (int64, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ResolveReports"),
+		slog.String("target_type", targetType), slog.Int64("target_id", targetID))
+	query := `
+		UPDATE report
+		SET status = ?, resolved_by = ?, resolution = ?, resolved_at = CURRENT_TIMESTAMP
+		WHERE target_type = ? AND target_id = ? AND status = 'open'
+	`
+	res, err := r.db.Exec(query, status, resolverID, resolution, targetType, targetID)
+	if err != nil {
+		log.Error("failed to resolve reports", sl.Error(err))
+		return 0, fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+
+	resolved, _ := res.RowsAffected()
+	if resolved == 0 {
+		log.Info("no open reports")
+		return 0, repository.ErrNotExists
+	}
+	return resolved, nil
+}
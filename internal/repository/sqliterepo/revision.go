@@ -0,0 +1,150 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type SQliteRevisionRepo struct {
+	log logger.Logger
+	db  *sql.DB
+}
+
+func (r *SQliteRevisionRepo) InitRevisionDatabase() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS post_revision (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		post_id INTEGER NOT NULL,
+		editor_id INTEGER NOT NULL,
+		title TEXT NOT NULL,
+		content TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (post_id) REFERENCES post(id) ON DELETE CASCADE,
+		FOREIGN KEY (editor_id) REFERENCES user(id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_post_revision_post_id ON post_revision(post_id);
+	`
+	if _, err := r.db.Exec(stmt); err != nil {
+		return err
+	}
+
+	// NULL in revisions stored before tags were tracked
+	return addColumn(r.db, "post_revision", "tags", "TEXT")
+}
+
+func (r *SQliteRevisionRepo) ListRevisions(postID int64) ([]*models.PostRevision, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListRevisions"), slog.Int64("post_id", postID))
+	query := `
+		SELECT id, post_id, editor_id, title, content, tags, created_at
+		FROM post_revision
+		WHERE post_id = ?
+		ORDER BY id DESC
+	`
+	rows, err := r.db.Query(query, postID)
+	if err != nil {
+		log.Error("failed to list revisions", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var revisions []*models.PostRevision
+	for rows.Next() {
+		var rev models.PostRevision
+		var tags sql.NullString
+		if err := rows.Scan(
+			&rev.ID,
+			&rev.PostID,
+			&rev.EditorID,
+			&rev.Title,
+			&rev.Content,
+			&tags,
+			&rev.CreatedAt,
+		); err != nil {
+			log.Error("failed to scan revision", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		rev.Tags = revisionTags(tags)
+		revisions = append(revisions, &rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return revisions, nil
+}
+
+func (r *SQliteRevisionRepo) GetRevision(postID, revisionID int64) (*models.PostRevision, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.GetRevision"),
+		slog.Int64("post_id", postID),
+		slog.Int64("revision_id", revisionID))
+	query := `
+		SELECT id, post_id, editor_id, title, content, tags, created_at
+		FROM post_revision
+		WHERE id = ? AND post_id = ?
+	`
+
+	var rev models.PostRevision
+	var tags sql.NullString
+	err := r.db.QueryRow(query, revisionID, postID).Scan(
+		&rev.ID,
+		&rev.PostID,
+		&rev.EditorID,
+		&rev.Title,
+		&rev.Content,
+		&tags,
+		&rev.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Info("revision not found")
+			return nil, repository.ErrNotExists
+		}
+		log.Error("failed to get revision", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+
+	rev.Tags = revisionTags(tags)
+	return &rev, nil
+}
+
+// snapshotTags selects the sorted tags of the post as one space separated
+// string, tags never contain spaces.
+const snapshotTags = `COALESCE((SELECT group_concat(tag, ' ') FROM (SELECT tag FROM post_tag WHERE post_id = post.id ORDER BY tag)), '')`
+
+// insertRevision snapshots the current state of the post, tags included, as a new revision.
+func insertRevision(tx *sql.Tx, postID, editorID int64) error {
+	_, err := tx.Exec(`
+		INSERT INTO post_revision (post_id, editor_id, title, content, tags)
+		SELECT id, ?, title, content, `+snapshotTags+` FROM post WHERE id = ?
+	`, editorID, postID)
+	return err
+}
+
+// insertBaseRevision records the pre-history state of posts created before
+// revisions were tracked, so the first edit can still be diffed and restored.
+func insertBaseRevision(tx *sql.Tx, postID int64) error {
+	_, err := tx.Exec(`
+		INSERT INTO post_revision (post_id, editor_id, title, content, tags, created_at)
+		SELECT id, author_id, title, content, `+snapshotTags+`, updated_at FROM post
+		WHERE id = ? AND NOT EXISTS (SELECT 1 FROM post_revision WHERE post_id = ?)
+	`, postID, postID)
+	return err
+}
+
+// revisionTags splits stored revision tags. Revisions stored before tags
+// were tracked have none, their tags are nil rather than empty.
+func revisionTags(tags sql.NullString) []string {
+	if !tags.Valid {
+		return nil
+	}
+	return append([]string{}, strings.Fields(tags.String)...)
+}
@@ -0,0 +1,227 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+// activeSanctionCond matches users (first verb) having an unexpired, not revoked
+// sanction of one of the kinds (second verb, a quoted SQL list).
+const activeSanctionCond = `EXISTS (
+	SELECT 1 FROM user_sanction
+	WHERE user_sanction.user_id = %s AND user_sanction.kind IN (%s) AND user_sanction.revoked_at IS NULL
+		AND (user_sanction.expires_at IS NULL OR user_sanction.expires_at > CURRENT_TIMESTAMP)
+)`
+
+const sanctionColumns = `id, user_id, kind, reason, COALESCE(issued_by, 0), created_at, expires_at, revoked_at, revoked_by`
+
+type SQliteSanctionRepo struct {
+	log logger.Logger
+	db  *sql.DB
+}
+
+func (r *SQliteSanctionRepo) InitSanctionDatabase() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS user_sanction (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		kind TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		issued_by INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		expires_at DATETIME,
+		revoked_at DATETIME,
+		revoked_by INTEGER,
+		FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+		FOREIGN KEY (issued_by) REFERENCES user(id) ON DELETE SET NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_user_sanction_user_id ON user_sanction(user_id, kind);
+	`
+	if _, err := r.db.Exec(stmt); err != nil {
+		return err
+	}
+
+	if err := addColumn(r.db, "user_sanction", "revoked_at", "DATETIME"); err != nil {
+		return err
+	}
+	return addColumn(r.db, "user_sanction", "revoked_by", "INTEGER")
+}
+
+func (r *SQliteSanctionRepo) CreateSanction(sanction *models.Sanction) (int64, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.CreateSanction"), slog.Int64("user_id", sanction.UserID))
+	query := `
+		INSERT INTO user_sanction (user_id, kind, reason, issued_by, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id, created_at
+	`
+	var expiresAt any
+	if sanction.ExpiresAt != nil {
+		expiresAt = sanction.ExpiresAt.UTC().Format(time.DateTime)
+	}
+	err := r.db.QueryRow(query,
+		sanction.UserID,
+		sanction.Kind,
+		sanction.Reason,
+		sanction.IssuedBy,
+		expiresAt).Scan(&sanction.ID, &sanction.CreatedAt)
+	if err != nil {
+		if isForeignKeyErr(err) {
+			return 0, repository.ErrForeignKeyFailed
+		}
+		log.Error("failed to create sanction", sl.Error(err))
+		return 0, fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+	}
+	return sanction.ID, nil
+}
+
+func scanSanction(row interface{ Scan(dest ...any) error }) (*models.Sanction, error) {
+	var s models.Sanction
+	err := row.Scan(
+		&s.ID,
+		&s.UserID,
+		&s.Kind,
+		&s.Reason,
+		&s.IssuedBy,
+		&s.CreatedAt,
+		&s.ExpiresAt,
+		&s.RevokedAt,
+		&s.RevokedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListSanctions returns the sanction history of a user, newest first.
+func (r *SQliteSanctionRepo) ListSanctions(userID int64) ([]*models.Sanction, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListSanctions"), slog.Int64("user_id", userID))
+	query := `SELECT ` + sanctionColumns + ` FROM user_sanction WHERE user_id = ? ORDER BY id DESC`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		log.Error("failed to list sanctions", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var sanctions []*models.Sanction
+	for rows.Next() {
+		s, err := scanSanction(rows)
+		if err != nil {
+			log.Error("failed to scan sanction", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		sanctions = append(sanctions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return sanctions, nil
+}
+
+// ActiveSanction returns the longest running active sanction of the given kinds,
+// permanent ones first, or ErrNotExists.
+func (r *SQliteSanctionRepo) ActiveSanction(userID int64, kinds ...string) (*models.Sanction, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ActiveSanction"), slog.Int64("user_id", userID))
+	if len(kinds) == 0 {
+		return nil, repository.ErrNotExists
+	}
+
+	args := make([]any, 0, len(kinds)+1)
+	args = append(args, userID)
+	for _, k := range kinds {
+		args = append(args, k)
+	}
+	query := `
+		SELECT ` + sanctionColumns + `
+		FROM user_sanction
+		WHERE user_id = ? AND kind IN (?` + strings.Repeat(",?", len(kinds)-1) + `) AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		ORDER BY expires_at IS NOT NULL, expires_at DESC
+		LIMIT 1
+	`
+	s, err := scanSanction(r.db.QueryRow(query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotExists
+		}
+		log.Error("failed to get active sanction", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return s, nil
+}
+
+// ShadowBannedUsers reports which of the given users are currently shadow-banned.
+func (r *SQliteSanctionRepo) ShadowBannedUsers(ids []int64) (map[int64]bool, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ShadowBannedUsers"))
+	banned := make(map[int64]bool)
+	if len(ids) == 0 {
+		return banned, nil
+	}
+
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	query := `
+		SELECT DISTINCT user_id
+		FROM user_sanction
+		WHERE kind = 'shadow_ban' AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+			AND user_id IN (?` + strings.Repeat(",?", len(ids)-1) + `)
+	`
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to get shadow-banned users", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			log.Error("failed to scan user id", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		banned[id] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return banned, nil
+}
+
+// RevokeSanction lifts an active sanction and returns it.
+func (r *SQliteSanctionRepo) RevokeSanction(id, revokedBy int64) (*models.Sanction, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.RevokeSanction"), slog.Int64("id", id))
+	query := `
+		UPDATE user_sanction
+		SET revoked_at = CURRENT_TIMESTAMP, revoked_by = ?
+		WHERE id = ? AND revoked_at IS NULL
+		RETURNING ` + sanctionColumns
+
+	s, err := scanSanction(r.db.QueryRow(query, revokedBy, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Info("sanction not found or already revoked")
+			return nil, repository.ErrNotExists
+		}
+		log.Error("failed to revoke sanction", sl.Error(err))
+		return nil, fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+	return s, nil
+}
@@ -0,0 +1,172 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type SQliteSpamRepo struct {
+	log logger.Logger
+	db  *sql.DB
+}
+
+// InitSpamDatabase creates the tables of the spam classifier. spam_token keeps
+// per-token sample counts, spam_training remembers how every comment was
+// labelled so a relabel can undo the previous counts.
+func (r *SQliteSpamRepo) InitSpamDatabase() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS spam_token (
+		token TEXT PRIMARY KEY,
+		spam INTEGER NOT NULL DEFAULT 0,
+		ham INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS spam_training (
+		comment_id INTEGER PRIMARY KEY,
+		is_spam BOOLEAN NOT NULL,
+		tokens TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	if _, err := r.db.Exec(stmt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SQliteSpamRepo) TokenCounts(tokens []string) (map[string]*models.SpamToken, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.TokenCounts"))
+	counts := make(map[string]*models.SpamToken, len(tokens))
+	if len(tokens) == 0 {
+		return counts, nil
+	}
+
+	args := make([]any, 0, len(tokens))
+	for _, t := range tokens {
+		args = append(args, t)
+	}
+	query := `
+		SELECT token, spam, ham
+		FROM spam_token
+		WHERE token IN (?` + strings.Repeat(",?", len(tokens)-1) + `)
+	`
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to get token counts", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t models.SpamToken
+		if err := rows.Scan(&t.Token, &t.Spam, &t.Ham); err != nil {
+			log.Error("failed to scan token", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		counts[t.Token] = &t
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return counts, nil
+}
+
+// TrainingTotals returns the number of spam and ham samples the model was trained on.
+func (r *SQliteSpamRepo) TrainingTotals() (spam, ham int64, err error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.TrainingTotals"))
+	query := `
+		SELECT COALESCE(SUM(is_spam), 0), COALESCE(SUM(NOT is_spam), 0)
+		FROM spam_training
+	`
+	if err := r.db.QueryRow(query).Scan(&spam, &ham); err != nil {
+		log.Error("failed to get training totals", sl.Error(err))
+		return 0, 0, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return spam, ham, nil
+}
+
+// Train records a comment as a spam or ham sample. Training the same comment
+// with the same label again is a no-op; the opposite label replaces the old one.
+func (r *SQliteSpamRepo) Train(commentID int64, tokens []string, spam bool) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.Train"), slog.Int64("comment_id", commentID))
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Error("failed to begin transaction", sl.Error(err))
+		return repository.ErrOperationFailed
+	}
+	defer tx.Rollback()
+
+	var (
+		wasSpam   bool
+		oldTokens string
+	)
+	err = tx.QueryRow(`SELECT is_spam, tokens FROM spam_training WHERE comment_id = ?`, commentID).Scan(&wasSpam, &oldTokens)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		log.Error("failed to get previous training", sl.Error(err))
+		return fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	case wasSpam == spam:
+		return nil
+	default:
+		if err := addTokenCounts(tx, strings.Fields(oldTokens), wasSpam, -1); err != nil {
+			log.Error("failed to forget previous training", sl.Error(err))
+			return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+		}
+	}
+
+	if err := addTokenCounts(tx, tokens, spam, 1); err != nil {
+		log.Error("failed to update token counts", sl.Error(err))
+		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+
+	query := `
+		INSERT INTO spam_training (comment_id, is_spam, tokens)
+		VALUES (?, ?, ?)
+		ON CONFLICT (comment_id) DO UPDATE SET is_spam = excluded.is_spam, tokens = excluded.tokens, created_at = CURRENT_TIMESTAMP
+	`
+	if _, err := tx.Exec(query, commentID, spam, strings.Join(tokens, " ")); err != nil {
+		log.Error("failed to save training", sl.Error(err))
+		return fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("failed to commit training", sl.Error(err))
+		return repository.ErrOperationFailed
+	}
+	return nil
+}
+
+func addTokenCounts(tx *sql.Tx, tokens []string, spam bool, delta int) error {
+	column := "ham"
+	if spam {
+		column = "spam"
+	}
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+		INSERT INTO spam_token (token, %[1]s) VALUES (?, MAX(?, 0))
+		ON CONFLICT (token) DO UPDATE SET %[1]s = MAX(%[1]s + ?, 0)
+	`, column))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, token := range tokens {
+		if _, err := stmt.Exec(token, delta, delta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,8 +2,10 @@ package sqliterepo
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 
 	"blog/internal/repository"
 	"blog/internal/util/logger"
@@ -25,6 +27,44 @@ func New(databasePath string, log logger.Logger) (*sql.DB, error) {
 	return db, nil
 }
 
+// isForeignKeyErr reports whether err is a failed foreign key constraint.
+// sqlite3.Error doesn't match the extended codes with errors.Is on its own.
+func isForeignKeyErr(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey
+}
+
+// addColumn adds a column to an existing table unless it is already there.
+// It lets tables created by older versions pick up new columns on startup.
+func addColumn(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid          int
+			name, typ    string
+			notNull, pk  int
+			defaultValue sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
 type SQLiteRepository struct {
 	db  *sql.DB
 	log logger.Logger
@@ -49,6 +89,58 @@ func (r *SQLiteRepository) Comment() repository.CommentRepository {
 	return &SQliteCommentRepo{log: r.log, db: r.db}
 }
 
+func (r *SQLiteRepository) Revision() repository.RevisionRepository {
+	return &SQliteRevisionRepo{log: r.log, db: r.db}
+}
+
+func (r *SQLiteRepository) Spam() repository.SpamRepository {
+	return &SQliteSpamRepo{log: r.log, db: r.db}
+}
+
+func (r *SQLiteRepository) Report() repository.ReportRepository {
+	return &SQliteReportRepo{log: r.log, db: r.db}
+}
+
+func (r *SQLiteRepository) Audit() repository.AuditRepository {
+	return &SQliteAuditRepo{log: r.log, db: r.db}
+}
+
+func (r *SQLiteRepository) Sanction() repository.SanctionRepository {
+	return &SQliteSanctionRepo{log: r.log, db: r.db}
+}
+
+func (r *SQLiteRepository) Reaction() repository.ReactionRepository {
+	return &SQliteReactionRepo{log: r.log, db: r.db}
+}
+
+func (r *SQLiteRepository) Bookmark() repository.BookmarkRepository {
+	return &SQliteBookmarkRepo{log: r.log, db: r.db}
+}
+
+func (r *SQLiteRepository) Follow() repository.FollowRepository {
+	return &SQliteFollowRepo{log: r.log, db: r.db}
+}
+
+func (r *SQLiteRepository) Notification() repository.NotificationRepository {
+	return &SQliteNotificationRepo{log: r.log, db: r.db}
+}
+
+func (r *SQLiteRepository) Mention() repository.MentionRepository {
+	return &SQliteMentionRepo{log: r.log, db: r.db}
+}
+
+func (r *SQLiteRepository) Federation() repository.FederationRepository {
+	return &SQliteFederationRepo{log: r.log, db: r.db}
+}
+
+func (r *SQLiteRepository) Webmention() repository.WebmentionRepository {
+	return &SQliteWebmentionRepo{log: r.log, db: r.db}
+}
+
+func (r *SQLiteRepository) Webhook() repository.WebhookRepository {
+	return &SQliteWebhookRepo{log: r.log, db: r.db}
+}
+
 func (r *SQLiteRepository) Close() error {
 	return r.db.Close()
 }
@@ -26,12 +26,16 @@ func (r *SQliteUserRepo) InitUserDatabase() error {
 		username TEXT NOT NULL,
 		password TEXT NOT NULL,
 		email TEXT NOT NULL UNIQUE,
+		role TEXT NOT NULL DEFAULT 'user',
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 	`
-	_, err := r.db.Exec(stmt)
-	return err
+	if _, err := r.db.Exec(stmt); err != nil {
+		return err
+	}
+
+	return addColumn(r.db, "user", "role", "TEXT NOT NULL DEFAULT 'user'")
 }
 
 func (r *SQliteUserRepo) CreateUser(user *models.User) (int64, error) {
@@ -70,7 +74,7 @@ func (r *SQliteUserRepo) CreateUser(user *models.User) (int64, error) {
 func (r *SQliteUserRepo) GetUserByID(id int64) (*models.User, error) {
 	log := r.log.With("fn", "repository.sqlite.GetUserByID")
 	query := `
-		SELECT id, username, password, email, created_at, updated_at
+		SELECT id, username, password, email, role, created_at, updated_at
 		FROM user
 		WHERE id = ?
 	`
@@ -82,6 +86,7 @@ func (r *SQliteUserRepo) GetUserByID(id int64) (*models.User, error) {
 		&user.Username,
 		&user.Password,
 		&user.Email,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -101,7 +106,7 @@ func (r *SQliteUserRepo) GetUserByID(id int64) (*models.User, error) {
 func (r *SQliteUserRepo) GetUserByEmail(email string) (*models.User, error) {
 	log := r.log.With("fn", "repository.sqlite.GetUserByEmail")
 	query := `
-		SELECT id, username, password, email, created_at, updated_at
+		SELECT id, username, password, email, role, created_at, updated_at
 		FROM user
 		WHERE email = ?
 	`
@@ -113,6 +118,7 @@ func (r *SQliteUserRepo) GetUserByEmail(email string) (*models.User, error) {
 		&user.Username,
 		&user.Password,
 		&user.Email,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -129,6 +135,108 @@ func (r *SQliteUserRepo) GetUserByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
+// GetUsersByIDs loads several users in one query. Unknown ids are absent from the result.
+func (r *SQliteUserRepo) GetUsersByIDs(ids []int64) (map[int64]*models.User, error) {
+	log := r.log.With("fn", "repository.sqlite.GetUsersByIDs")
+	users := make(map[int64]*models.User, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	query := `
+		SELECT id, username, email, role, created_at, updated_at
+		FROM user
+		WHERE id IN (?` + strings.Repeat(",?", len(ids)-1) + `)`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to get users", "error", err)
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.Role,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		); err != nil {
+			log.Error("failed to scan user", "error", err)
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		users[user.ID] = &user
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", "error", err)
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+
+	return users, nil
+}
+
+// GetUsersByUsernames resolves usernames case-insensitively. The result is
+// keyed by lowercased username; when several users share a name the earliest
+// registered one wins.
+func (r *SQliteUserRepo) GetUsersByUsernames(usernames []string) (map[string]*models.User, error) {
+	log := r.log.With("fn", "repository.sqlite.GetUsersByUsernames")
+	users := make(map[string]*models.User, len(usernames))
+	if len(usernames) == 0 {
+		return users, nil
+	}
+
+	args := make([]any, len(usernames))
+	for i, name := range usernames {
+		args[i] = strings.ToLower(name)
+	}
+	query := `
+		SELECT id, username, email, role, created_at, updated_at
+		FROM user
+		WHERE lower(username) IN (?` + strings.Repeat(",?", len(usernames)-1) + `)
+		ORDER BY id`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to get users", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.Role,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		); err != nil {
+			log.Error("failed to scan user", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		key := strings.ToLower(user.Username)
+		if _, ok := users[key]; !ok {
+			users[key] = &user
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+
+	return users, nil
+}
+
 func (r *SQliteUserRepo) UpdateUser(user *models.User) error {
 	log := r.log.With("fn", "repository.sqlite.UpdateUser")
 	query := `
@@ -170,6 +278,46 @@ func (r *SQliteUserRepo) UpdateUser(user *models.User) error {
 	return nil
 }
 
+func (r *SQliteUserRepo) SetUserRole(id int64, role string) error {
+	log := r.log.With("fn", "repository.sqlite.SetUserRole")
+	query := `UPDATE user SET role = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	res, err := r.db.Exec(query, role, id)
+	if err != nil {
+		log.Error("failed to set user role", "error", err, "id", id)
+		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		log.Info("user not found during role update", "id", id)
+		return repository.ErrNotExists
+	}
+
+	return nil
+}
+
+// PromoteAdmins grants the admin role to the users with the given emails.
+func (r *SQliteUserRepo) PromoteAdmins(emails []string) error {
+	log := r.log.With("fn", "repository.sqlite.PromoteAdmins")
+	if len(emails) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(emails)+1)
+	args = append(args, models.RoleAdmin)
+	for _, email := range emails {
+		args = append(args, email)
+	}
+	query := `UPDATE user SET role = ? WHERE email IN (?` + strings.Repeat(",?", len(emails)-1) + `)`
+
+	if _, err := r.db.Exec(query, args...); err != nil {
+		log.Error("failed to promote admins", "error", err)
+		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
 func (r *SQliteUserRepo) DeleteUser(id int64) error {
 	log := r.log.With("fn", "repository.sqlite.DeleteUser")
 	query := `DELETE FROM user WHERE id = ?`
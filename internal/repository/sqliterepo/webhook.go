@@ -0,0 +1,347 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+const (
+	webhookColumns  = `id, url, secret, events, active, created_by, created_at, updated_at`
+	deliveryColumns = `id, webhook_id, event, payload, status, attempts, next_attempt_at, response_status, last_error, redelivery_of, created_at, updated_at`
+)
+
+type SQliteWebhookRepo struct {
+	log logger.Logger
+	db  *sql.DB
+}
+
+// InitWebhookDatabase creates the tables for webhooks and their deliveries.
+// Pending deliveries are the queue, finished ones the delivery log.
+func (r *SQliteWebhookRepo) InitWebhookDatabase() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS webhook (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT NOT NULL,
+		active INTEGER NOT NULL DEFAULT 1,
+		created_by INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_delivery (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL,
+		event TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at DATETIME,
+		response_status INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		redelivery_of INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (webhook_id) REFERENCES webhook(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_delivery_webhook_id ON webhook_delivery(webhook_id, id);
+	CREATE INDEX IF NOT EXISTS idx_webhook_delivery_next_attempt_at ON webhook_delivery(status, next_attempt_at);
+	`
+	_, err := r.db.Exec(stmt)
+	return err
+}
+
+func scanWebhook(row interface{ Scan(dest ...any) error }) (*models.Webhook, error) {
+	var h models.Webhook
+	var events string
+	if err := row.Scan(&h.ID, &h.URL, &h.Secret, &events, &h.Active, &h.CreatedBy, &h.CreatedAt, &h.UpdatedAt); err != nil {
+		return nil, err
+	}
+	h.Events = strings.Split(events, ",")
+	return &h, nil
+}
+
+func scanDelivery(row interface{ Scan(dest ...any) error }) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload []byte
+	err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.ResponseStatus, &d.LastError, &d.RedeliveryOf, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	d.Payload = payload
+	return &d, nil
+}
+
+func (r *SQliteWebhookRepo) CreateWebhook(h *models.Webhook) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.CreateWebhook"))
+	query := `
+		INSERT INTO webhook (url, secret, events, active, created_by)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRow(query, h.URL, h.Secret, strings.Join(h.Events, ","), h.Active, h.CreatedBy).
+		Scan(&h.ID, &h.CreatedAt, &h.UpdatedAt)
+	if err != nil {
+		log.Error("failed to create webhook", sl.Error(err))
+		return fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+func (r *SQliteWebhookRepo) GetWebhook(id int64) (*models.Webhook, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.GetWebhook"), slog.Int64("id", id))
+	h, err := scanWebhook(r.db.QueryRow(`SELECT `+webhookColumns+` FROM webhook WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotExists
+		}
+		log.Error("failed to get webhook", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	return h, nil
+}
+
+// ListWebhooks returns all webhooks, oldest first.
+func (r *SQliteWebhookRepo) ListWebhooks() ([]*models.Webhook, error) {
+	return r.queryWebhooks("repository.sqliterepo.ListWebhooks", `SELECT `+webhookColumns+` FROM webhook ORDER BY id`)
+}
+
+// WebhooksFor returns the active webhooks subscribed to event.
+func (r *SQliteWebhookRepo) WebhooksFor(event string) ([]*models.Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhook
+		WHERE active = 1 AND instr(',' || events || ',', ',' || ? || ',') > 0
+		ORDER BY id
+	`
+	return r.queryWebhooks("repository.sqliterepo.WebhooksFor", query, event)
+}
+
+func (r *SQliteWebhookRepo) queryWebhooks(fn, query string, args ...any) ([]*models.Webhook, error) {
+	log := r.log.With(slog.String("fn", fn))
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to list webhooks", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var hooks []*models.Webhook
+	for rows.Next() {
+		h, err := scanWebhook(rows)
+		if err != nil {
+			log.Error("failed to scan webhook", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		hooks = append(hooks, h)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return hooks, nil
+}
+
+// UpdateWebhook saves the url, events and active flag of a webhook.
+func (r *SQliteWebhookRepo) UpdateWebhook(h *models.Webhook) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.UpdateWebhook"), slog.Int64("id", h.ID))
+	query := `
+		UPDATE webhook
+		SET url = ?, events = ?, active = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(query, h.URL, strings.Join(h.Events, ","), h.Active, h.ID).Scan(&h.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrNotExists
+		}
+		log.Error("failed to update webhook", sl.Error(err))
+		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// DeleteWebhook removes a webhook together with its deliveries.
+func (r *SQliteWebhookRepo) DeleteWebhook(id int64) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.DeleteWebhook"), slog.Int64("id", id))
+	res, err := r.db.Exec(`DELETE FROM webhook WHERE id = ?`, id)
+	if err != nil {
+		log.Error("failed to delete webhook", sl.Error(err))
+		return fmt.Errorf("delete error: %w", repository.ErrOperationFailed)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return repository.ErrNotExists
+	}
+	return nil
+}
+
+// EnqueueWebhookDeliveries queues payload for each of the webhooks, due right away.
+func (r *SQliteWebhookRepo) EnqueueWebhookDeliveries(event string, payload []byte, webhookIDs []int64) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.EnqueueWebhookDeliveries"), slog.String("event", event))
+	if len(webhookIDs) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Error("failed to begin transaction", sl.Error(err))
+		return fmt.Errorf("transaction error: %w", repository.ErrOperationFailed)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(time.DateTime)
+	query := `INSERT INTO webhook_delivery (webhook_id, event, payload, next_attempt_at) VALUES (?, ?, ?, ?)`
+	for _, id := range webhookIDs {
+		if _, err := tx.Exec(query, id, event, string(payload), now); err != nil {
+			if isForeignKeyErr(err) {
+				// deleted in the meantime
+				continue
+			}
+			log.Error("failed to enqueue webhook delivery", sl.Error(err), slog.Int64("webhook_id", id))
+			return fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("failed to commit webhook deliveries", sl.Error(err))
+		return fmt.Errorf("commit error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// DueWebhookDeliveries returns up to limit pending deliveries whose next
+// attempt is due, the oldest first.
+func (r *SQliteWebhookRepo) DueWebhookDeliveries(now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_delivery
+		WHERE status = 'pending' AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?
+	`
+	return r.queryDeliveries("repository.sqliterepo.DueWebhookDeliveries", query, now.UTC().Format(time.DateTime), limit)
+}
+
+// ListWebhookDeliveries returns a page of the deliveries of a webhook, newest first.
+func (r *SQliteWebhookRepo) ListWebhookDeliveries(webhookID int64, page repository.Page) ([]*models.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_delivery WHERE webhook_id = ?`
+	args := []any{webhookID}
+	cond, tail, pageArgs := keyset(page, "created_at", "id")
+	if cond != "" {
+		query += ` AND ` + cond
+	}
+	query += ` ` + tail
+	args = append(args, pageArgs...)
+
+	deliveries, err := r.queryDeliveries("repository.sqliterepo.ListWebhookDeliveries", query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return keysetRows(page, deliveries), nil
+}
+
+func (r *SQliteWebhookRepo) queryDeliveries(fn, query string, args ...any) ([]*models.WebhookDelivery, error) {
+	log := r.log.With(slog.String("fn", fn))
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Error("failed to list webhook deliveries", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			log.Error("failed to scan webhook delivery", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return deliveries, nil
+}
+
+// CompleteWebhookDelivery records the last attempt of a delivery and
+// takes it off the queue, status is succeeded or failed.
+func (r *SQliteWebhookRepo) CompleteWebhookDelivery(id int64, status string, responseStatus int, lastError string) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.CompleteWebhookDelivery"), slog.Int64("id", id))
+	query := `
+		UPDATE webhook_delivery
+		SET status = ?, attempts = attempts + 1, next_attempt_at = NULL, response_status = ?, last_error = ?,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+	if _, err := r.db.Exec(query, status, responseStatus, lastError, id); err != nil {
+		log.Error("failed to complete webhook delivery", sl.Error(err))
+		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// RetryWebhookDelivery records a failed attempt and schedules the next one.
+func (r *SQliteWebhookRepo) RetryWebhookDelivery(id int64, next time.Time, responseStatus int, lastError string) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.RetryWebhookDelivery"), slog.Int64("id", id))
+	query := `
+		UPDATE webhook_delivery
+		SET attempts = attempts + 1, next_attempt_at = ?, response_status = ?, last_error = ?,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+	if _, err := r.db.Exec(query, next.UTC().Format(time.DateTime), responseStatus, lastError, id); err != nil {
+		log.Error("failed to reschedule webhook delivery", sl.Error(err))
+		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// RedeliverWebhookDelivery queues the payload of an earlier delivery of the
+// webhook again, as a new delivery that is due right away.
+func (r *SQliteWebhookRepo) RedeliverWebhookDelivery(webhookID, deliveryID int64) (*models.WebhookDelivery, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.RedeliverWebhookDelivery"), slog.Int64("delivery_id", deliveryID))
+	query := `
+		INSERT INTO webhook_delivery (webhook_id, event, payload, next_attempt_at, redelivery_of)
+		SELECT webhook_id, event, payload, ?, id
+		FROM webhook_delivery
+		WHERE id = ? AND webhook_id = ?
+		RETURNING ` + deliveryColumns
+	now := time.Now().UTC().Format(time.DateTime)
+	d, err := scanDelivery(r.db.QueryRow(query, now, deliveryID, webhookID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotExists
+		}
+		log.Error("failed to redeliver webhook delivery", sl.Error(err))
+		return nil, fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+	}
+	return d, nil
+}
+
+// PurgeWebhookDeliveries removes finished deliveries last updated before
+// the given time, pending ones are kept.
+func (r *SQliteWebhookRepo) PurgeWebhookDeliveries(before time.Time) (int64, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.PurgeWebhookDeliveries"))
+	query := `DELETE FROM webhook_delivery WHERE status != 'pending' AND updated_at < ?`
+	res, err := r.db.Exec(query, before.UTC().Format(time.DateTime))
+	if err != nil {
+		log.Error("failed to purge webhook deliveries", sl.Error(err))
+		return 0, fmt.Errorf("delete error: %w", repository.ErrOperationFailed)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
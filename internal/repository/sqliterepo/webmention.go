@@ -0,0 +1,296 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+type SQliteWebmentionRepo struct {
+	log logger.Logger
+	db  *sql.DB
+}
+
+// InitWebmentionDatabase creates the tables for verified webmentions of our
+// posts, the external links each post was last sent webmentions for and the
+// queue of webmentions to send or verify.
+func (r *SQliteWebmentionRepo) InitWebmentionDatabase() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS webmention (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		post_id INTEGER NOT NULL,
+		source TEXT NOT NULL,
+		title TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (post_id, source),
+		FOREIGN KEY (post_id) REFERENCES post(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS webmention_target (
+		post_id INTEGER NOT NULL,
+		target TEXT NOT NULL,
+		PRIMARY KEY (post_id, target),
+		FOREIGN KEY (post_id) REFERENCES post(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS webmention_job (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		source TEXT NOT NULL,
+		target TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at DATETIME NOT NULL,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (kind, source, target)
+	);
+	CREATE INDEX IF NOT EXISTS idx_webmention_job_next_attempt_at ON webmention_job(next_attempt_at);
+	`
+	_, err := r.db.Exec(stmt)
+	return err
+}
+
+// EnqueueWebmentionJobs queues a job per target, due right away. A job
+// that is already queued starts over, the source may have changed since.
+func (r *SQliteWebmentionRepo) EnqueueWebmentionJobs(kind, source string, targets []string) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.EnqueueWebmentionJobs"), slog.String("kind", kind))
+	if len(targets) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Error("failed to begin transaction", sl.Error(err))
+		return fmt.Errorf("transaction error: %w", repository.ErrOperationFailed)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(time.DateTime)
+	query := `
+		INSERT INTO webmention_job (kind, source, target, next_attempt_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (kind, source, target) DO UPDATE SET
+			attempts = 0,
+			next_attempt_at = excluded.next_attempt_at,
+			last_error = ''
+	`
+	for _, target := range targets {
+		if _, err := tx.Exec(query, kind, source, target, now); err != nil {
+			log.Error("failed to enqueue webmention job", sl.Error(err), slog.String("target", target))
+			return fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("failed to commit webmention jobs", sl.Error(err))
+		return fmt.Errorf("commit error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// DueWebmentionJobs returns up to limit jobs whose next attempt is due, the
+// oldest first.
+func (r *SQliteWebmentionRepo) DueWebmentionJobs(now time.Time, limit int) ([]*models.WebmentionJob, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.DueWebmentionJobs"))
+	query := `
+		SELECT id, kind, source, target, attempts, next_attempt_at, last_error, created_at
+		FROM webmention_job
+		WHERE next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?
+	`
+	rows, err := r.db.Query(query, now.UTC().Format(time.DateTime), limit)
+	if err != nil {
+		log.Error("failed to list due webmention jobs", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var jobs []*models.WebmentionJob
+	for rows.Next() {
+		var j models.WebmentionJob
+		if err := rows.Scan(&j.ID, &j.Kind, &j.Source, &j.Target, &j.Attempts, &j.NextAttemptAt, &j.LastError, &j.CreatedAt); err != nil {
+			log.Error("failed to scan webmention job", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		jobs = append(jobs, &j)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return jobs, nil
+}
+
+// RetryWebmentionJob counts a failed attempt and schedules the next one.
+func (r *SQliteWebmentionRepo) RetryWebmentionJob(id int64, next time.Time, lastError string) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.RetryWebmentionJob"), slog.Int64("id", id))
+	query := `UPDATE webmention_job SET attempts = attempts + 1, next_attempt_at = ?, last_error = ? WHERE id = ?`
+	if _, err := r.db.Exec(query, next.UTC().Format(time.DateTime), lastError, id); err != nil {
+		log.Error("failed to reschedule webmention job", sl.Error(err))
+		return fmt.Errorf("update error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// DeleteWebmentionJob removes a job that is done or was given up on.
+func (r *SQliteWebmentionRepo) DeleteWebmentionJob(id int64) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.DeleteWebmentionJob"), slog.Int64("id", id))
+	if _, err := r.db.Exec(`DELETE FROM webmention_job WHERE id = ?`, id); err != nil {
+		log.Error("failed to delete webmention job", sl.Error(err))
+		return fmt.Errorf("delete error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// SaveWebmention inserts a verified webmention or refreshes the title of
+// one verified before.
+func (r *SQliteWebmentionRepo) SaveWebmention(m *models.Webmention) error {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.SaveWebmention"), slog.Int64("post_id", m.PostID))
+	query := `
+		INSERT INTO webmention (post_id, source, title)
+		VALUES (?, ?, ?)
+		ON CONFLICT (post_id, source) DO UPDATE SET
+			title = excluded.title,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRow(query, m.PostID, m.Source, m.Title).Scan(&m.ID, &m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		if isForeignKeyErr(err) {
+			return repository.ErrForeignKeyFailed
+		}
+		log.Error("failed to save webmention", sl.Error(err))
+		return fmt.Errorf("upsert error: %w", repository.ErrOperationFailed)
+	}
+	return nil
+}
+
+// DeleteWebmention removes a webmention whose source no longer links to the post.
+func (r *SQliteWebmentionRepo) DeleteWebmention(postID int64, source string) (bool, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.DeleteWebmention"), slog.Int64("post_id", postID))
+	res, err := r.db.Exec(`DELETE FROM webmention WHERE post_id = ? AND source = ?`, postID, source)
+	if err != nil {
+		log.Error("failed to delete webmention", sl.Error(err))
+		return false, fmt.Errorf("delete error: %w", repository.ErrOperationFailed)
+	}
+	deleted, _ := res.RowsAffected()
+	return deleted > 0, nil
+}
+
+// ListWebmentions returns the webmentions of a post, oldest first.
+func (r *SQliteWebmentionRepo) ListWebmentions(postID int64) ([]*models.Webmention, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ListWebmentions"), slog.Int64("post_id", postID))
+	query := `
+		SELECT id, post_id, source, title, created_at, updated_at
+		FROM webmention
+		WHERE post_id = ?
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.Query(query, postID)
+	if err != nil {
+		log.Error("failed to list webmentions", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var mentions []*models.Webmention
+	for rows.Next() {
+		var m models.Webmention
+		if err := rows.Scan(&m.ID, &m.PostID, &m.Source, &m.Title, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			log.Error("failed to scan webmention", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		mentions = append(mentions, &m)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return mentions, nil
+}
+
+// ReplaceWebmentionTargets stores the external links of a post and returns
+// the ones stored before.
+func (r *SQliteWebmentionRepo) ReplaceWebmentionTargets(postID int64, targets []string) ([]string, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.ReplaceWebmentionTargets"), slog.Int64("post_id", postID))
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Error("failed to begin transaction", sl.Error(err))
+		return nil, fmt.Errorf("transaction error: %w", repository.ErrOperationFailed)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`DELETE FROM webmention_target WHERE post_id = ? RETURNING target`, postID)
+	if err != nil {
+		log.Error("failed to delete webmention targets", sl.Error(err))
+		return nil, fmt.Errorf("delete error: %w", repository.ErrOperationFailed)
+	}
+	var previous []string
+	for rows.Next() {
+		var target string
+		if err := rows.Scan(&target); err != nil {
+			rows.Close()
+			log.Error("failed to scan webmention target", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		previous = append(previous, target)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+
+	for _, target := range targets {
+		_, err := tx.Exec(`INSERT INTO webmention_target (post_id, target) VALUES (?, ?) ON CONFLICT DO NOTHING`, postID, target)
+		if err != nil {
+			if isForeignKeyErr(err) {
+				return nil, repository.ErrForeignKeyFailed
+			}
+			log.Error("failed to insert webmention target", sl.Error(err))
+			return nil, fmt.Errorf("insert error: %w", repository.ErrOperationFailed)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("failed to commit webmention targets", sl.Error(err))
+		return nil, fmt.Errorf("commit error: %w", repository.ErrOperationFailed)
+	}
+	return previous, nil
+}
+
+// WebmentionTargets returns the external links a post was last sent
+// webmentions for.
+func (r *SQliteWebmentionRepo) WebmentionTargets(postID int64) ([]string, error) {
+	log := r.log.With(slog.String("fn", "repository.sqliterepo.WebmentionTargets"), slog.Int64("post_id", postID))
+	rows, err := r.db.Query(`SELECT target FROM webmention_target WHERE post_id = ?`, postID)
+	if err != nil {
+		log.Error("failed to list webmention targets", sl.Error(err))
+		return nil, fmt.Errorf("query error: %w", repository.ErrOperationFailed)
+	}
+	defer rows.Close()
+
+	var targets []string
+	for rows.Next() {
+		var target string
+		if err := rows.Scan(&target); err != nil {
+			log.Error("failed to scan webmention target", sl.Error(err))
+			return nil, fmt.Errorf("scan error: %w", repository.ErrOperationFailed)
+		}
+		targets = append(targets, target)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("iteration error", sl.Error(err))
+		return nil, fmt.Errorf("row iteration error: %w", repository.ErrOperationFailed)
+	}
+	return targets, nil
+}
@@ -0,0 +1,301 @@
+package sitemap
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/events"
+	"blog/internal/models"
+	"blog/internal/util/logger"
+)
+
+const xmlns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type postStore interface {
+	PostUpdateTimes() (map[int64]time.Time, error)
+	GetPostsByIDs(ids []int64) (map[int64]*models.Post, error)
+}
+
+// Document is a rendered sitemap file.
+type Document struct {
+	Body    []byte
+	LastMod time.Time
+}
+
+// Sitemap keeps the URLs of visible posts in memory and renders them as
+// sitemap files. Posts are split into parts by id, part n holding ids
+// (n-1)*max_urls+1 to n*max_urls, so a change only re-renders its own part.
+// The post table is scanned once by Load, later changes come from events.
+type Sitemap struct {
+	log     logger.Logger
+	posts   postStore
+	baseURL string
+	cfg     config.SitemapConfig
+
+	mu    sync.Mutex
+	parts map[int]*part
+	root  *Document // sitemap.xml, nil when stale
+}
+
+type part struct {
+	lastMod map[int64]time.Time // post id -> last update
+	doc     *Document           // nil when stale
+}
+
+func New(log logger.Logger, posts postStore, baseURL string, cfg config.SitemapConfig) *Sitemap {
+	return &Sitemap{
+		log:     log,
+		posts:   posts,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		cfg:     cfg,
+		parts:   make(map[int]*part),
+	}
+}
+
+// Load reads the update times of all visible posts.
+func (s *Sitemap) Load() error {
+	times, err := s.posts.PostUpdateTimes()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.parts = make(map[int]*part)
+	s.root = nil
+	for id, updatedAt := range times {
+		s.set(id, updatedAt)
+	}
+	return nil
+}
+
+// Subscribe keeps the sitemap up to date with new, edited and removed posts.
+func (s *Sitemap) Subscribe(bus *events.Bus) {
+	events.On(bus, func(ctx context.Context, e events.PostPublished) error {
+		return s.Refresh(e.Post.ID)
+	})
+	events.On(bus, func(ctx context.Context, e events.PostUpdated) error {
+		return s.Refresh(e.Post.ID)
+	})
+	events.On(bus, func(ctx context.Context, e events.PostVisibilityChanged) error {
+		return s.Refresh(e.PostID)
+	})
+}
+
+// Refresh looks the post up again, adding, updating or dropping its URL.
+func (s *Sitemap) Refresh(postID int64) error {
+	posts, err := s.posts.GetPostsByIDs([]int64{postID})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if post, ok := posts[postID]; ok {
+		s.set(postID, post.UpdatedAt)
+	} else {
+		s.remove(postID)
+	}
+	s.log.Debug("sitemap refreshed", slog.String("fn", "sitemap.Refresh"),
+		slog.Int64("post_id", postID), slog.Bool("listed", posts[postID] != nil))
+	return nil
+}
+
+// partOf returns the number of the part listing a post, starting at 1.
+func (s *Sitemap) partOf(postID int64) int {
+	return int((postID-1)/int64(s.cfg.MaxURLs)) + 1
+}
+
+func (s *Sitemap) set(postID int64, updatedAt time.Time) {
+	n := s.partOf(postID)
+	p, ok := s.parts[n]
+	if !ok {
+		p = &part{lastMod: make(map[int64]time.Time)}
+		s.parts[n] = p
+	}
+	if prev, ok := p.lastMod[postID]; ok && prev.Equal(updatedAt) {
+		return
+	}
+	p.lastMod[postID] = updatedAt
+	p.doc = nil
+	s.root = nil
+}
+
+func (s *Sitemap) remove(postID int64) {
+	n := s.partOf(postID)
+	p, ok := s.parts[n]
+	if !ok {
+		return
+	}
+	if _, ok := p.lastMod[postID]; !ok {
+		return
+	}
+	delete(p.lastMod, postID)
+	if len(p.lastMod) == 0 {
+		delete(s.parts, n)
+	}
+	p.doc = nil
+	s.root = nil
+}
+
+// Root returns sitemap.xml: a plain sitemap while all posts fit into one
+// file, a sitemap index of the parts once there are more than max_urls.
+func (s *Sitemap) Root() (*Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.root != nil {
+		return s.root, nil
+	}
+
+	total := 0
+	for _, p := range s.parts {
+		total += len(p.lastMod)
+	}
+
+	var (
+		doc *Document
+		err error
+	)
+	if total <= s.cfg.MaxURLs {
+		all := make(map[int64]time.Time, total)
+		for _, p := range s.parts {
+			maps.Copy(all, p.lastMod)
+		}
+		doc, err = s.renderURLSet(all)
+	} else {
+		doc, err = s.renderIndex()
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.root = doc
+	return doc, nil
+}
+
+// Part returns the sitemap of part n, false if it lists no posts.
+func (s *Sitemap) Part(n int) (*Document, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.parts[n]
+	if !ok {
+		return nil, false, nil
+	}
+	if p.doc == nil {
+		doc, err := s.renderURLSet(p.lastMod)
+		if err != nil {
+			return nil, false, err
+		}
+		p.doc = doc
+	}
+	return p.doc, true, nil
+}
+
+// PartPath is the path part n is served at.
+func PartPath(n int) string {
+	return fmt.Sprintf("/sitemaps/posts-%d.xml", n)
+}
+
+type urlSet struct {
+	XMLName xml.Name  `xml:"urlset"`
+	XMLNS   string    `xml:"xmlns,attr"`
+	URLs    []urlItem `xml:"url"`
+}
+
+type urlItem struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name    `xml:"sitemapindex"`
+	XMLNS    string      `xml:"xmlns,attr"`
+	Sitemaps []indexItem `xml:"sitemap"`
+}
+
+type indexItem struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+func (s *Sitemap) renderURLSet(lastMod map[int64]time.Time) (*Document, error) {
+	doc := urlSet{XMLNS: xmlns, URLs: make([]urlItem, 0, len(lastMod))}
+	var newest time.Time
+	for _, id := range slices.Sorted(maps.Keys(lastMod)) {
+		updatedAt := lastMod[id]
+		if updatedAt.After(newest) {
+			newest = updatedAt
+		}
+		doc.URLs = append(doc.URLs, urlItem{
+			Loc:     fmt.Sprintf("%s/api/post/%d", s.baseURL, id),
+			LastMod: updatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	body, err := encode(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Body: body, LastMod: newest}, nil
+}
+
+func (s *Sitemap) renderIndex() (*Document, error) {
+	doc := sitemapIndex{XMLNS: xmlns}
+	var newest time.Time
+	for _, n := range slices.Sorted(maps.Keys(s.parts)) {
+		var partNewest time.Time
+		for _, updatedAt := range s.parts[n].lastMod {
+			if updatedAt.After(partNewest) {
+				partNewest = updatedAt
+			}
+		}
+		if partNewest.After(newest) {
+			newest = partNewest
+		}
+		doc.Sitemaps = append(doc.Sitemaps, indexItem{
+			Loc:     s.baseURL + PartPath(n),
+			LastMod: partNewest.UTC().Format(time.RFC3339),
+		})
+	}
+	body, err := encode(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Body: body, LastMod: newest}, nil
+}
+
+func encode(doc any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Robots renders robots.txt from the configured rules and points crawlers
+// to sitemap.xml.
+func Robots(baseURL string, cfg config.SitemapConfig) []byte {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	for _, path := range cfg.Allow {
+		fmt.Fprintf(&b, "Allow: %s\n", path)
+	}
+	for _, path := range cfg.Disallow {
+		fmt.Fprintf(&b, "Disallow: %s\n", path)
+	}
+	if len(cfg.Allow) == 0 && len(cfg.Disallow) == 0 {
+		b.WriteString("Disallow:\n")
+	}
+	fmt.Fprintf(&b, "\nSitemap: %s/sitemap.xml\n", strings.TrimSuffix(baseURL, "/"))
+	return []byte(b.String())
+}
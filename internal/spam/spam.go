@@ -0,0 +1,230 @@
+package spam
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"blog/internal/config"
+	"blog/internal/models"
+	"blog/internal/util/logger"
+)
+
+const (
+	maxTokens      = 200 // distinct tokens kept per comment
+	bayesTokens    = 20  // most significant tokens used by the bayes model
+	minTokenLength = 3
+	maxTokenLength = 24
+)
+
+// Heuristic weights, each is the spam probability of the signal on its own.
+const (
+	weightLinks       = 0.15 // per link, up to max_links
+	weightTooManyLink = 0.6
+	weightDuplicate   = 0.7
+	weightVelocity    = 0.6
+	weightNewAccount  = 0.25
+)
+
+var (
+	linkRe    = regexp.MustCompile(`(?i)\b(?:https?://|www\.)([a-z0-9.-]+)`)
+	fullURLRe = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+)
+
+type store interface {
+	TokenCounts(tokens []string) (map[string]*models.SpamToken, error)
+	TrainingTotals() (spam, ham int64, err error)
+	Train(commentID int64, tokens []string, spam bool) error
+}
+
+type activity interface {
+	CountCommentsSince(authorID int64, since time.Time) (int, error)
+	CountDuplicateComments(authorID int64, content string, since time.Time) (int, error)
+}
+
+type userGetter interface {
+	GetUserByID(id int64) (*models.User, error)
+}
+
+// Signal is one piece of evidence that contributed to a score.
+type Signal struct {
+	Name string  `json:"name"`
+	P    float64 `json:"p"`
+}
+
+type Result struct {
+	Score   float64  `json:"score"`
+	Signals []Signal `json:"signals,omitempty"`
+}
+
+// Classifier scores new comments with a few heuristics and a naive Bayes model
+// trained from moderator decisions. Everything runs locally.
+type Classifier struct {
+	log      logger.Logger
+	store    store
+	activity activity
+	users    userGetter
+	cfg      config.SpamConfig
+}
+
+func New(log logger.Logger, store store, activity activity, users userGetter, cfg config.SpamConfig) *Classifier {
+	return &Classifier{
+		log:      log,
+		store:    store,
+		activity: activity,
+		users:    users,
+		cfg:      cfg,
+	}
+}
+
+// Score rates a comment that is about to be created. Signals are combined as
+// independent probabilities: score = 1 - Π(1 - p).
+func (c *Classifier) Score(comment *models.Comment) (Result, error) {
+	var res Result
+	if !c.cfg.Enabled {
+		return res, nil
+	}
+
+	links := len(linkRe.FindAllStringIndex(comment.Content, -1))
+	switch {
+	case links > c.cfg.MaxLinks:
+		res.Signals = append(res.Signals, Signal{"too_many_links", weightTooManyLink})
+	case links > 0:
+		res.Signals = append(res.Signals, Signal{"links", weightLinks * float64(links)})
+	}
+
+	now := time.Now()
+	dups, err := c.activity.CountDuplicateComments(comment.AuthorID, comment.Content, now.Add(-c.cfg.DuplicateWindow))
+	if err != nil {
+		return res, fmt.Errorf("count duplicates: %w", err)
+	}
+	if dups > 0 {
+		res.Signals = append(res.Signals, Signal{"duplicate_content", weightDuplicate})
+	}
+
+	recent, err := c.activity.CountCommentsSince(comment.AuthorID, now.Add(-c.cfg.VelocityWindow))
+	if err != nil {
+		return res, fmt.Errorf("count recent comments: %w", err)
+	}
+	if recent >= c.cfg.VelocityLimit {
+		res.Signals = append(res.Signals, Signal{"posting_velocity", weightVelocity})
+	}
+
+	user, err := c.users.GetUserByID(comment.AuthorID)
+	if err != nil {
+		return res, fmt.Errorf("get author: %w", err)
+	}
+	if now.Sub(user.CreatedAt) < c.cfg.NewAccountAge {
+		res.Signals = append(res.Signals, Signal{"new_account", weightNewAccount})
+	}
+
+	p, trained, err := c.bayes(Tokenize(comment.Content))
+	if err != nil {
+		return res, fmt.Errorf("bayes: %w", err)
+	}
+	if trained {
+		res.Signals = append(res.Signals, Signal{"bayes", p})
+	}
+
+	notSpam := 1.0
+	for _, s := range res.Signals {
+		notSpam *= 1 - min(s.P, 1)
+	}
+	res.Score = math.Round((1-notSpam)*1000) / 1000
+	return res, nil
+}
+
+// Status maps a score to the moderation status it forces, or "" when the
+// comment may follow the normal moderation rules.
+func (c *Classifier) Status(score float64) string {
+	switch {
+	case !c.cfg.Enabled:
+		return ""
+	case score >= c.cfg.SpamThreshold:
+		return models.CommentStatusSpam
+	case score >= c.cfg.HoldThreshold:
+		return models.CommentStatusPending
+	}
+	return ""
+}
+
+// Learn trains the model with a moderator decision about a comment.
+func (c *Classifier) Learn(comment *models.Comment, spam bool) error {
+	if err := c.store.Train(comment.ID, Tokenize(comment.Content), spam); err != nil {
+		return err
+	}
+	c.log.Debug("spam model trained", slog.Int64("comment_id", comment.ID), slog.Bool("spam", spam))
+	return nil
+}
+
+// bayes returns P(spam | tokens) from a Bernoulli naive Bayes model with
+// Laplace smoothing. trained is false until enough samples of both classes exist.
+func (c *Classifier) bayes(tokens []string) (p float64, trained bool, err error) {
+	spamDocs, hamDocs, err := c.store.TrainingTotals()
+	if err != nil {
+		return 0, false, err
+	}
+	if spamDocs < int64(c.cfg.MinTraining) || hamDocs < int64(c.cfg.MinTraining) {
+		return 0, false, nil
+	}
+
+	counts, err := c.store.TokenCounts(tokens)
+	if err != nil {
+		return 0, false, err
+	}
+
+	contributions := make([]float64, 0, len(counts))
+	for _, t := range counts {
+		pSpam := float64(t.Spam+1) / float64(spamDocs+2)
+		pHam := float64(t.Ham+1) / float64(hamDocs+2)
+		contributions = append(contributions, math.Log(pSpam/pHam))
+	}
+	// long comments would otherwise get overconfident, keep the strongest evidence only
+	sort.Slice(contributions, func(i, j int) bool {
+		return math.Abs(contributions[i]) > math.Abs(contributions[j])
+	})
+	if len(contributions) > bayesTokens {
+		contributions = contributions[:bayesTokens]
+	}
+
+	logOdds := math.Log(float64(spamDocs) / float64(hamDocs))
+	for _, v := range contributions {
+		logOdds += v
+	}
+	return 1 / (1 + math.Exp(-logOdds)), true, nil
+}
+
+// Tokenize splits text into distinct lowercase word tokens. Linked hosts are
+// kept as "link:<host>" tokens so that spammy domains are learned as well.
+func Tokenize(text string) []string {
+	seen := make(map[string]struct{})
+	var tokens []string
+	add := func(t string) {
+		if _, ok := seen[t]; ok || len(tokens) >= maxTokens {
+			return
+		}
+		seen[t] = struct{}{}
+		tokens = append(tokens, t)
+	}
+
+	for _, m := range linkRe.FindAllStringSubmatch(text, -1) {
+		add("link:" + strings.TrimPrefix(strings.ToLower(m[1]), "www."))
+	}
+
+	text = fullURLRe.ReplaceAllString(text, " ")
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		if n := len([]rune(w)); n < minTokenLength || n > maxTokenLength {
+			continue
+		}
+		add(w)
+	}
+	return tokens
+}
@@ -0,0 +1,322 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/repository/redisrepo"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
+)
+
+// PostsTopic is the topic of new, edited and deleted posts.
+const PostsTopic = "posts"
+
+const (
+	listenerBuffer   = 32              // events queued for a slow client before it's dropped
+	resubscribeDelay = 5 * time.Second // wait before subscribing to Redis again after an error
+	publishTimeout   = 5 * time.Second
+	sweepInterval    = time.Minute
+)
+
+var (
+	ErrTooManyClients = errors.New("too many stream clients")
+	ErrClosed         = errors.New("stream hub closed")
+)
+
+// CommentsTopic is the topic of the comments of a post.
+func CommentsTopic(postID int64) string {
+	return fmt.Sprintf("post:%d:comments", postID)
+}
+
+// Event is one message of a stream. Ids grow over all topics, so a client
+// can resume after the last id it saw.
+type Event struct {
+	ID    int64           `json:"id"`
+	Topic string          `json:"topic"`
+	Type  string          `json:"type"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Listener receives the events of one topic. C is closed when the listener
+// is dropped, because the client fell behind or the hub shut down.
+type Listener struct {
+	C     <-chan Event
+	ch    chan Event
+	topic string
+}
+
+type buffered struct {
+	event Event
+	at    time.Time
+}
+
+// replay keeps the recent events of a topic. dropped is the highest id
+// that was evicted, a client that saw less than that missed events.
+type replay struct {
+	events  []buffered
+	dropped int64
+}
+
+// Hub fans events out to the stream clients of this instance. With Redis
+// events are published to every instance and ids are taken from a shared
+// counter, without it the hub works within the process only.
+type Hub struct {
+	log logger.Logger
+	rdb *redisrepo.RedisRepo
+	cfg config.StreamConfig
+
+	seq atomic.Int64 // ids without Redis
+
+	mu        sync.Mutex
+	closed    bool
+	since     int64 // events with greater ids are known to this instance
+	evicted   int64 // highest id evicted from a replay buffer that was removed
+	listeners map[string]map[*Listener]struct{}
+	count     int
+	replays   map[string]*replay
+}
+
+func New(log logger.Logger, rdb *redisrepo.RedisRepo, cfg config.StreamConfig) *Hub {
+	h := &Hub{
+		log:       log,
+		rdb:       rdb,
+		cfg:       cfg,
+		since:     math.MaxInt64, // until subscribed to Redis
+		listeners: make(map[string]map[*Listener]struct{}),
+		replays:   make(map[string]*replay),
+	}
+	if rdb == nil {
+		// ids stay increasing over restarts, clients resuming with an id
+		// of the previous process are told to reload
+		start := time.Now().UnixMicro()
+		h.seq.Store(start)
+		h.since = start
+	}
+	return h
+}
+
+// Publish sends an event to the clients of topic on all instances.
+func (h *Hub) Publish(ctx context.Context, topic, typ string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	ev := Event{Topic: topic, Type: typ, Data: raw}
+
+	if h.rdb == nil {
+		ev.ID = h.seq.Add(1)
+		h.dispatch(ev)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	if ev.ID, err = h.rdb.StreamNextID(ctx); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return h.rdb.StreamPublish(ctx, payload)
+}
+
+// Run receives the events published through Redis and evicts old events
+// from the replay buffers until ctx is done.
+func (h *Hub) Run(ctx context.Context) {
+	log := h.log.With(slog.String("fn", "stream.Hub.Run"))
+	sweep := time.NewTicker(sweepInterval)
+	defer sweep.Stop()
+
+	var events <-chan []byte
+	var retry <-chan time.Time
+	subscribe := func() {
+		var err error
+		if events, err = h.subscribe(ctx); err != nil {
+			log.Error("failed to subscribe to stream events", sl.Error(err))
+			retry = time.After(resubscribeDelay)
+		}
+	}
+	if h.rdb != nil {
+		subscribe()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sweep.C:
+			h.sweep()
+		case <-retry:
+			retry = nil
+			subscribe()
+		case payload, ok := <-events:
+			if !ok {
+				events = nil
+				if ctx.Err() == nil {
+					log.Warn("stream subscription closed, subscribing again")
+					retry = time.After(resubscribeDelay)
+				}
+				continue
+			}
+			var ev Event
+			if err := json.Unmarshal(payload, &ev); err != nil {
+				log.Error("failed to decode stream event", sl.Error(err))
+				continue
+			}
+			h.dispatch(ev)
+		}
+	}
+}
+
+// subscribe starts receiving from Redis. Events published while this
+// instance wasn't subscribed are unknown, clients resuming from before
+// then are told to reload.
+func (h *Hub) subscribe(ctx context.Context) (<-chan []byte, error) {
+	events, err := h.rdb.StreamSubscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	last, err := h.rdb.StreamLastID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	h.since = last
+	h.mu.Unlock()
+	return events, nil
+}
+
+// Listen registers a listener for topic. It returns the buffered events
+// after lastID and whether events after lastID may be missing, then the
+// client should reload what it shows. lastID 0 means a fresh start.
+func (h *Hub) Listen(topic string, lastID int64) (*Listener, []Event, bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, nil, false, ErrClosed
+	}
+	if h.count >= h.cfg.MaxClients {
+		return nil, nil, false, ErrTooManyClients
+	}
+
+	var missed []Event
+	reset := false
+	if lastID > 0 {
+		r := h.replays[topic]
+		reset = lastID < h.since
+		if r == nil {
+			// the topic may have had events that were evicted along with
+			// its buffer
+			reset = reset || lastID < h.evicted
+		} else {
+			reset = reset || lastID < r.dropped
+			for _, b := range r.events {
+				if b.event.ID > lastID {
+					missed = append(missed, b.event)
+				}
+			}
+		}
+	}
+
+	ch := make(chan Event, listenerBuffer)
+	l := &Listener{C: ch, ch: ch, topic: topic}
+	if h.listeners[topic] == nil {
+		h.listeners[topic] = make(map[*Listener]struct{})
+	}
+	h.listeners[topic][l] = struct{}{}
+	h.count++
+	return l, missed, reset, nil
+}
+
+// Unlisten removes a listener, it's safe to call after it was dropped.
+func (h *Hub) Unlisten(l *Listener) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drop(l)
+}
+
+// Close drops all listeners so their streams end, it's called when the
+// server shuts down.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for _, listeners := range h.listeners {
+		for l := range listeners {
+			h.drop(l)
+		}
+	}
+}
+
+func (h *Hub) dispatch(ev Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r := h.replays[ev.Topic]
+	if r == nil {
+		r = &replay{}
+		h.replays[ev.Topic] = r
+	}
+	r.events = append(r.events, buffered{event: ev, at: time.Now()})
+	if over := len(r.events) - h.cfg.Buffer; over > 0 {
+		r.dropped = max(r.dropped, r.events[over-1].event.ID)
+		r.events = r.events[over:]
+	}
+
+	for l := range h.listeners[ev.Topic] {
+		select {
+		case l.ch <- ev:
+		default:
+			// too slow, the client reconnects and resumes from the buffer
+			h.drop(l)
+		}
+	}
+}
+
+// drop must be called with mu held.
+func (h *Hub) drop(l *Listener) {
+	listeners, ok := h.listeners[l.topic]
+	if !ok {
+		return
+	}
+	if _, ok := listeners[l]; !ok {
+		return
+	}
+	delete(listeners, l)
+	if len(listeners) == 0 {
+		delete(h.listeners, l.topic)
+	}
+	h.count--
+	close(l.ch)
+}
+
+// sweep evicts events older than the replay window.
+func (h *Hub) sweep() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cutoff := time.Now().Add(-h.cfg.ReplayWindow)
+	for topic, r := range h.replays {
+		i := 0
+		for i < len(r.events) && r.events[i].at.Before(cutoff) {
+			r.dropped = max(r.dropped, r.events[i].event.ID)
+			i++
+		}
+		r.events = r.events[i:]
+		if len(r.events) == 0 {
+			delete(h.replays, topic)
+			h.evicted = max(h.evicted, r.dropped)
+		}
+	}
+}
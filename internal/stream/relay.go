@@ -0,0 +1,152 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"blog/internal/events"
+	"blog/internal/models"
+	"blog/internal/repository"
+)
+
+// Stream event types.
+const (
+	PostCreated    = "post.created"
+	PostUpdated    = "post.updated"
+	PostDeleted    = "post.deleted"
+	CommentCreated = "comment.created"
+	CommentUpdated = "comment.updated"
+)
+
+type publisher interface {
+	Publish(ctx context.Context, topic, typ string, data any) error
+}
+
+type postStore interface {
+	GetPostsByIDs(ids []int64) (map[int64]*models.Post, error)
+}
+
+type userStore interface {
+	GetUserByID(id int64) (*models.User, error)
+}
+
+type sanctionChecker interface {
+	ActiveSanction(userID int64, kinds ...string) (*models.Sanction, error)
+}
+
+type postView struct {
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	AuthorID  int64     `json:"author_id"`
+	Username  string    `json:"username,omitempty"`
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type commentView struct {
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	ParentID  *int64    `json:"parent_id,omitempty"`
+	Depth     int       `json:"depth"`
+	Content   string    `json:"content"`
+	AuthorID  int64     `json:"author_id"`
+	Username  string    `json:"username,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Relay publishes what happens in the write paths to the streams: posts to
+// PostsTopic, comments to the CommentsTopic of their post. Edits of hidden
+// posts and comments of shadow-banned users are left out, like in the thread.
+type Relay struct {
+	hub       publisher
+	posts     postStore
+	users     userStore
+	sanctions sanctionChecker
+}
+
+func NewRelay(hub publisher, posts postStore, users userStore, sanctions sanctionChecker) *Relay {
+	return &Relay{
+		hub:       hub,
+		posts:     posts,
+		users:     users,
+		sanctions: sanctions,
+	}
+}
+
+func (r *Relay) Subscribe(bus *events.Bus) {
+	events.On(bus, func(ctx context.Context, e events.PostPublished) error {
+		return r.post(ctx, PostCreated, e.Post)
+	})
+	events.On(bus, func(ctx context.Context, e events.PostUpdated) error {
+		posts, err := r.posts.GetPostsByIDs([]int64{e.Post.ID})
+		if err != nil {
+			return err
+		}
+		// hidden posts can be edited by their author, the edit stays private
+		if post, ok := posts[e.Post.ID]; ok {
+			return r.post(ctx, PostUpdated, post)
+		}
+		return nil
+	})
+	events.On(bus, func(ctx context.Context, e events.PostVisibilityChanged) error {
+		posts, err := r.posts.GetPostsByIDs([]int64{e.PostID})
+		if err != nil {
+			return err
+		}
+		if post, ok := posts[e.PostID]; ok {
+			return r.post(ctx, PostCreated, post)
+		}
+		return r.hub.Publish(ctx, PostsTopic, PostDeleted, map[string]int64{"post_id": e.PostID})
+	})
+	events.On(bus, func(ctx context.Context, e events.CommentPublished) error {
+		return r.comment(ctx, CommentCreated, e.Comment)
+	})
+	events.On(bus, func(ctx context.Context, e events.CommentUpdated) error {
+		return r.comment(ctx, CommentUpdated, e.Comment)
+	})
+}
+
+func (r *Relay) post(ctx context.Context, typ string, post *models.Post) error {
+	view := postView{
+		ID:        post.ID,
+		Title:     post.Title,
+		Content:   post.Content,
+		AuthorID:  post.AuthorID,
+		Version:   post.Version,
+		CreatedAt: post.CreatedAt,
+		UpdatedAt: post.UpdatedAt,
+	}
+	if user, err := r.users.GetUserByID(post.AuthorID); err == nil {
+		view.Username = user.Username
+	}
+	return r.hub.Publish(ctx, PostsTopic, typ, map[string]postView{"post": view})
+}
+
+func (r *Relay) comment(ctx context.Context, typ string, c *models.Comment) error {
+	_, err := r.sanctions.ActiveSanction(c.AuthorID, models.SanctionShadowBan)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, repository.ErrNotExists) {
+		return err
+	}
+
+	view := commentView{
+		ID:        c.ID,
+		PostID:    c.PostID,
+		ParentID:  c.ParentID,
+		Depth:     c.Depth,
+		Content:   c.Content,
+		AuthorID:  c.AuthorID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+	if user, err := r.users.GetUserByID(c.AuthorID); err == nil {
+		view.Username = user.Username
+	}
+	return r.hub.Publish(ctx, CommentsTopic(c.PostID), typ, map[string]commentView{"comment": view})
+}
@@ -0,0 +1,79 @@
+package stream
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"blog/internal/events"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+)
+
+type published struct {
+	topic, typ string
+	data       any
+}
+
+// hub keeps what is published.
+type hub struct {
+	mu     sync.Mutex
+	events []published
+}
+
+func (h *hub) Publish(ctx context.Context, topic, typ string, data any) error {
+	h.mu.Lock()
+	h.events = append(h.events, published{topic, typ, data})
+	h.mu.Unlock()
+	return nil
+}
+
+// posts are the visible posts.
+type posts map[int64]*models.Post
+
+func (p posts) GetPostsByIDs(ids []int64) (map[int64]*models.Post, error) {
+	out := make(map[int64]*models.Post)
+	for _, id := range ids {
+		if post, ok := p[id]; ok {
+			out[id] = post
+		}
+	}
+	return out, nil
+}
+
+type noUsers struct{}
+
+func (noUsers) GetUserByID(id int64) (*models.User, error) {
+	return nil, repository.ErrNotExists
+}
+
+type noSanctions struct{}
+
+func (noSanctions) ActiveSanction(userID int64, kinds ...string) (*models.Sanction, error) {
+	return nil, repository.ErrNotExists
+}
+
+func TestHiddenPostEditsAreLeftOut(t *testing.T) {
+	h := &hub{}
+	bus := events.NewBus(logger.NewLogger(nil))
+	NewRelay(h, posts{1: {ID: 1, Title: "visible"}}, noUsers{}, noSanctions{}).Subscribe(bus)
+
+	ctx := context.Background()
+	bus.Publish(ctx, events.PostUpdated{Post: &models.Post{ID: 1, Title: "visible"}})
+	// post 2 is hidden by moderation, its author edits it anyway
+	bus.Publish(ctx, events.PostUpdated{Post: &models.Post{ID: 2, Title: "hidden"}})
+	bus.Wait()
+
+	var got []int64
+	for _, e := range h.events {
+		if e.topic != PostsTopic || e.typ != PostUpdated {
+			t.Fatalf("published %s on %s, want %s on %s", e.typ, e.topic, PostUpdated, PostsTopic)
+		}
+		got = append(got, e.data.(map[string]postView)["post"].ID)
+	}
+	if want := []int64{1}; !slices.Equal(got, want) {
+		t.Fatalf("published edits of posts %v, want %v", got, want)
+	}
+}
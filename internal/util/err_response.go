@@ -1,13 +1,42 @@
 package util
 
 import (
+	"log/slog"
 	"net/http"
 
-	"blog/internal/api/jsonutil"
-	"blog/internal/api/response"
+	"blog/internal/api/problem"
+	requestid "blog/internal/middlewares/request_id"
+	"blog/internal/util/logger"
+	"blog/internal/util/logger/sl"
 )
 
-func ErrorResponse(w http.ResponseWriter, status int, msg string) {
-	jsonutil.WriteJSON(w, status,
-		response.Error(status, msg))
+// ErrorResponse answers with an application/problem+json body, detail
+// explains the status to the client.
+func ErrorResponse(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	problem.Write(w, r, problem.New(status, detail))
+}
+
+// ValidationError answers 400 for a request body that couldn't be decoded
+// or failed validation, listing the fields and the tags that failed.
+func ValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	errs, ok := problem.FieldErrors(err)
+	if !ok {
+		ErrorResponse(w, r, http.StatusBadRequest, "The request body is not valid JSON.")
+		return
+	}
+	problem.Write(w, r, problem.Validation(errs))
+}
+
+// RepositoryError answers with the status err maps to, detail says which
+// resource it's about. Errors that aren't the client's fault are logged and
+// answered with 500 without details.
+func RepositoryError(w http.ResponseWriter, r *http.Request, log logger.Logger, err error, detail string) {
+	status := problem.Status(err)
+	if status == http.StatusInternalServerError {
+		log.Error("repository error", sl.Error(err),
+			slog.String("path", r.URL.Path),
+			slog.String("request_id", requestid.Get(r.Context())))
+		detail = ""
+	}
+	ErrorResponse(w, r, status, detail)
 }
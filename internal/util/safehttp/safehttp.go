@@ -0,0 +1,97 @@
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned for URLs that are not allowed to be
+// requested: other schemes than https, and hosts in private networks.
+var ErrForbiddenAddress = errors.New("address not allowed")
+
+// maxRedirects is how many redirects a request may follow.
+const maxRedirects = 3
+
+// NewClient returns a client for requests to URLs supplied by others, such
+// as remote actors or webmention sources. Unless allowInsecure is set only
+// https URLs on public addresses are requested, so they can't be used to
+// probe the local network. Addresses are checked after DNS resolution.
+func NewClient(timeout time.Duration, allowInsecure bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowInsecure {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !publicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			return CheckURL(req.URL, allowInsecure)
+		},
+	}
+}
+
+// CheckURL rejects URLs that are not absolute https URLs, or http ones
+// with allowInsecure.
+func CheckURL(u *url.URL, allowInsecure bool) error {
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && allowInsecure:
+	default:
+		return fmt.Errorf("%w: scheme %q", ErrForbiddenAddress, u.Scheme)
+	}
+	if u.Host == "" || u.User != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, u.Redacted())
+	}
+	return nil
+}
+
+// reservedNets are not public but not covered by the net.IP checks either:
+// carrier-grade NAT (RFC 6598), often the internal network of cloud hosts,
+// and "this network" (RFC 1122), which Linux connects to the local host.
+var reservedNets = []*net.IPNet{
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("0.0.0.0/8"),
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, n := range reservedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
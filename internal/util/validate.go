@@ -1,7 +1,9 @@
 package util
 
 import (
+	"reflect"
 	"regexp"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -9,6 +11,14 @@ import (
 // TODO: add checking errors
 func NewCustomValidator() *validator.Validate {
 	validate := validator.New()
+	// errors name fields like the clients see them
+	validate.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
 	_ = validate.RegisterValidation("username", func(fl validator.FieldLevel) bool {
 		value := fl.Field().String()
 		if len(value) < 5 || len(value) > 30 {
@@ -28,5 +38,12 @@ func NewCustomValidator() *validator.Validate {
 		return hasNumber && hasUpper && hasLower
 	})
 
+	_ = validate.RegisterValidation("tag", func(fl validator.FieldLevel) bool {
+		return tagRe.MatchString(fl.Field().String())
+	})
+
 	return validate
 }
+
+// tagRe matches post tags: words of letters and digits joined by hyphens.
+var tagRe = regexp.MustCompile(`^[a-zA-Z0-9]+(-[a-zA-Z0-9]+)*$`)
@@ -0,0 +1,280 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"blog/internal/config"
+	"blog/internal/events"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+	"blog/internal/util/safehttp"
+)
+
+// Request headers of a delivery.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// ErrRejected is returned for deliveries that can't succeed by retrying,
+// such as a 4xx answer or a webhook that was disabled.
+var ErrRejected = errors.New("delivery rejected")
+
+type store interface {
+	GetWebhook(id int64) (*models.Webhook, error)
+	WebhooksFor(event string) ([]*models.Webhook, error)
+	EnqueueWebhookDeliveries(event string, payload []byte, webhookIDs []int64) error
+}
+
+type postStore interface {
+	GetPostsByIDs(ids []int64) (map[int64]*models.Post, error)
+}
+
+type userStore interface {
+	GetUserByID(id int64) (*models.User, error)
+}
+
+type sanctionChecker interface {
+	ActiveSanction(userID int64, kinds ...string) (*models.Sanction, error)
+}
+
+// Payload is the body of every delivery.
+type Payload struct {
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+type postData struct {
+	Post *models.Post `json:"post"`
+}
+
+type deletedPostData struct {
+	PostID int64 `json:"post_id"`
+}
+
+type commentData struct {
+	Comment *models.Comment `json:"comment"`
+}
+
+type userData struct {
+	User struct {
+		ID        int64     `json:"id"`
+		Username  string    `json:"username"`
+		Role      string    `json:"role"`
+		CreatedAt time.Time `json:"created_at"`
+	} `json:"user"`
+}
+
+// Service turns events into signed webhook payloads. Payloads are queued
+// per subscribed webhook when the event happens and posted by Send.
+type Service struct {
+	log       logger.Logger
+	store     store
+	posts     postStore
+	users     userStore
+	sanctions sanctionChecker
+	cfg       config.WebhooksConfig
+
+	userAgent string
+	client    *http.Client
+
+	queued chan struct{}
+}
+
+func New(log logger.Logger, store store, posts postStore, users userStore, sanctions sanctionChecker, baseURL string,
+	cfg config.WebhooksConfig) *Service {
+	return &Service{
+		log:       log,
+		store:     store,
+		posts:     posts,
+		users:     users,
+		sanctions: sanctions,
+		cfg:       cfg,
+		userAgent: "blog-webhooks (+" + baseURL + ")",
+		client:    safehttp.NewClient(cfg.Timeout, cfg.AllowPrivate),
+		queued:    make(chan struct{}, 1),
+	}
+}
+
+// Queued signals after deliveries were added to the queue.
+func (s *Service) Queued() <-chan struct{} {
+	return s.queued
+}
+
+// Subscribe maps events of the write paths to webhook events. A post that
+// is deleted or hidden is post.deleted, one that comes back post.created.
+// Edits of hidden posts and comments of shadow-banned users are left out.
+func (s *Service) Subscribe(bus *events.Bus) {
+	events.On(bus, func(ctx context.Context, e events.PostPublished) error {
+		return s.emit(models.WebhookPostCreated, postData{Post: e.Post})
+	})
+	events.On(bus, func(ctx context.Context, e events.PostUpdated) error {
+		posts, err := s.posts.GetPostsByIDs([]int64{e.Post.ID})
+		if err != nil {
+			return err
+		}
+		// hidden posts can be edited by their author, the edit stays private
+		if post, ok := posts[e.Post.ID]; ok {
+			return s.emit(models.WebhookPostUpdated, postData{Post: post})
+		}
+		return nil
+	})
+	events.On(bus, func(ctx context.Context, e events.PostVisibilityChanged) error {
+		posts, err := s.posts.GetPostsByIDs([]int64{e.PostID})
+		if err != nil {
+			return err
+		}
+		if post, ok := posts[e.PostID]; ok {
+			return s.emit(models.WebhookPostCreated, postData{Post: post})
+		}
+		return s.emit(models.WebhookPostDeleted, deletedPostData{PostID: e.PostID})
+	})
+	events.On(bus, func(ctx context.Context, e events.CommentPublished) error {
+		_, err := s.sanctions.ActiveSanction(e.Comment.AuthorID, models.SanctionShadowBan)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, repository.ErrNotExists) {
+			return err
+		}
+		return s.emit(models.WebhookCommentCreated, commentData{Comment: e.Comment})
+	})
+	events.On(bus, func(ctx context.Context, e events.UserCreated) error {
+		user, err := s.users.GetUserByID(e.UserID)
+		if err != nil {
+			return err
+		}
+		var data userData
+		data.User.ID = user.ID
+		data.User.Username = user.Username
+		data.User.Role = user.Role
+		data.User.CreatedAt = user.CreatedAt
+		return s.emit(models.WebhookUserCreated, data)
+	})
+}
+
+func (s *Service) emit(event string, data any) error {
+	hooks, err := s.store.WebhooksFor(event)
+	if err != nil || len(hooks) == 0 {
+		return err
+	}
+	payload, err := json.Marshal(Payload{Event: event, OccurredAt: time.Now().UTC().Truncate(time.Second), Data: data})
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0, len(hooks))
+	for _, h := range hooks {
+		ids = append(ids, h.ID)
+	}
+	if err := s.store.EnqueueWebhookDeliveries(event, payload, ids); err != nil {
+		return err
+	}
+	s.log.Debug("webhook deliveries queued", slog.String("fn", "webhook.emit"),
+		slog.String("event", event), slog.Int("webhooks", len(ids)))
+	s.Wake()
+	return nil
+}
+
+// Wake makes the delivery job look at the queue now.
+func (s *Service) Wake() {
+	select {
+	case s.queued <- struct{}{}:
+	default:
+	}
+}
+
+// Send posts a delivery to its webhook and returns the response status, 0
+// if there was no response. Refusals that retrying can't fix are wrapped
+// in ErrRejected.
+func (s *Service) Send(ctx context.Context, d *models.WebhookDelivery) (int, error) {
+	hook, err := s.store.GetWebhook(d.WebhookID)
+	if errors.Is(err, repository.ErrNotExists) {
+		return 0, fmt.Errorf("%w: webhook deleted", ErrRejected)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !hook.Active {
+		return 0, fmt.Errorf("%w: webhook disabled", ErrRejected)
+	}
+
+	u, err := url.Parse(hook.URL)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	if err := safehttp.CheckURL(u, s.cfg.AllowPrivate); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", s.userAgent)
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		if errors.Is(err, safehttp.ErrForbiddenAddress) {
+			return 0, fmt.Errorf("%w: %v", ErrRejected, err)
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	switch status := resp.StatusCode; {
+	case status >= 200 && status < 300:
+		return status, nil
+	case status == http.StatusTooManyRequests || status == http.StatusRequestTimeout:
+		return status, fmt.Errorf("status %d", status)
+	case status >= 400 && status < 500:
+		return status, fmt.Errorf("%w: status %d", ErrRejected, status)
+	default:
+		return status, fmt.Errorf("status %d", status)
+	}
+}
+
+// Sign returns the X-Webhook-Signature of a payload: "sha256=" and the hex
+// HMAC-SHA256 of timestamp, a dot and body, keyed with the webhook secret.
+// Receivers should compare it in constant time and reject old timestamps.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random secret for a webhook registered without one.
+func NewSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+
+	"blog/internal/config"
+	"blog/internal/events"
+	"blog/internal/models"
+	"blog/internal/repository"
+	"blog/internal/util/logger"
+)
+
+// queue subscribes one webhook to every event and keeps what is queued.
+type queue struct {
+	mu       sync.Mutex
+	payloads []Payload
+}
+
+func (q *queue) GetWebhook(id int64) (*models.Webhook, error) {
+	return &models.Webhook{ID: id, Active: true}, nil
+}
+
+func (q *queue) WebhooksFor(event string) ([]*models.Webhook, error) {
+	return []*models.Webhook{{ID: 1, Active: true}}, nil
+}
+
+func (q *queue) EnqueueWebhookDeliveries(event string, payload []byte, webhookIDs []int64) error {
+	var p Payload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+	q.mu.Lock()
+	q.payloads = append(q.payloads, p)
+	q.mu.Unlock()
+	return nil
+}
+
+func (q *queue) events() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out []string
+	for _, p := range q.payloads {
+		out = append(out, p.Event)
+	}
+	return out
+}
+
+// posts are the visible posts.
+type posts map[int64]*models.Post
+
+func (p posts) GetPostsByIDs(ids []int64) (map[int64]*models.Post, error) {
+	out := make(map[int64]*models.Post)
+	for _, id := range ids {
+		if post, ok := p[id]; ok {
+			out[id] = post
+		}
+	}
+	return out, nil
+}
+
+type noUsers struct{}
+
+func (noUsers) GetUserByID(id int64) (*models.User, error) {
+	return nil, repository.ErrNotExists
+}
+
+// shadowBanned are the ids of the shadow-banned users.
+type shadowBanned []int64
+
+func (s shadowBanned) ActiveSanction(userID int64, kinds ...string) (*models.Sanction, error) {
+	if slices.Contains(s, userID) && slices.Contains(kinds, models.SanctionShadowBan) {
+		return &models.Sanction{UserID: userID, Kind: models.SanctionShadowBan}, nil
+	}
+	return nil, repository.ErrNotExists
+}
+
+func newTestService(t *testing.T, visible posts, banned shadowBanned) (*events.Bus, *queue) {
+	t.Helper()
+	log := logger.NewLogger(nil)
+	q := &queue{}
+	bus := events.NewBus(log)
+	New(log, q, visible, noUsers{}, banned, "https://blog.example", config.WebhooksConfig{}).Subscribe(bus)
+	return bus, q
+}
+
+func TestShadowBannedCommentsAreLeftOut(t *testing.T) {
+	bus, q := newTestService(t, posts{}, shadowBanned{2})
+
+	ctx := context.Background()
+	bus.Publish(ctx, events.CommentPublished{Comment: &models.Comment{ID: 1, PostID: 1, AuthorID: 1}})
+	bus.Publish(ctx, events.CommentPublished{Comment: &models.Comment{ID: 2, PostID: 1, AuthorID: 2}})
+	bus.Wait()
+
+	if got, want := q.events(), []string{models.WebhookCommentCreated}; !slices.Equal(got, want) {
+		t.Fatalf("queued %v, want %v", got, want)
+	}
+	if data := q.payloads[0].Data.(map[string]any)["comment"].(map[string]any); data["id"] != float64(1) {
+		t.Fatalf("queued comment %v, want comment 1", data["id"])
+	}
+}
+
+func TestHiddenPostEditsAreLeftOut(t *testing.T) {
+	visible := &models.Post{ID: 1, Title: "visible"}
+	bus, q := newTestService(t, posts{1: visible}, nil)
+
+	ctx := context.Background()
+	bus.Publish(ctx, events.PostUpdated{Post: &models.Post{ID: 1, Title: "visible"}})
+	// post 2 is hidden by moderation, its author edits it anyway
+	bus.Publish(ctx, events.PostUpdated{Post: &models.Post{ID: 2, Title: "hidden"}})
+	bus.Wait()
+
+	if got, want := q.events(), []string{models.WebhookPostUpdated}; !slices.Equal(got, want) {
+		t.Fatalf("queued %v, want %v", got, want)
+	}
+	if data := q.payloads[0].Data.(map[string]any)["post"].(map[string]any); data["title"] != "visible" {
+		t.Fatalf("queued post %v, want the visible one", data["title"])
+	}
+}
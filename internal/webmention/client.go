@@ -0,0 +1,322 @@
+package webmention
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"blog/internal/models"
+	"blog/internal/util/safehttp"
+)
+
+const (
+	maxPageSize  = 1 << 20
+	maxTitleSize = 200
+)
+
+var linkHeaderRe = regexp.MustCompile(`<([^>]*)>((?:\s*;\s*[^;,]+)*)`)
+
+// send notifies target that source links to it. Targets without an
+// endpoint don't support webmentions, that is not an error.
+func (s *Service) send(ctx context.Context, source, target string) error {
+	endpoint, err := s.discover(ctx, target)
+	if err != nil || endpoint == nil {
+		return err
+	}
+
+	form := url.Values{"source": {source}, "target": {target}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", s.userAgent)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxPageSize))
+	return statusError(resp.StatusCode)
+}
+
+// discover finds the webmention endpoint of target, first in the Link
+// header, then in the first <link> or <a> element with rel="webmention".
+// It returns nil when there is none.
+func (s *Service) discover(ctx context.Context, target string) (*url.URL, error) {
+	resp, err := s.get(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := statusError(resp.StatusCode); err != nil {
+		return nil, err
+	}
+
+	var href string
+	found := false
+	for _, header := range resp.Header.Values("Link") {
+		if href, found = linkHeaderEndpoint(header); found {
+			break
+		}
+	}
+	if !found && isHTML(resp) {
+		href, found = htmlEndpoint(io.LimitReader(resp.Body, maxPageSize))
+	}
+	if !found {
+		s.log.Debug("no webmention endpoint", slog.String("fn", "webmention.discover"), slog.String("target", target))
+		return nil, nil
+	}
+
+	// relative to the page after redirects, an empty href is the page itself
+	endpoint, err := resp.Request.URL.Parse(href)
+	if err != nil {
+		return nil, fmt.Errorf("%w: endpoint %q: %v", ErrRejected, href, err)
+	}
+	if err := safehttp.CheckURL(endpoint, s.cfg.AllowInsecure); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	return endpoint, nil
+}
+
+// verify checks that source links to target, one of our posts, and saves or
+// removes the webmention accordingly. A source that is gone removes it too.
+func (s *Service) verify(ctx context.Context, source, target string) error {
+	postID, ok := s.postID(target)
+	if !ok {
+		return fmt.Errorf("%w: target %q is not a post", ErrRejected, target)
+	}
+	posts, err := s.posts.GetPostsByIDs([]int64{postID})
+	if err != nil {
+		return err
+	}
+	if _, ok := posts[postID]; !ok {
+		return nil
+	}
+
+	resp, err := s.get(ctx, source)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return s.remove(postID, source)
+	}
+	if err := statusError(resp.StatusCode); err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return err
+	}
+
+	var title string
+	var linked bool
+	switch {
+	case isHTML(resp):
+		title, linked = htmlLinksTo(body, resp.Request.URL, target)
+	case mediaType(resp) == "application/json":
+		linked = jsonLinksTo(body, target)
+	default:
+		linked = bytes.Contains(body, []byte(target))
+	}
+	if !linked {
+		return s.remove(postID, source)
+	}
+
+	m := &models.Webmention{PostID: postID, Source: source, Title: title}
+	if err := s.store.SaveWebmention(m); err != nil {
+		return err
+	}
+	s.log.Info("webmention verified", slog.String("fn", "webmention.verify"),
+		slog.Int64("post_id", postID), slog.String("source", source))
+	return nil
+}
+
+func (s *Service) remove(postID int64, source string) error {
+	deleted, err := s.store.DeleteWebmention(postID, source)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		s.log.Info("webmention removed", slog.String("fn", "webmention.verify"),
+			slog.Int64("post_id", postID), slog.String("source", source))
+	}
+	return nil
+}
+
+func (s *Service) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	if err := safehttp.CheckURL(u, s.cfg.AllowInsecure); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html, application/json;q=0.9, */*;q=0.5")
+	req.Header.Set("User-Agent", s.userAgent)
+	return s.do(req)
+}
+
+func (s *Service) do(req *http.Request) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil && errors.Is(err, safehttp.ErrForbiddenAddress) {
+		return nil, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	return resp, err
+}
+
+// statusError maps a response status to nil, a retryable error, or one
+// wrapping ErrRejected for client errors other than timeouts and rate limits.
+func statusError(status int) error {
+	switch {
+	case status >= 200 && status < 300:
+		return nil
+	case status == http.StatusTooManyRequests || status == http.StatusRequestTimeout:
+		return fmt.Errorf("status %d", status)
+	case status >= 400 && status < 500:
+		return fmt.Errorf("%w: status %d", ErrRejected, status)
+	default:
+		return fmt.Errorf("status %d", status)
+	}
+}
+
+func mediaType(resp *http.Response) string {
+	mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mt
+}
+
+func isHTML(resp *http.Response) bool {
+	mt := mediaType(resp)
+	return mt == "text/html" || mt == "application/xhtml+xml"
+}
+
+// linkHeaderEndpoint returns the target of the first link with
+// rel="webmention" in a Link header value.
+func linkHeaderEndpoint(header string) (string, bool) {
+	for _, m := range linkHeaderRe.FindAllStringSubmatch(header, -1) {
+		for _, param := range strings.Split(m[2], ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(name), "rel") && hasRel(strings.Trim(strings.TrimSpace(value), `"`)) {
+				return m[1], true
+			}
+		}
+	}
+	return "", false
+}
+
+func htmlEndpoint(r io.Reader) (string, bool) {
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return "", false
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			if t.DataAtom != atom.Link && t.DataAtom != atom.A {
+				continue
+			}
+			href, hasHref := attr(t, "href")
+			if rel, _ := attr(t, "rel"); hasHref && hasRel(rel) {
+				return href, true
+			}
+		}
+	}
+}
+
+// htmlLinksTo reports whether a page links to target, through the href of
+// a link or the src of an embed, and returns the title of the page.
+func htmlLinksTo(body []byte, page *url.URL, target string) (string, bool) {
+	var title strings.Builder
+	inTitle, linked := false, false
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(title.String()), " "), linked
+		case html.TextToken:
+			if inTitle && title.Len() < maxTitleSize {
+				title.Write(z.Text())
+			}
+		case html.EndTagToken:
+			if z.Token().DataAtom == atom.Title {
+				inTitle = false
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			if t.DataAtom == atom.Title && title.Len() == 0 {
+				inTitle = true
+				continue
+			}
+			for _, key := range []string{"href", "src"} {
+				v, ok := attr(t, key)
+				if !ok {
+					continue
+				}
+				if u, err := page.Parse(strings.TrimSpace(v)); err == nil && u.String() == target {
+					linked = true
+				}
+			}
+		}
+	}
+}
+
+// jsonLinksTo reports whether any string in a JSON document contains target.
+func jsonLinksTo(body []byte, target string) bool {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return bytes.Contains(body, []byte(target))
+	}
+	var walk func(v any) bool
+	walk = func(v any) bool {
+		switch v := v.(type) {
+		case string:
+			return strings.Contains(v, target)
+		case []any:
+			return slices.ContainsFunc(v, walk)
+		case map[string]any:
+			for _, item := range v {
+				if walk(item) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return walk(doc)
+}
+
+func hasRel(rel string) bool {
+	return slices.ContainsFunc(strings.Fields(rel), func(r string) bool {
+		return strings.EqualFold(r, "webmention")
+	})
+}
+
+func attr(t html.Token, key string) (string, bool) {
+	for _, a := range t.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,225 @@
+package webmention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"blog/internal/config"
+	"blog/internal/events"
+	"blog/internal/models"
+	"blog/internal/util/logger"
+	"blog/internal/util/safehttp"
+)
+
+var (
+	// ErrInvalid is returned for received webmentions that can't be
+	// accepted, such as a target that is not one of our posts.
+	ErrInvalid = errors.New("invalid webmention")
+	// ErrRejected is returned for jobs that failed for good, they are not
+	// retried.
+	ErrRejected = errors.New("webmention rejected")
+)
+
+var (
+	linkRe    = regexp.MustCompile(`https?://[^\s<>"'()\[\]{}]+`)
+	postPaths = []*regexp.Regexp{
+		regexp.MustCompile(`^/api/post/(\d+)$`),
+		regexp.MustCompile(`^/ap/posts/(\d+)$`),
+	}
+)
+
+type store interface {
+	EnqueueWebmentionJobs(kind, source string, targets []string) error
+	SaveWebmention(m *models.Webmention) error
+	DeleteWebmention(postID int64, source string) (bool, error)
+	ReplaceWebmentionTargets(postID int64, targets []string) ([]string, error)
+	WebmentionTargets(postID int64) ([]string, error)
+}
+
+type postStore interface {
+	GetPostsByIDs(ids []int64) (map[int64]*models.Post, error)
+}
+
+// Service sends webmentions to the pages linked from our posts and
+// verifies the ones other sites send us. Both only queue jobs, the network
+// requests are made by Process when the job runs them.
+type Service struct {
+	log   logger.Logger
+	store store
+	posts postStore
+	cfg   config.WebmentionConfig
+
+	baseURL   string
+	base      *url.URL
+	userAgent string
+	client    *http.Client
+
+	queued chan struct{}
+}
+
+func New(log logger.Logger, store store, posts postStore, baseURL string, cfg config.WebmentionConfig) (*Service, error) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid base url %q", baseURL)
+	}
+	return &Service{
+		log:       log,
+		store:     store,
+		posts:     posts,
+		cfg:       cfg,
+		baseURL:   baseURL,
+		base:      u,
+		userAgent: "blog (+" + baseURL + ")",
+		client:    safehttp.NewClient(cfg.Timeout, cfg.AllowInsecure),
+		queued:    make(chan struct{}, 1),
+	}, nil
+}
+
+// Queued signals after jobs were added to the queue.
+func (s *Service) Queued() <-chan struct{} {
+	return s.queued
+}
+
+// EndpointURL is where other sites send webmentions to.
+func (s *Service) EndpointURL() string {
+	return s.baseURL + "/webmention"
+}
+
+// PostURL is the source of the webmentions sent for a post.
+func (s *Service) PostURL(postID int64) string {
+	return fmt.Sprintf("%s/api/post/%d", s.baseURL, postID)
+}
+
+// Subscribe sends webmentions when a post is published or edited, and
+// again to the same pages when it's deleted or restored so they can update
+// or drop their copy.
+func (s *Service) Subscribe(bus *events.Bus) {
+	events.On(bus, func(ctx context.Context, e events.PostPublished) error {
+		return s.sendLinks(e.Post)
+	})
+	events.On(bus, func(ctx context.Context, e events.PostUpdated) error {
+		return s.sendLinks(e.Post)
+	})
+	events.On(bus, func(ctx context.Context, e events.PostVisibilityChanged) error {
+		targets, err := s.store.WebmentionTargets(e.PostID)
+		if err != nil {
+			return err
+		}
+		return s.enqueue(models.WebmentionSend, s.PostURL(e.PostID), targets)
+	})
+}
+
+// sendLinks queues webmentions for the external links of a post. Links
+// removed by an edit are notified as well, the receiver then finds them gone.
+func (s *Service) sendLinks(post *models.Post) error {
+	links := s.Links(post.Content)
+	previous, err := s.store.ReplaceWebmentionTargets(post.ID, links)
+	if err != nil {
+		return err
+	}
+	for _, target := range previous {
+		if !slices.Contains(links, target) {
+			links = append(links, target)
+		}
+	}
+	return s.enqueue(models.WebmentionSend, s.PostURL(post.ID), links)
+}
+
+// Links returns the distinct http(s) links in content that point to other
+// sites, at most max_links of them.
+func (s *Service) Links(content string) []string {
+	var links []string
+	for _, match := range linkRe.FindAllString(content, -1) {
+		link := strings.TrimRight(match, ".,;:!?")
+		u, err := url.Parse(link)
+		if err != nil || u.Host == "" || strings.EqualFold(u.Host, s.base.Host) {
+			continue
+		}
+		if slices.Contains(links, link) {
+			continue
+		}
+		links = append(links, link)
+		if len(links) == s.cfg.MaxLinks {
+			break
+		}
+	}
+	return links
+}
+
+// Receive queues the verification of a webmention sent to us. The target
+// must be the URL of a visible post.
+func (s *Service) Receive(source, target string) error {
+	src, err := url.Parse(source)
+	if err != nil || (src.Scheme != "http" && src.Scheme != "https") || src.Host == "" {
+		return fmt.Errorf("%w: source must be an http(s) URL", ErrInvalid)
+	}
+	if source == target {
+		return fmt.Errorf("%w: source and target must differ", ErrInvalid)
+	}
+	postID, ok := s.postID(target)
+	if !ok {
+		return fmt.Errorf("%w: target is not a post of this site", ErrInvalid)
+	}
+	posts, err := s.posts.GetPostsByIDs([]int64{postID})
+	if err != nil {
+		return err
+	}
+	if _, ok := posts[postID]; !ok {
+		return fmt.Errorf("%w: target post not found", ErrInvalid)
+	}
+	return s.enqueue(models.WebmentionVerify, source, []string{target})
+}
+
+// postID resolves a URL of one of our posts, its API or its ActivityPub
+// URL, to the id of the post.
+func (s *Service) postID(target string) (int64, bool) {
+	u, err := url.Parse(target)
+	if err != nil || !strings.EqualFold(u.Host, s.base.Host) || (u.Scheme != "http" && u.Scheme != "https") {
+		return 0, false
+	}
+	for _, re := range postPaths {
+		if m := re.FindStringSubmatch(u.Path); m != nil {
+			id, err := strconv.ParseInt(m[1], 10, 64)
+			return id, err == nil
+		}
+	}
+	return 0, false
+}
+
+func (s *Service) enqueue(kind, source string, targets []string) error {
+	if len(targets) == 0 {
+		return nil
+	}
+	if err := s.store.EnqueueWebmentionJobs(kind, source, targets); err != nil {
+		return err
+	}
+	s.log.Debug("webmentions queued", slog.String("fn", "webmention.enqueue"),
+		slog.String("kind", kind), slog.String("source", source), slog.Int("targets", len(targets)))
+	select {
+	case s.queued <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Process runs a queued job. Errors wrapping ErrRejected won't get better
+// by retrying.
+func (s *Service) Process(ctx context.Context, job *models.WebmentionJob) error {
+	switch job.Kind {
+	case models.WebmentionSend:
+		return s.send(ctx, job.Source, job.Target)
+	case models.WebmentionVerify:
+		return s.verify(ctx, job.Source, job.Target)
+	default:
+		return fmt.Errorf("%w: unknown job kind %q", ErrRejected, job.Kind)
+	}
+}